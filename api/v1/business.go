@@ -19,6 +19,9 @@ func BusinessRoute(app *fiber.App, ApiVersion string, validator *validator.Valid
 		businessUrlSec.Get("", businessController.GetAllBusiness)
 		businessUrlSec.Get("/:id", businessController.GetBusinessByID)
 		businessUrlSec.Patch("/business-id/:id", businessController.UpdateBusinessID)
+		businessUrlSec.Patch("/irn-template/:id", businessController.UpdateIRNTemplate)
+		businessUrlSec.Patch("/supplier-profile/:id", businessController.UpdateSupplierProfile)
+		businessUrlSec.Patch("/peppol/:id", businessController.UpdatePeppolSettings)
 	}
 
 	return app
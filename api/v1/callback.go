@@ -3,6 +3,7 @@ package v1
 import (
 	"einvoice-access-point/internal/controller/callback"
 	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
 	"einvoice-access-point/pkg/utility"
 	"fmt"
 
@@ -15,9 +16,23 @@ func CallbackRoute(app *fiber.App, ApiVersion string, validator *validator.Valid
 
 	callUrlSec := app.Group(fmt.Sprintf("%v", ApiVersion))
 	{
-		//callUrlSec.Get("/zoho/callback", callController.ZohoCallback)
-		callUrlSec.Get("/zoho/auth", callController.ZohoAuthCode)
-		callUrlSec.Get("/zoho/auth/access-token", callController.ZohoGetAcessToken)
+		callUrlSec.Get("/zoho/callback", callController.ZohoCallback)
+		// auth and connection are authorized per route: the group prefix is shared with the public zoho webhook
+		callUrlSec.Get("/zoho/auth", middleware.Authorize(db.Postgresql.DB()), callController.ZohoAuthCode)
+		callUrlSec.Get("/zoho/connection", middleware.Authorize(db.Postgresql.DB()), callController.GetZohoConnection)
+		callUrlSec.Get("/zoho/sync", middleware.Authorize(db.Postgresql.DB()), callController.GetZohoSync)
+		callUrlSec.Put("/zoho/sync", middleware.Authorize(db.Postgresql.DB()), callController.UpdateZohoSync)
+		callUrlSec.Post("/zoho/sync/backfill", middleware.Authorize(db.Postgresql.DB()), callController.BackfillZohoInvoices)
+		callUrlSec.Get("/odoo/sync", middleware.Authorize(db.Postgresql.DB()), callController.GetOdooSync)
+		callUrlSec.Put("/odoo/sync", middleware.Authorize(db.Postgresql.DB()), callController.UpdateOdooSync)
+		callUrlSec.Post("/odoo/sync/backfill", middleware.Authorize(db.Postgresql.DB()), callController.BackfillOdooInvoices)
+		callUrlSec.Get("/zoho/mapping", middleware.Authorize(db.Postgresql.DB()), callController.GetZohoMapping)
+		callUrlSec.Put("/zoho/mapping", middleware.Authorize(db.Postgresql.DB()), callController.SaveZohoMapping)
+		callUrlSec.Get("/platform/:name/callback", callController.PlatformCallback)
+		callUrlSec.Get("/platform/:name/auth", middleware.Authorize(db.Postgresql.DB()), callController.PlatformAuth)
+		callUrlSec.Get("/platform/:name/connection", middleware.Authorize(db.Postgresql.DB()), callController.GetPlatformConnection)
+		callUrlSec.Get("/platform/:name/mapping", middleware.Authorize(db.Postgresql.DB()), callController.GetPlatformMapping)
+		callUrlSec.Put("/platform/:name/mapping", middleware.Authorize(db.Postgresql.DB()), callController.SavePlatformMapping)
 	}
 
 	return app
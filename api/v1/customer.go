@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"einvoice-access-point/internal/controller/customer"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func CustomerRoute(app *fiber.App, ApiVersion string, validator *validator.Validate, db *database.Database, logger *utility.Logger) *fiber.App {
+	customerController := customer.Controller{Db: db, Validator: validator, Logger: logger}
+
+	customerUrlSec := app.Group(fmt.Sprintf("%v/customers", ApiVersion), middleware.Authorize(db.Postgresql.DB()))
+	{
+		customerUrlSec.Get("", customerController.GetCustomers)
+		customerUrlSec.Post("/import", customerController.ImportCustomers)
+		customerUrlSec.Post("/:id/verify", customerController.VerifyCustomer)
+		customerUrlSec.Delete("/:id", customerController.DeleteCustomer)
+	}
+
+	return app
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"einvoice-access-point/internal/controller/export"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ExportRoute(app *fiber.App, ApiVersion string, validator *validator.Validate, db *database.Database, logger *utility.Logger) *fiber.App {
+	exportController := export.Controller{Db: db, Validator: validator, Logger: logger}
+
+	exportUrlSec := app.Group(fmt.Sprintf("%v/exports", ApiVersion), middleware.Authorize(db.Postgresql.DB()))
+	{
+		exportUrlSec.Post("", exportController.CreateExport)
+		exportUrlSec.Get("", exportController.GetExports)
+		exportUrlSec.Get("/:id", exportController.GetExport)
+		exportUrlSec.Get("/:id/download", exportController.DownloadExport)
+	}
+
+	return app
+}
@@ -21,6 +21,16 @@ func InvoiceRoute(app *fiber.App, ApiVersion string, validator *validator.Valida
 		invoiceUrlUnSec.Post("/webhook", invoiceController.HandleZohoWebhook)
 	}
 
+	{
+		booksUrlUnSec := app.Group(fmt.Sprintf("%v/zoho-books", ApiVersion))
+		booksUrlUnSec.Post("/webhook", invoiceController.HandleZohoBooksWebhook)
+	}
+
+	{
+		platformUrlUnSec := app.Group(fmt.Sprintf("%v/platform", ApiVersion))
+		platformUrlUnSec.Post("/:name/webhook", invoiceController.HandlePlatformWebhook)
+	}
+
 	{
 		webhookUrl := app.Group(fmt.Sprintf("%v/webhook", ApiVersion))
 		webhookUrl.Post("/firs", invoiceController.FirsWebhook)
@@ -30,7 +40,18 @@ func InvoiceRoute(app *fiber.App, ApiVersion string, validator *validator.Valida
 		invoiceUrlSec.Get("/business/:business_id/:invoice_id", invoiceController.GetInvoiceDetails)
 		invoiceUrlSec.Post("/create", invoiceController.CreateInvoice)
 		invoiceUrlSec.Delete("/business/:business_id/:invoice_id", invoiceController.DeleteInvoice)
+		invoiceUrlSec.Post("/business/:business_id/:invoice_id/peppol", invoiceController.SendPeppolInvoice)
+		invoiceUrlSec.Get("/business/:business_id/:invoice_id/delivery", invoiceController.GetInvoiceDelivery)
+		invoiceUrlSec.Post("/business/:business_id/:invoice_id/delivery/resend", invoiceController.ResendInvoiceDelivery)
+		invoiceUrlSec.Get("/business/:business_id/:invoice_id/attachments", invoiceController.GetInvoiceAttachments)
+		invoiceUrlSec.Post("/business/:business_id/:invoice_id/attachments/retry", invoiceController.RetryInvoiceAttachments)
+		invoiceUrlSec.Get("/business/:business_id/:invoice_id/write-back", invoiceController.GetInvoiceWriteBack)
+		invoiceUrlSec.Post("/business/:business_id/:invoice_id/write-back/retry", invoiceController.RetryInvoiceWriteBack)
 		invoiceUrlSec.Post("/upload", invoiceController.UploadInvoice)
+		invoiceUrlSec.Post("/import", invoiceController.ImportInvoices)
+		invoiceUrlSec.Get("/import/mapping", invoiceController.GetImportMapping)
+		invoiceUrlSec.Put("/import/mapping", invoiceController.SaveImportMapping)
+		invoiceUrlSec.Get("/import/:import_id", invoiceController.GetImport)
 	}
 	{
 		invoiceUrlSec.Post("/validate-irn", invoiceController.ValidateIRN)
@@ -41,6 +62,7 @@ func InvoiceRoute(app *fiber.App, ApiVersion string, validator *validator.Valida
 		invoiceUrlSec.Patch("/update/:irn", invoiceController.UpdateInvoice)
 		invoiceUrlSec.Get("/confirm/:irn", invoiceController.ConfirmInvoice)
 		invoiceUrlSec.Get("/download/:irn", invoiceController.DownloadInvoice)
+		invoiceUrlSec.Get("/:irn/qr", invoiceController.GetInvoiceQRCode)
 
 		invoiceUrlSec.Post("/transmit/:irn", invoiceController.TransmitInvoice)
 		invoiceUrlSec.Get("/transmit/confirm/:irn", invoiceController.TransmitConfirmInvoice)
@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"einvoice-access-point/internal/controller/product"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ProductRoute(app *fiber.App, ApiVersion string, validator *validator.Validate, db *database.Database, logger *utility.Logger) *fiber.App {
+	productController := product.Controller{Db: db, Validator: validator, Logger: logger}
+
+	productUrlSec := app.Group(fmt.Sprintf("%v/products", ApiVersion), middleware.Authorize(db.Postgresql.DB()))
+	{
+		productUrlSec.Get("", productController.GetProducts)
+		productUrlSec.Post("/import", productController.ImportProducts)
+		productUrlSec.Delete("/:id", productController.DeleteProduct)
+	}
+
+	return app
+}
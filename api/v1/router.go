@@ -66,6 +66,11 @@ func Setup(logger *utility.Logger, validator *validator.Validate, db *database.D
 	BusinessRoute(r, ApiVersion, validator, db, logger)
 	CallbackRoute(r, ApiVersion, validator, db, logger)
 	InvoiceRoute(r, ApiVersion, validator, db, logger, keys)
+	SeriesRoute(r, ApiVersion, validator, db, logger)
+	ProductRoute(r, ApiVersion, validator, db, logger)
+	CustomerRoute(r, ApiVersion, validator, db, logger)
+	ExportRoute(r, ApiVersion, validator, db, logger)
+	SchemaRoute(r, validator, db, logger)
 	RegisterBaseRoutes(r, ApiVersion)
 
 	return r
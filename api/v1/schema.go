@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"einvoice-access-point/internal/controller/schema"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/utility"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SchemaRoute publishes the request schemas outside the versioned API so their $id stays stable
+func SchemaRoute(app *fiber.App, validator *validator.Validate, db *database.Database, logger *utility.Logger) *fiber.App {
+	schemaController := schema.Controller{Db: db, Validator: validator, Logger: logger}
+
+	app.Get("/schemas/invoice.json", schemaController.GetInvoiceSchema)
+
+	return app
+}
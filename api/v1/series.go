@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"einvoice-access-point/internal/controller/series"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SeriesRoute(app *fiber.App, ApiVersion string, validator *validator.Validate, db *database.Database, logger *utility.Logger) *fiber.App {
+	seriesController := series.Controller{Db: db, Validator: validator, Logger: logger}
+
+	seriesUrlSec := app.Group(fmt.Sprintf("%v/invoice-series", ApiVersion), middleware.Authorize(db.Postgresql.DB()))
+	{
+		seriesUrlSec.Get("", seriesController.GetAllSeries)
+		seriesUrlSec.Post("", seriesController.SaveSeries)
+		seriesUrlSec.Post("/:name/reserve", seriesController.ReserveInvoiceNumbers)
+	}
+
+	return app
+}
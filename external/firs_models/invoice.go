@@ -1,18 +1,20 @@
 package firs_models
 
+import "encoding/json"
+
 type InvoiceRequest struct {
-	InvoiceNumber               string                 `json:"invoice_number"`
-	BusinessID                  string                 `json:"business_id"`
-	IRN                         *string                `json:"irn"`
-	IssueDate                   string                 `json:"issue_date"`
-	DueDate                     *string                `json:"due_date,omitempty"`
-	IssueTime                   *string                `json:"issue_time,omitempty"`
-	InvoiceTypeCode             string                 `json:"invoice_type_code"`
-	PaymentStatus               *string                `json:"payment_status,omitempty"`
+	InvoiceNumber               string                 `json:"invoice_number" jsonschema:"description=Assigned from the default invoice series when omitted on upload"`
+	BusinessID                  string                 `json:"business_id" jsonschema:"required,format=uuid"`
+	IRN                         *string                `json:"irn" jsonschema:"oneof_type=string;null,description=Issued by the access point when omitted"`
+	IssueDate                   string                 `json:"issue_date" jsonschema:"required,format=date"`
+	DueDate                     *string                `json:"due_date,omitempty" jsonschema:"format=date"`
+	IssueTime                   *string                `json:"issue_time,omitempty" jsonschema:"pattern=^([01][0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9]$"`
+	InvoiceTypeCode             string                 `json:"invoice_type_code" jsonschema:"required"`
+	PaymentStatus               *string                `json:"payment_status,omitempty" jsonschema:"enum=PENDING,enum=PAID,enum=REJECTED"`
 	Note                        *string                `json:"note,omitempty"`
-	TaxPointDate                *string                `json:"tax_point_date,omitempty"`
-	DocumentCurrencyCode        string                 `json:"document_currency_code"`
-	TaxCurrencyCode             *string                `json:"tax_currency_code,omitempty"`
+	TaxPointDate                *string                `json:"tax_point_date,omitempty" jsonschema:"format=date"`
+	DocumentCurrencyCode        string                 `json:"document_currency_code" jsonschema:"required,pattern=^[A-Z]{3}$"`
+	TaxCurrencyCode             *string                `json:"tax_currency_code,omitempty" jsonschema:"pattern=^[A-Z]{3}$"`
 	AccountingCost              *string                `json:"accounting_cost,omitempty"`
 	BuyerReference              *string                `json:"buyer_reference,omitempty"`
 	InvoiceDeliveryPeriod       *InvoiceDeliveryPeriod `json:"invoice_delivery_period,omitempty"`
@@ -22,34 +24,53 @@ type InvoiceRequest struct {
 	ReceiptDocumentReference    *DocumentReference     `json:"receipt_document_reference,omitempty"`
 	OriginatorDocumentReference *DocumentReference     `json:"originator_document_reference,omitempty"`
 	ContractDocumentReference   *DocumentReference     `json:"contract_document_reference,omitempty"`
-	AdditionalDocumentReference []DocumentReference    `json:"_document_reference,omitempty"`
-	AccountingSupplierParty     Party                  `json:"accounting_supplier_party"`
-	AccountingCustomerParty     Party                  `json:"accounting_customer_party"`
+	AdditionalDocumentReference []DocumentReference    `json:"_document_reference,omitempty"` // additional_document_reference in the published schema
+	AccountingSupplierParty     Party                  `json:"accounting_supplier_party" jsonschema:"description=Filled from the business supplier profile when it is complete"`
+	AccountingCustomerParty     Party                  `json:"accounting_customer_party" jsonschema:"required"`
 	PayeeParty                  *Party                 `json:"payee_party,omitempty"`
 	TaxRepresentativeParty      *Party                 `json:"tax_representative_party,omitempty"`
-	ActualDeliveryDate          *string                `json:"actual_delivery_date,omitempty"`
+	ActualDeliveryDate          *string                `json:"actual_delivery_date,omitempty" jsonschema:"format=date"`
+	DeliveryAddress             *PostalAddress         `json:"delivery_address,omitempty"`
 	PaymentMeans                []PaymentMeans         `json:"payment_means,omitempty"`
 	PaymentTermsNote            *string                `json:"payment_terms_note,omitempty"`
 	AllowanceCharge             []AllowanceCharge      `json:"allowance_charge,omitempty"`
 	TaxTotal                    []TaxTotal             `json:"tax_total,omitempty"`
-	LegalMonetaryTotal          LegalMonetaryTotal     `json:"legal_monetary_total"`
-	InvoiceLine                 []InvoiceLine          `json:"invoice_line"`
+	LegalMonetaryTotal          LegalMonetaryTotal     `json:"legal_monetary_total" jsonschema:"required"`
+	InvoiceLine                 []InvoiceLine          `json:"invoice_line" jsonschema:"required,minItems=1"`
+}
+
+// UnmarshalJSON reads the additional document references under the name the published invoice schema gives them
+// as well as under the name FIRS uses
+func (r *InvoiceRequest) UnmarshalJSON(data []byte) error {
+	type invoiceRequest InvoiceRequest
+	aux := struct {
+		*invoiceRequest
+		AdditionalDocumentReference []DocumentReference `json:"additional_document_reference"`
+	}{invoiceRequest: (*invoiceRequest)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.AdditionalDocumentReference) > 0 {
+		r.AdditionalDocumentReference = aux.AdditionalDocumentReference
+	}
+	return nil
 }
 
 type InvoiceDeliveryPeriod struct {
-	StartDate string `json:"start_date"`
-	EndDate   string `json:"end_date"`
+	StartDate string `json:"start_date" jsonschema:"required,format=date"`
+	EndDate   string `json:"end_date" jsonschema:"required,format=date"`
 }
 
 type DocumentReference struct {
-	IRN       string `json:"irn"`
-	IssueDate string `json:"issue_date"`
+	IRN       string `json:"irn" jsonschema:"required,minLength=1"`
+	IssueDate string `json:"issue_date" jsonschema:"required,format=date"`
 }
 
 type Party struct {
 	PartyName           *string        `json:"party_name,omitempty"`
-	TIN                 string         `json:"tin"`
-	Email               string         `json:"email"`
+	TIN                 string         `json:"tin" jsonschema:"required,minLength=1"`
+	Email               string         `json:"email" jsonschema:"required,format=email"`
 	Telephone           *string        `json:"telephone,omitempty"`
 	BusinessDescription *string        `json:"business_description,omitempty"`
 	PostalAddress       *PostalAddress `json:"postal_address,omitempty"`
@@ -59,65 +80,65 @@ type PostalAddress struct {
 	StreetName  string `json:"street_name,omitempty"`
 	CityName    string `json:"city_name,omitempty"`
 	PostalZone  string `json:"postal_zone,omitempty"`
-	Country     string `json:"country,omitempty"`
-	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty" jsonschema:"pattern=^[A-Z]{2}$"`
+	CountryCode string `json:"country_code,omitempty" jsonschema:"pattern=^[A-Z]{2}$"`
 }
 
 type PaymentMeans struct {
-	PaymentMeansCode string `json:"payment_means_code"`
-	PaymentDueDate   string `json:"payment_due_date"`
+	PaymentMeansCode string `json:"payment_means_code" jsonschema:"required"`
+	PaymentDueDate   string `json:"payment_due_date" jsonschema:"format=date"`
 }
 
 type AllowanceCharge struct {
-	ChargeIndicator bool    `json:"charge_indicator"`
-	Amount          float64 `json:"amount"`
+	ChargeIndicator bool    `json:"charge_indicator" jsonschema:"required"`
+	Amount          float64 `json:"amount" jsonschema:"required,minimum=0"`
 }
 
 type TaxTotal struct {
-	TaxAmount   float64       `json:"tax_amount"`
+	TaxAmount   float64       `json:"tax_amount" jsonschema:"required,minimum=0"`
 	TaxSubtotal []TaxSubtotal `json:"tax_subtotal,omitempty"`
 }
 
 type TaxSubtotal struct {
-	TaxableAmount float64     `json:"taxable_amount"`
-	TaxAmount     float64     `json:"tax_amount"`
-	TaxCategory   TaxCategory `json:"tax_category"`
+	TaxableAmount float64     `json:"taxable_amount" jsonschema:"required,minimum=0"`
+	TaxAmount     float64     `json:"tax_amount" jsonschema:"required,minimum=0"`
+	TaxCategory   TaxCategory `json:"tax_category" jsonschema:"required"`
 }
 
 type TaxCategory struct {
-	ID      string  `json:"id"`
-	Percent float64 `json:"percent"`
+	ID      string  `json:"id" jsonschema:"required"`
+	Percent float64 `json:"percent" jsonschema:"required,minimum=0,maximum=100"`
 }
 
 type LegalMonetaryTotal struct {
-	LineExtensionAmount float64 `json:"line_extension_amount"`
-	TaxExclusiveAmount  float64 `json:"tax_exclusive_amount"`
-	TaxInclusiveAmount  float64 `json:"tax_inclusive_amount"`
-	PayableAmount       float64 `json:"payable_amount"`
+	LineExtensionAmount float64 `json:"line_extension_amount" jsonschema:"required,minimum=0"`
+	TaxExclusiveAmount  float64 `json:"tax_exclusive_amount" jsonschema:"required,minimum=0"`
+	TaxInclusiveAmount  float64 `json:"tax_inclusive_amount" jsonschema:"required,minimum=0"`
+	PayableAmount       float64 `json:"payable_amount" jsonschema:"required,minimum=0"`
 }
 
 type InvoiceLine struct {
-	HSNCode             string  `json:"hsn_code"`
-	ProductCategory     string  `json:"product_category"`
-	DiscountRate        float64 `json:"discount_rate"`
-	DiscountAmount      float64 `json:"discount_amount"`
-	FeeRate             float64 `json:"fee_rate"`
-	FeeAmount           float64 `json:"fee_amount"`
-	InvoicedQuantity    int     `json:"invoiced_quantity"`
-	LineExtensionAmount float64 `json:"line_extension_amount"`
-	Item                Item    `json:"item"`
-	Price               Price   `json:"price"`
+	HSNCode             string  `json:"hsn_code" jsonschema:"required,minLength=1"`
+	ProductCategory     string  `json:"product_category" jsonschema:"required,minLength=1"`
+	DiscountRate        float64 `json:"discount_rate" jsonschema:"minimum=0,maximum=100"`
+	DiscountAmount      float64 `json:"discount_amount" jsonschema:"minimum=0"`
+	FeeRate             float64 `json:"fee_rate" jsonschema:"minimum=0,maximum=100"`
+	FeeAmount           float64 `json:"fee_amount" jsonschema:"minimum=0"`
+	InvoicedQuantity    int     `json:"invoiced_quantity" jsonschema:"required,minimum=1"`
+	LineExtensionAmount float64 `json:"line_extension_amount" jsonschema:"required,minimum=0"`
+	Item                Item    `json:"item" jsonschema:"required"`
+	Price               Price   `json:"price" jsonschema:"required"`
 }
 
 type Item struct {
-	Name                      string  `json:"name"`
+	Name                      string  `json:"name" jsonschema:"required,minLength=1"`
 	Description               string  `json:"description"`
 	SellersItemIdentification *string `json:"sellers_item_identification,omitempty"`
 }
 
 type Price struct {
-	PriceAmount  float64 `json:"price_amount"`
-	BaseQuantity int     `json:"base_quantity"`
+	PriceAmount  float64 `json:"price_amount" jsonschema:"required,minimum=0"`
+	BaseQuantity int     `json:"base_quantity" jsonschema:"minimum=1"`
 	PriceUnit    string  `json:"price_unit"`
 }
 
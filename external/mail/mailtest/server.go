@@ -0,0 +1,142 @@
+// Package mailtest provides a local SMTP server standing in for the mail server in tests
+package mailtest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Received is a message the server accepted
+type Received struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Server is a plain SMTP server on a local port. It accepts every message except for the recipients it is told to
+// reject, and keeps what it accepted.
+type Server struct {
+	Host string
+	Port string
+
+	listener net.Listener
+	mu       sync.Mutex
+	rejects  map[string]string
+	received []Received
+}
+
+// NewServer starts a server on a free local port
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	s := &Server{Host: host, Port: port, listener: listener, rejects: map[string]string{}}
+	go s.serve()
+	return s, nil
+}
+
+// Reject makes the server answer RCPT TO for the address with the reply, such as "550 5.1.1 user unknown"
+func (s *Server) Reject(address, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejects[strings.ToLower(address)] = reply
+}
+
+// Received returns the messages accepted so far
+func (s *Server) Received() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Received(nil), s.received...)
+}
+
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	var current Received
+	reply("220 localhost ESMTP mailtest")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(verb, "EHLO"), strings.HasPrefix(verb, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(verb, "MAIL FROM:"):
+			current = Received{From: address(line[len("MAIL FROM:"):])}
+			reply("250 2.1.0 ok")
+		case strings.HasPrefix(verb, "RCPT TO:"):
+			to := address(line[len("RCPT TO:"):])
+			s.mu.Lock()
+			rejection, rejected := s.rejects[strings.ToLower(to)]
+			s.mu.Unlock()
+			if rejected {
+				reply(rejection)
+				continue
+			}
+			current.To = append(current.To, to)
+			reply("250 2.1.5 ok")
+		case verb == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			current.Data = data.String()
+			s.mu.Lock()
+			s.received = append(s.received, current)
+			s.mu.Unlock()
+			reply("250 2.0.0 queued")
+		case verb == "RSET", verb == "NOOP":
+			reply("250 ok")
+		case verb == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func address(arg string) string {
+	arg = strings.TrimSpace(arg)
+	if i := strings.Index(arg, " "); i >= 0 {
+		arg = arg[:i]
+	}
+	return strings.Trim(arg, "<>")
+}
@@ -0,0 +1,266 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"einvoice-access-point/pkg/config"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var ErrMailNotConfigured = errors.New("mail server is not configured")
+
+// Attachment is a file sent along with a message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an email with a plain text and an HTML body
+type Message struct {
+	To          string
+	Subject     string
+	TextBody    string
+	HTMLBody    string
+	Attachments []Attachment
+}
+
+// Sender delivers messages and returns the Message-ID they were sent with
+type Sender interface {
+	Send(msg Message) (string, error)
+}
+
+// SMTPError is a rejection reported by the mail server
+type SMTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *SMTPError) Error() string {
+	return fmt.Sprintf("smtp %d: %s", e.Code, e.Message)
+}
+
+// Permanent reports whether the server rejected the message for good, such as an unknown recipient
+func (e *SMTPError) Permanent() bool {
+	return e.Code >= 500
+}
+
+// SMTPSender sends messages through an SMTP server, upgrading to TLS when the server offers STARTTLS
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	FromName string
+	Timeout  time.Duration
+}
+
+// DefaultSender returns the sender configured through the MAIL_* settings
+func DefaultSender() (Sender, error) {
+	configs := config.GetConfig()
+	if configs == nil || configs.Mail.Server == "" {
+		return nil, ErrMailNotConfigured
+	}
+
+	from := configs.Mail.From
+	if from == "" {
+		from = configs.Mail.Username
+	}
+
+	return &SMTPSender{
+		Host:     configs.Mail.Server,
+		Port:     configs.Mail.Port,
+		Username: configs.Mail.Username,
+		Password: configs.Mail.Password,
+		From:     from,
+		FromName: configs.Mail.FromName,
+		Timeout:  30 * time.Second,
+	}, nil
+}
+
+func (s *SMTPSender) Send(msg Message) (string, error) {
+	port := s.Port
+	if port == "" {
+		port = "25"
+	}
+
+	messageID, body, err := s.build(msg)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.Host, port), s.Timeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	if s.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return "", smtpError(err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return "", fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if ok, _ := client.Extension("AUTH"); ok && s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return "", smtpError(err)
+		}
+	}
+
+	if err := client.Mail(s.From); err != nil {
+		return "", smtpError(err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return "", smtpError(err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", smtpError(err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return "", smtpError(err)
+	}
+	if err := w.Close(); err != nil {
+		return "", smtpError(err)
+	}
+
+	_ = client.Quit()
+	return messageID, nil
+}
+
+// build renders the message as multipart/mixed with the bodies in a multipart/alternative part
+func (s *SMTPSender) build(msg Message) (string, []byte, error) {
+	messageID, err := newMessageID(s.From)
+	if err != nil {
+		return "", nil, err
+	}
+
+	from := mail.Address{Name: s.FromName, Address: s.From}
+	to := mail.Address{Address: msg.To}
+
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + to.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + mixed.Boundary(),
+	}
+	var head bytes.Buffer
+	head.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	var alternative bytes.Buffer
+	alt := multipart.NewWriter(&alternative)
+	for _, body := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.TextBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	} {
+		if body.content == "" {
+			continue
+		}
+		part, err := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {body.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if err := writeBase64(part, []byte(body.content)); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := alt.Close(); err != nil {
+		return "", nil, err
+	}
+
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := part.Write(alternative.Bytes()); err != nil {
+		return "", nil, err
+	}
+
+	for _, attachment := range msg.Attachments {
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return "", nil, err
+	}
+
+	return messageID, append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := w.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := w.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+func newMessageID(from string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain), nil
+}
+
+// smtpError keeps the reply code of server rejections so callers can tell bounces from transient failures
+func smtpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return &SMTPError{Code: protoErr.Code, Message: protoErr.Msg}
+	}
+	return err
+}
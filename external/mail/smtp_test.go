@@ -0,0 +1,146 @@
+package mail_test
+
+import (
+	"einvoice-access-point/external/mail"
+	"einvoice-access-point/external/mail/mailtest"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSender(t *testing.T) (*mail.SMTPSender, *mailtest.Server) {
+	t.Helper()
+
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return &mail.SMTPSender{
+		Host:     server.Host,
+		Port:     server.Port,
+		From:     "invoices@example.com",
+		FromName: "Acme Ltd",
+		Timeout:  5 * time.Second,
+	}, server
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	sender, server := newSender(t)
+
+	messageID, err := sender.Send(mail.Message{
+		To:       "buyer@example.com",
+		Subject:  "Invoice INV-001",
+		TextBody: "Please find your invoice attached.",
+		HTMLBody: "<p>Please find your invoice attached.</p>",
+		Attachments: []mail.Attachment{
+			{Filename: "INV-001.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.HasSuffix(messageID, "@example.com>") {
+		t.Errorf("message id = %q, want one on the sender domain", messageID)
+	}
+
+	received := server.Received()
+	if len(received) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(received))
+	}
+	if received[0].From != "invoices@example.com" {
+		t.Errorf("MAIL FROM = %q, want invoices@example.com", received[0].From)
+	}
+	if len(received[0].To) != 1 || received[0].To[0] != "buyer@example.com" {
+		t.Errorf("RCPT TO = %v, want [buyer@example.com]", received[0].To)
+	}
+
+	msg, err := netmail.ReadMessage(strings.NewReader(received[0].Data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	if got := msg.Header.Get("Message-ID"); got != messageID {
+		t.Errorf("Message-ID header = %q, want %q", got, messageID)
+	}
+	if got := msg.Header.Get("Subject"); got != "Invoice INV-001" {
+		t.Errorf("Subject header = %q, want Invoice INV-001", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+
+	var types, filenames []string
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, contentType)
+		if name := part.FileName(); name != "" {
+			filenames = append(filenames, name)
+		}
+	}
+	if strings.Join(types, ",") != "multipart/alternative,application/pdf" {
+		t.Errorf("parts = %v, want the bodies and the pdf", types)
+	}
+	if strings.Join(filenames, ",") != "INV-001.pdf" {
+		t.Errorf("attachments = %v, want [INV-001.pdf]", filenames)
+	}
+}
+
+func TestSMTPSenderRejection(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		code      int
+		permanent bool
+	}{
+		{"unknown recipient", "550 5.1.1 user unknown", 550, true},
+		{"mailbox busy", "451 4.3.0 try again later", 451, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, server := newSender(t)
+			server.Reject("buyer@example.com", tt.reply)
+
+			_, err := sender.Send(mail.Message{To: "buyer@example.com", Subject: "Invoice", TextBody: "Invoice"})
+
+			var smtpErr *mail.SMTPError
+			if !errors.As(err, &smtpErr) {
+				t.Fatalf("Send() error = %v, want an SMTPError", err)
+			}
+			if smtpErr.Code != tt.code || smtpErr.Permanent() != tt.permanent {
+				t.Errorf("SMTPError = %d permanent %v, want %d permanent %v", smtpErr.Code, smtpErr.Permanent(), tt.code, tt.permanent)
+			}
+			if len(server.Received()) != 0 {
+				t.Errorf("server received a message for a rejected recipient")
+			}
+		})
+	}
+}
+
+func TestSMTPSenderUnreachable(t *testing.T) {
+	sender, server := newSender(t)
+	server.Close()
+
+	_, err := sender.Send(mail.Message{To: "buyer@example.com", Subject: "Invoice", TextBody: "Invoice"})
+
+	var smtpErr *mail.SMTPError
+	if err == nil || errors.As(err, &smtpErr) {
+		t.Errorf("Send() error = %v, want a connection failure", err)
+	}
+}
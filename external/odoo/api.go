@@ -0,0 +1,147 @@
+package odoo
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// Models of the records the connector reads and writes
+const (
+	ModelMove       = "account.move"
+	ModelMoveLine   = "account.move.line"
+	ModelTax        = "account.tax"
+	ModelPartner    = "res.partner"
+	ModelProduct    = "product.product"
+	ModelAttachment = "ir.attachment"
+)
+
+// SearchMovesQuery selects the customer invoices and credit notes that were posted or cancelled. Moves are selected
+// by last change when WrittenSince is set and by invoice date otherwise.
+type SearchMovesQuery struct {
+	WrittenSince *time.Time
+	DateFrom     string
+	DateTo       string
+	Limit        int
+	Offset       int
+}
+
+func (q SearchMovesQuery) domain() []interface{} {
+	domain := []interface{}{
+		[]interface{}{"move_type", "in", []string{MoveTypeInvoice, MoveTypeRefund}},
+		[]interface{}{"state", "in", []string{StatePosted, StateCancel}},
+	}
+	if q.WrittenSince != nil {
+		// inclusive, so moves written in the same second as the last one pulled are not missed
+		domain = append(domain, []interface{}{"write_date", ">=", q.WrittenSince.UTC().Format(TimeLayout)})
+	}
+	if q.DateFrom != "" {
+		domain = append(domain, []interface{}{"invoice_date", ">=", q.DateFrom})
+	}
+	if q.DateTo != "" {
+		domain = append(domain, []interface{}{"invoice_date", "<=", q.DateTo})
+	}
+	return domain
+}
+
+// SearchMoves returns the moves the query selects, oldest first
+func (c *Client) SearchMoves(query SearchMovesQuery) ([]Move, error) {
+	order := "invoice_date asc, id asc"
+	if query.WrittenSince != nil {
+		order = "write_date asc, id asc"
+	}
+
+	var moves []Move
+	err := c.ExecuteKw(ModelMove, "search_read", []interface{}{query.domain()}, map[string]interface{}{
+		"fields": MoveFields,
+		"order":  order,
+		"limit":  query.Limit,
+		"offset": query.Offset,
+	}, &moves)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search odoo invoices: %w", err)
+	}
+	return moves, nil
+}
+
+// GetMove reads a move by ID
+func (c *Client) GetMove(id int) (*Move, error) {
+	var moves []Move
+	if err := c.read(ModelMove, []int{id}, MoveFields, &moves); err != nil {
+		return nil, err
+	}
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("odoo invoice %d not found", id)
+	}
+	return &moves[0], nil
+}
+
+func (c *Client) GetMoveLines(ids []int) ([]MoveLine, error) {
+	var lines []MoveLine
+	err := c.read(ModelMoveLine, ids, MoveLineFields, &lines)
+	return lines, err
+}
+
+func (c *Client) GetTaxes(ids []int) ([]Tax, error) {
+	var taxes []Tax
+	err := c.read(ModelTax, ids, TaxFields, &taxes)
+	return taxes, err
+}
+
+func (c *Client) GetPartner(id int) (*Partner, error) {
+	var partners []Partner
+	if err := c.read(ModelPartner, []int{id}, PartnerFields, &partners); err != nil {
+		return nil, err
+	}
+	if len(partners) == 0 {
+		return nil, fmt.Errorf("odoo partner %d not found", id)
+	}
+	return &partners[0], nil
+}
+
+func (c *Client) GetProducts(ids []int) ([]Product, error) {
+	var products []Product
+	err := c.read(ModelProduct, ids, ProductFields, &products)
+	return products, err
+}
+
+// read reads the fields of the records that the model has
+func (c *Client) read(model string, ids []int, fields []string, result interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	available, err := c.availableFields(model, fields)
+	if err != nil {
+		return fmt.Errorf("failed to read odoo %s fields: %w", model, err)
+	}
+	if err := c.ExecuteKw(model, "read", []interface{}{ids}, map[string]interface{}{"fields": available}, result); err != nil {
+		return fmt.Errorf("failed to read odoo %s: %w", model, err)
+	}
+	return nil
+}
+
+// WriteMove sets fields of a move. Custom fields can be written on posted moves.
+func (c *Client) WriteMove(id int, values map[string]interface{}) error {
+	var ok bool
+	if err := c.ExecuteKw(ModelMove, "write", []interface{}{[]int{id}, values}, nil, &ok); err != nil {
+		return fmt.Errorf("failed to update odoo invoice %d: %w", id, err)
+	}
+	return nil
+}
+
+// AttachToMove attaches a file to a move, where it is listed in the chatter of the invoice
+func (c *Client) AttachToMove(id int, filename, contentType string, data []byte) error {
+	attachment := Attachment{
+		Name:     filename,
+		Datas:    base64.StdEncoding.EncodeToString(data),
+		Mimetype: contentType,
+		ResModel: ModelMove,
+		ResID:    id,
+	}
+
+	var attachmentID int
+	if err := c.ExecuteKw(ModelAttachment, "create", []interface{}{attachment}, nil, &attachmentID); err != nil {
+		return fmt.Errorf("failed to attach file to odoo invoice %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package odoo
+
+import (
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	ErrNotConfigured = errors.New("odoo instance URL, database, username and API key must be set")
+	// ErrAuthenticationFailed is returned when Odoo does not accept the username and API key
+	ErrAuthenticationFailed = errors.New("odoo rejected the username or API key")
+)
+
+var requestID int64
+
+// Client calls the external JSON-RPC API of an Odoo database as the user the API key belongs to
+type Client struct {
+	URL      string
+	Database string
+	Username string
+	APIKey   string
+
+	uid    int
+	fields map[string]map[string]bool
+}
+
+// NewClient returns a client of the database on the Odoo instance at the URL
+func NewClient(baseURL, database, username, apiKey string) (*Client, error) {
+	if baseURL == "" || database == "" || username == "" || apiKey == "" {
+		return nil, ErrNotConfigured
+	}
+	return &Client{
+		URL:      strings.TrimRight(baseURL, "/"),
+		Database: database,
+		Username: username,
+		APIKey:   apiKey,
+	}, nil
+}
+
+// call invokes a method of a JSON-RPC service and decodes its result
+func (c *Client) call(service, method string, args []interface{}, result interface{}) error {
+	config := utility.RequestConfig{
+		URL:     c.URL + "/jsonrpc",
+		Headers: map[string]string{"Content-Type": "application/json", "Accept": "application/json"},
+		Body: rpcRequest{
+			JSONRPC: "2.0",
+			Method:  "call",
+			Params:  rpcParams{Service: service, Method: method, Args: args},
+			ID:      atomic.AddInt64(&requestID, 1),
+		},
+	}
+
+	var rpcResp rpcResponse
+
+	resp, err := utility.PostRequest(utility.DefaultHTTPClient, config, &rpcResp)
+	if err != nil {
+		return fmt.Errorf("failed to call odoo %s.%s: %w", service, method, err)
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("odoo API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// Authenticate logs in with the API key and keeps the ID of the user for the calls that follow
+func (c *Client) Authenticate() (int, error) {
+	if c.uid != 0 {
+		return c.uid, nil
+	}
+
+	// Odoo answers false instead of a user ID when the login fails
+	var result json.RawMessage
+	if err := c.call("common", "authenticate", []interface{}{c.Database, c.Username, c.APIKey, map[string]interface{}{}}, &result); err != nil {
+		return 0, err
+	}
+	var uid int
+	if err := json.Unmarshal(result, &uid); err != nil || uid == 0 {
+		return 0, ErrAuthenticationFailed
+	}
+	c.uid = uid
+	return uid, nil
+}
+
+// ExecuteKw calls a method of a model with positional and keyword arguments
+func (c *Client) ExecuteKw(model, method string, args []interface{}, kwargs map[string]interface{}, result interface{}) error {
+	uid, err := c.Authenticate()
+	if err != nil {
+		return err
+	}
+	if args == nil {
+		args = []interface{}{}
+	}
+	if kwargs == nil {
+		kwargs = map[string]interface{}{}
+	}
+	return c.call("object", "execute_kw", []interface{}{c.Database, uid, c.APIKey, model, method, args, kwargs}, result)
+}
+
+// availableFields narrows the fields to the ones the model has, since fields such as the HS code of a product only
+// exist when the module adding them is installed
+func (c *Client) availableFields(model string, fields []string) ([]string, error) {
+	known, ok := c.fields[model]
+	if !ok {
+		var definitions map[string]json.RawMessage
+		if err := c.ExecuteKw(model, "fields_get", nil, map[string]interface{}{"attributes": []string{"type"}}, &definitions); err != nil {
+			return nil, err
+		}
+		known = map[string]bool{}
+		for name := range definitions {
+			known[name] = true
+		}
+		if c.fields == nil {
+			c.fields = map[string]map[string]bool{}
+		}
+		c.fields[model] = known
+	}
+
+	available := []string{}
+	for _, field := range fields {
+		if known[field] {
+			available = append(available, field)
+		}
+	}
+	return available, nil
+}
@@ -0,0 +1,260 @@
+package odoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Move types of the customer documents sent to FIRS
+const (
+	MoveTypeInvoice = "out_invoice"
+	MoveTypeRefund  = "out_refund"
+)
+
+// States of an account.move
+const (
+	StateDraft  = "draft"
+	StatePosted = "posted"
+	StateCancel = "cancel"
+)
+
+// Payment states of a posted account.move
+const (
+	PaymentStateNotPaid   = "not_paid"
+	PaymentStatePartial   = "partial"
+	PaymentStateInPayment = "in_payment"
+	PaymentStatePaid      = "paid"
+	PaymentStateReversed  = "reversed"
+)
+
+// Display types of account.move.line. Only product lines carry amounts; sections and notes only carry text.
+const (
+	DisplayTypeProduct = "product"
+	DisplayTypeSection = "line_section"
+	DisplayTypeNote    = "line_note"
+)
+
+// Default custom fields of account.move the FIRS results are written to. Odoo requires the names of custom fields to start with x_.
+const (
+	DefaultIRNField    = "x_firs_irn"
+	DefaultQRField     = "x_firs_qr"
+	DefaultStatusField = "x_firs_status"
+	DefaultErrorField  = "x_firs_error"
+)
+
+// TimeLayout is the layout Odoo uses for datetime fields, always in UTC
+const TimeLayout = "2006-01-02 15:04:05"
+
+// String is a char or text field. Odoo sends false instead of an empty string.
+type String string
+
+func (s *String) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("false")) || bytes.Equal(data, []byte("null")) {
+		*s = ""
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = String(value)
+	return nil
+}
+
+// Many2One is a reference to another record, sent by Odoo as [id, display name] or false when it is not set
+type Many2One struct {
+	ID   int
+	Name string
+}
+
+func (m *Many2One) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("false")) || bytes.Equal(data, []byte("null")) {
+		*m = Many2One{}
+		return nil
+	}
+	var pair []interface{}
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("odoo many2one value %s is not an id and a name", string(data))
+	}
+	id, ok := pair[0].(float64)
+	if !ok {
+		return fmt.Errorf("odoo many2one value %s has no id", string(data))
+	}
+	name, _ := pair[1].(string)
+	*m = Many2One{ID: int(id), Name: name}
+	return nil
+}
+
+func (m Many2One) MarshalJSON() ([]byte, error) {
+	if m.ID == 0 {
+		return []byte("false"), nil
+	}
+	return json.Marshal([]interface{}{m.ID, m.Name})
+}
+
+// Move is a customer invoice or credit note (account.move)
+type Move struct {
+	ID              int      `json:"id"`
+	Name            String   `json:"name"` // the number, "/" until the move is posted
+	MoveType        string   `json:"move_type"`
+	State           string   `json:"state"`
+	PaymentState    String   `json:"payment_state"`
+	Ref             String   `json:"ref"`
+	InvoiceOrigin   String   `json:"invoice_origin"`
+	InvoiceDate     String   `json:"invoice_date"`
+	InvoiceDateDue  String   `json:"invoice_date_due"`
+	PartnerID       Many2One `json:"partner_id"`
+	CurrencyID      Many2One `json:"currency_id"` // the name is the ISO code of the currency
+	PaymentTermID   Many2One `json:"invoice_payment_term_id"`
+	ReversedEntryID Many2One `json:"reversed_entry_id"` // the invoice a credit note reverses
+	AmountUntaxed   float64  `json:"amount_untaxed"`
+	AmountTax       float64  `json:"amount_tax"`
+	AmountTotal     float64  `json:"amount_total"`
+	AmountResidual  float64  `json:"amount_residual"`
+	InvoiceLineIDs  []int    `json:"invoice_line_ids"`
+	Narration       String   `json:"narration"`
+	CreateDate      String   `json:"create_date"`
+	WriteDate       String   `json:"write_date"`
+}
+
+// MoveFields are the fields read for a Move
+var MoveFields = []string{
+	"name", "move_type", "state", "payment_state", "ref", "invoice_origin", "invoice_date", "invoice_date_due",
+	"partner_id", "currency_id", "invoice_payment_term_id", "reversed_entry_id", "amount_untaxed", "amount_tax",
+	"amount_total", "amount_residual", "invoice_line_ids", "narration", "create_date", "write_date",
+}
+
+// Number is the number of the move, or its ID while it has none
+func (m Move) Number() string {
+	if m.Name != "" && m.Name != "/" {
+		return string(m.Name)
+	}
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m Move) IsRefund() bool {
+	return m.MoveType == MoveTypeRefund
+}
+
+// MoveLine is a line of a move (account.move.line)
+type MoveLine struct {
+	ID            int      `json:"id"`
+	Name          String   `json:"name"`
+	DisplayType   String   `json:"display_type"`
+	ProductID     Many2One `json:"product_id"`
+	ProductUomID  Many2One `json:"product_uom_id"`
+	Quantity      float64  `json:"quantity"`
+	PriceUnit     float64  `json:"price_unit"`
+	Discount      float64  `json:"discount"` // percentage
+	PriceSubtotal float64  `json:"price_subtotal"`
+	PriceTotal    float64  `json:"price_total"`
+	TaxIDs        []int    `json:"tax_ids"`
+}
+
+// MoveLineFields are the fields read for a MoveLine
+var MoveLineFields = []string{
+	"name", "display_type", "product_id", "product_uom_id", "quantity", "price_unit", "discount",
+	"price_subtotal", "price_total", "tax_ids",
+}
+
+// Tax is a sales tax (account.tax)
+type Tax struct {
+	ID           int     `json:"id"`
+	Name         String  `json:"name"`
+	Amount       float64 `json:"amount"`
+	AmountType   string  `json:"amount_type"` // percent, fixed, division or group
+	PriceInclude bool    `json:"price_include"`
+}
+
+// TaxFields are the fields read for a Tax
+var TaxFields = []string{"name", "amount", "amount_type", "price_include"}
+
+// Partner is a customer (res.partner)
+type Partner struct {
+	ID          int    `json:"id"`
+	Name        String `json:"name"`
+	VAT         String `json:"vat"`
+	Email       String `json:"email"`
+	Phone       String `json:"phone"`
+	Mobile      String `json:"mobile"`
+	Street      String `json:"street"`
+	Street2     String `json:"street2"`
+	City        String `json:"city"`
+	Zip         String `json:"zip"`
+	CountryCode String `json:"country_code"`
+}
+
+// PartnerFields are the fields read for a Partner
+var PartnerFields = []string{"name", "vat", "email", "phone", "mobile", "street", "street2", "city", "zip", "country_code"}
+
+// Product is a product variant (product.product). The HS code is the one the delivery module adds to products.
+type Product struct {
+	ID          int      `json:"id"`
+	Name        String   `json:"name"`
+	DefaultCode String   `json:"default_code"`
+	HSCode      String   `json:"hs_code"`
+	CategID     Many2One `json:"categ_id"`
+}
+
+// ProductFields are the fields read for a Product
+var ProductFields = []string{"name", "default_code", "hs_code", "categ_id"}
+
+// InvoiceDocument is a move with the records it refers to, as stored for conversion
+type InvoiceDocument struct {
+	Move     Move       `json:"move"`
+	Lines    []MoveLine `json:"lines"`
+	Taxes    []Tax      `json:"taxes"`
+	Partner  *Partner   `json:"partner,omitempty"`
+	Products []Product  `json:"products"`
+}
+
+// Attachment is a file attached to a record (ir.attachment)
+type Attachment struct {
+	Name     string `json:"name"`
+	Datas    string `json:"datas"` // base64 content
+	Mimetype string `json:"mimetype"`
+	ResModel string `json:"res_model"`
+	ResID    int    `json:"res_id"`
+}
+
+// rpcRequest is a JSON-RPC 2.0 call of a service method
+type rpcRequest struct {
+	JSONRPC string    `json:"jsonrpc"`
+	Method  string    `json:"method"`
+	Params  rpcParams `json:"params"`
+	ID      int64     `json:"id"`
+}
+
+type rpcParams struct {
+	Service string        `json:"service"`
+	Method  string        `json:"method"`
+	Args    []interface{} `json:"args"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+}
+
+// RPCError is the error of a failed JSON-RPC call
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Name    string `json:"name"`
+		Message string `json:"message"`
+	} `json:"data"`
+}
+
+func (e *RPCError) Error() string {
+	if e.Data.Message != "" {
+		return fmt.Sprintf("odoo error %d: %s (%s)", e.Code, e.Data.Message, e.Data.Name)
+	}
+	return fmt.Sprintf("odoo error %d: %s", e.Code, e.Message)
+}
@@ -0,0 +1,87 @@
+package peppol
+
+import (
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/utility"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrAccessPointNotConfigured = errors.New("peppol access point is not configured")
+
+// SendRequest is a PEPPOL BIS document addressed between two participant identifiers ("scheme:id")
+type SendRequest struct {
+	SenderID   string
+	ReceiverID string
+	DocumentID string
+	Document   []byte
+}
+
+type SendResult struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+}
+
+// AccessPoint hands documents to a PEPPOL access point for delivery over the network
+type AccessPoint interface {
+	Send(req SendRequest) (*SendResult, error)
+}
+
+// HTTPAccessPoint submits documents to an access point exposing a JSON submission API
+type HTTPAccessPoint struct {
+	URL    string
+	APIKey string
+	Client utility.HTTPClient
+}
+
+type httpSendPayload struct {
+	SenderID   string `json:"sender_id"`
+	ReceiverID string `json:"receiver_id"`
+	DocumentID string `json:"document_id"`
+	Document   string `json:"document"`
+}
+
+// DefaultAccessPoint returns the access point configured through PEPPOL_AP_URL and PEPPOL_AP_KEY
+func DefaultAccessPoint() (AccessPoint, error) {
+	configs := config.GetConfig()
+	if configs == nil || configs.Peppol.AccessPointUrl == "" {
+		return nil, ErrAccessPointNotConfigured
+	}
+
+	return &HTTPAccessPoint{
+		URL:    configs.Peppol.AccessPointUrl,
+		APIKey: configs.Peppol.AccessPointKey,
+		Client: utility.DefaultHTTPClient,
+	}, nil
+}
+
+func (ap *HTTPAccessPoint) Send(req SendRequest) (*SendResult, error) {
+	requestConfig := utility.RequestConfig{
+		URL: fmt.Sprintf("%v/documents", ap.URL),
+		Headers: map[string]string{
+			"x-api-key": ap.APIKey,
+		},
+		Body: httpSendPayload{
+			SenderID:   req.SenderID,
+			ReceiverID: req.ReceiverID,
+			DocumentID: req.DocumentID,
+			Document:   base64.StdEncoding.EncodeToString(req.Document),
+		},
+	}
+
+	resp, err := utility.PostRequest(ap.Client, requestConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit document to peppol access point: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("peppol access point rejected document with status %d: %s", resp.StatusCode, string(resp.Body))
+	}
+
+	var result SendResult
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode peppol access point response: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,169 @@
+package quickbooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/utility"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MINOR_VERSION is the API minor version requests are made against
+const MINOR_VERSION = "73"
+
+// companyURL is the endpoint of an entity of the company (realm)
+func companyURL(realmID, entity string) string {
+	apiURL := strings.TrimRight(config.GetConfig().QuickBooks.ApiUrl, "/")
+	return fmt.Sprintf("%s/v3/company/%s/%s", apiURL, url.PathEscape(realmID), entity)
+}
+
+func headers(accessToken string) map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + accessToken,
+		"Accept":        "application/json",
+	}
+}
+
+// apiError describes a failed response, with the messages of the QuickBooks fault when it returned one
+func apiError(resp *utility.Response) error {
+	var body struct {
+		Fault Fault `json:"Fault"`
+	}
+	if json.Unmarshal(resp.Body, &body) == nil && len(body.Fault.Error) > 0 {
+		messages := make([]string, 0, len(body.Fault.Error))
+		for _, e := range body.Fault.Error {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Message, e.Detail))
+		}
+		return fmt.Errorf("quickbooks API error: %v, %s", resp.StatusCode, strings.Join(messages, "; "))
+	}
+	return fmt.Errorf("quickbooks API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+}
+
+// getEntity reads one entity of the company into the response, which wraps it under the entity name
+func getEntity(accessToken, realmID, entity, id string, response interface{}) error {
+	apiURL := fmt.Sprintf("%s/%s?minorversion=%s", companyURL(realmID, strings.ToLower(entity)), url.PathEscape(id), MINOR_VERSION)
+
+	config := utility.RequestConfig{
+		URL:     apiURL,
+		Headers: headers(accessToken),
+	}
+
+	resp, err := utility.GetRequest(utility.DefaultHTTPClient, config, response)
+	if err != nil {
+		return fmt.Errorf("failed to get QuickBooks %s: %w", entity, err)
+	}
+	if resp.StatusCode != 200 {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// GetInvoice reads an invoice of the company
+func GetInvoice(accessToken, realmID, invoiceID string) (*Invoice, error) {
+	var resp struct {
+		Invoice Invoice `json:"Invoice"`
+	}
+	if err := getEntity(accessToken, realmID, "Invoice", invoiceID, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Invoice, nil
+}
+
+// GetCustomer reads a customer of the company
+func GetCustomer(accessToken, realmID, customerID string) (*Customer, error) {
+	var resp struct {
+		Customer Customer `json:"Customer"`
+	}
+	if err := getEntity(accessToken, realmID, "Customer", customerID, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Customer, nil
+}
+
+// GetPayment reads a payment of the company, whose lines name the invoices it was applied to
+func GetPayment(accessToken, realmID, paymentID string) (*Payment, error) {
+	var resp struct {
+		Payment Payment `json:"Payment"`
+	}
+	if err := getEntity(accessToken, realmID, "Payment", paymentID, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Payment, nil
+}
+
+// UpdateInvoice applies a sparse update to an invoice. The sync token must be the one the invoice was last read with.
+func UpdateInvoice(accessToken, realmID string, update InvoiceUpdate) error {
+	update.Sparse = true
+	apiURL := fmt.Sprintf("%s?minorversion=%s", companyURL(realmID, "invoice"), MINOR_VERSION)
+
+	config := utility.RequestConfig{
+		URL:     apiURL,
+		Headers: headers(accessToken),
+		Body:    update,
+	}
+
+	var qbResp map[string]interface{}
+
+	resp, err := utility.PostRequest(utility.DefaultHTTPClient, config, &qbResp)
+	if err != nil {
+		return fmt.Errorf("failed to update QuickBooks invoice: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// UploadInvoiceAttachment attaches a file to an invoice. The attachment is included when the invoice is emailed from QuickBooks.
+func UploadInvoiceAttachment(accessToken, realmID, invoiceID, filename, contentType string, data []byte) error {
+	apiURL := fmt.Sprintf("%s?minorversion=%s", companyURL(realmID, "upload"), MINOR_VERSION)
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"AttachableRef": []map[string]interface{}{
+			{
+				"EntityRef":     map[string]string{"type": "Invoice", "value": invoiceID},
+				"IncludeOnSend": true,
+			},
+		},
+		"FileName":    filename,
+		"ContentType": contentType,
+	})
+	if err != nil {
+		return err
+	}
+
+	config := utility.RequestConfig{
+		URL:     apiURL,
+		Headers: headers(accessToken),
+	}
+	files := []utility.MultipartFile{
+		{Field: "file_metadata_01", Filename: "attachment.json", ContentType: "application/json", Data: metadata},
+		{Field: "file_content_01", Filename: filename, ContentType: contentType, Data: data},
+	}
+
+	var qbResp map[string]interface{}
+
+	resp, err := utility.PostMultipartFilesRequest(utility.DefaultHTTPClient, config, nil, files, &qbResp)
+	if err != nil {
+		return fmt.Errorf("failed to upload QuickBooks invoice attachment: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// VerifySignature checks the intuit-signature header, the base64 HMAC-SHA256 of the raw body keyed with the webhook verifier token
+func VerifySignature(body []byte, verifier, signature string) bool {
+	if verifier == "" || signature == "" {
+		return false
+	}
+	hash := hmac.New(sha256.New, []byte(verifier))
+	hash.Write(body)
+	expected := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
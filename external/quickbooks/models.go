@@ -0,0 +1,208 @@
+package quickbooks
+
+// Line detail types of a QuickBooks invoice
+const (
+	SalesItemLine   = "SalesItemLineDetail"
+	GroupLine       = "GroupLineDetail"
+	DiscountLine    = "DiscountLineDetail"
+	SubTotalLine    = "SubTotalLineDetail"
+	DescriptionLine = "DescriptionOnly"
+)
+
+// ShippingItemID is the item reference QuickBooks gives the shipping line of an invoice
+const ShippingItemID = "SHIPPING_ITEM_ID"
+
+// NonTaxableCode is the tax code of lines QuickBooks does not tax
+const NonTaxableCode = "NON"
+
+// WebhookPayload is a QuickBooks webhook. One notification can carry the changes of several companies.
+type WebhookPayload struct {
+	EventNotifications []EventNotification `json:"eventNotifications"`
+}
+
+// EventNotification lists the entities changed in one company
+type EventNotification struct {
+	RealmID         string          `json:"realmId" validate:"required"`
+	DataChangeEvent DataChangeEvent `json:"dataChangeEvent"`
+}
+
+type DataChangeEvent struct {
+	Entities []ChangedEntity `json:"entities" validate:"dive"`
+}
+
+// ChangedEntity is a created, updated, deleted or voided entity. The webhook names it only, the entity is read from the API.
+type ChangedEntity struct {
+	Name        string `json:"name" validate:"required"`
+	ID          string `json:"id" validate:"required"`
+	Operation   string `json:"operation" validate:"required"`
+	LastUpdated string `json:"lastUpdated"`
+}
+
+type Ref struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+}
+
+type Address struct {
+	Line1                  string `json:"Line1,omitempty"`
+	Line2                  string `json:"Line2,omitempty"`
+	Line3                  string `json:"Line3,omitempty"`
+	City                   string `json:"City,omitempty"`
+	CountrySubDivisionCode string `json:"CountrySubDivisionCode,omitempty"`
+	PostalCode             string `json:"PostalCode,omitempty"`
+	Country                string `json:"Country,omitempty"`
+}
+
+type EmailAddress struct {
+	Address string `json:"Address"`
+}
+
+type PhoneNumber struct {
+	FreeFormNumber string `json:"FreeFormNumber"`
+}
+
+type MemoRef struct {
+	Value string `json:"value"`
+}
+
+type CustomField struct {
+	DefinitionID string `json:"DefinitionId"`
+	Name         string `json:"Name,omitempty"`
+	Type         string `json:"Type"`
+	StringValue  string `json:"StringValue"`
+}
+
+type MetaData struct {
+	CreateTime      string `json:"CreateTime"`
+	LastUpdatedTime string `json:"LastUpdatedTime"`
+}
+
+type Invoice struct {
+	ID                    string        `json:"Id"`
+	SyncToken             string        `json:"SyncToken"`
+	DocNumber             string        `json:"DocNumber"`
+	TxnDate               string        `json:"TxnDate"`
+	DueDate               string        `json:"DueDate"`
+	CurrencyRef           Ref           `json:"CurrencyRef"`
+	CustomerRef           Ref           `json:"CustomerRef"`
+	Line                  []Line        `json:"Line"`
+	TxnTaxDetail          TxnTaxDetail  `json:"TxnTaxDetail"`
+	TotalAmt              float64       `json:"TotalAmt"`
+	Balance               float64       `json:"Balance"`
+	ApplyTaxAfterDiscount bool          `json:"ApplyTaxAfterDiscount"`
+	GlobalTaxCalculation  string        `json:"GlobalTaxCalculation"` // TaxExcluded, TaxInclusive or NotApplicable
+	BillEmail             *EmailAddress `json:"BillEmail,omitempty"`
+	BillAddr              *Address      `json:"BillAddr,omitempty"`
+	ShipAddr              *Address      `json:"ShipAddr,omitempty"`
+	CustomerMemo          *MemoRef      `json:"CustomerMemo,omitempty"`
+	PrivateNote           string        `json:"PrivateNote"`
+	SalesTermRef          *Ref          `json:"SalesTermRef,omitempty"`
+	CustomField           []CustomField `json:"CustomField"`
+	MetaData              MetaData      `json:"MetaData"`
+}
+
+type Line struct {
+	ID                  string               `json:"Id"`
+	LineNum             int                  `json:"LineNum"`
+	Description         string               `json:"Description"`
+	Amount              float64              `json:"Amount"`
+	DetailType          string               `json:"DetailType"`
+	SalesItemLineDetail *SalesItemLineDetail `json:"SalesItemLineDetail,omitempty"`
+	GroupLineDetail     *GroupLineDetail     `json:"GroupLineDetail,omitempty"`
+	DiscountLineDetail  *DiscountLineDetail  `json:"DiscountLineDetail,omitempty"`
+}
+
+type SalesItemLineDetail struct {
+	ItemRef      Ref     `json:"ItemRef"`
+	UnitPrice    float64 `json:"UnitPrice"`
+	Qty          float64 `json:"Qty"`
+	TaxCodeRef   *Ref    `json:"TaxCodeRef,omitempty"`
+	DiscountAmt  float64 `json:"DiscountAmt"`
+	DiscountRate float64 `json:"DiscountRate"`
+}
+
+type GroupLineDetail struct {
+	GroupItemRef Ref     `json:"GroupItemRef"`
+	Quantity     float64 `json:"Quantity"`
+	Line         []Line  `json:"Line"`
+}
+
+type DiscountLineDetail struct {
+	PercentBased    bool    `json:"PercentBased"`
+	DiscountPercent float64 `json:"DiscountPercent"`
+}
+
+type TxnTaxDetail struct {
+	TotalTax float64   `json:"TotalTax"`
+	TaxLine  []TaxLine `json:"TaxLine"`
+}
+
+type TaxLine struct {
+	Amount        float64       `json:"Amount"`
+	TaxLineDetail TaxLineDetail `json:"TaxLineDetail"`
+}
+
+type TaxLineDetail struct {
+	TaxRateRef       Ref     `json:"TaxRateRef"`
+	PercentBased     bool    `json:"PercentBased"`
+	TaxPercent       float64 `json:"TaxPercent"`
+	NetAmountTaxable float64 `json:"NetAmountTaxable"`
+}
+
+type Customer struct {
+	ID               string        `json:"Id"`
+	DisplayName      string        `json:"DisplayName"`
+	CompanyName      string        `json:"CompanyName"`
+	PrimaryEmailAddr *EmailAddress `json:"PrimaryEmailAddr,omitempty"`
+	PrimaryPhone     *PhoneNumber  `json:"PrimaryPhone,omitempty"`
+	Mobile           *PhoneNumber  `json:"Mobile,omitempty"`
+	BillAddr         *Address      `json:"BillAddr,omitempty"`
+}
+
+type Payment struct {
+	ID   string        `json:"Id"`
+	Line []PaymentLine `json:"Line"`
+}
+
+type PaymentLine struct {
+	Amount    float64     `json:"Amount"`
+	LinkedTxn []LinkedTxn `json:"LinkedTxn"`
+}
+
+type LinkedTxn struct {
+	TxnID   string `json:"TxnId"`
+	TxnType string `json:"TxnType"`
+}
+
+// InvoiceDocument is a QuickBooks invoice stored with the customer it was read with, which the converter falls back on for contact details
+type InvoiceDocument struct {
+	Invoice  Invoice   `json:"invoice"`
+	Customer *Customer `json:"customer,omitempty"`
+}
+
+// InvoiceUpdate is a sparse update of an invoice: only the fields sent are changed
+type InvoiceUpdate struct {
+	ID          string        `json:"Id"`
+	SyncToken   string        `json:"SyncToken"`
+	Sparse      bool          `json:"sparse"`
+	PrivateNote *string       `json:"PrivateNote,omitempty"`
+	CustomField []CustomField `json:"CustomField,omitempty"`
+}
+
+// Fault is the error body of the QuickBooks API
+type Fault struct {
+	Error []struct {
+		Message string `json:"Message"`
+		Detail  string `json:"Detail"`
+		Code    string `json:"code"`
+	} `json:"Error"`
+	Type string `json:"type"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Error        string `json:"error"`
+}
@@ -0,0 +1,228 @@
+package xero
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/utility"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned when the tenant has no document with the ID
+var ErrNotFound = errors.New("xero document not found")
+
+func apiURL(path string) string {
+	return strings.TrimRight(config.GetConfig().Xero.ApiUrl, "/") + path
+}
+
+// accountingURL is the endpoint of a resource of the accounting API
+func accountingURL(path string) string {
+	return apiURL("/api.xro/2.0/" + path)
+}
+
+// documentPath is the path of an invoice or credit note, which Xero serves from separate endpoints
+func documentPath(creditNote bool, id string) string {
+	if creditNote {
+		return "CreditNotes/" + url.PathEscape(id)
+	}
+	return "Invoices/" + url.PathEscape(id)
+}
+
+func headers(accessToken, tenantID string) map[string]string {
+	headers := map[string]string{
+		"Authorization": "Bearer " + accessToken,
+		"Accept":        "application/json",
+	}
+	if tenantID != "" {
+		headers["Xero-tenant-id"] = tenantID
+	}
+	return headers
+}
+
+// apiError describes a failed response, with the messages of the Xero error when it returned one
+func apiError(resp *utility.Response) error {
+	if resp.StatusCode == 404 {
+		return ErrNotFound
+	}
+
+	var body Error
+	if json.Unmarshal(resp.Body, &body) == nil {
+		var messages []string
+		for _, element := range body.Elements {
+			for _, validation := range element.ValidationErrors {
+				messages = append(messages, validation.Message)
+			}
+		}
+		if len(messages) == 0 && body.Detail != "" {
+			messages = append(messages, body.Detail)
+		}
+		if len(messages) == 0 && body.Message != "" {
+			messages = append(messages, body.Message)
+		}
+		if len(messages) > 0 {
+			return fmt.Errorf("xero API error: %v, %s", resp.StatusCode, strings.Join(messages, "; "))
+		}
+	}
+	return fmt.Errorf("xero API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+}
+
+// GetConnections lists the organisations the access token was granted for
+func GetConnections(accessToken string) ([]Connection, error) {
+	config := utility.RequestConfig{
+		URL:     apiURL("/connections"),
+		Headers: headers(accessToken, ""),
+	}
+
+	var connections []Connection
+
+	resp, err := utility.GetRequest(utility.DefaultHTTPClient, config, &connections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Xero connections: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, apiError(resp)
+	}
+	return connections, nil
+}
+
+// GetInvoice reads an invoice or, for a credit note, the credit note of the tenant
+func GetInvoice(accessToken, tenantID, id string, creditNote bool) (*Invoice, error) {
+	config := utility.RequestConfig{
+		URL:     accountingURL(documentPath(creditNote, id)),
+		Headers: headers(accessToken, tenantID),
+	}
+
+	var xeroResp struct {
+		Invoices    []Invoice `json:"Invoices"`
+		CreditNotes []Invoice `json:"CreditNotes"`
+	}
+
+	resp, err := utility.GetRequest(utility.DefaultHTTPClient, config, &xeroResp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Xero invoice: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, apiError(resp)
+	}
+
+	documents := xeroResp.Invoices
+	if creditNote {
+		documents = xeroResp.CreditNotes
+	}
+	if len(documents) == 0 {
+		return nil, ErrNotFound
+	}
+	return &documents[0], nil
+}
+
+// GetContact reads a contact of the tenant
+func GetContact(accessToken, tenantID, contactID string) (*Contact, error) {
+	config := utility.RequestConfig{
+		URL:     accountingURL("Contacts/" + url.PathEscape(contactID)),
+		Headers: headers(accessToken, tenantID),
+	}
+
+	var xeroResp struct {
+		Contacts []Contact `json:"Contacts"`
+	}
+
+	resp, err := utility.GetRequest(utility.DefaultHTTPClient, config, &xeroResp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Xero contact: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, apiError(resp)
+	}
+	if len(xeroResp.Contacts) == 0 {
+		return nil, ErrNotFound
+	}
+	return &xeroResp.Contacts[0], nil
+}
+
+// UpdateReference replaces the reference of an invoice or credit note. Xero keeps at most 255 characters.
+func UpdateReference(accessToken, tenantID, id string, creditNote bool, reference string) error {
+	document := map[string]string{"InvoiceID": id, "Reference": reference}
+	body := map[string]interface{}{"Invoices": []map[string]string{document}}
+	if creditNote {
+		document = map[string]string{"CreditNoteID": id, "Reference": reference}
+		body = map[string]interface{}{"CreditNotes": []map[string]string{document}}
+	}
+
+	config := utility.RequestConfig{
+		URL:     accountingURL(documentPath(creditNote, id)),
+		Headers: headers(accessToken, tenantID),
+		Body:    body,
+	}
+
+	var xeroResp map[string]interface{}
+
+	resp, err := utility.PostRequest(utility.DefaultHTTPClient, config, &xeroResp)
+	if err != nil {
+		return fmt.Errorf("failed to update Xero invoice: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// AddHistoryNote adds a note to the history of an invoice or credit note
+func AddHistoryNote(accessToken, tenantID, id string, creditNote bool, details string) error {
+	config := utility.RequestConfig{
+		URL:     accountingURL(documentPath(creditNote, id) + "/History"),
+		Headers: headers(accessToken, tenantID),
+		Body:    map[string][]HistoryRecord{"HistoryRecords": {{Details: details}}},
+	}
+
+	var xeroResp map[string]interface{}
+
+	resp, err := utility.PutRequest(utility.DefaultHTTPClient, config, &xeroResp)
+	if err != nil {
+		return fmt.Errorf("failed to add Xero history note: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// UploadAttachment attaches a file to an invoice or credit note. Attachments of invoices are shown to the
+// customer on the online invoice.
+func UploadAttachment(accessToken, tenantID, id string, creditNote bool, filename, contentType string, data []byte) error {
+	apiURL := accountingURL(documentPath(creditNote, id) + "/Attachments/" + url.PathEscape(filename))
+	if !creditNote {
+		apiURL += "?IncludeOnline=true"
+	}
+
+	config := utility.RequestConfig{
+		URL:     apiURL,
+		Headers: headers(accessToken, tenantID),
+	}
+
+	var xeroResp map[string]interface{}
+
+	resp, err := utility.PutBytesRequest(utility.DefaultHTTPClient, config, contentType, data, &xeroResp)
+	if err != nil {
+		return fmt.Errorf("failed to upload Xero attachment: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// VerifySignature checks the x-xero-signature header, the base64 HMAC-SHA256 of the raw body keyed with the webhook key
+func VerifySignature(body []byte, key, signature string) bool {
+	if key == "" || signature == "" {
+		return false
+	}
+	hash := hmac.New(sha256.New, []byte(key))
+	hash.Write(body)
+	expected := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
@@ -0,0 +1,120 @@
+package xero
+
+import (
+	"einvoice-access-point/pkg/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	XERO_SCOPE             = "openid profile email offline_access accounting.transactions accounting.contacts accounting.attachments"
+	XERO_DEFAULT_AUTH_URL  = "https://login.xero.com/identity/connect/authorize"
+	XERO_DEFAULT_TOKEN_URL = "https://identity.xero.com/connect/token"
+)
+
+var ErrClientNotConfigured = errors.New("xero client id, secret and redirect url are not configured")
+
+// TokenError is an error returned by the Xero identity token endpoint
+type TokenError struct {
+	Code string
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("error from Xero: %s", e.Code)
+}
+
+// Revoked reports whether the refresh token expired or was revoked, after which the organisation has to be connected again
+func (e *TokenError) Revoked() bool {
+	return e.Code == "invalid_grant"
+}
+
+// GenerateAuthURL returns the Xero consent URL carrying the state
+func GenerateAuthURL(state string) (string, error) {
+	configs := config.GetConfig().Xero
+	if configs.ClientID == "" || configs.RedirectUrl == "" {
+		return "", ErrClientNotConfigured
+	}
+
+	authURL := configs.AuthUrl
+	if authURL == "" {
+		authURL = XERO_DEFAULT_AUTH_URL
+	}
+
+	params := url.Values{}
+	params.Add("client_id", configs.ClientID)
+	params.Add("scope", XERO_SCOPE)
+	params.Add("redirect_uri", configs.RedirectUrl)
+	params.Add("response_type", "code")
+	params.Add("state", state)
+
+	return fmt.Sprintf("%s?%s", authURL, params.Encode()), nil
+}
+
+// ExchangeCodeForTokens trades the authorization code of the callback for the tokens of the user, which are good for every organisation they granted access to
+func ExchangeCodeForTokens(code string) (*TokenResponse, error) {
+	params := url.Values{}
+	params.Add("grant_type", "authorization_code")
+	params.Add("code", code)
+	params.Add("redirect_uri", config.GetConfig().Xero.RedirectUrl)
+
+	return requestToken(params)
+}
+
+// RefreshAccessToken trades the refresh token for a new access token. Xero rotates refresh tokens, so the one returned replaces it.
+func RefreshAccessToken(refreshToken string) (*TokenResponse, error) {
+	params := url.Values{}
+	params.Add("grant_type", "refresh_token")
+	params.Add("refresh_token", refreshToken)
+
+	return requestToken(params)
+}
+
+// requestToken posts a token grant to the token endpoint, authenticated with the configured client credentials
+func requestToken(params url.Values) (*TokenResponse, error) {
+	configs := config.GetConfig().Xero
+	if configs.ClientID == "" || configs.ClientSecret == "" {
+		return nil, ErrClientNotConfigured
+	}
+
+	tokenURL := configs.TokenUrl
+	if tokenURL == "" {
+		tokenURL = XERO_DEFAULT_TOKEN_URL
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(configs.ClientID, configs.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResp TokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("xero token endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+	if tokenResp.Error != "" {
+		return nil, &TokenError{Code: tokenResp.Error}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("xero token endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return &tokenResp, nil
+}
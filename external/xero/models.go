@@ -0,0 +1,209 @@
+package xero
+
+// Types of the Xero invoices and credit notes. Only sales documents are sent to FIRS.
+const (
+	TypeSalesInvoice    = "ACCREC"
+	TypeSalesCreditNote = "ACCRECCREDIT"
+)
+
+// Statuses of Xero invoices and credit notes
+const (
+	StatusDraft      = "DRAFT"
+	StatusSubmitted  = "SUBMITTED"
+	StatusAuthorised = "AUTHORISED"
+	StatusPaid       = "PAID"
+	StatusVoided     = "VOIDED"
+	StatusDeleted    = "DELETED"
+)
+
+// Line amount types tell whether the line amounts include tax
+const (
+	LineAmountsExclusive = "Exclusive"
+	LineAmountsInclusive = "Inclusive"
+	LineAmountsNoTax     = "NoTax"
+)
+
+// Event categories of a Xero webhook
+const (
+	EventCategoryInvoice    = "INVOICE"
+	EventCategoryCreditNote = "CREDITNOTE"
+)
+
+// WebhookPayload is a Xero webhook. Events of several tenants can come in one payload; the intent to receive
+// Xero sends when the webhook is set up carries no events at all.
+type WebhookPayload struct {
+	Events             []Event `json:"events"`
+	FirstEventSequence int     `json:"firstEventSequence"`
+	LastEventSequence  int     `json:"lastEventSequence"`
+	Entropy            string  `json:"entropy"`
+}
+
+// Event names a created or updated resource. The webhook carries no data, the resource is read from the API.
+type Event struct {
+	ResourceURL   string `json:"resourceUrl"`
+	ResourceID    string `json:"resourceId" validate:"required"`
+	EventDateUTC  string `json:"eventDateUtc"`
+	EventType     string `json:"eventType" validate:"required"`
+	EventCategory string `json:"eventCategory" validate:"required"`
+	TenantID      string `json:"tenantId" validate:"required"`
+	TenantType    string `json:"tenantType"`
+}
+
+// TenantEvents are the events of one tenant
+type TenantEvents struct {
+	TenantID string  `json:"tenantId" validate:"required"`
+	Events   []Event `json:"events" validate:"dive"`
+}
+
+// Connection is an organisation the user granted access to. Its tenant ID names the organisation on every request.
+type Connection struct {
+	ID         string `json:"id"`
+	TenantID   string `json:"tenantId"`
+	TenantType string `json:"tenantType"`
+	TenantName string `json:"tenantName"`
+}
+
+type ContactRef struct {
+	ContactID string `json:"ContactID"`
+	Name      string `json:"Name,omitempty"`
+}
+
+type Address struct {
+	AddressType  string `json:"AddressType"` // POBOX or STREET
+	AddressLine1 string `json:"AddressLine1,omitempty"`
+	AddressLine2 string `json:"AddressLine2,omitempty"`
+	AddressLine3 string `json:"AddressLine3,omitempty"`
+	AddressLine4 string `json:"AddressLine4,omitempty"`
+	City         string `json:"City,omitempty"`
+	Region       string `json:"Region,omitempty"`
+	PostalCode   string `json:"PostalCode,omitempty"`
+	Country      string `json:"Country,omitempty"`
+}
+
+type Phone struct {
+	PhoneType        string `json:"PhoneType"` // DEFAULT, DDI, MOBILE or FAX
+	PhoneNumber      string `json:"PhoneNumber,omitempty"`
+	PhoneAreaCode    string `json:"PhoneAreaCode,omitempty"`
+	PhoneCountryCode string `json:"PhoneCountryCode,omitempty"`
+}
+
+type Contact struct {
+	ContactID    string    `json:"ContactID"`
+	Name         string    `json:"Name"`
+	EmailAddress string    `json:"EmailAddress,omitempty"`
+	TaxNumber    string    `json:"TaxNumber,omitempty"`
+	Addresses    []Address `json:"Addresses,omitempty"`
+	Phones       []Phone   `json:"Phones,omitempty"`
+}
+
+type ItemRef struct {
+	ItemID string `json:"ItemID"`
+	Code   string `json:"Code"`
+	Name   string `json:"Name"`
+}
+
+type LineItem struct {
+	LineItemID     string   `json:"LineItemID"`
+	Description    string   `json:"Description"`
+	Quantity       float64  `json:"Quantity"`
+	UnitAmount     float64  `json:"UnitAmount"`
+	ItemCode       string   `json:"ItemCode,omitempty"`
+	Item           *ItemRef `json:"Item,omitempty"`
+	AccountCode    string   `json:"AccountCode,omitempty"`
+	TaxType        string   `json:"TaxType,omitempty"`
+	TaxAmount      float64  `json:"TaxAmount"`
+	LineAmount     float64  `json:"LineAmount"`
+	DiscountRate   float64  `json:"DiscountRate,omitempty"`
+	DiscountAmount float64  `json:"DiscountAmount,omitempty"`
+}
+
+type InvoiceRef struct {
+	InvoiceID     string `json:"InvoiceID"`
+	InvoiceNumber string `json:"InvoiceNumber,omitempty"`
+}
+
+// Allocation applies a credit note to an invoice
+type Allocation struct {
+	Invoice InvoiceRef `json:"Invoice"`
+	Amount  float64    `json:"Amount"`
+	Date    string     `json:"Date,omitempty"`
+}
+
+// Invoice is a Xero invoice or credit note. Both share their shape apart from the ID and number fields and the
+// allocations of credit notes.
+type Invoice struct {
+	Type             string       `json:"Type"`
+	InvoiceID        string       `json:"InvoiceID,omitempty"`
+	InvoiceNumber    string       `json:"InvoiceNumber,omitempty"`
+	CreditNoteID     string       `json:"CreditNoteID,omitempty"`
+	CreditNoteNumber string       `json:"CreditNoteNumber,omitempty"`
+	Reference        string       `json:"Reference,omitempty"`
+	Contact          ContactRef   `json:"Contact"`
+	DateString       string       `json:"DateString"`
+	DueDateString    string       `json:"DueDateString,omitempty"`
+	Status           string       `json:"Status"`
+	LineAmountTypes  string       `json:"LineAmountTypes"`
+	LineItems        []LineItem   `json:"LineItems"`
+	SubTotal         float64      `json:"SubTotal"`
+	TotalTax         float64      `json:"TotalTax"`
+	Total            float64      `json:"Total"`
+	TotalDiscount    float64      `json:"TotalDiscount,omitempty"`
+	AmountDue        float64      `json:"AmountDue"`
+	AmountPaid       float64      `json:"AmountPaid"`
+	RemainingCredit  float64      `json:"RemainingCredit,omitempty"`
+	CurrencyCode     string       `json:"CurrencyCode"`
+	Allocations      []Allocation `json:"Allocations,omitempty"`
+	UpdatedDateUTC   string       `json:"UpdatedDateUTC,omitempty"`
+}
+
+// ID is the invoice ID of an invoice and the credit note ID of a credit note
+func (i Invoice) ID() string {
+	if i.Type == TypeSalesCreditNote {
+		return i.CreditNoteID
+	}
+	return i.InvoiceID
+}
+
+// Number is the invoice number of an invoice and the credit note number of a credit note
+func (i Invoice) Number() string {
+	if i.Type == TypeSalesCreditNote {
+		return i.CreditNoteNumber
+	}
+	return i.InvoiceNumber
+}
+
+// IsCreditNote reports whether the document is a sales credit note
+func (i Invoice) IsCreditNote() bool {
+	return i.Type == TypeSalesCreditNote
+}
+
+// InvoiceDocument is a Xero invoice or credit note stored with the contact it was read with, which the converter
+// falls back on for contact details
+type InvoiceDocument struct {
+	Invoice Invoice  `json:"invoice"`
+	Contact *Contact `json:"contact,omitempty"`
+}
+
+type HistoryRecord struct {
+	Details string `json:"Details"`
+}
+
+// Error is the error body of the Xero API
+type Error struct {
+	Title    string `json:"Title"`
+	Detail   string `json:"Detail"`
+	Message  string `json:"Message"`
+	Elements []struct {
+		ValidationErrors []struct {
+			Message string `json:"Message"`
+		} `json:"ValidationErrors"`
+	} `json:"Elements"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Error        string `json:"error"`
+}
@@ -0,0 +1,87 @@
+package zoho
+
+import (
+	"einvoice-access-point/pkg/config"
+	"fmt"
+	"net/url"
+)
+
+// API is one of the Zoho products invoices are received from. Both share the invoice shape, OAuth and
+// write-back and differ in their base URL, scope and how the organisation is named on each request.
+type API struct {
+	Platform string // key of the product in the business platform configs
+	Scope    string
+	// OrgHeader carries the organisation ID next to the organization_id query parameter, blank when the product only reads the parameter
+	OrgHeader string
+	IRNField  string // api name of the custom field the IRN is written back to, unless the business maps another
+	QRField   string // api name of the custom field the signed QR payload is written back to, unless the business maps another
+	// StatusField and ErrorField are the custom fields the FIRS processing status and its last error are written to
+	StatusField string
+	ErrorField  string
+	apiURL      func(config.ZOHO) string
+}
+
+var (
+	InvoiceAPI = API{
+		Platform:    "zoho",
+		Scope:       ZOHO_SCOPE,
+		OrgHeader:   "X-com-zoho-invoice-organizationid",
+		IRNField:    "cf_irn",
+		QRField:     "cf_qr_code",
+		StatusField: "cf_firs_status",
+		ErrorField:  "cf_firs_error",
+		apiURL:      func(c config.ZOHO) string { return c.ZohoApiUrl },
+	}
+	BooksAPI = API{
+		Platform:    "zoho_books",
+		Scope:       ZOHO_BOOKS_SCOPE,
+		IRNField:    "cf_irn",
+		QRField:     "cf_qr_code",
+		StatusField: "cf_firs_status",
+		ErrorField:  "cf_firs_error",
+		apiURL:      func(c config.ZOHO) string { return c.BooksApiUrl },
+	}
+)
+
+// APIFor returns the Zoho product of a platform config key
+func APIFor(platform string) (API, error) {
+	switch platform {
+	case InvoiceAPI.Platform:
+		return InvoiceAPI, nil
+	case BooksAPI.Platform:
+		return BooksAPI, nil
+	}
+	return API{}, fmt.Errorf("unknown zoho platform %q", platform)
+}
+
+// InvoicesURL is the invoices endpoint on the API domain of the organisation's datacenter.
+// The configured URL supplies the path and is used as is when no API domain is known.
+func (a API) InvoicesURL(apiDomain string) string {
+	apiURL := a.apiURL(config.GetConfig().Zoho)
+	if apiDomain == "" {
+		return apiURL
+	}
+
+	configured, err := url.Parse(apiURL)
+	if err != nil {
+		return apiURL
+	}
+	domain, err := url.Parse(apiDomain)
+	if err != nil || domain.Host == "" {
+		return apiURL
+	}
+
+	configured.Scheme = domain.Scheme
+	configured.Host = domain.Host
+	return configured.String()
+}
+
+func (a API) headers(accessToken, orgID string) map[string]string {
+	headers := map[string]string{
+		"Authorization": "Zoho-oauthtoken " + accessToken,
+	}
+	if a.OrgHeader != "" {
+		headers[a.OrgHeader] = orgID
+	}
+	return headers
+}
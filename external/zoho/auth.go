@@ -1,7 +1,9 @@
 package zoho
 
 import (
+	"einvoice-access-point/pkg/config"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,37 +12,111 @@ import (
 )
 
 const (
-	ZOHO_REDIRECT_URL = "http://localhost:8091/api/v1/zoho/callback"
+	ZOHO_SCOPE                   = "ZohoInvoice.fullaccess.all"
+	ZOHO_BOOKS_SCOPE             = "ZohoBooks.fullaccess.all"
+	ZOHO_DEFAULT_ACCOUNTS_SERVER = "https://accounts.zoho.com"
 )
 
+var ErrClientNotConfigured = errors.New("zoho client id, secret and redirect url are not configured")
+
+// revokedTokenErrors are the token endpoint errors after which the grant can only be replaced by connecting again
+var revokedTokenErrors = map[string]bool{
+	"invalid_code":  true,
+	"invalid_grant": true,
+	"access_denied": true,
+}
+
+// TokenError is an error returned by the Zoho token endpoint
+type TokenError struct {
+	Code string
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("error from Zoho: %s", e.Code)
+}
+
+// Revoked reports whether the refresh token was revoked or is no longer valid
+func (e *TokenError) Revoked() bool {
+	return revokedTokenErrors[e.Code]
+}
+
+// accountsServers are the Zoho accounts servers of every datacenter. The callback names the one
+// that issued the grant, and tokens must be exchanged and refreshed against that same server.
+var accountsServers = map[string]bool{
+	"accounts.zoho.com":     true,
+	"accounts.zoho.eu":      true,
+	"accounts.zoho.in":      true,
+	"accounts.zoho.com.au":  true,
+	"accounts.zoho.jp":      true,
+	"accounts.zoho.uk":      true,
+	"accounts.zoho.sa":      true,
+	"accounts.zohocloud.ca": true,
+	"accounts.zoho.com.cn":  true,
+}
+
+// DefaultAccountsServer is where the consent flow starts; Zoho sends users of other datacenters on to theirs
+func DefaultAccountsServer() string {
+	if configs := config.GetConfig(); configs != nil && configs.Zoho.AccountsUrl != "" {
+		return strings.TrimSuffix(configs.Zoho.AccountsUrl, "/")
+	}
+	return ZOHO_DEFAULT_ACCOUNTS_SERVER
+}
+
+// ResolveAccountsServer checks the accounts-server returned on the callback against the known Zoho datacenters
+// so authorization codes and client secrets are never posted anywhere else. A blank value means the default server.
+func ResolveAccountsServer(raw string) (string, error) {
+	if raw == "" {
+		return DefaultAccountsServer(), nil
+	}
+
+	server, err := url.Parse(raw)
+	if err != nil || server.Scheme != "https" || !accountsServers[strings.ToLower(server.Host)] {
+		return "", fmt.Errorf("unknown zoho accounts server %q", raw)
+	}
+	return "https://" + strings.ToLower(server.Host), nil
+}
+
 // GenerateAuthURL generates the authorization URL for Step 2: Generating Grant Token.
-func GenerateAuthURL(state, redirectURI string) string {
-	scope := "ZohoInvoice.fullaccess.all"
-	authURL := "https://accounts.zoho.com/oauth/v2/auth"
+func (a API) GenerateAuthURL(state string) (string, error) {
+	configs := config.GetConfig()
+	if configs.Zoho.ClientID == "" || configs.Zoho.RedirectUrl == "" {
+		return "", ErrClientNotConfigured
+	}
 
 	params := url.Values{}
-	params.Add("scope", scope)
-	params.Add("client_id", "ZOHO_CLIENT_ID")
+	params.Add("scope", a.Scope)
+	params.Add("client_id", configs.Zoho.ClientID)
 	params.Add("state", state)
 	params.Add("response_type", "code")
-	params.Add("redirect_uri", redirectURI)
+	params.Add("redirect_uri", configs.Zoho.RedirectUrl)
 	params.Add("access_type", "offline")
+	params.Add("prompt", "consent")
 
-	return fmt.Sprintf("%s?%s", authURL, params.Encode())
+	return fmt.Sprintf("%s/oauth/v2/auth?%s", DefaultAccountsServer(), params.Encode()), nil
 }
 
 // ExchangeCodeForTokens performs Step 3: Generate Access and Refresh Token.
-func ExchangeCodeForTokens(code, clientID, clientSecret string) (*TokenResponse, error) {
-	tokenURL := "https://accounts.zoho.com/oauth/v2/token"
+func ExchangeCodeForTokens(accountsServer, code string) (*TokenResponse, error) {
+	configs := config.GetConfig()
 
 	params := url.Values{}
 	params.Add("code", code)
-	params.Add("client_id", clientID)
-	params.Add("client_secret", clientSecret)
-	params.Add("redirect_uri", ZOHO_REDIRECT_URL)
+	params.Add("redirect_uri", configs.Zoho.RedirectUrl)
 	params.Add("grant_type", "authorization_code")
 
-	resp, err := http.Post(tokenURL, "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
+	return requestToken(accountsServer, params)
+}
+
+// requestToken posts a token grant with the configured client credentials to the accounts server
+func requestToken(accountsServer string, params url.Values) (*TokenResponse, error) {
+	configs := config.GetConfig()
+	if configs.Zoho.ClientID == "" || configs.Zoho.ClientSecret == "" {
+		return nil, ErrClientNotConfigured
+	}
+	params.Set("client_id", configs.Zoho.ClientID)
+	params.Set("client_secret", configs.Zoho.ClientSecret)
+
+	resp, err := http.Post(accountsServer+"/oauth/v2/token", "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +134,7 @@ func ExchangeCodeForTokens(code, clientID, clientSecret string) (*TokenResponse,
 	}
 
 	if tokenResp.Error != "" {
-		return nil, fmt.Errorf("error from Zoho: %s", tokenResp.Error)
+		return nil, &TokenError{Code: tokenResp.Error}
 	}
 
 	return &tokenResp, nil
@@ -0,0 +1,62 @@
+package zoho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Discount is a Zoho discount, sent either as an amount or as a percentage string such as "12.5%"
+type Discount struct {
+	Value   float64
+	Percent bool
+}
+
+// Amount is the discount taken off base, which is the amount a percentage applies to
+func (d Discount) Amount(base float64) float64 {
+	if d.Percent {
+		return base * d.Value / 100
+	}
+	return d.Value
+}
+
+func (d *Discount) UnmarshalJSON(data []byte) error {
+	*d = Discount{}
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		d.Value = number
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("discount must be a number or a string: %s", data)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if strings.HasSuffix(text, "%") {
+		d.Percent = true
+		text = strings.TrimSpace(strings.TrimSuffix(text, "%"))
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid discount %q", text)
+	}
+	d.Value = value
+	return nil
+}
+
+func (d Discount) MarshalJSON() ([]byte, error) {
+	if d.Percent {
+		return json.Marshal(strconv.FormatFloat(d.Value, 'f', -1, 64) + "%")
+	}
+	return json.Marshal(d.Value)
+}
@@ -0,0 +1,104 @@
+package zoho
+
+import (
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TimeLayout is the layout of the created and last modified times Zoho returns and filters on
+const TimeLayout = "2006-01-02T15:04:05-0700"
+
+// ListInvoicesQuery filters the invoice list. Zero values are left out of the request.
+type ListInvoicesQuery struct {
+	LastModifiedTime time.Time // invoices modified at or after this time
+	DateStart        string    // invoice date range, yyyy-mm-dd
+	DateEnd          string
+	Page             int
+	PerPage          int
+}
+
+// InvoiceSummary is an invoice as it appears in the invoice list
+type InvoiceSummary struct {
+	InvoiceID        string `json:"invoice_id"`
+	InvoiceNumber    string `json:"invoice_number"`
+	Status           string `json:"status"`
+	LastModifiedTime string `json:"last_modified_time"`
+}
+
+type PageContext struct {
+	Page        int  `json:"page"`
+	PerPage     int  `json:"per_page"`
+	HasMorePage bool `json:"has_more_page"`
+}
+
+type InvoiceList struct {
+	Code        int              `json:"code"`
+	Message     string           `json:"message"`
+	Invoices    []InvoiceSummary `json:"invoices"`
+	PageContext PageContext      `json:"page_context"`
+}
+
+type invoiceResponse struct {
+	Code    int     `json:"code"`
+	Message string  `json:"message"`
+	Invoice Invoice `json:"invoice"`
+}
+
+// ListInvoices returns one page of the organisation's invoices, oldest modification first
+func (a API) ListInvoices(accessToken, apiDomain, orgID string, query ListInvoicesQuery) (*InvoiceList, error) {
+	params := url.Values{}
+	params.Set("organization_id", orgID)
+	params.Set("sort_column", "last_modified_time")
+	params.Set("sort_order", "A")
+	if !query.LastModifiedTime.IsZero() {
+		params.Set("last_modified_time", query.LastModifiedTime.Format(TimeLayout))
+	}
+	if query.DateStart != "" {
+		params.Set("date_start", query.DateStart)
+	}
+	if query.DateEnd != "" {
+		params.Set("date_end", query.DateEnd)
+	}
+	if query.Page > 0 {
+		params.Set("page", strconv.Itoa(query.Page))
+	}
+	if query.PerPage > 0 {
+		params.Set("per_page", strconv.Itoa(query.PerPage))
+	}
+
+	config := utility.RequestConfig{
+		URL:     a.InvoicesURL(apiDomain) + "?" + params.Encode(),
+		Headers: a.headers(accessToken, orgID),
+	}
+
+	var list InvoiceList
+	resp, err := utility.GetRequest(utility.DefaultHTTPClient, config, &list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Zoho invoices: %w", err)
+	}
+	if resp.StatusCode != 200 || list.Code != 0 {
+		return nil, fmt.Errorf("zoho API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+	}
+	return &list, nil
+}
+
+// GetInvoice returns an invoice with its line items, which the invoice list leaves out
+func (a API) GetInvoice(accessToken, apiDomain, orgID, invoiceID string) (*Invoice, error) {
+	config := utility.RequestConfig{
+		URL:     fmt.Sprintf("%s/%s?organization_id=%s", a.InvoicesURL(apiDomain), url.PathEscape(invoiceID), url.QueryEscape(orgID)),
+		Headers: a.headers(accessToken, orgID),
+	}
+
+	var invoiceResp invoiceResponse
+	resp, err := utility.GetRequest(utility.DefaultHTTPClient, config, &invoiceResp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Zoho invoice: %w", err)
+	}
+	if resp.StatusCode != 200 || invoiceResp.Code != 0 {
+		return nil, fmt.Errorf("zoho API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+	}
+	return &invoiceResp.Invoice, nil
+}
@@ -1,7 +1,16 @@
 package zoho
 
+// Invoice events a webhook can be configured for. Webhooks without an event type are routed by the invoice status.
+const (
+	EventInvoiceCreated  = "invoice_created"
+	EventInvoiceUpdated  = "invoice_updated"
+	EventInvoiceVoided   = "invoice_voided"
+	EventPaymentRecorded = "payment_recorded"
+)
+
 type WebhookPayload struct {
-	Invoice Invoice `json:"invoice" validate:"required"`
+	EventType string  `json:"event_type"`
+	Invoice   Invoice `json:"invoice" validate:"required"`
 }
 
 type Invoice struct {
@@ -14,6 +23,8 @@ type Invoice struct {
 	ContactPersonsDetails []ContactPerson `json:"contact_persons_details"`                      // For phone extraction
 	Date                  string          `json:"date" validate:"required,datetime=2006-01-02"` // Invoice date
 	DueDate               string          `json:"due_date" validate:"required,datetime=2006-01-02"`
+	SubTotal              float64         `json:"sub_total"` // Zoho Books, before tax and adjustments
+	TaxTotal              float64         `json:"tax_total"` // Zoho Books
 	Total                 float64         `json:"total" validate:"gte=0"`
 	Balance               float64         `json:"balance" validate:"gte=0"`
 	CurrencyCode          string          `json:"currency_code" validate:"required"`
@@ -24,32 +35,40 @@ type Invoice struct {
 	Notes                 string          `json:"notes"`
 	Terms                 string          `json:"terms"`
 	PaymentTerms          int             `json:"payment_terms"`
+	PaymentTermsLabel     string          `json:"payment_terms_label"`
+	Discount              Discount        `json:"discount"`
+	DiscountAmount        float64         `json:"discount_amount"`
+	DiscountType          string          `json:"discount_type"` // entity_level or item_level
+	IsDiscountBeforeTax   bool            `json:"is_discount_before_tax"`
 	Adjustment            float64         `json:"adjustment"`
 	ShippingCharge        float64         `json:"shipping_charge"`
+	ReferenceNumber       string          `json:"reference_number"`
 	CreatedTime           string          `json:"created_time"`
 	LastModifiedTime      string          `json:"last_modified_time"`
 	SalespersonName       string          `json:"salesperson_name"`
 }
 
 type ContactPerson struct {
-	Phone     string `json:"phone"`
-	Mobile    string `json:"mobile"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
+	Phone            string `json:"phone"`
+	Mobile           string `json:"mobile"`
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	Email            string `json:"email"`
+	IsPrimaryContact bool   `json:"is_primary_contact"`
 }
 
 type LineItem struct {
-	ItemID        string  `json:"item_id" validate:"required"`
-	Name          string  `json:"name" validate:"required"`
-	Description   string  `json:"description"`
-	Quantity      float64 `json:"quantity" validate:"gte=0"`
-	Rate          float64 `json:"rate" validate:"gte=0"`
-	ItemTotal     float64 `json:"item_total" validate:"gte=0"`
-	TaxID         *string `json:"tax_id"`   // Nullable
-	TaxName       *string `json:"tax_name"` // Nullable
-	TaxPercentage float64 `json:"tax_percentage"`
-	Discount      float64 `json:"discount"`
+	ItemID         string   `json:"item_id" validate:"required"`
+	Name           string   `json:"name" validate:"required"`
+	Description    string   `json:"description"`
+	Quantity       float64  `json:"quantity" validate:"gte=0"`
+	Rate           float64  `json:"rate" validate:"gte=0"`
+	ItemTotal      float64  `json:"item_total" validate:"gte=0"`
+	TaxID          *string  `json:"tax_id"`   // Nullable
+	TaxName        *string  `json:"tax_name"` // Nullable
+	TaxPercentage  float64  `json:"tax_percentage"`
+	Discount       Discount `json:"discount"`
+	DiscountAmount float64  `json:"discount_amount"`
 }
 
 type Address struct {
@@ -83,6 +102,7 @@ type WebhookResponse struct {
 	CustomerName   string  `json:"customer_name"`
 	Total          float64 `json:"total"`
 	OrganizationID string  `json:"organization_id"`
+	Event          string  `json:"event"`
 	Updated        bool    `json:"updated"`
 }
 
@@ -1,40 +1,47 @@
 package zoho
 
 import (
-	"einvoice-access-point/pkg/config"
 	"einvoice-access-point/pkg/models"
 	"einvoice-access-point/pkg/utility"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
-func UpdateZohoInvoice(accessToken, invoiceID, theIRN, theQrCodeValue string, accConfig models.AccountingPlatformConfig) error {
-	var (
-		configs = config.GetConfig()
-	)
-
-	apiURL := fmt.Sprintf("%s/%s?organization_id=%s", configs.Zoho.ZohoApiUrl, invoiceID, accConfig.OrgID)
+// UpdateInvoice writes the IRN and the signed QR payload back to the custom fields of the invoice,
+// using the custom fields named in the business mapping in place of the product defaults
+func (a API) UpdateInvoice(accessToken, apiDomain, invoiceID, theIRN, theQrCodeValue string, accConfig models.AccountingPlatformConfig, mapping *models.PlatformMapping) error {
+	apiURL := fmt.Sprintf("%s/%s?organization_id=%s", a.InvoicesURL(apiDomain), invoiceID, accConfig.OrgID)
 	fmt.Println("Zoho Invoice Update URL:", apiURL)
 
+	irnField, qrField := a.IRNField, a.QRField
+	if mapping != nil && mapping.IRNField != "" {
+		irnField = mapping.IRNField
+	}
+	if mapping != nil && mapping.QRField != "" {
+		qrField = mapping.QRField
+	}
+
 	updateData := ZohoUpdateInvoice{
 		CustomFields: []ZohoCustomField{
 			{
-				ApiName: "cf_irn",
+				ApiName: irnField,
 				Value:   theIRN,
 			},
 			{
-				ApiName: "cf_qr_code",
+				ApiName: qrField,
 				Value:   theQrCodeValue,
 			},
 		},
 	}
 
+	headers := a.headers(accessToken, accConfig.OrgID)
+	headers["Content-Type"] = "application/json"
+
 	config := utility.RequestConfig{
-		URL: apiURL,
-		Headers: map[string]string{
-			"Authorization": "Zoho-oauthtoken " + accessToken,
-			"Content-Type":  "application/json",
-		},
-		Body: updateData,
+		URL:     apiURL,
+		Headers: headers,
+		Body:    updateData,
 	}
 
 	var zohoResp map[string]interface{}
@@ -48,59 +55,74 @@ func UpdateZohoInvoice(accessToken, invoiceID, theIRN, theQrCodeValue string, ac
 		return fmt.Errorf("zoho API error: %v, body: %s", resp.StatusCode, string(resp.Body))
 	}
 
-	// theErr := updateZohoInvoiceAttachment(configs.Zoho.ZohoApiUrl, accessToken, invoiceID, accConfig.OrgID, theQrCode)
-	// if theErr != nil {
-	// 	return fmt.Errorf("failed to add attachment invoice: %w", theErr)
-	// }
+	return nil
+}
+
+// UpdateFirsStatus writes the FIRS processing status and its last error to the custom fields of the invoice,
+// using the custom fields named in the business mapping in place of the product defaults
+func (a API) UpdateFirsStatus(accessToken, apiDomain, invoiceID, orgID, status, firsError string, mapping *models.PlatformMapping) error {
+	apiURL := fmt.Sprintf("%s/%s?organization_id=%s", a.InvoicesURL(apiDomain), invoiceID, url.QueryEscape(orgID))
+
+	statusField, errorField := a.StatusField, a.ErrorField
+	if mapping != nil && mapping.StatusField != "" {
+		statusField = mapping.StatusField
+	}
+	if mapping != nil && mapping.ErrorField != "" {
+		errorField = mapping.ErrorField
+	}
+
+	headers := a.headers(accessToken, orgID)
+	headers["Content-Type"] = "application/json"
+
+	config := utility.RequestConfig{
+		URL:     apiURL,
+		Headers: headers,
+		Body: ZohoUpdateInvoice{
+			CustomFields: []ZohoCustomField{
+				{ApiName: statusField, Value: status},
+				{ApiName: errorField, Value: firsError},
+			},
+		},
+	}
+
+	var zohoResp map[string]interface{}
+
+	resp, err := utility.PutRequest(utility.DefaultHTTPClient, config, &zohoResp)
+	if err != nil {
+		return fmt.Errorf("failed to update Zoho invoice status: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("zoho API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+	}
 
 	return nil
 }
 
-// func updateZohoInvoiceAttachment(url, accessToken, invoiceID, orgID, qrBase64 string) error {
-
-// 	apiURL := fmt.Sprintf("%s/%s/attachment?organization_id=%s", url, invoiceID, orgID)
-// 	fmt.Println("Zoho Invoice Update URL:", apiURL)
-
-// 	qrBytes, err := base64.StdEncoding.DecodeString(qrBase64)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to decode QR base64: %w", err)
-// 	}
-
-// 	var buf bytes.Buffer
-// 	writer := multipart.NewWriter(&buf)
-
-// 	fileField, err := writer.CreateFormFile("attachment", filepath.Base("qrcode.png"))
-// 	if err != nil {
-// 		return fmt.Errorf("failed to create form file: %w", err)
-// 	}
-// 	if _, err = fileField.Write(qrBytes); err != nil {
-// 		return fmt.Errorf("failed to write file bytes: %w", err)
-// 	}
-
-// 	_ = writer.WriteField("can_send_in_mail", "true")
-
-// 	if err := writer.Close(); err != nil {
-// 		return fmt.Errorf("failed to close writer: %w", err)
-// 	}
-
-// 	req, err := http.NewRequest("POST", apiURL, &buf)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to create request: %w", err)
-// 	}
-// 	req.Header.Set("Authorization", "Zoho-oauthtoken "+accessToken)
-// 	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-// 	client := &http.Client{}
-// 	resp, err := client.Do(req)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to send request: %w", err)
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode != 200 {
-// 		return fmt.Errorf("zoho API error: %v", resp.Status)
-// 	}
-
-// 	fmt.Println("Attachment uploaded successfully ✅")
-// 	return nil
-// }
+// UploadInvoiceAttachment attaches a file to a Zoho invoice. Zoho sends attachments marked canSendInMail
+// along with the invoice when it is emailed from Zoho.
+func (a API) UploadInvoiceAttachment(accessToken, apiDomain, invoiceID, orgID, filename, contentType string, data []byte, canSendInMail bool) error {
+	apiURL := fmt.Sprintf("%s/%s/attachment?organization_id=%s", a.InvoicesURL(apiDomain), invoiceID, url.QueryEscape(orgID))
+
+	config := utility.RequestConfig{
+		URL:     apiURL,
+		Headers: a.headers(accessToken, orgID),
+	}
+	fields := map[string]string{
+		"can_send_in_mail": strconv.FormatBool(canSendInMail),
+	}
+	file := utility.MultipartFile{Field: "attachment", Filename: filename, ContentType: contentType, Data: data}
+
+	var zohoResp map[string]interface{}
+
+	resp, err := utility.PostMultipartRequest(utility.DefaultHTTPClient, config, fields, file, &zohoResp)
+	if err != nil {
+		return fmt.Errorf("failed to upload Zoho invoice attachment: %w", err)
+	}
+
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return fmt.Errorf("zoho API error: %v, body: %s", resp.StatusCode, string(resp.Body))
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package business
+
+import (
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/utility"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ownBusiness reports whether the business in the path is the authenticated business. When it is not, the
+// rejection has already been written to the response.
+func ownBusiness(c *fiber.Ctx, id string) (bool, error) {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return false, c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if userDetails.ID != id {
+		rd := utility.BuildErrorResponse(fiber.StatusForbidden, "error", "business settings can only be changed by the business itself", nil, nil)
+		return false, c.Status(fiber.StatusForbidden).JSON(rd)
+	}
+	return true, nil
+}
@@ -121,3 +121,183 @@ func (base *Controller) UpdateBusinessID(c *fiber.Ctx) error {
 	rd := utility.BuildSuccessResponse(http.StatusOK, "business id updated successfully", nil)
 	return c.Status(http.StatusOK).JSON(rd)
 }
+
+// @Summary      Update IRN Template
+// @Description Set the FIRS IRN template used when generating IRNs for a business
+// @Tags         Business
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param        id   path      string  true  "Business ID" format(uuid)
+// @Param data body models.UpdateIRNTemplateRequest true "Update IRN template request payload"
+// @Success      200 {object} models.Response "IRN template updated successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      403 {object} models.Response "Another business"
+// @Failure      404 {object} models.Response "Business not found"
+// @Failure      500 {object} models.Response "Internal server error"
+// @Router       /business/irn-template/{id} [patch]
+func (base *Controller) UpdateIRNTemplate(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business id is required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid business id format", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, id); !ok {
+		return err
+	}
+
+	var req models.UpdateIRNTemplateRequest
+	err = c.BodyParser(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	_, err = business.GetBusinessByID(base.Db.Postgresql.DB(), id)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(http.StatusNotFound).JSON(rd)
+	}
+
+	err = business.UpdateIRNTemplate(base.Db.Postgresql.DB(), id, req.IRNTemplate)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "irn template updated successfully", nil)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Update Supplier Profile
+// @Description Set the supplier details, address and bank details sent as the supplier party on every invoice
+// @Tags         Business
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param        id   path      string  true  "Business ID" format(uuid)
+// @Param data body models.UpdateSupplierProfileRequest true "Update supplier profile request payload"
+// @Success      200 {object} models.Response "Supplier profile updated successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      403 {object} models.Response "Another business"
+// @Failure      404 {object} models.Response "Business not found"
+// @Failure      500 {object} models.Response "Internal server error"
+// @Router       /business/supplier-profile/{id} [patch]
+func (base *Controller) UpdateSupplierProfile(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business id is required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid business id format", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, id); !ok {
+		return err
+	}
+
+	var req models.UpdateSupplierProfileRequest
+	err = c.BodyParser(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	_, err = business.GetBusinessByID(base.Db.Postgresql.DB(), id)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(http.StatusNotFound).JSON(rd)
+	}
+
+	err = business.UpdateSupplierProfile(base.Db.Postgresql.DB(), id, req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "supplier profile updated successfully", nil)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Update PEPPOL Settings
+// @Description Enable PEPPOL BIS 3.0 output for a business and set its PEPPOL participant identifier
+// @Tags         Business
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param        id   path      string  true  "Business ID" format(uuid)
+// @Param data body models.UpdatePeppolSettingsRequest true "Update PEPPOL settings request payload"
+// @Success      200 {object} models.Response "PEPPOL settings updated successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      403 {object} models.Response "Another business"
+// @Failure      404 {object} models.Response "Business not found"
+// @Failure      500 {object} models.Response "Internal server error"
+// @Router       /business/peppol/{id} [patch]
+func (base *Controller) UpdatePeppolSettings(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business id is required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid business id format", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, id); !ok {
+		return err
+	}
+
+	var req models.UpdatePeppolSettingsRequest
+	err = c.BodyParser(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	_, err = business.GetBusinessByID(base.Db.Postgresql.DB(), id)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(http.StatusNotFound).JSON(rd)
+	}
+
+	err = business.UpdatePeppolSettings(base.Db.Postgresql.DB(), id, req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "peppol settings updated successfully", nil)
+	return c.Status(http.StatusOK).JSON(rd)
+}
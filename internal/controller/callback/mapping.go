@@ -0,0 +1,110 @@
+package callback
+
+import (
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/platformmapping"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Get Zoho Mapping
+// @Description  Returns the custom field, tax and payment terms mapping of the authenticated business for the Zoho product, with the codes each entry can map onto. Blank custom fields write back to cf_irn, cf_qr_code, cf_firs_status and cf_firs_error.
+// @Tags         Zoho
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Success      200 {object} models.Response "Zoho mapping retrieved"
+// @Failure      400 {object} models.Response "Bad request"
+// @Router       /zoho/mapping [get]
+func (base *Controller) GetZohoMapping(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	return base.getMapping(c, userDetails.ID, api.Platform, "zoho mapping retrieved")
+}
+
+// @Summary      Save Zoho Mapping
+// @Description  Replaces the mapping of Zoho custom field names, Zoho tax IDs to FIRS tax categories, payment terms labels to payment means codes and the default invoice type code. Every code is checked before the mapping is saved.
+// @Tags         Zoho
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Param        data body models.PlatformMappingRequest true "Zoho mapping"
+// @Success      200 {object} models.Response "Zoho mapping saved"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /zoho/mapping [put]
+func (base *Controller) SaveZohoMapping(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	return base.saveMapping(c, userDetails.ID, api.Platform, "zoho mapping saved")
+}
+
+func (base *Controller) getMapping(c *fiber.Ctx, businessID, platform, message string) error {
+	mapping, err := platformmapping.GetMapping(base.Db.Postgresql.DB(), businessID, platform)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, message, fiber.Map{
+		"mapping":             mapping,
+		"tax_categories":      converter.TaxCategoryCodes(),
+		"payment_means_codes": converter.PaymentMeansCodes(),
+		"invoice_type_codes":  converter.InvoiceTypeCodes(),
+	})
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+func (base *Controller) saveMapping(c *fiber.Ctx, businessID, platform, message string) error {
+	var req models.PlatformMappingRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	mapping, err := platformmapping.SaveMapping(base.Db.Postgresql.DB(), businessID, platform, req)
+	if err != nil {
+		var mappingErr *platformmapping.MappingError
+		if errors.As(err, &mappingErr) {
+			rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", mappingErr.Problems, nil)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, message, mapping)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
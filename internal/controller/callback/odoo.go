@@ -0,0 +1,113 @@
+package callback
+
+import (
+	"einvoice-access-point/internal/services/connector/odooconnector"
+	"einvoice-access-point/internal/services/platformsync"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Get Odoo Sync
+// @Description  Returns the polling sync of the Odoo database of the authenticated business: whether it is enabled, its cursor, the last error and the backfill progress.
+// @Tags         Odoo
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} models.Response "Odoo sync retrieved"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      404 {object} models.Response "Odoo sync not set up"
+// @Router       /odoo/sync [get]
+func (base *Controller) GetOdooSync(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	sync, err := platformsync.GetSync(base.Db.Postgresql.DB(), userDetails.ID, odooconnector.Platform)
+	if err != nil {
+		return syncErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "odoo sync retrieved", sync)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// @Summary      Update Odoo Sync
+// @Description  Turns polling of the customer invoices and credit notes posted or cancelled in Odoo on or off. Odoo is reached with the base URL, database, username and API key in the platform configs. A new sync starts from the time it is enabled.
+// @Tags         Odoo
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data body models.UpdateSyncRequest true "Sync settings"
+// @Success      200 {object} models.Response "Odoo sync updated"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /odoo/sync [put]
+func (base *Controller) UpdateOdooSync(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.UpdateSyncRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	sync, err := platformsync.UpdateSync(base.Db.Postgresql.DB(), userDetails.ID, odooconnector.Platform, req)
+	if err != nil {
+		return syncErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "odoo sync updated", sync)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// @Summary      Backfill Odoo Invoices
+// @Description  Queues the pull of every Odoo customer invoice and credit note dated in the range. Invoices already received are only updated.
+// @Tags         Odoo
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data body models.BackfillRequest true "Invoice date range"
+// @Success      202 {object} models.Response "Backfill queued"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /odoo/sync/backfill [post]
+func (base *Controller) BackfillOdooInvoices(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.BackfillRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	sync, err := platformsync.RequestBackfill(base.Db.Postgresql.DB(), userDetails.ID, odooconnector.Platform, req)
+	if err != nil {
+		return syncErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusAccepted, "odoo backfill queued", sync)
+	return c.Status(fiber.StatusAccepted).JSON(rd)
+}
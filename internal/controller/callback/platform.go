@@ -0,0 +1,203 @@
+package callback
+
+import (
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/token"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary      Start Platform Connection
+// @Description  Returns the consent URL of the accounting platform for the authenticated business. The state it carries is single use and expires after ten minutes.
+// @Tags         Platform
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name path string true "Platform, as keyed in the business platform configs"
+// @Success      200 {object} models.Response "Authorization URL generated"
+// @Failure      400 {object} models.Response "Organisation not configured"
+// @Failure      404 {object} models.Response "Unknown platform"
+// @Router       /platform/{name}/auth [get]
+func (base *Controller) PlatformAuth(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	return base.startAuthorization(c, userDetails.ID, c.Params("name"))
+}
+
+// @Summary      Get Platform Connection
+// @Description  Returns the connection of the authenticated business to the accounting platform. needs_reconnect is set once the platform rejects the stored refresh token.
+// @Tags         Platform
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name path string true "Platform, as keyed in the business platform configs"
+// @Success      200 {object} models.Response "Connection retrieved"
+// @Failure      400 {object} models.Response "Organisation not configured"
+// @Failure      404 {object} models.Response "Unknown platform or not connected"
+// @Router       /platform/{name}/connection [get]
+func (base *Controller) GetPlatformConnection(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	return base.getConnection(c, userDetails.ID, c.Params("name"))
+}
+
+// @Summary      Platform OAuth Callback
+// @Description  Redirect target of the consent screen of an accounting platform. Checks the state and stores the tokens the code is exchanged for by the platform the state was issued for.
+// @Tags         Platform
+// @Produce      json
+// @Param        name   path  string true "Platform, as keyed in the business platform configs"
+// @Param        code   query string true "Authorization code"
+// @Param        state  query string true "State returned by /platform/{name}/auth"
+// @Success      200 {object} models.Response "Platform connected"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      403 {object} models.Response "Invalid or expired state"
+// @Router       /platform/{name}/callback [get]
+func (base *Controller) PlatformCallback(c *fiber.Ctx) error {
+	return base.completeAuthorization(c)
+}
+
+// @Summary      Get Platform Mapping
+// @Description  Returns the custom field, tax and payment terms mapping of the authenticated business for the accounting platform, with the codes each entry can map onto. For QuickBooks the custom fields are custom field definition IDs, and results go to the private note of the invoice when none is mapped. For Xero a custom field of Reference writes to the reference of the invoice, and results go to its history notes otherwise.
+// @Tags         Platform
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name path string true "Platform, as keyed in the business platform configs"
+// @Success      200 {object} models.Response "Platform mapping retrieved"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      404 {object} models.Response "Unknown platform"
+// @Router       /platform/{name}/mapping [get]
+func (base *Controller) GetPlatformMapping(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	conn, err := connector.Get(c.Params("name"))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+
+	return base.getMapping(c, userDetails.ID, conn.Name(), "platform mapping retrieved")
+}
+
+// @Summary      Save Platform Mapping
+// @Description  Replaces the mapping of custom fields, platform tax codes to FIRS tax categories, payment terms to payment means codes and the default invoice type code of the accounting platform. Every code is checked before the mapping is saved.
+// @Tags         Platform
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name path string true "Platform, as keyed in the business platform configs"
+// @Param        data body models.PlatformMappingRequest true "Platform mapping"
+// @Success      200 {object} models.Response "Platform mapping saved"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      404 {object} models.Response "Unknown platform"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /platform/{name}/mapping [put]
+func (base *Controller) SavePlatformMapping(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	conn, err := connector.Get(c.Params("name"))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+
+	return base.saveMapping(c, userDetails.ID, conn.Name(), "platform mapping saved")
+}
+
+func (base *Controller) startAuthorization(c *fiber.Ctx, businessID, platform string) error {
+	conn, err := connector.Get(platform)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+
+	authURL, err := token.StartAuthorization(base.Db.Postgresql.DB(), businessID, conn)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "authorization url generated", fiber.Map{"auth_url": authURL})
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+func (base *Controller) getConnection(c *fiber.Ctx, businessID, platform string) error {
+	if _, err := connector.Get(platform); err != nil {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+
+	connection, err := token.GetConnection(base.Db.Postgresql.DB(), businessID, platform)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", platform+" is not connected", nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, platform+" connection retrieved", fiber.Map{
+		"platform":        connection.Provider,
+		"organization_id": connection.OrganizationID,
+		"api_domain":      connection.APIDomain,
+		"accounts_server": connection.AccountsServer,
+		"expires_at":      connection.ExpiresAt,
+		"needs_reconnect": connection.NeedsReconnect,
+		"reconnect_error": connection.ReconnectError,
+		"updated_at":      connection.UpdatedAt,
+	})
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// completeAuthorization finishes the consent flow of whichever platform the state was issued for
+func (base *Controller) completeAuthorization(c *fiber.Ctx) error {
+	if errorParam := c.Query("error"); errorParam != "" {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", errorParam, nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	code := c.Query("code")
+	state := c.Query("state")
+	if code == "" || state == "" {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", "code and state are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	connected, err := token.CompleteAuthorization(base.Db.Postgresql.DB(), state, code, c.Queries())
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, token.ErrInvalidOAuthState) {
+			status = http.StatusForbidden
+		}
+		rd := utility.BuildErrorResponse(status, "error", err.Error(), err, nil)
+		return c.Status(status).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, connected.Provider+" connected successfully", fiber.Map{
+		"platform":        connected.Provider,
+		"organization_id": connected.OrganizationID,
+		"api_domain":      connected.APIDomain,
+		"accounts_server": connected.AccountsServer,
+		"expires_at":      connected.ExpiresAt,
+	})
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
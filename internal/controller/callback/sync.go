@@ -0,0 +1,144 @@
+package callback
+
+import (
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/platformsync"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Get Zoho Sync
+// @Description  Returns the polling sync of the authenticated business: whether it is enabled, its cursor, the last error and the backfill progress.
+// @Tags         Zoho
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Success      200 {object} models.Response "Zoho sync retrieved"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      404 {object} models.Response "Zoho sync not set up"
+// @Router       /zoho/sync [get]
+func (base *Controller) GetZohoSync(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	sync, err := platformsync.GetSync(base.Db.Postgresql.DB(), userDetails.ID, api.Platform)
+	if err != nil {
+		return syncErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "zoho sync retrieved", sync)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// @Summary      Update Zoho Sync
+// @Description  Turns polling of changed Zoho invoices on or off for organisations that cannot configure webhooks. A new sync starts from the time it is enabled.
+// @Tags         Zoho
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Param        data body models.UpdateSyncRequest true "Sync settings"
+// @Success      200 {object} models.Response "Zoho sync updated"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /zoho/sync [put]
+func (base *Controller) UpdateZohoSync(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.UpdateSyncRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	sync, err := platformsync.UpdateSync(base.Db.Postgresql.DB(), userDetails.ID, api.Platform, req)
+	if err != nil {
+		return syncErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "zoho sync updated", sync)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// @Summary      Backfill Zoho Invoices
+// @Description  Queues the pull of every Zoho invoice dated in the range through the same processing as the webhook. Invoices already received are only updated.
+// @Tags         Zoho
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Param        data body models.BackfillRequest true "Invoice date range"
+// @Success      202 {object} models.Response "Backfill queued"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /zoho/sync/backfill [post]
+func (base *Controller) BackfillZohoInvoices(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.BackfillRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	sync, err := platformsync.RequestBackfill(base.Db.Postgresql.DB(), userDetails.ID, api.Platform, req)
+	if err != nil {
+		return syncErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusAccepted, "zoho backfill queued", sync)
+	return c.Status(fiber.StatusAccepted).JSON(rd)
+}
+
+func syncErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, platformsync.ErrSyncNotFound) {
+		rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+	rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+	return c.Status(fiber.StatusBadRequest).JSON(rd)
+}
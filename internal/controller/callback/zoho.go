@@ -2,12 +2,9 @@ package callback
 
 import (
 	"einvoice-access-point/external/zoho"
-	"einvoice-access-point/internal/services/token"
-	"einvoice-access-point/internal/services/webhooks"
 	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
 	"einvoice-access-point/pkg/utility"
-	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -20,87 +17,68 @@ type Controller struct {
 	Logger    *utility.Logger
 }
 
+// @Summary      Start Zoho Connection
+// @Description  Returns the Zoho consent URL for the authenticated business. The state it carries is single use and expires after ten minutes.
+// @Tags         Zoho
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Success      200 {object} models.Response "Authorization URL generated"
+// @Failure      400 {object} models.Response "Zoho organisation not configured"
+// @Router       /zoho/auth [get]
 func (base *Controller) ZohoAuthCode(c *fiber.Ctx) error {
-
-	state := "testing"
-	redirectURI := "http://localhost:8091/api/v1/zoho/callback"
-	authURL := zoho.GenerateAuthURL(state, redirectURI)
-	fmt.Println(authURL)
-	return c.Redirect(authURL)
-}
-
-// // @Summary      Get Zoho Access Token
-// // @Description  Exchange an authorization code for a Zoho access token and save it to the database.
-// // @Tags         Zoho
-// // @Accept       json
-// // @Produce      json
-// // @Param        code            query   string  true  "Authorization Code returned from Zoho OAuth"
-// // @Param        organisation_id query   string  true  "Zoho Organization ID"
-// // @Security     BearerAuth
-// // @Success      200 {object} models.Response "Token generated successfully"
-// // @Failure      400 {object} models.Response "Bad request (missing code or organisation_id)"
-// // @Failure      401 {object} models.Response "Unauthorized"
-// // @Failure      500 {object} models.Response "Internal server error"
-// // @Router       /zoho/auth/access-token [get]
-func (base *Controller) ZohoGetAcessToken(c *fiber.Ctx) error {
-
-	platform := "zoho"
-
-	code := c.Query("code")
-	if code == "" {
-		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", "no coded provided", nil, nil)
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
 
-	orgID := c.Query("organisation_id")
-	if orgID == "" {
-		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", "no organisation ID provided", nil, nil)
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
 
-	_, config, err := webhooks.GetBuinessConfigs(base.Db.Postgresql.DB(), platform, orgID)
+	return base.startAuthorization(c, userDetails.ID, api.Platform)
+}
+
+// @Summary      Get Zoho Connection
+// @Description  Returns the Zoho connection of the authenticated business. needs_reconnect is set once Zoho rejects the stored refresh token.
+// @Tags         Zoho
+// @Produce      json
+// @Security     BearerAuth
+// @Param        platform query string false "Zoho product" Enums(zoho, zoho_books) default(zoho)
+// @Success      200 {object} models.Response "Zoho connection retrieved"
+// @Failure      400 {object} models.Response "Zoho organisation not configured"
+// @Failure      404 {object} models.Response "Zoho not connected"
+// @Router       /zoho/connection [get]
+func (base *Controller) GetZohoConnection(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
 	if err != nil {
-		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", "cant get business wrong org ID", nil, nil)
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
 
-	tokens, err := token.GetValidAccessToken(base.Db.Postgresql.DB(), *config, platform, orgID, code)
+	api, err := zoho.APIFor(c.Query("platform", zoho.InvoiceAPI.Platform))
 	if err != nil {
 		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
 
-	log.Printf("Access Token: %s\n", tokens)
-	//.Printf("Refresh Token: %s\n", tokens.RefreshToken)
-
-	rd := utility.BuildSuccessResponse(http.StatusOK, "token generated succesfully", nil)
-	return c.Status(fiber.StatusOK).JSON(rd)
+	return base.getConnection(c, userDetails.ID, api.Platform)
 }
 
-// func (base *Controller) ZohoCallback(c *fiber.Ctx) error {
-
-// 	code := c.Query("code")
-// 	errorParam := c.Query("error")
-
-// 	if errorParam != "" {
-// 		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", errorParam, nil, nil)
-// 		return c.Status(fiber.StatusBadRequest).JSON(rd)
-// 	}
-
-// 	if code == "" {
-// 		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", "no coded provided", nil, nil)
-// 		return c.Status(fiber.StatusBadRequest).JSON(rd)
-// 	}
-
-// 	tokens, err := zoho.ExchangeCodeForTokens(code)
-// 	if err != nil {
-// 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
-// 	}
-
-// 	// Here you can store the tokens, e.g., in a database or file
-// 	log.Printf("Access Token: %s\n", tokens.AccessToken)
-// 	log.Printf("Refresh Token: %s\n", tokens.RefreshToken)
-
-// 	rd := utility.BuildSuccessResponse(http.StatusOK, "token generated succesfully", nil)
-// 	return c.Status(fiber.StatusOK).JSON(rd)
-// }
+// @Summary      Zoho OAuth Callback
+// @Description  Redirect target of the Zoho consent screen. Checks the state, exchanges the code at the accounts server of the organisation's datacenter and stores the tokens.
+// @Tags         Zoho
+// @Produce      json
+// @Param        code             query string true  "Authorization code"
+// @Param        state            query string true  "State returned by /zoho/auth"
+// @Param        accounts-server  query string false "Accounts server of the organisation's datacenter"
+// @Success      200 {object} models.Response "Zoho connected"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      403 {object} models.Response "Invalid or expired state"
+// @Router       /zoho/callback [get]
+func (base *Controller) ZohoCallback(c *fiber.Ctx) error {
+	return base.completeAuthorization(c)
+}
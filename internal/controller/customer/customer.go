@@ -0,0 +1,186 @@
+package customer
+
+import (
+	"einvoice-access-point/internal/services/customer"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	Db        *database.Database
+	Validator *validator.Validate
+	Logger    *utility.Logger
+}
+
+// @Summary      Get Customers
+// @Description  List the customer directory of the authenticated business
+// @Tags         Customer
+// @Produce      json
+// @Security BearerAuth
+// @Param        platform query string false "Filter by platform, e.g. zoho"
+// @Success      200 {object} models.Response "Customers retrieved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Router       /customers [get]
+func (base *Controller) GetCustomers(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	respData, err := customer.GetCustomers(base.Db.Postgresql.DB(), userDetails.ID, strings.ToLower(c.Query("platform")))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "customers gotten successfully", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Import Customers
+// @Description  Create or update directory entries from a JSON payload or a CSV file upload (multipart field "file"); every TIN is verified with FIRS in the background
+// @Tags         Customer
+// @Accept       json
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security BearerAuth
+// @Param data body models.ImportCustomersRequest false "Customers payload"
+// @Param file formData file false "Customers CSV file"
+// @Success      200 {object} models.Response "Customers imported successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /customers/import [post]
+func (base *Controller) ImportCustomers(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.ImportCustomersRequest
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "customers CSV file is required", nil, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+
+		fileContent, err := file.Open()
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "failed to read file", nil, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+		defer fileContent.Close()
+
+		req.Customers, err = customer.ParseCustomersCSV(fileContent)
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err.Error(), nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	respData, err := customer.ImportCustomers(base.Db.Postgresql.DB(), userDetails.ID, req.Customers)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "customers imported successfully", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Verify Customer TIN
+// @Description  Re-run the FIRS TIN check of a customer in the directory
+// @Tags         Customer
+// @Produce      json
+// @Security BearerAuth
+// @Param        id path string true "Customer ID" format(uuid)
+// @Success      200 {object} models.Response "Customer verified successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      404 {object} models.Response "Customer not found"
+// @Router       /customers/{id}/verify [post]
+func (base *Controller) VerifyCustomer(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	id := c.Params("id")
+	if !utility.IsValidUUID(id) {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid customer id format", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	respData, err := customer.VerifyCustomer(base.Db.Postgresql.DB(), userDetails.ID, id)
+	if err != nil {
+		if errors.Is(err, customer.ErrCustomerNotFound) {
+			rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+			return c.Status(http.StatusNotFound).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "customer verification completed", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Delete Customer
+// @Description  Remove an entry from the customer directory
+// @Tags         Customer
+// @Produce      json
+// @Security BearerAuth
+// @Param        id path string true "Customer ID" format(uuid)
+// @Success      200 {object} models.Response "Customer deleted successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      404 {object} models.Response "Customer not found"
+// @Router       /customers/{id} [delete]
+func (base *Controller) DeleteCustomer(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	id := c.Params("id")
+	if !utility.IsValidUUID(id) {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid customer id format", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = customer.DeleteCustomer(base.Db.Postgresql.DB(), userDetails.ID, id)
+	if err != nil {
+		if errors.Is(err, customer.ErrCustomerNotFound) {
+			rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+			return c.Status(http.StatusNotFound).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "customer deleted successfully", nil)
+	return c.Status(http.StatusOK).JSON(rd)
+}
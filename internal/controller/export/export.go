@@ -0,0 +1,158 @@
+package export
+
+import (
+	"einvoice-access-point/internal/services/export"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	Db        *database.Database
+	Validator *validator.Validate
+	Logger    *utility.Logger
+}
+
+// CreateExport godoc
+// @Summary      Request Invoice Archive Export
+// @Description  Queues a ZIP archive of the invoices issued in a period, with each invoice's data, FIRS download, PDF and status history and a manifest CSV of checksums
+// @Tags         Export
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param data body models.CreateExportRequest true "Period and status filter"
+// @Success      202 {object} models.Response "Export queued"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      403 {object} models.Response "Business not owned by caller"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /exports [post]
+func (base *Controller) CreateExport(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.CreateExportRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	result, err := export.CreateExport(base.Db.Postgresql.DB(), userDetails.ID, req)
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusAccepted, "Export queued", result)
+	return c.Status(fiber.StatusAccepted).JSON(rd)
+}
+
+// GetExports godoc
+// @Summary      List Invoice Archive Exports
+// @Description  Lists the exports of the authenticated business, newest first
+// @Tags         Export
+// @Produce      json
+// @Security BearerAuth
+// @Success      200 {object} models.Response "Exports retrieved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Router       /exports [get]
+func (base *Controller) GetExports(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	result, err := export.GetExports(base.Db.Postgresql.DB(), userDetails.ID)
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Exports retrieved successfully", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// GetExport godoc
+// @Summary      Get Invoice Archive Export
+// @Description  Returns the status of an export; once ready it carries the archive size, checksum and expiry
+// @Tags         Export
+// @Produce      json
+// @Security BearerAuth
+// @Param id path string true "Export ID" format(uuid)
+// @Success      200 {object} models.Response "Export retrieved successfully"
+// @Failure      404 {object} models.Response "Export not found"
+// @Router       /exports/{id} [get]
+func (base *Controller) GetExport(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	result, err := export.GetExport(base.Db.Postgresql.DB(), userDetails.ID, c.Params("id"))
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Export retrieved successfully", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// DownloadExport godoc
+// @Summary      Download Invoice Archive Export
+// @Description  Streams the ZIP archive of a ready export until it expires
+// @Tags         Export
+// @Produce      application/zip
+// @Security BearerAuth
+// @Param id path string true "Export ID" format(uuid)
+// @Success      200 {file} file "ZIP archive"
+// @Failure      404 {object} models.Response "Export not found"
+// @Failure      409 {object} models.Response "Export not ready"
+// @Failure      410 {object} models.Response "Export expired"
+// @Router       /exports/{id}/download [get]
+func (base *Controller) DownloadExport(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	result, file, err := export.OpenExport(base.Db.Postgresql.DB(), userDetails.ID, c.Params("id"))
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, export.ArchiveName(result)))
+	c.Set("Digest", "sha-256="+result.Checksum)
+	c.Set("X-Checksum-Sha256", result.Checksum)
+	return c.Status(fiber.StatusOK).SendStream(file, int(result.FileSize))
+}
+
+func exportErrorResponse(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, export.ErrExportNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, export.ErrExportForbidden):
+		status = fiber.StatusForbidden
+	case errors.Is(err, export.ErrExportNotReady):
+		status = fiber.StatusConflict
+	case errors.Is(err, export.ErrExportExpired):
+		status = fiber.StatusGone
+	}
+	rd := utility.BuildErrorResponse(status, "error", err.Error(), err, nil)
+	return c.Status(status).JSON(rd)
+}
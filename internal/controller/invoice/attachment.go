@@ -0,0 +1,86 @@
+package invoice
+
+import (
+	"einvoice-access-point/internal/services/attachment"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetInvoiceAttachments godoc
+// @Summary Get invoice platform attachments
+// @Description Returns the status, attempts and last error of the QR code and PDF uploaded to the invoice on its accounting platform
+// @Tags Internal Invoice
+// @Produce json
+// @Security BearerAuth
+// @Param business_id path string true "Business ID" format(uuid)
+// @Param invoice_id path string true "Invoice ID" format(uuid)
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 403 {object} models.Response "Invoice of another business"
+// @Failure 404 {object} models.Response "Invoice has no platform attachments"
+// @Router /invoice/business/{business_id}/{invoice_id}/attachments [get]
+func (base *Controller) GetInvoiceAttachments(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	result, err := attachment.GetAttachments(base.Db.Postgresql.DB(), businessID, invoiceID)
+	if err != nil {
+		return attachmentErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice attachments retrieved successfully", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// RetryInvoiceAttachments godoc
+// @Summary Retry failed invoice platform attachments
+// @Description Queues the failed QR code and PDF uploads of the invoice again
+// @Tags Internal Invoice
+// @Produce json
+// @Security BearerAuth
+// @Param business_id path string true "Business ID" format(uuid)
+// @Param invoice_id path string true "Invoice ID" format(uuid)
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 403 {object} models.Response "Invoice of another business"
+// @Failure 404 {object} models.Response "Invoice has no platform attachments"
+// @Router /invoice/business/{business_id}/{invoice_id}/attachments/retry [post]
+func (base *Controller) RetryInvoiceAttachments(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	result, err := attachment.RetryAttachments(base.Db.Postgresql.DB(), businessID, invoiceID)
+	if err != nil {
+		return attachmentErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice attachments queued", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+func attachmentErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, attachment.ErrAttachmentsNotFound) {
+		rd := utility.BuildErrorResponse(fiber.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+	rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+	return c.Status(fiber.StatusBadRequest).JSON(rd)
+}
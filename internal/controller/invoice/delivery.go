@@ -0,0 +1,103 @@
+package invoice
+
+import (
+	"einvoice-access-point/internal/services/delivery"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetInvoiceDelivery godoc
+// @Summary Get invoice email delivery
+// @Description Returns the status, attempts, last error and bounce of the email that sends the confirmed invoice to the buyer
+// @Tags Internal Invoice
+// @Produce json
+// @Security BearerAuth
+// @Param business_id path string true "Business ID" format(uuid)
+// @Param invoice_id path string true "Invoice ID" format(uuid)
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 403 {object} models.Response "Invoice of another business"
+// @Failure 404 {object} models.Response "Invoice has no delivery"
+// @Router /invoice/business/{business_id}/{invoice_id}/delivery [get]
+func (base *Controller) GetInvoiceDelivery(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	result, err := delivery.GetDelivery(base.Db.Postgresql.DB(), businessID, invoiceID)
+	if err != nil {
+		return deliveryErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice delivery retrieved successfully", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// ResendInvoiceDelivery godoc
+// @Summary Resend invoice email
+// @Description Queues the confirmed invoice email again, optionally to another recipient
+// @Tags Internal Invoice
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param business_id path string true "Business ID" format(uuid)
+// @Param invoice_id path string true "Invoice ID" format(uuid)
+// @Param data body models.ResendDeliveryRequest false "Recipient override"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 403 {object} models.Response "Invoice of another business"
+// @Failure 404 {object} models.Response "Invoice has no delivery"
+// @Failure 422 {object} models.Response "Validation failed"
+// @Router /invoice/business/{business_id}/{invoice_id}/delivery/resend [post]
+func (base *Controller) ResendInvoiceDelivery(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	var req models.ResendDeliveryRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+	}
+
+	if err := base.Validator.Struct(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	result, err := delivery.ResendDelivery(base.Db.Postgresql.DB(), businessID, invoiceID, req.Recipient)
+	if err != nil {
+		return deliveryErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice delivery queued", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+func deliveryErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, delivery.ErrDeliveryNotFound) {
+		rd := utility.BuildErrorResponse(fiber.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+	rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+	return c.Status(fiber.StatusBadRequest).JSON(rd)
+}
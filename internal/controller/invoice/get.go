@@ -1,11 +1,18 @@
 package invoice
 
 import (
+	"bytes"
 	"einvoice-access-point/external/firs_models"
+	peppolAccessPoint "einvoice-access-point/external/peppol"
+	"einvoice-access-point/internal/services/converter"
 	"einvoice-access-point/internal/services/invoice"
+	"einvoice-access-point/internal/services/peppol"
+	"einvoice-access-point/internal/services/schema"
+	"einvoice-access-point/internal/services/upload"
 	"einvoice-access-point/pkg/middleware"
 	"einvoice-access-point/pkg/utility"
-	"log"
+	"errors"
+	"io"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -108,8 +115,10 @@ func (base *Controller) GetAllInvoicesByBusinessID(c *fiber.Ctx) error {
 // // @Security BearerAuth
 // // @Param business_id path string true "Business ID" format(uuid)
 // // @Param invoice_id path string true "Invoice ID" format(uuid)
+// // @Param format query string false "Set to ubl to export the invoice as UBL 2.1 XML, or peppol for PEPPOL BIS 3.0"
 // // @Success 200 {object} models.Response
 // // @Failure 400 {object} models.Response
+// // @Failure 403 {object} models.Response "Invoice of another business"
 // // @Router /invoice/business/{business_id}/{invoice_id} [get]
 func (base *Controller) GetInvoiceDetails(c *fiber.Ctx) error {
 	businessID := c.Params("business_id")
@@ -119,6 +128,36 @@ func (base *Controller) GetInvoiceDetails(c *fiber.Ctx) error {
 		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	if c.Query("format") == "ubl" {
+		req, err := invoice.GetInvoiceRequest(base.Db.Postgresql.DB(), businessID, invoiceID)
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+
+		document, err := converter.ConvertFIRSToUBL(*req)
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", err.Error(), err, nil)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+		return c.Status(fiber.StatusOK).Send(document)
+	}
+
+	if c.Query("format") == "peppol" {
+		document, _, err := peppol.ExportInvoice(base.Db.Postgresql.DB(), businessID, invoiceID)
+		if err != nil {
+			return peppolErrorResponse(c, err)
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+		return c.Status(fiber.StatusOK).Send(document)
+	}
 
 	invoice, err := invoice.GetInvoiceDetails(base.Db.Postgresql.DB(), businessID, invoiceID)
 	if err != nil {
@@ -167,8 +206,18 @@ func (base *Controller) CreateInvoice(c *fiber.Ctx) error {
 	}
 	defer fileContent.Close()
 
+	data, err := io.ReadAll(fileContent)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "failed to read file", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	if err := schema.ValidateInvoice(data); err != nil {
+		return schemaErrorResponse(c, err)
+	}
+
 	var payload firs_models.InvoiceRequest
-	if err := utility.DecodeJSONWithDefaults(fileContent, &payload); err != nil {
+	if err := utility.DecodeJSONWithDefaults(bytes.NewReader(data), &payload); err != nil {
 		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid JSON format", nil, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
@@ -217,14 +266,16 @@ func (base *Controller) DeleteInvoice(c *fiber.Ctx) error {
 
 // UploadInvoice godoc
 // @Summary Initializes invoice creation in one go
-// @Description Receives invoice data as a json
+// @Description Receives invoice data as json, or as a UBL 2.1 Invoice/CreditNote document with Content-Type application/xml
 // @Tags Internal Invoice
 // @Accept json
+// @Accept xml
 // @Produce json
 // @Security
 // @Param   payload  body  firs_models.InvoiceRequest  true  "Invoice Payload"
 // @Success 200 {object} models.Response "Invoice created successfully"
 // @Failure 400 {object} models.Response "Bad request"
+// @Failure 422 {object} models.Response "Schema validation failed"
 // @Router /invoice/upload [post]
 func (base *Controller) UploadInvoice(c *fiber.Ctx) error {
 
@@ -235,75 +286,68 @@ func (base *Controller) UploadInvoice(c *fiber.Ctx) error {
 	}
 	var req firs_models.InvoiceRequest
 
-	err = c.BodyParser(&req)
-	if err != nil {
-		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
-		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	if isXMLContent(c) {
+		// The converted invoice is held to the same schema as a JSON upload
+		req, err = upload.ReadUBLInvoice(base.Db.Postgresql.DB(), userDetails.ID, bytes.NewReader(c.Body()))
+		if err != nil {
+			var mappingErr *converter.UBLMappingError
+			var schemaErr *schema.ValidationError
+			switch {
+			case errors.As(err, &mappingErr):
+				rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "UBL mapping failed", mappingErr.Errors, nil)
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+			case errors.As(err, &schemaErr):
+				return schemaErrorResponse(c, err)
+			}
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err.Error(), nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+	} else {
+		if err := schema.ValidateInvoice(c.Body()); err != nil {
+			return schemaErrorResponse(c, err)
+		}
+		err = c.BodyParser(&req)
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
 	}
 
-	if req.InvoiceNumber == "" {
-		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invoice number is required", err, nil)
+	assigned, err := upload.Prepare(base.Db.Postgresql.DB(), userDetails.ID, &req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err.Error(), nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
 
 	err = base.Validator.Struct(&req)
 	if err != nil {
+		upload.Release(base.Db.Postgresql.DB(), userDetails.ID, assigned)
 		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
 	}
 
-	irnPayload := make(map[string]string)
-	if req.InvoiceNumber != "" {
-		irnPayload["invoice_number"] = req.InvoiceNumber
+	result, err := upload.Submit(base.Db.Postgresql.DB(), base.Keys, userDetails.ID, req)
+	if result == nil || result.Invoice == nil {
+		upload.Release(base.Db.Postgresql.DB(), userDetails.ID, assigned)
 	}
-
-	if req.IRN == nil {
-		generatedIRN, err := invoice.GenerateIRN(req.InvoiceNumber, userDetails.ServiceID)
-		if err != nil {
-			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
-			return c.Status(fiber.StatusBadRequest).JSON(rd)
+	if result == nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, invoice.ErrIRNAlreadyIssued) {
+			status = fiber.StatusConflict
 		}
-
-		_, _, err = invoice.ValidateIRN(firs_models.IRNValidationRequest{
-			InvoiceReference: req.InvoiceNumber,
-			BusinessID:       req.BusinessID,
-			IRN:              *generatedIRN,
-		})
-		if err != nil {
-			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
-			return c.Status(fiber.StatusBadRequest).JSON(rd)
-		}
-
-		keys, err := utility.LoadCryptoKeys("crypto_keys.txt")
-		if err != nil {
-			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
-			return c.Status(fiber.StatusBadRequest).JSON(rd)
-		}
-
-		signedIRNResponse, err := invoice.SignIRN(*generatedIRN, keys)
-		if err != nil {
-			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
-			return c.Status(fiber.StatusBadRequest).JSON(rd)
-		}
-		irnPayload["irn"] = *generatedIRN
-		irnPayload["qr_code"] = signedIRNResponse.QrCodeImage
-	} else {
-		irnPayload["irn"] = *req.IRN
+		rd := utility.BuildErrorResponse(status, "error", err.Error(), err, nil)
+		return c.Status(status).JSON(rd)
 	}
 
-	value := irnPayload["irn"]
-	req.IRN = &value
-	log.Println(irnPayload)
-	createdInvoice, _, err, isInvoiceSigned := invoice.CreateInvoice(base.Db.Postgresql.DB(), req, req.InvoiceNumber, userDetails.ID)
-
-	response := map[string]interface{}{
-		"metadata": createdInvoice.StatusHistory,
+	response := map[string]interface{}{}
+	if result.Invoice != nil {
+		response["metadata"] = result.Invoice.StatusHistory
 	}
-	if isInvoiceSigned {
+	if result.Signed {
 		response["data"] = map[string]string{
-			"invoice_number": irnPayload["invoice_number"],
-			"irn":            irnPayload["irn"],
-			"qr_code":        irnPayload["qr_code"],
+			"invoice_number": result.InvoiceNumber,
+			"irn":            result.IRN,
+			"qr_code":        result.QRCode,
 		}
 	}
 
@@ -316,3 +360,74 @@ func (base *Controller) UploadInvoice(c *fiber.Ctx) error {
 	rd := utility.BuildSuccessResponse(fiber.StatusCreated, "Invoice created successfully", response)
 	return c.Status(fiber.StatusCreated).JSON(rd)
 }
+
+// isXMLContent reports whether the request body is a UBL XML document
+func isXMLContent(c *fiber.Ctx) bool {
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+	return strings.HasPrefix(contentType, fiber.MIMEApplicationXML) || strings.HasPrefix(contentType, fiber.MIMETextXML)
+}
+
+// // SendPeppolInvoice godoc
+// // @Summary Send invoice over PEPPOL
+// // @Description Exports the invoice as PEPPOL BIS 3.0 and hands it to the configured PEPPOL access point
+// // @Tags Internal Invoice
+// // @Produce json
+// // @Security BearerAuth
+// // @Param business_id path string true "Business ID" format(uuid)
+// // @Param invoice_id path string true "Invoice ID" format(uuid)
+// // @Success 200 {object} models.Response
+// // @Failure 400 {object} models.Response
+// // @Failure 403 {object} models.Response "Invoice of another business"
+// // @Failure 422 {object} models.Response "PEPPOL business rule violations"
+// // @Router /invoice/business/{business_id}/{invoice_id}/peppol [post]
+func (base *Controller) SendPeppolInvoice(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	accessPoint, err := peppolAccessPoint.DefaultAccessPoint()
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusServiceUnavailable, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(rd)
+	}
+
+	result, err := peppol.SendInvoice(base.Db.Postgresql.DB(), accessPoint, businessID, invoiceID)
+	if err != nil {
+		return peppolErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice sent over PEPPOL successfully", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+func peppolErrorResponse(c *fiber.Ctx, err error) error {
+	var validationErr *converter.PeppolValidationError
+	if errors.As(err, &validationErr) {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "PEPPOL validation failed", validationErr.Violations, nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+	if errors.Is(err, peppol.ErrPeppolNotEnabled) {
+		rd := utility.BuildErrorResponse(fiber.StatusForbidden, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusForbidden).JSON(rd)
+	}
+	rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+	return c.Status(fiber.StatusBadRequest).JSON(rd)
+}
+
+// schemaErrorResponse reports schema violations with the JSON pointer of every offending value
+func schemaErrorResponse(c *fiber.Ctx, err error) error {
+	var schemaErr *schema.ValidationError
+	if errors.As(err, &schemaErr) {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Schema validation failed", schemaErr.Errors, nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+	rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid JSON format", err.Error(), nil)
+	return c.Status(fiber.StatusBadRequest).JSON(rd)
+}
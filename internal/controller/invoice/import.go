@@ -0,0 +1,168 @@
+package invoice
+
+import (
+	"einvoice-access-point/internal/services/upload"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetImportMapping godoc
+// @Summary      Get Import Column Mapping
+// @Description  Returns the saved spreadsheet column mapping of the business, or the default mapping where every field is read from a column of the same name and dates are DD/MM/YYYY
+// @Tags         Internal Invoice
+// @Produce      json
+// @Security BearerAuth
+// @Success      200 {object} models.Response "Column mapping retrieved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Router       /invoice/import/mapping [get]
+func (base *Controller) GetImportMapping(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	mapping, err := upload.GetImportMapping(base.Db.Postgresql.DB(), userDetails.ID)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "column mapping retrieved successfully", fiber.Map{
+		"columns":         mapping.Columns,
+		"date_format":     mapping.DateFormat,
+		"fields":          models.ImportFields,
+		"required_fields": models.RequiredImportFields,
+		"date_formats":    models.ImportDateFormats,
+	})
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// SaveImportMapping godoc
+// @Summary      Save Import Column Mapping
+// @Description  Maps invoice fields onto the column headers of the business spreadsheets and sets the format of their date columns: YYYY-MM-DD, DD/MM/YYYY or MM/DD/YYYY
+// @Tags         Internal Invoice
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param data body models.ImportMappingRequest true "Column mapping"
+// @Success      200 {object} models.Response "Column mapping saved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /invoice/import/mapping [put]
+func (base *Controller) SaveImportMapping(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.ImportMappingRequest
+	if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	mapping, err := upload.SaveImportMapping(base.Db.Postgresql.DB(), userDetails.ID, req)
+	if err != nil {
+		var mappingErr *upload.MappingError
+		if errors.As(err, &mappingErr) {
+			rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", mappingErr.Problems, nil)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "column mapping saved successfully", mapping)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// ImportInvoices godoc
+// @Summary      Import Invoices From Spreadsheet
+// @Description  Reads a CSV or XLSX file (multipart field "file") with one row per line item, groups the rows into invoices by invoice number using the saved column mapping and queues every invoice without row errors for submission. Poll the import for the outcome of each invoice.
+// @Tags         Internal Invoice
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security BearerAuth
+// @Param file formData file true "Invoices CSV or XLSX file"
+// @Success      202 {object} models.Response "Invoice import queued"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      422 {object} models.Response "Invalid column mapping"
+// @Router       /invoice/import [post]
+func (base *Controller) ImportInvoices(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invoices CSV or XLSX file is required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	fileContent, err := file.Open()
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "failed to read file", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	defer fileContent.Close()
+
+	invoiceImport, err := upload.ImportInvoices(base.Db.Postgresql.DB(), base.Validator, userDetails.ID, file.Filename, fileContent)
+	if err != nil {
+		var mappingErr *upload.MappingError
+		if errors.As(err, &mappingErr) {
+			rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Invalid column mapping", mappingErr.Problems, nil)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err.Error(), nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusAccepted, "invoice import queued", invoiceImport)
+	return c.Status(fiber.StatusAccepted).JSON(rd)
+}
+
+// GetImport godoc
+// @Summary      Get Invoice Import
+// @Description  Returns the status of a spreadsheet import with the row errors and the outcome of every invoice grouped from the file
+// @Tags         Internal Invoice
+// @Produce      json
+// @Security BearerAuth
+// @Param import_id path string true "Import ID" format(uuid)
+// @Success      200 {object} models.Response "Import retrieved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      404 {object} models.Response "Import not found"
+// @Router       /invoice/import/{import_id} [get]
+func (base *Controller) GetImport(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	invoiceImport, err := upload.GetImport(base.Db.Postgresql.DB(), userDetails.ID, c.Params("import_id"))
+	if err != nil {
+		if errors.Is(err, upload.ErrImportNotFound) {
+			rd := utility.BuildErrorResponse(fiber.StatusNotFound, "error", err.Error(), nil, nil)
+			return c.Status(fiber.StatusNotFound).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "import retrieved successfully", invoiceImport)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
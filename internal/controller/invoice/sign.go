@@ -5,8 +5,10 @@ import (
 	"einvoice-access-point/internal/services/invoice"
 	"einvoice-access-point/pkg/middleware"
 	"einvoice-access-point/pkg/utility"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // // SignIRN godoc
@@ -47,6 +49,49 @@ func (base *Controller) SignIRN(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(rd)
 }
 
+// GetInvoiceQRCode godoc
+// @Summary Get invoice QR code
+// @Description Regenerates the QR code of an invoice from its stored encrypted IRN payload as a PNG or SVG image
+// @Tags Invoice
+// @Produce png
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param irn path string true "Invoice IRN"
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param size query int false "Width and height in pixels (64-2048)" default(256)
+// @Param ecc query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Success 200 {file} binary "QR code image"
+// @Failure 400 {object} models.Response "Bad request"
+// @Failure 404 {object} models.Response "Invoice not found"
+// @Router /invoice/{irn}/qr [get]
+func (base *Controller) GetInvoiceQRCode(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	opts, err := invoice.ParseQROptions(c.Query("format"), c.Query("size"), c.Query("ecc"))
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err.Error(), nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	image, contentType, err := invoice.GetInvoiceQRCode(base.Db.Postgresql.DB(), base.Keys, userDetails.ID, c.Params("irn"), opts)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rd := utility.BuildErrorResponse(fiber.StatusNotFound, "error", "invoice not found", nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	return c.Status(fiber.StatusOK).Send(image)
+}
+
 // // SignInvoice godoc
 // // @Summary Sign Invoice
 // // @Description Signs an invoice and generates a digital signature.
@@ -118,7 +163,7 @@ func (base *Controller) GenerateIRN(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
 	}
 
-	respData, err := invoice.GenerateIRN(req.InvoiceNumber, userDetails.ServiceID)
+	respData, err := invoice.IssueIRN(base.Db.Postgresql.DB(), userDetails.ID, req.InvoiceNumber)
 	if err != nil {
 		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
@@ -2,44 +2,80 @@ package invoice
 
 import (
 	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/connector"
 	services "einvoice-access-point/internal/services/webhooks"
 	"einvoice-access-point/pkg/utility"
-	"fmt"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
 func (base *Controller) HandleZohoWebhook(c *fiber.Ctx) error {
-	body := c.Body()
-	fmt.Printf("Webhook body is: %s\n\n", string(body))
+	return base.handlePlatformWebhook(c, zoho.InvoiceAPI.Platform)
+}
 
-	organisationID := c.Query("organisation_id")
+func (base *Controller) HandleZohoBooksWebhook(c *fiber.Ctx) error {
+	return base.handlePlatformWebhook(c, zoho.BooksAPI.Platform)
+}
 
-	if organisationID == "" {
-		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "No organisation ID present", nil, nil)
-		return c.Status(fiber.StatusBadRequest).JSON(rd)
+// @Summary      Platform Webhook
+// @Description  Receives invoice webhooks of a connected accounting platform. The connector of the platform decodes the webhook and checks its signature against the secret of the organisation. A webhook without events, such as the intent to receive Xero sends, is answered with a bare status.
+// @Tags         Invoice
+// @Accept       json
+// @Produce      json
+// @Param        name path string true "Platform, as keyed in the business platform configs"
+// @Success      200 {object} models.Response "Webhook processed successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Invalid webhook signature"
+// @Failure      404 {object} models.Response "Unknown platform"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /platform/{name}/webhook [post]
+func (base *Controller) HandlePlatformWebhook(c *fiber.Ctx) error {
+	return base.handlePlatformWebhook(c, c.Params("name"))
+}
+
+// handlePlatformWebhook hands a webhook to the connector registered for the platform
+func (base *Controller) handlePlatformWebhook(c *fiber.Ctx, platform string) error {
+	conn, err := connector.Get(platform)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusNotFound, "error", err.Error(), nil, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
 	}
 
-	var payload zoho.WebhookPayload
-	if err := c.BodyParser(&payload); err != nil {
+	req := connector.WebhookRequest{
+		Body:    append([]byte(nil), c.Body()...),
+		Query:   c.Queries(),
+		Headers: c.GetReqHeaders(),
+	}
+
+	// platforms checking the endpoint with an empty, signed webhook expect a bare status back
+	webhooks, err := conn.ParseWebhook(req)
+	if errors.Is(err, connector.ErrInvalidSignature) {
+		base.Logger.Error("Invalid webhook signature", zap.String("platform", platform))
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	if err != nil {
 		base.Logger.Error("Failed to parse request body", zap.Error(err))
 		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err.Error(), nil)
 		return c.Status(fiber.StatusBadRequest).JSON(rd)
 	}
 
-	if err := base.Validator.Struct(&payload); err != nil {
-		base.Logger.Error("Validation failed", zap.Error(err))
-		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
-		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	if len(webhooks) == 0 {
+		return c.SendStatus(fiber.StatusOK)
 	}
 
-	// Keep raw body for signature check
-	signature := c.Get("X-Zoho-Signature")
+	for _, webhook := range webhooks {
+		if err := base.Validator.Struct(webhook.Payload); err != nil {
+			base.Logger.Error("Validation failed", zap.Error(err))
+			rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+		}
+	}
 
-	respData, errDetails, err := services.HandleZohoWebhookService(payload, string(c.Body()), signature, base.Db.Postgresql.DB(), base.Logger, base.Keys, organisationID)
+	respData, errDetails, err := services.HandlePlatformWebhook(conn, req, webhooks, base.Db.Postgresql.DB(), base.Logger, base.Keys)
 	if err != nil {
-		if err == services.ErrInvalidSignature {
+		if errors.Is(err, connector.ErrInvalidSignature) {
 			rd := utility.BuildErrorResponse(fiber.StatusUnauthorized, "error", "Invalid webhook signature", nil, nil)
 			return c.Status(fiber.StatusUnauthorized).JSON(rd)
 		}
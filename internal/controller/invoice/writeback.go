@@ -0,0 +1,86 @@
+package invoice
+
+import (
+	"einvoice-access-point/internal/services/writeback"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetInvoiceWriteBack godoc
+// @Summary Get invoice status write-back
+// @Description Returns the FIRS status last queued for the invoice on its accounting platform, with the attempts and last error of sending it
+// @Tags Internal Invoice
+// @Produce json
+// @Security BearerAuth
+// @Param business_id path string true "Business ID" format(uuid)
+// @Param invoice_id path string true "Invoice ID" format(uuid)
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 403 {object} models.Response "Invoice of another business"
+// @Failure 404 {object} models.Response "Invoice has no status write-back"
+// @Router /invoice/business/{business_id}/{invoice_id}/write-back [get]
+func (base *Controller) GetInvoiceWriteBack(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	result, err := writeback.GetWriteBack(base.Db.Postgresql.DB(), businessID, invoiceID)
+	if err != nil {
+		return writeBackErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice status write-back retrieved successfully", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+// RetryInvoiceWriteBack godoc
+// @Summary Retry invoice status write-back
+// @Description Queues the current FIRS status of the invoice to be written back to its accounting platform again
+// @Tags Internal Invoice
+// @Produce json
+// @Security BearerAuth
+// @Param business_id path string true "Business ID" format(uuid)
+// @Param invoice_id path string true "Invoice ID" format(uuid)
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 403 {object} models.Response "Invoice of another business"
+// @Failure 404 {object} models.Response "Invoice has no status write-back"
+// @Router /invoice/business/{business_id}/{invoice_id}/write-back/retry [post]
+func (base *Controller) RetryInvoiceWriteBack(c *fiber.Ctx) error {
+	businessID := c.Params("business_id")
+	invoiceID := c.Params("invoice_id")
+
+	if businessID == "" || invoiceID == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "business_id and invoice_id are required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+	if ok, err := ownBusiness(c, businessID); !ok {
+		return err
+	}
+
+	result, err := writeback.RetryWriteBack(base.Db.Postgresql.DB(), businessID, invoiceID)
+	if err != nil {
+		return writeBackErrorResponse(c, err)
+	}
+
+	rd := utility.BuildSuccessResponse(fiber.StatusOK, "Invoice status write-back queued", result)
+	return c.Status(fiber.StatusOK).JSON(rd)
+}
+
+func writeBackErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, writeback.ErrWriteBackNotFound) {
+		rd := utility.BuildErrorResponse(fiber.StatusNotFound, "error", err.Error(), err, nil)
+		return c.Status(fiber.StatusNotFound).JSON(rd)
+	}
+	rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err, nil)
+	return c.Status(fiber.StatusBadRequest).JSON(rd)
+}
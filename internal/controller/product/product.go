@@ -0,0 +1,148 @@
+package product
+
+import (
+	"einvoice-access-point/internal/services/product"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	Db        *database.Database
+	Validator *validator.Validate
+	Logger    *utility.Logger
+}
+
+// @Summary      Get Products
+// @Description  List the product catalogue of the authenticated business
+// @Tags         Product
+// @Produce      json
+// @Security BearerAuth
+// @Param        platform query string false "Filter by platform, e.g. zoho"
+// @Success      200 {object} models.Response "Products retrieved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Router       /products [get]
+func (base *Controller) GetProducts(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	respData, err := product.GetProducts(base.Db.Postgresql.DB(), userDetails.ID, strings.ToLower(c.Query("platform")))
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "products gotten successfully", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Import Products
+// @Description  Create or update catalogue entries from a JSON payload or a CSV file upload (multipart field "file")
+// @Tags         Product
+// @Accept       json
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security BearerAuth
+// @Param data body models.ImportProductsRequest false "Products payload"
+// @Param file formData file false "Products CSV file"
+// @Success      200 {object} models.Response "Products imported successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /products/import [post]
+func (base *Controller) ImportProducts(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.ImportProductsRequest
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "products CSV file is required", nil, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+
+		fileContent, err := file.Open()
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "failed to read file", nil, nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+		defer fileContent.Close()
+
+		req.Products, err = product.ParseProductsCSV(fileContent)
+		if err != nil {
+			rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", err.Error(), err.Error(), nil)
+			return c.Status(fiber.StatusBadRequest).JSON(rd)
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	respData, err := product.ImportProducts(base.Db.Postgresql.DB(), userDetails.ID, req.Products)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "products imported successfully", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Delete Product
+// @Description  Remove an entry from the product catalogue
+// @Tags         Product
+// @Produce      json
+// @Security BearerAuth
+// @Param        id path string true "Product ID" format(uuid)
+// @Success      200 {object} models.Response "Product deleted successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      404 {object} models.Response "Product not found"
+// @Router       /products/{id} [delete]
+func (base *Controller) DeleteProduct(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	id := c.Params("id")
+	if !utility.IsValidUUID(id) {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "invalid product id format", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = product.DeleteProduct(base.Db.Postgresql.DB(), userDetails.ID, id)
+	if err != nil {
+		if errors.Is(err, product.ErrProductNotFound) {
+			rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+			return c.Status(http.StatusNotFound).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "product deleted successfully", nil)
+	return c.Status(http.StatusOK).JSON(rd)
+}
@@ -0,0 +1,37 @@
+package schema
+
+import (
+	"einvoice-access-point/internal/services/schema"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/utility"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	Db        *database.Database
+	Validator *validator.Validate
+	Logger    *utility.Logger
+}
+
+// GetInvoiceSchema godoc
+// @Summary      Invoice JSON Schema
+// @Description  Returns the JSON Schema (draft 2020-12) that invoices sent to /invoice/upload and /invoice/create are validated against
+// @Tags         Schemas
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  models.Response
+// @Router       /schemas/invoice.json [get]
+func (base *Controller) GetInvoiceSchema(c *fiber.Ctx) error {
+	document, err := schema.InvoiceSchema()
+	if err != nil {
+		base.Logger.Error("failed to generate invoice schema", err)
+		rd := utility.BuildErrorResponse(fiber.StatusInternalServerError, "error", "failed to generate invoice schema", err, nil)
+		return c.Status(fiber.StatusInternalServerError).JSON(rd)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/schema+json")
+	c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	return c.Status(fiber.StatusOK).Send(document)
+}
@@ -0,0 +1,143 @@
+package series
+
+import (
+	seriesRepository "einvoice-access-point/internal/repository/series"
+	"einvoice-access-point/internal/services/series"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/middleware"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	Db        *database.Database
+	Validator *validator.Validate
+	Logger    *utility.Logger
+}
+
+// @Summary      Get Invoice Series
+// @Description  List the invoice number series configured for the authenticated business
+// @Tags         Invoice Series
+// @Produce      json
+// @Security BearerAuth
+// @Success      200 {object} models.Response "Invoice series retrieved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Router       /invoice-series [get]
+func (base *Controller) GetAllSeries(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	respData, err := series.GetAllSeries(base.Db.Postgresql.DB(), userDetails.ID)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "invoice series gotten successfully", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Save Invoice Series
+// @Description  Create or update an invoice number series (prefix, padding, yearly reset and branch code)
+// @Tags         Invoice Series
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param data body models.InvoiceSeriesRequest true "Invoice series payload"
+// @Success      200 {object} models.Response "Invoice series saved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /invoice-series [post]
+func (base *Controller) SaveSeries(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.InvoiceSeriesRequest
+	err = c.BodyParser(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	respData, err := series.SaveSeries(base.Db.Postgresql.DB(), userDetails.ID, req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "invoice series saved successfully", respData)
+	return c.Status(http.StatusOK).JSON(rd)
+}
+
+// @Summary      Reserve Invoice Numbers
+// @Description  Reserve the next invoice numbers of a series. Numbers are handed out exactly once, even under concurrent requests.
+// @Tags         Invoice Series
+// @Accept       json
+// @Produce      json
+// @Security BearerAuth
+// @Param        name path string true "Series name"
+// @Param data body models.ReserveInvoiceNumbersRequest true "Reserve invoice numbers payload"
+// @Success      200 {object} models.Response "Invoice numbers reserved successfully"
+// @Failure      400 {object} models.Response "Bad request"
+// @Failure      401 {object} models.Response "Unauthorized"
+// @Failure      404 {object} models.Response "Series not found"
+// @Failure      422 {object} models.Response "Validation failed"
+// @Router       /invoice-series/{name}/reserve [post]
+func (base *Controller) ReserveInvoiceNumbers(c *fiber.Ctx) error {
+	userDetails, err := middleware.GetUserDetails(c)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "unable to get user claims", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	name := c.Params("name")
+	if name == "" {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "series name is required", nil, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	var req models.ReserveInvoiceNumbersRequest
+	err = c.BodyParser(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusBadRequest, "error", "Failed to parse request body", err, nil)
+		return c.Status(fiber.StatusBadRequest).JSON(rd)
+	}
+
+	err = base.Validator.Struct(&req)
+	if err != nil {
+		rd := utility.BuildErrorResponse(fiber.StatusUnprocessableEntity, "error", "Validation failed", utility.ValidationResponse(err, base.Validator), nil)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(rd)
+	}
+
+	numbers, err := series.ReserveInvoiceNumbers(base.Db.Postgresql.DB(), userDetails.ID, name, req.Count)
+	if err != nil {
+		if errors.Is(err, seriesRepository.ErrSeriesNotFound) {
+			rd := utility.BuildErrorResponse(http.StatusNotFound, "error", err.Error(), err, nil)
+			return c.Status(http.StatusNotFound).JSON(rd)
+		}
+		rd := utility.BuildErrorResponse(http.StatusBadRequest, "error", err.Error(), err, nil)
+		return c.Status(http.StatusBadRequest).JSON(rd)
+	}
+
+	rd := utility.BuildSuccessResponse(http.StatusOK, "invoice numbers reserved successfully", fiber.Map{"series": name, "invoice_numbers": numbers})
+	return c.Status(http.StatusOK).JSON(rd)
+}
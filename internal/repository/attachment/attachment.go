@@ -0,0 +1,60 @@
+package attachment
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+func FindAttachmentsByInvoice(db database.DatabaseManager, businessID, invoiceID string) ([]models.InvoiceAttachment, error) {
+	var attachments []models.InvoiceAttachment
+	if err := db.DB().Where("business_id = ? AND invoice_id = ?", businessID, invoiceID).Order("kind asc").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func FindDueAttachments(db database.DatabaseManager, now time.Time, limit int) ([]models.InvoiceAttachment, error) {
+	var attachments []models.InvoiceAttachment
+	if err := db.DB().
+		Where("status = ? AND next_attempt_at <= ?", models.AttachmentPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// UpsertAttachment queues the upload, restarting it when the invoice already has one of the same kind
+func UpsertAttachment(db database.DatabaseManager, attachment *models.InvoiceAttachment) error {
+	return db.DB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "invoice_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"platform", "org_id", "external_invoice_id", "irn", "filename", "document", "status", "attempts",
+			"last_error", "next_attempt_at", "sent_at", "updated_at", "deleted_at",
+		}),
+	}).Create(attachment).Error
+}
+
+func UpdateAttachment(db database.DatabaseManager, attachment *models.InvoiceAttachment) error {
+	return db.DB().Save(attachment).Error
+}
+
+// ClaimAttachment pushes the next attempt of a due upload past the lease so concurrent workers skip it.
+// It reports false when another worker claimed the upload first.
+func ClaimAttachment(db database.DatabaseManager, attachment *models.InvoiceAttachment, until time.Time) (bool, error) {
+	result := db.DB().Model(&models.InvoiceAttachment{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", attachment.ID, models.AttachmentPending, attachment.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	attachment.NextAttemptAt = &until
+	return true, nil
+}
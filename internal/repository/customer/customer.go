@@ -0,0 +1,125 @@
+package customer
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func FindCustomersByBusiness(db database.DatabaseManager, businessID, platform string) ([]models.Customer, error) {
+	var customers []models.Customer
+	query := db.DB().Where("business_id = ?", businessID)
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	if err := query.Order("name asc").Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func FindCustomer(db database.DatabaseManager, businessID, customerID string) (*models.Customer, error) {
+	var customer models.Customer
+	if err := db.DB().Where("business_id = ? AND id = ?", businessID, customerID).First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func FindCustomerByPlatformID(db database.DatabaseManager, businessID, platform, platformCustomerID string) (*models.Customer, error) {
+	var customer models.Customer
+	if err := db.DB().
+		Where("business_id = ? AND platform = ? AND platform_customer_id = ?", businessID, platform, platformCustomerID).
+		First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func FindCustomerByTIN(db database.DatabaseManager, businessID, tin string) (*models.Customer, error) {
+	var customer models.Customer
+	if err := db.DB().Where("business_id = ? AND tin = ?", businessID, tin).First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// FindCustomersDueForVerification returns customers never verified or last verified before verifiedBefore that were
+// not checked since attemptedBefore, least recently checked first. Ordering by the last check rather than the last
+// success keeps customers whose checks keep failing from starving the rest of the directory.
+func FindCustomersDueForVerification(db database.DatabaseManager, verifiedBefore, attemptedBefore time.Time, limit int) ([]models.Customer, error) {
+	var customers []models.Customer
+	if err := db.DB().
+		Where("tin_verified_at IS NULL OR tin_verified_at < ?", verifiedBefore).
+		Where("last_attempt_at IS NULL OR last_attempt_at < ?", attemptedBefore).
+		Order("last_attempt_at asc nulls first").
+		Limit(limit).
+		Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+// UpsertCustomers inserts the customers or overwrites the details of those already in the directory. The TIN
+// verification of an existing customer is kept, unless its TIN changed, in which case it is checked again.
+func UpsertCustomers(db database.DatabaseManager, customers []models.Customer) error {
+	updates := clause.AssignmentColumns([]string{
+		"name", "tin", "email", "telephone", "street_name", "city_name", "postal_zone", "country", "peppol_endpoint",
+		"updated_at", "deleted_at",
+	})
+	// the right hand sides read the row as it was before the update
+	for _, column := range [][2]string{
+		{"tin_verified", "false"},
+		{"tin_verified_at", "NULL"},
+		{"last_attempt_at", "NULL"},
+		{"verification_error", "''"},
+	} {
+		updates = append(updates, clause.Assignment{
+			Column: clause.Column{Name: column[0]},
+			Value:  gorm.Expr(fmt.Sprintf("CASE WHEN customers.tin = excluded.tin THEN customers.%s ELSE %s END", column[0], column[1])),
+		})
+	}
+
+	return db.DB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "business_id"}, {Name: "platform"}, {Name: "platform_customer_id"}},
+		DoUpdates: updates,
+	}).Create(&customers).Error
+}
+
+// FindCustomersByPlatformIDs returns the stored customers with the platform and platform ID of the given ones
+func FindCustomersByPlatformIDs(db database.DatabaseManager, businessID string, customers []models.Customer) ([]models.Customer, error) {
+	keys := make([][]interface{}, 0, len(customers))
+	for _, customer := range customers {
+		keys = append(keys, []interface{}{customer.Platform, customer.PlatformCustomerID})
+	}
+
+	var found []models.Customer
+	if len(keys) == 0 {
+		return found, nil
+	}
+	if err := db.DB().
+		Where("business_id = ? AND (platform, platform_customer_id) IN ?", businessID, keys).
+		Order("platform asc, platform_customer_id asc").
+		Find(&found).Error; err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func UpdateVerification(db database.DatabaseManager, customer *models.Customer) error {
+	return db.DB().Model(customer).Updates(map[string]interface{}{
+		"tin_verified":       customer.TINVerified,
+		"tin_verified_at":    customer.TINVerifiedAt,
+		"last_attempt_at":    customer.LastAttemptAt,
+		"verification_error": customer.VerificationError,
+	}).Error
+}
+
+func DeleteCustomer(db database.DatabaseManager, businessID, customerID string) (int64, error) {
+	result := db.DB().Where("business_id = ? AND id = ?", businessID, customerID).Delete(&models.Customer{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,60 @@
+package delivery
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+func FindDeliveryByInvoice(db database.DatabaseManager, businessID, invoiceID string) (*models.InvoiceDelivery, error) {
+	var delivery models.InvoiceDelivery
+	if err := db.DB().Where("business_id = ? AND invoice_id = ?", businessID, invoiceID).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func FindDueDeliveries(db database.DatabaseManager, now time.Time, limit int) ([]models.InvoiceDelivery, error) {
+	var deliveries []models.InvoiceDelivery
+	if err := db.DB().
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// UpsertDelivery queues the delivery, restarting it when the invoice already has one
+func UpsertDelivery(db database.DatabaseManager, delivery *models.InvoiceDelivery) error {
+	return db.DB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "invoice_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"recipient", "document", "status", "attempts", "last_error", "message_id",
+			"next_attempt_at", "sent_at", "bounced_at", "updated_at", "deleted_at",
+		}),
+	}).Create(delivery).Error
+}
+
+func UpdateDelivery(db database.DatabaseManager, delivery *models.InvoiceDelivery) error {
+	return db.DB().Save(delivery).Error
+}
+
+// ClaimDelivery pushes the next attempt of a due delivery past the lease so concurrent workers skip it.
+// It reports false when another worker claimed the delivery first.
+func ClaimDelivery(db database.DatabaseManager, delivery *models.InvoiceDelivery, until time.Time) (bool, error) {
+	result := db.DB().Model(&models.InvoiceDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, models.DeliveryPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.NextAttemptAt = &until
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"time"
+)
+
+func CreateExport(db database.DatabaseManager, export *models.InvoiceExport) error {
+	return db.DB().Create(export).Error
+}
+
+func FindExport(db database.DatabaseManager, businessID, exportID string) (*models.InvoiceExport, error) {
+	var export models.InvoiceExport
+	if err := db.DB().Where("business_id = ? AND id = ?", businessID, exportID).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func FindExportsByBusiness(db database.DatabaseManager, businessID string) ([]models.InvoiceExport, error) {
+	var exports []models.InvoiceExport
+	if err := db.DB().Where("business_id = ?", businessID).Order("created_at desc").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// FindQueuedExports returns pending exports, and exports whose build stalled before staleBefore
+func FindQueuedExports(db database.DatabaseManager, staleBefore time.Time, limit int) ([]models.InvoiceExport, error) {
+	var exports []models.InvoiceExport
+	if err := db.DB().
+		Where("status = ? OR (status = ? AND updated_at < ?)", models.ExportPending, models.ExportProcessing, staleBefore).
+		Order("created_at asc").
+		Limit(limit).
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func FindExpiredExports(db database.DatabaseManager, now time.Time, limit int) ([]models.InvoiceExport, error) {
+	var exports []models.InvoiceExport
+	if err := db.DB().
+		Where("status IN ? AND expires_at <= ?", []string{models.ExportReady, models.ExportFailed}, now).
+		Limit(limit).
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func UpdateExport(db database.DatabaseManager, export *models.InvoiceExport) error {
+	return db.DB().Save(export).Error
+}
+
+// ClaimExport marks a queued export as processing so concurrent workers skip it.
+// It reports false when another worker claimed the export first.
+func ClaimExport(db database.DatabaseManager, export *models.InvoiceExport) (bool, error) {
+	now := time.Now()
+	result := db.DB().Model(&models.InvoiceExport{}).
+		Where("id = ? AND status = ? AND updated_at = ?", export.ID, export.Status, export.UpdatedAt).
+		Updates(map[string]interface{}{"status": models.ExportProcessing, "updated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	export.Status = models.ExportProcessing
+	export.UpdatedAt = now
+	return true, nil
+}
+
+// FindInvoicesForExport returns a page of the invoices of a business issued in the period, oldest first
+func FindInvoicesForExport(db database.DatabaseManager, businessID, fromDate, toDate string, statuses []string, offset, limit int) ([]models.Invoice, error) {
+	query := db.DB().
+		Where("business_id = ? AND issue_date BETWEEN ? AND ?", businessID, fromDate, toDate)
+	if len(statuses) > 0 {
+		query = query.Where("current_status IN ?", statuses)
+	}
+
+	var invoices []models.Invoice
+	if err := query.
+		Order("issue_date asc, invoice_number asc, id asc").
+		Offset(offset).
+		Limit(limit).
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
@@ -0,0 +1,24 @@
+package importmapping
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func FindMappingByBusiness(db database.DatabaseManager, businessID string) (*models.ImportMapping, error) {
+	var mapping models.ImportMapping
+	if err := db.DB().Where("business_id = ?", businessID).First(&mapping).Error; err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// UpsertMapping saves the mapping, replacing the columns and date format of an existing mapping of the business
+func UpsertMapping(db database.DatabaseManager, mapping *models.ImportMapping) error {
+	return db.DB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "business_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"columns", "date_format", "updated_at", "deleted_at"}),
+	}).Create(mapping).Error
+}
@@ -1,48 +1,37 @@
 package invoice
 
 import (
+	seriesRepository "einvoice-access-point/internal/repository/series"
 	"einvoice-access-point/pkg/database"
+	inst "einvoice-access-point/pkg/dbinit"
 	"einvoice-access-point/pkg/models"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
-	"strconv"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// GenerateUniqueInvoiceID reserves the next number of the business default invoice series
 func GenerateUniqueInvoiceID(businessID string, db *gorm.DB) string {
-	var lastInvoice models.Invoice
-	var newInvoiceNumber string
-
-	err := db.Where("business_id = ?", businessID).
-		Order("invoice_number DESC").
-		First(&lastInvoice).Error
-
+	numbers, err := seriesRepository.ReserveNumbers(inst.InitDB(db, true), businessID, models.DefaultInvoiceSeries, 1)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			newInvoiceNumber = "INV00001"
-		} else {
-			log.Println("Error fetching last invoice:", err)
-			return ""
-		}
-	} else {
-		lastNumber, _ := strconv.Atoi(lastInvoice.InvoiceNumber[3:])
-		newInvoiceNumber = fmt.Sprintf("INV%05d", lastNumber+1)
+		log.Println("Error reserving invoice number:", err)
+		return ""
 	}
 
-	return newInvoiceNumber
+	return numbers[0]
 }
 
 func CreateInvoice(db database.DatabaseManager, invoice *models.Invoice) error {
 	return db.DB().Create(invoice).Error
 }
 
-func FindInvoiceByNumber(db database.DatabaseManager, invoiceNumber string) (*models.Invoice, error) {
+func FindInvoiceByNumber(db database.DatabaseManager, businessID, invoiceNumber string) (*models.Invoice, error) {
 	var invoice models.Invoice
-	err := db.DB().Where("invoice_number = ?", invoiceNumber).First(&invoice).Error
+	err := db.DB().Where("business_id = ? AND invoice_number = ?", businessID, invoiceNumber).First(&invoice).Error
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +39,12 @@ func FindInvoiceByNumber(db database.DatabaseManager, invoiceNumber string) (*mo
 }
 
 func UpdateInvoiceStatus(db database.DatabaseManager, invoice *models.Invoice, step string, status string) error {
+	return UpdateInvoiceStatusDetail(db, invoice, step, status, "")
+}
+
+// UpdateInvoiceStatusDetail records the outcome of a processing step with the reason it failed, if any. Invoices
+// received from a platform get their status write-back queued in the same transaction.
+func UpdateInvoiceStatusDetail(db database.DatabaseManager, invoice *models.Invoice, step, status, detail string) error {
 	var history []models.StatusHistoryEntry
 
 	if len(invoice.StatusHistory) > 0 {
@@ -60,6 +55,7 @@ func UpdateInvoiceStatus(db database.DatabaseManager, invoice *models.Invoice, s
 		if history[i].Step == step {
 			history[i].Status = status
 			history[i].Timestamp = time.Now()
+			history[i].Detail = detail
 			break
 		}
 	}
@@ -68,14 +64,136 @@ func UpdateInvoiceStatus(db database.DatabaseManager, invoice *models.Invoice, s
 	invoice.StatusHistory = historyJSON
 	invoice.CurrentStatus = step
 
+	return db.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(invoice).Error; err != nil {
+			return err
+		}
+		return queueStatusWriteBack(tx, invoice, step, status, detail)
+	})
+}
+
+// queueStatusWriteBack replaces the pending write-back of an invoice that came from a platform with its new status
+func queueStatusWriteBack(tx *gorm.DB, invoice *models.Invoice, step, status, detail string) error {
+	if invoice.Platform == "" || invoice.PlatformMetadata == "" {
+		return nil
+	}
+	var metadata models.PlatformMetadata
+	if err := json.Unmarshal([]byte(invoice.PlatformMetadata), &metadata); err != nil {
+		return nil
+	}
+	externalID := metadata[invoice.Platform].InvoiceID
+	if externalID == "" {
+		return nil
+	}
+
+	firsError := ""
+	if status != "success" {
+		firsError = detail
+	}
+	now := time.Now()
+	writeBack := &models.StatusWriteBack{
+		BusinessID:        invoice.BusinessID,
+		InvoiceID:         invoice.ID,
+		Platform:          invoice.Platform,
+		ExternalInvoiceID: externalID,
+		FirsStatus:        step + ":" + status,
+		FirsError:         firsError,
+		Revision:          1,
+		Status:            models.WriteBackPending,
+		NextAttemptAt:     &now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "invoice_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"external_invoice_id": writeBack.ExternalInvoiceID,
+			"firs_status":         writeBack.FirsStatus,
+			"firs_error":          writeBack.FirsError,
+			"revision":            gorm.Expr("status_write_backs.revision + 1"),
+			"status":              models.WriteBackPending,
+			"attempts":            0,
+			"last_error":          "",
+			"next_attempt_at":     now,
+			"updated_at":          now,
+			"deleted_at":          nil,
+		}),
+	}).Create(writeBack).Error
+}
+
+// AppendInvoiceEvent adds a platform event to the invoice timeline, leaving the current status as it is
+func AppendInvoiceEvent(db database.DatabaseManager, invoice *models.Invoice, step, status, detail string) error {
+	var history []models.StatusHistoryEntry
+
+	if len(invoice.StatusHistory) > 0 {
+		_ = json.Unmarshal(invoice.StatusHistory, &history)
+	}
+
+	history = append(history, models.StatusHistoryEntry{
+		Step:      step,
+		Status:    status,
+		Timestamp: time.Now(),
+		Detail:    detail,
+	})
+
+	historyJSON, _ := json.Marshal(history)
+	invoice.StatusHistory = historyJSON
+
+	return db.DB().Save(invoice).Error
+}
+
+// FindInvoiceByPlatformID finds the invoice created from a document of an accounting platform
+func FindInvoiceByPlatformID(db database.DatabaseManager, businessID, platform, externalID string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := db.DB().
+		Where("business_id = ? AND platform = ? AND platform_metadata::jsonb -> ? ->> 'invoice_id' = ?", businessID, platform, platform, externalID).
+		Order("created_at asc").
+		First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// UpdateInvoiceData replaces the stored document and platform metadata of an invoice
+func UpdateInvoiceData(db database.DatabaseManager, invoice *models.Invoice, invoiceData []byte, platformMetadata string) error {
+	invoice.InvoiceData = invoiceData
+	invoice.PlatformMetadata = platformMetadata
 	return db.DB().Save(invoice).Error
 }
 
+// UpdateInvoiceDocument stores the FIRS document the invoice is submitted with and the issue date exports filter on
+func UpdateInvoiceDocument(db database.DatabaseManager, invoice *models.Invoice, document []byte, issueDate string) error {
+	invoice.FIRSDocument = document
+	invoice.IssueDate = issueDate
+	return db.DB().Model(invoice).Updates(map[string]interface{}{
+		"firs_document": invoice.FIRSDocument,
+		"issue_date":    issueDate,
+	}).Error
+}
+
 func UpdateInvoiceIRN(db database.DatabaseManager, invoice *models.Invoice, irn string) error {
 	invoice.IRN = irn
 	return db.DB().Save(invoice).Error
 }
 
+// UpdateInvoiceSignature stores the encrypted IRN payload and QR image of a signed invoice
+func UpdateInvoiceSignature(db database.DatabaseManager, invoice *models.Invoice, encryptedIRN, qrCode string) error {
+	invoice.EncryptedIRN = encryptedIRN
+	invoice.QRCode = qrCode
+	return db.DB().Model(invoice).Updates(map[string]interface{}{
+		"encrypted_irn": encryptedIRN,
+		"qr_code":       qrCode,
+	}).Error
+}
+
+func FindInvoiceByIRN(db database.DatabaseManager, businessID, irn string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := db.DB().Where("business_id = ? AND irn = ?", businessID, irn).First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
 func FindMinimalInvoicesByBusinessID(db database.DatabaseManager, businessID string) ([]models.MinimalInvoiceDTO, error) {
 	var result []models.MinimalInvoiceDTO
 
@@ -0,0 +1,54 @@
+package invoiceimport
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"time"
+)
+
+func CreateImport(db database.DatabaseManager, invoiceImport *models.InvoiceImport) error {
+	return db.DB().Create(invoiceImport).Error
+}
+
+func FindImport(db database.DatabaseManager, businessID, importID string) (*models.InvoiceImport, error) {
+	var invoiceImport models.InvoiceImport
+	if err := db.DB().Where("business_id = ? AND id = ?", businessID, importID).First(&invoiceImport).Error; err != nil {
+		return nil, err
+	}
+	return &invoiceImport, nil
+}
+
+// FindQueuedImports returns pending imports, and imports whose submission stalled before staleBefore
+func FindQueuedImports(db database.DatabaseManager, staleBefore time.Time, limit int) ([]models.InvoiceImport, error) {
+	var imports []models.InvoiceImport
+	if err := db.DB().
+		Where("status = ? OR (status = ? AND updated_at < ?)", models.ImportPending, models.ImportProcessing, staleBefore).
+		Order("created_at asc").
+		Limit(limit).
+		Find(&imports).Error; err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+func UpdateImport(db database.DatabaseManager, invoiceImport *models.InvoiceImport) error {
+	return db.DB().Save(invoiceImport).Error
+}
+
+// ClaimImport marks a queued import as processing so concurrent workers skip it.
+// It reports false when another worker claimed the import first.
+func ClaimImport(db database.DatabaseManager, invoiceImport *models.InvoiceImport) (bool, error) {
+	now := time.Now()
+	result := db.DB().Model(&models.InvoiceImport{}).
+		Where("id = ? AND status = ? AND updated_at = ?", invoiceImport.ID, invoiceImport.Status, invoiceImport.UpdatedAt).
+		Updates(map[string]interface{}{"status": models.ImportProcessing, "updated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	invoiceImport.Status = models.ImportProcessing
+	invoiceImport.UpdatedAt = now
+	return true, nil
+}
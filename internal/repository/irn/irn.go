@@ -0,0 +1,51 @@
+package irn
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"errors"
+
+	"gorm.io/gorm/clause"
+)
+
+var ErrIRNAlreadyIssued = errors.New("irn has already been issued to another invoice")
+
+// ReserveIRN records the IRN in the registry. Reserving the same IRN again for the same
+// business and invoice number is a no-op, so retries of a failed submission stay idempotent.
+func ReserveIRN(db database.DatabaseManager, businessID, invoiceNumber, irn string) error {
+	entry := models.IRNRegistry{
+		IRN:           irn,
+		BusinessID:    businessID,
+		InvoiceNumber: invoiceNumber,
+	}
+
+	result := db.DB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "irn"}},
+		DoNothing: true,
+	}).Create(&entry)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+
+	existing, err := FindByIRN(db, irn)
+	if err != nil {
+		return err
+	}
+
+	if existing.BusinessID != businessID || existing.InvoiceNumber != invoiceNumber {
+		return ErrIRNAlreadyIssued
+	}
+
+	return nil
+}
+
+func FindByIRN(db database.DatabaseManager, irn string) (*models.IRNRegistry, error) {
+	var entry models.IRNRegistry
+	if err := db.DB().Where("irn = ?", irn).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
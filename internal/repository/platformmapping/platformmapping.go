@@ -0,0 +1,26 @@
+package platformmapping
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func FindMapping(db database.DatabaseManager, businessID, platform string) (*models.PlatformMapping, error) {
+	var mapping models.PlatformMapping
+	if err := db.DB().Where("business_id = ? AND platform = ?", businessID, platform).First(&mapping).Error; err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// UpsertMapping saves the mapping, replacing an existing mapping of the business for the platform
+func UpsertMapping(db database.DatabaseManager, mapping *models.PlatformMapping) error {
+	return db.DB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "business_id"}, {Name: "platform"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"irn_field", "qr_field", "status_field", "error_field", "tax_categories", "payment_means", "invoice_type_code", "updated_at", "deleted_at",
+		}),
+	}).Create(mapping).Error
+}
@@ -0,0 +1,45 @@
+package product
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func FindProductsByBusiness(db database.DatabaseManager, businessID, platform string) ([]models.Product, error) {
+	var products []models.Product
+	query := db.DB().Where("business_id = ?", businessID)
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	if err := query.Order("platform_item_id asc").Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func FindProductsByItemIDs(db database.DatabaseManager, businessID, platform string, itemIDs []string) ([]models.Product, error) {
+	var products []models.Product
+	if err := db.DB().
+		Where("business_id = ? AND platform = ? AND platform_item_id IN ?", businessID, platform, itemIDs).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// UpsertProducts inserts the products or overwrites the mapping of items that are already in the catalogue
+func UpsertProducts(db database.DatabaseManager, products []models.Product) error {
+	return db.DB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "business_id"}, {Name: "platform"}, {Name: "platform_item_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"name", "hs_code", "product_category", "unit_of_measure", "tax_category", "tax_percent", "updated_at", "deleted_at",
+		}),
+	}).Create(&products).Error
+}
+
+func DeleteProduct(db database.DatabaseManager, businessID, productID string) (int64, error) {
+	result := db.DB().Where("business_id = ? AND id = ?", businessID, productID).Delete(&models.Product{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,246 @@
+package series
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSeriesNotFound = errors.New("invoice series not found")
+
+func FindSeriesByBusiness(db database.DatabaseManager, businessID string) ([]models.InvoiceSeries, error) {
+	var series []models.InvoiceSeries
+	if err := db.DB().Where("business_id = ?", businessID).Order("name asc").Find(&series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func FindSeries(db database.DatabaseManager, businessID, name string) (*models.InvoiceSeries, error) {
+	var series models.InvoiceSeries
+	if err := db.DB().Where("business_id = ? AND name = ?", businessID, name).First(&series).Error; err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// SaveSeries creates a series or updates the numbering format of an existing one. The series row is locked while
+// it is changed, and only the format columns and a counter moved ahead are written, so numbers reserved meanwhile
+// are never handed out again.
+func SaveSeries(db database.DatabaseManager, businessID string, req models.InvoiceSeriesRequest) (*models.InvoiceSeries, error) {
+	var series models.InvoiceSeries
+	err := db.DB().Transaction(func(tx *gorm.DB) error {
+		found, err := lockSeries(tx, businessID, req.Name, true)
+		if err != nil {
+			return err
+		}
+		series = *found
+
+		series.Prefix = req.Prefix
+		series.BranchCode = req.BranchCode
+		series.Padding = req.Padding
+		series.ResetYearly = req.ResetYearly
+		updates := map[string]interface{}{
+			"prefix":       series.Prefix,
+			"branch_code":  series.BranchCode,
+			"padding":      series.Padding,
+			"reset_yearly": series.ResetYearly,
+		}
+		if req.StartValue > series.NextValue {
+			series.NextValue = req.StartValue
+			updates["next_value"] = series.NextValue
+		}
+		return tx.Model(&series).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// lockSeries reads a series for update. The default series, and any series when create is set, is created on
+// first use. A new default series continues after the INV00001 style numbers issued before series existed.
+func lockSeries(tx *gorm.DB, businessID, name string, create bool) (*models.InvoiceSeries, error) {
+	find := func() (*models.InvoiceSeries, error) {
+		var series models.InvoiceSeries
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("business_id = ? AND name = ?", businessID, name).
+			First(&series).Error
+		return &series, err
+	}
+
+	series, err := find()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return series, err
+	}
+	if !create && name != models.DefaultInvoiceSeries {
+		return nil, ErrSeriesNotFound
+	}
+
+	series = &models.InvoiceSeries{BusinessID: businessID, Name: name, Prefix: "INV", Padding: 5, NextValue: 1}
+	if name == models.DefaultInvoiceSeries {
+		var last int64
+		// deleted invoices keep their numbers in the unique index
+		err := tx.Unscoped().Model(&models.Invoice{}).
+			Select("COALESCE(MAX(CAST(SUBSTRING(invoice_number FROM 4) AS BIGINT)), 0)").
+			Where("business_id = ? AND invoice_number ~ ?", businessID, `^INV[0-9]{1,18}$`).
+			Scan(&last).Error
+		if err != nil {
+			return nil, err
+		}
+		series.NextValue = last + 1
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(series).Error; err != nil {
+		return nil, err
+	}
+	return find()
+}
+
+// ReserveNumbers hands out the next count numbers of a series, released numbers first. The series
+// row is locked for the duration of the transaction, so concurrent callers are serialised and never
+// receive the same number or leave a gap. The default series is created on first use.
+func ReserveNumbers(db database.DatabaseManager, businessID, name string, count int) ([]string, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+
+	var numbers []string
+	err := db.DB().Transaction(func(tx *gorm.DB) error {
+		locked, err := lockSeries(tx, businessID, name, false)
+		if err != nil {
+			return err
+		}
+		series := *locked
+
+		year := time.Now().Year()
+		if series.ResetYearly && series.CurrentYear != year {
+			series.CurrentYear = year
+			series.NextValue = 1
+			// Numbers released last year belong to the sequence of that year
+			if err := tx.Where("series_id = ?", series.ID).Delete(&models.ReleasedInvoiceNumber{}).Error; err != nil {
+				return err
+			}
+		}
+
+		var released []models.ReleasedInvoiceNumber
+		if err := tx.Where("series_id = ?", series.ID).Order("value asc").Limit(count).Find(&released).Error; err != nil {
+			return err
+		}
+		for _, number := range released {
+			numbers = append(numbers, FormatNumber(series, number.Value))
+		}
+		if len(released) > 0 {
+			if err := tx.Delete(&released).Error; err != nil {
+				return err
+			}
+		}
+
+		for len(numbers) < count {
+			numbers = append(numbers, FormatNumber(series, series.NextValue))
+			series.NextValue++
+		}
+
+		return tx.Model(&series).Updates(map[string]interface{}{
+			"next_value":   series.NextValue,
+			"current_year": series.CurrentYear,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return numbers, nil
+}
+
+// ReleaseNumbers returns numbers of a series that were reserved but never used, so they are handed out again.
+// Releasing the last numbers handed out moves the counter back; any other number waits for the next reservation.
+// Numbers the series did not hand out, such as numbers of a previous year, and numbers in use are ignored.
+func ReleaseNumbers(db database.DatabaseManager, businessID, name string, numbers []string) error {
+	return db.DB().Transaction(func(tx *gorm.DB) error {
+		var series models.InvoiceSeries
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("business_id = ? AND name = ?", businessID, name).
+			First(&series).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSeriesNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var values []int64
+		for _, number := range numbers {
+			value, ok := parseNumber(series, number)
+			if !ok {
+				continue
+			}
+			// A number an invoice was stored with, such as one entered by hand, must not be handed out again
+			var used int64
+			if err := tx.Model(&models.Invoice{}).Where("business_id = ? AND invoice_number = ?", businessID, number).Count(&used).Error; err != nil {
+				return err
+			}
+			if used == 0 {
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+
+		for _, value := range values {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.ReleasedInvoiceNumber{SeriesID: series.ID, Value: value}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Take the released numbers at the top of the sequence back into the counter
+		for series.NextValue > 1 {
+			result := tx.Where("series_id = ? AND value = ?", series.ID, series.NextValue-1).Delete(&models.ReleasedInvoiceNumber{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				break
+			}
+			series.NextValue--
+		}
+
+		return tx.Model(&series).Update("next_value", series.NextValue).Error
+	})
+}
+
+// parseNumber returns the sequence value of a number the series handed out
+func parseNumber(series models.InvoiceSeries, number string) (int64, bool) {
+	digits, ok := strings.CutPrefix(number, numberPrefix(series))
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || value < 1 || value >= series.NextValue || FormatNumber(series, value) != number {
+		return 0, false
+	}
+	return value, true
+}
+
+// FormatNumber renders a sequence value as PREFIX[BRANCH][YYYY]00001. FIRS only accepts alphanumeric invoice
+// numbers in an IRN, so the parts are not separated; the default series numbers invoices INV00001 onwards.
+func FormatNumber(series models.InvoiceSeries, value int64) string {
+	return fmt.Sprintf("%s%0*d", numberPrefix(series), series.Padding, value)
+}
+
+func numberPrefix(series models.InvoiceSeries) string {
+	prefix := series.Prefix + series.BranchCode
+	if series.ResetYearly {
+		prefix += strconv.Itoa(series.CurrentYear)
+	}
+	return prefix
+}
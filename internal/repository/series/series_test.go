@@ -0,0 +1,53 @@
+package series
+
+import (
+	"einvoice-access-point/pkg/models"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	series := models.InvoiceSeries{Prefix: "INV", BranchCode: "LOS", Padding: 5, ResetYearly: true, CurrentYear: 2025, NextValue: 42}
+
+	tests := []struct {
+		number string
+		value  int64
+		ok     bool
+	}{
+		{"INVLOS202500041", 41, true},
+		{"INVLOS202500001", 1, true},
+		{"INVLOS202500042", 0, false}, // not handed out yet
+		{"INVLOS202400007", 0, false}, // of the previous year
+		{"INVABJ202500007", 0, false}, // of another branch
+		{"INVLOS20257", 0, false},     // not padded like the series
+		{"INVLOS202500000", 0, false},
+		{"INV-LOS-2025-00041", 0, false},
+		{"CUSTOM42", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			value, ok := parseNumber(series, tt.number)
+			if value != tt.value || ok != tt.ok {
+				t.Errorf("parseNumber(%q) = %d, %v, want %d, %v", tt.number, value, ok, tt.value, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		series models.InvoiceSeries
+		want   string
+	}{
+		// the default series keeps the numbers issued before series existed
+		{models.InvoiceSeries{Prefix: "INV", Padding: 5}, "INV00042"},
+		{models.InvoiceSeries{Prefix: "INV", BranchCode: "LOS", Padding: 6}, "INVLOS000042"},
+		{models.InvoiceSeries{Prefix: "CN", ResetYearly: true, CurrentYear: 2025, Padding: 4}, "CN20250042"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatNumber(tt.series, 42); got != tt.want {
+			t.Errorf("FormatNumber(%+v, 42) = %q, want %q", tt.series, got, tt.want)
+		}
+	}
+}
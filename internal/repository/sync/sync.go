@@ -0,0 +1,53 @@
+package sync
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"time"
+)
+
+func FindSync(db database.DatabaseManager, businessID, platform string) (*models.PlatformSync, error) {
+	var sync models.PlatformSync
+	if err := db.DB().Where("business_id = ? AND platform = ?", businessID, platform).First(&sync).Error; err != nil {
+		return nil, err
+	}
+	return &sync, nil
+}
+
+// FindDueSyncs returns the syncs that are enabled or have a backfill waiting and are not held by another worker
+func FindDueSyncs(db database.DatabaseManager, platform string, now time.Time, limit int) ([]models.PlatformSync, error) {
+	var syncs []models.PlatformSync
+	if err := db.DB().
+		Where("platform = ? AND (enabled = ? OR backfill_status = ?)", platform, true, models.BackfillPending).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Order("last_sync_at asc nulls first").
+		Limit(limit).
+		Find(&syncs).Error; err != nil {
+		return nil, err
+	}
+	return syncs, nil
+}
+
+func CreateSync(db database.DatabaseManager, sync *models.PlatformSync) error {
+	return db.DB().Create(sync).Error
+}
+
+func UpdateSync(db database.DatabaseManager, sync *models.PlatformSync) error {
+	return db.DB().Save(sync).Error
+}
+
+// ClaimSync locks the sync until the lease ends so concurrent workers skip it.
+// It reports false when another worker claimed the sync first.
+func ClaimSync(db database.DatabaseManager, sync *models.PlatformSync, now, until time.Time) (bool, error) {
+	result := db.DB().Model(&models.PlatformSync{}).
+		Where("id = ? AND (locked_until IS NULL OR locked_until < ?)", sync.ID, now).
+		Update("locked_until", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	sync.LockedUntil = &until
+	return true, nil
+}
@@ -3,7 +3,8 @@ package token
 import (
 	"einvoice-access-point/pkg/database"
 	"einvoice-access-point/pkg/models"
-	"time"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func FindToken(db database.DatabaseManager, provider, orgID string) (*models.TokenManager, error) {
@@ -15,27 +16,37 @@ func FindToken(db database.DatabaseManager, provider, orgID string) (*models.Tok
 	return &token, nil
 }
 
-func SaveNewZohoToken(db database.DatabaseManager, provider, orgID, accessToken, refreshToken string, expiresIn int) error {
-	zohoToken := models.TokenManager{
-		Provider:       provider,
-		OrganizationID: orgID,
-		AccessToken:    accessToken,
-		RefreshToken:   refreshToken,
-		ExpiresAt:      time.Now().Add(time.Duration(expiresIn) * time.Second),
-	}
-
-	return db.DB().Create(&zohoToken).Error
+// UpsertToken stores the tokens of a newly authorised organisation, replacing those of an earlier connection
+func UpsertToken(db database.DatabaseManager, token *models.TokenManager) error {
+	return db.DB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "provider"}, {Name: "organization_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"business_id", "refresh_token", "access_token", "expires_at", "accounts_server", "api_domain",
+			"needs_reconnect", "reconnect_error", "updated_at", "deleted_at",
+		}),
+	}).Create(token).Error
 }
 
-func UpdateZohoToken(db database.DatabaseManager, provider, orgID, accessToken, refreshToken string, expiresIn int) error {
-	var zohoToken models.TokenManager
-	if err := db.DB().Where("provider = ? AND organization_id = ?", provider, orgID).First(&zohoToken).Error; err != nil {
-		return err
+// RefreshToken locks the token row while it is refreshed, so callers on every instance wait for a single
+// refresh and then read its result. refresh only runs when the locked token is still due.
+func RefreshToken(db database.DatabaseManager, provider, orgID string, due func(*models.TokenManager) bool, refresh func(*models.TokenManager) error) (*models.TokenManager, error) {
+	var token models.TokenManager
+	err := db.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND organization_id = ?", provider, orgID).
+			First(&token).Error; err != nil {
+			return err
+		}
+		if !due(&token) {
+			return nil
+		}
+		if err := refresh(&token); err != nil {
+			return err
+		}
+		return tx.Save(&token).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	zohoToken.AccessToken = accessToken
-	zohoToken.RefreshToken = refreshToken
-	zohoToken.ExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
-
-	return db.DB().Save(&zohoToken).Error
+	return &token, nil
 }
@@ -0,0 +1,78 @@
+package writeback
+
+import (
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func FindWriteBackByInvoice(db database.DatabaseManager, businessID, invoiceID string) (*models.StatusWriteBack, error) {
+	var writeBack models.StatusWriteBack
+	if err := db.DB().Where("business_id = ? AND invoice_id = ?", businessID, invoiceID).First(&writeBack).Error; err != nil {
+		return nil, err
+	}
+	return &writeBack, nil
+}
+
+func FindDueWriteBacks(db database.DatabaseManager, now time.Time, limit int) ([]models.StatusWriteBack, error) {
+	var writeBacks []models.StatusWriteBack
+	if err := db.DB().
+		Where("status = ? AND next_attempt_at <= ?", models.WriteBackPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&writeBacks).Error; err != nil {
+		return nil, err
+	}
+	return writeBacks, nil
+}
+
+// ClaimWriteBack pushes the next attempt of a due write-back past the lease so concurrent workers skip it.
+// It reports false when another worker claimed it first or a newer status replaced it.
+func ClaimWriteBack(db database.DatabaseManager, writeBack *models.StatusWriteBack, until time.Time) (bool, error) {
+	result := db.DB().Model(&models.StatusWriteBack{}).
+		Where("id = ? AND revision = ? AND status = ? AND next_attempt_at = ?", writeBack.ID, writeBack.Revision, models.WriteBackPending, writeBack.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	writeBack.NextAttemptAt = &until
+	return true, nil
+}
+
+// RecordWriteBackAttempt saves the outcome of a send unless a newer status replaced the write-back meanwhile,
+// in which case the newer status stays queued and false is returned
+func RecordWriteBackAttempt(db database.DatabaseManager, writeBack *models.StatusWriteBack) (bool, error) {
+	result := db.DB().Model(&models.StatusWriteBack{}).
+		Where("id = ? AND revision = ?", writeBack.ID, writeBack.Revision).
+		Updates(map[string]interface{}{
+			"status":          writeBack.Status,
+			"attempts":        writeBack.Attempts,
+			"last_error":      writeBack.LastError,
+			"next_attempt_at": writeBack.NextAttemptAt,
+			"sent_at":         writeBack.SentAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RequeueWriteBack queues the write-back again with a fresh attempt budget. Only the delivery columns are written,
+// so a newer status queued meanwhile is kept, and the revision moves on so a worker still sending the previous
+// attempt does not record it over the retry.
+func RequeueWriteBack(db database.DatabaseManager, writeBack *models.StatusWriteBack, at time.Time) error {
+	return db.DB().Model(&models.StatusWriteBack{}).
+		Where("id = ?", writeBack.ID).
+		Updates(map[string]interface{}{
+			"status":          models.WriteBackPending,
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": at,
+			"revision":        gorm.Expr("revision + 1"),
+		}).Error
+}
@@ -0,0 +1,252 @@
+package attachment
+
+import (
+	"einvoice-access-point/external/firs_models"
+	repository "einvoice-access-point/internal/repository/attachment"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/delivery"
+	"einvoice-access-point/internal/services/invoice"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAttachmentAttempts is the number of uploads tried before an attachment is marked failed
+	MaxAttachmentAttempts = 5
+	// AttachmentInterval is how often the worker looks for due uploads
+	AttachmentInterval = time.Minute
+
+	attachmentBatchSize = 20
+	attachmentLease     = 10 * time.Minute
+)
+
+// attachmentBackoff is the wait before each retry, indexed by the number of failed attempts
+var attachmentBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+var ErrAttachmentsNotFound = errors.New("invoice has no platform attachments")
+
+// QueueQRCode schedules the upload of the invoice QR code to the invoice it was created from on the platform
+func QueueQRCode(db *gorm.DB, invoiceModel *models.Invoice, platform, orgID, externalInvoiceID string) error {
+	return queue(db, &models.InvoiceAttachment{
+		BusinessID:        invoiceModel.BusinessID,
+		InvoiceID:         invoiceModel.ID,
+		Kind:              models.AttachmentQRCode,
+		Platform:          platform,
+		OrgID:             orgID,
+		ExternalInvoiceID: externalInvoiceID,
+		IRN:               invoiceModel.IRN,
+		Filename:          fmt.Sprintf("invoice-%s-qr.png", invoiceModel.InvoiceNumber),
+		Document:          []byte("{}"),
+	})
+}
+
+// QueueInvoicePDF schedules the upload of the PDF rendered from the signed FIRS invoice
+func QueueInvoicePDF(db *gorm.DB, invoiceModel *models.Invoice, platform, orgID, externalInvoiceID string, doc firs_models.InvoiceRequest) error {
+	document, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invoice document: %w", err)
+	}
+
+	irn := invoiceModel.IRN
+	if doc.IRN != nil && *doc.IRN != "" {
+		irn = *doc.IRN
+	}
+
+	return queue(db, &models.InvoiceAttachment{
+		BusinessID:        invoiceModel.BusinessID,
+		InvoiceID:         invoiceModel.ID,
+		Kind:              models.AttachmentInvoicePDF,
+		Platform:          platform,
+		OrgID:             orgID,
+		ExternalInvoiceID: externalInvoiceID,
+		IRN:               irn,
+		Filename:          fmt.Sprintf("invoice-%s.pdf", doc.InvoiceNumber),
+		Document:          document,
+	})
+}
+
+func queue(db *gorm.DB, attachment *models.InvoiceAttachment) error {
+	pdb := inst.InitDB(db, true)
+
+	if attachment.IRN == "" {
+		return errors.New("invoice has no IRN")
+	}
+
+	now := time.Now()
+	attachment.Status = models.AttachmentPending
+	attachment.NextAttemptAt = &now
+	return repository.UpsertAttachment(pdb, attachment)
+}
+
+func GetAttachments(db *gorm.DB, businessID, invoiceID string) ([]models.InvoiceAttachment, error) {
+	pdb := inst.InitDB(db, true)
+
+	attachments, err := repository.FindAttachmentsByInvoice(pdb, businessID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, ErrAttachmentsNotFound
+	}
+	return attachments, nil
+}
+
+// RetryAttachments queues the failed uploads of an invoice again with a fresh attempt budget
+func RetryAttachments(db *gorm.DB, businessID, invoiceID string) ([]models.InvoiceAttachment, error) {
+	pdb := inst.InitDB(db, true)
+
+	attachments, err := GetAttachments(db, businessID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range attachments {
+		attachment := &attachments[i]
+		if attachment.Status != models.AttachmentFailed {
+			continue
+		}
+		attachment.Status = models.AttachmentPending
+		attachment.Attempts = 0
+		attachment.LastError = ""
+		attachment.NextAttemptAt = &now
+		if err := repository.UpdateAttachment(pdb, attachment); err != nil {
+			return nil, fmt.Errorf("failed to queue attachment: %w", err)
+		}
+	}
+	return attachments, nil
+}
+
+// ProcessDueAttachments uploads every attachment whose next attempt is due and returns how many were sent
+func ProcessDueAttachments(db *gorm.DB, keys *utility.CryptoKeys) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	attachments, err := repository.FindDueAttachments(pdb, time.Now(), attachmentBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range attachments {
+		attachment := &attachments[i]
+
+		claimed, err := repository.ClaimAttachment(pdb, attachment, time.Now().Add(attachmentLease))
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		recordAttempt(attachment, upload(db, keys, attachment))
+		if err := repository.UpdateAttachment(pdb, attachment); err != nil {
+			return sent, fmt.Errorf("failed to save attachment %s: %w", attachment.ID, err)
+		}
+		if attachment.Status == models.AttachmentSent {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// StartAttachmentWorker runs ProcessDueAttachments every AttachmentInterval until the process exits
+func StartAttachmentWorker(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger) {
+	go func() {
+		ticker := time.NewTicker(AttachmentInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ProcessDueAttachments(db, keys)
+			if err != nil {
+				logger.Error("invoice attachment upload failed", err)
+				continue
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("uploaded %d invoice attachments", count))
+			}
+		}
+	}()
+}
+
+// recordAttempt stores the outcome of an upload, failures are retried with backoff until MaxAttachmentAttempts is reached
+func recordAttempt(attachment *models.InvoiceAttachment, err error) {
+	now := time.Now()
+	attachment.Attempts++
+
+	if err == nil {
+		attachment.Status = models.AttachmentSent
+		attachment.LastError = ""
+		attachment.SentAt = &now
+		attachment.NextAttemptAt = nil
+		return
+	}
+
+	attachment.LastError = err.Error()
+
+	if attachment.Attempts >= MaxAttachmentAttempts {
+		attachment.Status = models.AttachmentFailed
+		attachment.NextAttemptAt = nil
+		return
+	}
+
+	backoff := attachmentBackoff[len(attachmentBackoff)-1]
+	if attachment.Attempts <= len(attachmentBackoff) {
+		backoff = attachmentBackoff[attachment.Attempts-1]
+	}
+	next := now.Add(backoff)
+	attachment.Status = models.AttachmentPending
+	attachment.NextAttemptAt = &next
+}
+
+// upload renders the file of the attachment and sends it to the platform
+func upload(db *gorm.DB, keys *utility.CryptoKeys, attachment *models.InvoiceAttachment) error {
+	data, contentType, err := render(db, keys, attachment)
+	if err != nil {
+		return err
+	}
+
+	conn, err := connector.Get(attachment.Platform)
+	if err != nil {
+		return fmt.Errorf("attachments cannot be uploaded to %s", attachment.Platform)
+	}
+
+	account, err := connector.LoadAccount(db, attachment.BusinessID, attachment.Platform)
+	if err != nil {
+		return err
+	}
+	return conn.UploadAttachment(db, account, attachment.ExternalInvoiceID, attachment.Filename, contentType, data)
+}
+
+func render(db *gorm.DB, keys *utility.CryptoKeys, attachment *models.InvoiceAttachment) ([]byte, string, error) {
+	switch attachment.Kind {
+	case models.AttachmentQRCode:
+		qrPNG, _, err := invoice.GetInvoiceQRCode(db, keys, attachment.BusinessID, attachment.IRN, invoice.DefaultQROptions())
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to render qr code: %w", err)
+		}
+		return qrPNG, "image/png", nil
+	case models.AttachmentInvoicePDF:
+		var doc firs_models.InvoiceRequest
+		if err := json.Unmarshal(attachment.Document, &doc); err != nil {
+			return nil, "", fmt.Errorf("failed to decode invoice document: %w", err)
+		}
+		if doc.IRN == nil || *doc.IRN == "" {
+			doc.IRN = &attachment.IRN
+		}
+		pdf, err := delivery.RenderInvoicePDF(db, keys, attachment.BusinessID, doc)
+		if err != nil {
+			return nil, "", err
+		}
+		return pdf, "application/pdf", nil
+	default:
+		return nil, "", fmt.Errorf("unknown attachment kind %q", attachment.Kind)
+	}
+}
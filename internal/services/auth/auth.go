@@ -90,10 +90,13 @@ func CreateUser(req models.CreateUserRequestModel, db *gorm.DB) (fiber.Map, int,
 
 		platformConfigs[platform] = models.AccountingPlatformConfig{
 			OrgID:      cfg.OrgID,
+			BaseURL:    cfg.BaseURL,
+			Username:   cfg.Username,
 			HMACSecret: common.EncryptedString(encryptedHMACSecret),
 			AuthToken:  common.EncryptedString(encryptedAuthToken),
 			APIKey:     common.EncryptedString(encryptedAPIKey),
 			APISecret:  common.EncryptedString(encryptedAPISecret),
+			AttachPDF:  cfg.AttachPDF,
 		}
 	}
 
@@ -102,8 +105,8 @@ func CreateUser(req models.CreateUserRequestModel, db *gorm.DB) (fiber.Map, int,
 		Name:            name,
 		Email:           email,
 		Password:        password,
-		BusinessID:      "ac0d4848-c898-49ce-8fc7-46f529a9354a",
-		ServiceID:       "6A2BC898", //userRepo.GenerateUniqueServiceID(pdb.Db)
+		BusinessID:      req.BusinessID,
+		ServiceID:       userRepo.GenerateUniqueServiceID(pdb.Db),
 		APIKey:          common.EncryptedString(encryptedAPIKey),
 		APIKeyHash:      apiKeyHashStr,
 		PlatformConfigs: platformConfigs,
@@ -2,7 +2,13 @@ package business
 
 import (
 	repository "einvoice-access-point/internal/repository/business"
+	"einvoice-access-point/internal/services/invoice"
 	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -31,6 +37,13 @@ func GetAllBusinesses(db *gorm.DB) ([]fiber.Map, error) {
 			"name":             business.Name,
 			"business_id":      business.BusinessID,
 			"service_id":       business.ServiceID,
+			"irn_template":     business.IRNTemplate,
+			"company_name":     business.CompanyName,
+			"tin":              business.TIN,
+			"phone_number":     business.PhoneNumber,
+			"supplier_profile": business.SupplierProfile,
+			"support_peppol":   business.SupportPeppol,
+			"peppol_endpoint":  business.PeppolEndpoint,
 			"platform_configs": cleanConfigs,
 			"api_key":          string(business.APIKey),
 			"invoices":         business.Invoices,
@@ -62,6 +75,13 @@ func GetBusinessByID(db *gorm.DB, id string) (fiber.Map, error) {
 		"name":             business.Name,
 		"business_id":      business.BusinessID,
 		"service_id":       business.ServiceID,
+		"irn_template":     business.IRNTemplate,
+		"company_name":     business.CompanyName,
+		"tin":              business.TIN,
+		"phone_number":     business.PhoneNumber,
+		"supplier_profile": business.SupplierProfile,
+		"support_peppol":   business.SupportPeppol,
+		"peppol_endpoint":  business.PeppolEndpoint,
 		"platform_configs": cleanConfigs,
 		"api_key":          string(business.APIKey),
 		"invoices":         business.Invoices,
@@ -90,3 +110,48 @@ func UpdateBusinessID(db *gorm.DB, id, businessID string) error {
 	}
 	return nil
 }
+
+// UpdateIRNTemplate stores the FIRS IRN template for a business. FIRS templates carry the
+// business service ID as a literal middle segment, in which case it replaces the stored one.
+func UpdateIRNTemplate(db *gorm.DB, id, template string) error {
+	pdb := inst.InitDB(db, true)
+
+	if err := invoice.ValidateIRNTemplate(template); err != nil {
+		return err
+	}
+
+	business, err := repository.FindBusinessByID(pdb, id)
+	if err != nil {
+		return err
+	}
+
+	if serviceID, err := utility.ExtractServiceIDFromIRN(template); err == nil && !strings.Contains(serviceID, "{{") {
+		if !regexp.MustCompile(`^[A-Za-z0-9]{8}$`).MatchString(serviceID) {
+			return fmt.Errorf("invalid service ID in irn template: must be 8 alphanumeric characters")
+		}
+		business.ServiceID = serviceID
+	}
+
+	business.IRNTemplate = template
+
+	return repository.UpdateAUser(business, pdb)
+}
+
+// UpdatePeppolSettings flags a business for PEPPOL output and stores its participant identifier ("scheme:id")
+func UpdatePeppolSettings(db *gorm.DB, id string, req models.UpdatePeppolSettingsRequest) error {
+	pdb := inst.InitDB(db, true)
+
+	if req.PeppolEndpoint != "" && !regexp.MustCompile(`^[0-9]{4}:\S+$`).MatchString(req.PeppolEndpoint) {
+		return fmt.Errorf("invalid peppol endpoint: must be written as scheme:id, e.g. 0088:7300010000001")
+	}
+
+	business, err := repository.FindBusinessByID(pdb, id)
+	if err != nil {
+		return err
+	}
+
+	business.SupportPeppol = req.SupportPeppol
+	business.PeppolEndpoint = req.PeppolEndpoint
+
+	return repository.UpdateAUser(business, pdb)
+}
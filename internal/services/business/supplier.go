@@ -0,0 +1,150 @@
+package business
+
+import (
+	"einvoice-access-point/external/firs_models"
+	repository "einvoice-access-point/internal/repository/business"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrIncompleteSupplierProfile = errors.New("supplier profile is incomplete: company name, tin and address are required")
+
+func UpdateSupplierProfile(db *gorm.DB, id string, req models.UpdateSupplierProfileRequest) error {
+	pdb := inst.InitDB(db, true)
+
+	business, err := repository.FindBusinessByID(pdb, id)
+	if err != nil {
+		return err
+	}
+
+	business.CompanyName = req.CompanyName
+	business.TIN = strings.TrimSpace(req.TIN)
+	business.PhoneNumber = req.PhoneNumber
+	business.SupplierProfile = models.SupplierProfile{
+		Email:               req.Email,
+		BusinessDescription: req.BusinessDescription,
+		StreetName:          req.StreetName,
+		CityName:            req.CityName,
+		PostalZone:          req.PostalZone,
+		Country:             strings.ToUpper(req.Country),
+		BankName:            req.BankName,
+		BankAccountName:     req.BankAccountName,
+		BankAccountNumber:   req.BankAccountNumber,
+		PaymentMeansCode:    req.PaymentMeansCode,
+		PaymentTermsNote:    req.PaymentTermsNote,
+	}
+
+	return repository.UpdateAUser(business, pdb)
+}
+
+// SupplierProfileComplete reports whether the business holds enough details to act as the supplier party
+func SupplierProfileComplete(business *models.Business) bool {
+	profile := business.SupplierProfile
+	return supplierName(business) != "" && business.TIN != "" &&
+		profile.StreetName != "" && profile.CityName != "" && profile.Country != ""
+}
+
+// SupplierParty builds the FIRS supplier party from the business profile
+func SupplierParty(business *models.Business) (firs_models.Party, error) {
+	if !SupplierProfileComplete(business) {
+		return firs_models.Party{}, ErrIncompleteSupplierProfile
+	}
+
+	profile := business.SupplierProfile
+	name := supplierName(business)
+	email := profile.Email
+	if email == "" {
+		email = business.Email
+	}
+
+	party := firs_models.Party{
+		PartyName: &name,
+		TIN:       business.TIN,
+		Email:     email,
+		PostalAddress: &firs_models.PostalAddress{
+			StreetName: profile.StreetName,
+			CityName:   profile.CityName,
+			PostalZone: profile.PostalZone,
+			Country:    profile.Country,
+		},
+	}
+	if business.PhoneNumber != "" {
+		telephone := utility.FormatPhone(business.PhoneNumber)
+		party.Telephone = &telephone
+	}
+	if profile.BusinessDescription != "" {
+		description := profile.BusinessDescription
+		party.BusinessDescription = &description
+	}
+
+	return party, nil
+}
+
+// ApplySupplierProfile sets the business as the supplier of the invoice and fills the payment
+// means and terms from the profile when the invoice does not carry its own
+func ApplySupplierProfile(req *firs_models.InvoiceRequest, business *models.Business) error {
+	party, err := SupplierParty(business)
+	if err != nil {
+		return err
+	}
+	req.AccountingSupplierParty = party
+
+	profile := business.SupplierProfile
+	if len(req.PaymentMeans) == 0 && profile.PaymentMeansCode != "" && req.DueDate != nil {
+		req.PaymentMeans = []firs_models.PaymentMeans{{
+			PaymentMeansCode: profile.PaymentMeansCode,
+			PaymentDueDate:   *req.DueDate,
+		}}
+	}
+
+	if req.PaymentTermsNote == nil || *req.PaymentTermsNote == "" {
+		if note := paymentTermsNote(profile); note != "" {
+			req.PaymentTermsNote = &note
+		}
+	}
+
+	return nil
+}
+
+func supplierName(business *models.Business) string {
+	if business.CompanyName != "" {
+		return business.CompanyName
+	}
+	return business.Name
+}
+
+// paymentTermsNote combines the profile terms with the bank account buyers should pay into
+func paymentTermsNote(profile models.SupplierProfile) string {
+	note := profile.PaymentTermsNote
+	if profile.BankAccountNumber == "" {
+		return note
+	}
+
+	bank := fmt.Sprintf("Bank: %s, Account name: %s, Account number: %s", profile.BankName, profile.BankAccountName, profile.BankAccountNumber)
+	if note == "" {
+		return bank
+	}
+	return note + ". " + bank
+}
+
+// InjectSupplierParty applies the supplier profile of a business to an uploaded invoice. Businesses that have not
+// completed their profile keep the supplier party sent by the client.
+func InjectSupplierParty(db *gorm.DB, id string, req *firs_models.InvoiceRequest) error {
+	pdb := inst.InitDB(db, true)
+
+	business, err := repository.FindBusinessByID(pdb, id)
+	if err != nil {
+		return err
+	}
+
+	if !SupplierProfileComplete(business) {
+		return nil
+	}
+	return ApplySupplierProfile(req, business)
+}
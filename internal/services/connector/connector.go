@@ -0,0 +1,192 @@
+package connector
+
+import (
+	"einvoice-access-point/external/firs_models"
+	businessRepository "einvoice-access-point/internal/repository/business"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownConnector    = errors.New("no connector is registered for the platform")
+	ErrInvalidSignature    = errors.New("invalid webhook signature")
+	ErrTokenRevoked        = errors.New("the platform no longer accepts the refresh token")
+	ErrAccountNotConnected = errors.New("business has no organisation configured for the platform")
+	ErrNotSupported        = errors.New("the platform does not support the operation")
+)
+
+// Connector is an accounting platform invoices are received from and FIRS results are written back to.
+// Connectors are registered under the key the platform has in the business platform configs.
+type Connector interface {
+	// Name is the key of the platform in the business platform configs and in the invoice platform metadata
+	Name() string
+
+	// ParseWebhook decodes an inbound webhook into one webhook per organisation it carries events for.
+	// Each payload is validated before the webhook is handled.
+	ParseWebhook(req WebhookRequest) ([]*Webhook, error)
+	// VerifyWebhook checks the webhook was signed by the platform for the organisation, returning ErrInvalidSignature when it was not
+	VerifyWebhook(req WebhookRequest, accConfig models.AccountingPlatformConfig) error
+	// HandleWebhook processes a verified webhook of the business and returns the response data
+	HandleWebhook(webhook *Webhook, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+		business *models.Business, accConfig models.AccountingPlatformConfig) (interface{}, *string, error)
+
+	// Convert builds the FIRS invoice from the invoice data stored for an invoice of the platform
+	Convert(db *gorm.DB, business *models.Business, invoiceData []byte, irn string) (firs_models.InvoiceRequest, []string, error)
+
+	// AuthURL returns the consent URL of the platform carrying the state
+	AuthURL(state string) (string, error)
+	// ExchangeCode trades the authorization code the callback brought back for the tokens of the organisation
+	ExchangeCode(code, orgID string, callback map[string]string) (*Tokens, error)
+	// RefreshTokens renews the access token, wrapping ErrTokenRevoked when the organisation has to be connected again
+	RefreshTokens(token *models.TokenManager) (*Tokens, error)
+
+	// WriteBackIRN stores the IRN and QR code on the invoice in the platform
+	WriteBackIRN(db *gorm.DB, account Account, externalID, irn, qrCode string) error
+	// WriteBackStatus stores the FIRS processing status on the invoice in the platform
+	WriteBackStatus(db *gorm.DB, account Account, externalID, status, firsError string) error
+	// UploadAttachment attaches a file to the invoice in the platform
+	UploadAttachment(db *gorm.DB, account Account, externalID, filename, contentType string, data []byte) error
+
+	// PollInvoices pulls the page of invoices the query selects, oldest change first, and takes each through the FIRS
+	// steps. The platform sync polls platforms this way; platforms that push every change by webhook return ErrNotSupported.
+	PollInvoices(db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, business *models.Business,
+		account Account, query PollQuery) (*PollPage, error)
+}
+
+// WebhookRequest is an inbound webhook as the platform sent it. The raw body is kept for signature checks.
+type WebhookRequest struct {
+	Body    []byte
+	Query   map[string]string
+	Headers map[string][]string
+}
+
+// Header returns the first value of a header regardless of its case
+func (r WebhookRequest) Header(name string) string {
+	for key, values := range r.Headers {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Webhook is a decoded webhook and the organisation it was sent for
+type Webhook struct {
+	OrgID   string
+	Payload interface{}
+}
+
+// Invoice is an invoice read from a platform, described by what the FIRS steps and the invoice platform metadata need
+type Invoice struct {
+	ExternalID string
+	Number     string
+	Status     string // status on the platform, kept in the invoice platform metadata
+	Total      float64
+	Balance    float64
+	Currency   string
+	Voided     bool
+	Data       []byte // the invoice as stored, which Convert turns into the FIRS invoice
+}
+
+// PollQuery selects the invoices of a poll: the ones changed since a time, or the ones dated in a range
+type PollQuery struct {
+	ModifiedSince time.Time
+	DateFrom      string // invoice date range, yyyy-mm-dd
+	DateTo        string
+	Page          int // from 1
+	PerPage       int
+}
+
+// PollPage is a page of polled invoices in the order the platform returned them
+type PollPage struct {
+	Invoices []PolledInvoice
+	More     bool
+}
+
+// PolledInvoice is the outcome of processing a polled invoice. A failed invoice does not stop the rest of the page.
+type PolledInvoice struct {
+	Number   string
+	Modified time.Time // when the invoice last changed on the platform, zero when the platform did not say
+	Changed  bool      // whether processing changed the stored invoice
+	Err      error
+}
+
+// Tokens are the OAuth tokens a platform issued for an organisation
+type Tokens struct {
+	AccessToken    string
+	RefreshToken   string
+	ExpiresIn      int
+	APIDomain      string
+	AccountsServer string
+}
+
+// Account is the organisation a business connected on a platform
+type Account struct {
+	BusinessID string
+	Config     models.AccountingPlatformConfig
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Connector{}
+)
+
+// Register makes the connector available under its name, replacing any connector registered under the same name
+func Register(connector Connector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[connector.Name()] = connector
+}
+
+// Get returns the connector registered for the platform
+func Get(name string) (Connector, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	connector, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownConnector, name)
+	}
+	return connector, nil
+}
+
+// Names returns the names of the registered connectors in order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadAccount returns the organisation the business configured for the platform, with its secrets decrypted
+func LoadAccount(db *gorm.DB, businessID, platform string) (Account, error) {
+	pdb := inst.InitDB(db, true)
+
+	business, err := businessRepository.FindBusinessByID(pdb, businessID)
+	if err != nil {
+		return Account{}, err
+	}
+	accConfig, ok := business.PlatformConfigs[platform]
+	if !ok || accConfig.OrgID == "" {
+		return Account{}, fmt.Errorf("%w: %s", ErrAccountNotConnected, platform)
+	}
+
+	accConfig.HMACSecret.AfterFind(pdb.DB())
+	accConfig.AuthToken.AfterFind(pdb.DB())
+	accConfig.APIKey.AfterFind(pdb.DB())
+	accConfig.APISecret.AfterFind(pdb.DB())
+
+	return Account{BusinessID: business.ID, Config: accConfig}, nil
+}
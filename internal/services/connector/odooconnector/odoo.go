@@ -0,0 +1,380 @@
+package odooconnector
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/odoo"
+	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/customer"
+	"einvoice-access-point/internal/services/platformmapping"
+	"einvoice-access-point/internal/services/product"
+	"einvoice-access-point/internal/services/webhooks"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Platform is the key of Odoo in the business platform configs. The organisation ID is the name of the Odoo database,
+// the base URL the Odoo instance and the username and API key the user the connector works as.
+const Platform = "odoo"
+
+// Connector connects Odoo databases. Odoo sends no webhooks and has no OAuth app to connect; customer invoices and
+// credit notes are polled by the platform sync with the API key of the business.
+type Connector struct{}
+
+// New returns the Odoo connector
+func New() *Connector {
+	return &Connector{}
+}
+
+func (o *Connector) Name() string {
+	return Platform
+}
+
+func (o *Connector) ParseWebhook(req connector.WebhookRequest) ([]*connector.Webhook, error) {
+	return nil, fmt.Errorf("%w: odoo invoices are pulled by the platform sync", connector.ErrNotSupported)
+}
+
+func (o *Connector) VerifyWebhook(req connector.WebhookRequest, accConfig models.AccountingPlatformConfig) error {
+	return connector.ErrInvalidSignature
+}
+
+func (o *Connector) HandleWebhook(webhook *connector.Webhook, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (interface{}, *string, error) {
+	return nil, nil, fmt.Errorf("%w: odoo invoices are pulled by the platform sync", connector.ErrNotSupported)
+}
+
+func (o *Connector) Convert(db *gorm.DB, business *models.Business, invoiceData []byte, irn string) (firs_models.InvoiceRequest, []string, error) {
+	var doc odoo.InvoiceDocument
+	if err := json.Unmarshal(invoiceData, &doc); err != nil {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("failed to decode %s invoice: %w", Platform, err)
+	}
+
+	var productIDs []string
+	for _, p := range doc.Products {
+		productIDs = append(productIDs, strconv.Itoa(p.ID))
+	}
+
+	catalogue, err := product.GetCatalogue(db, business.ID, Platform, productIDs)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	buyer, err := customer.FindCustomer(db, business.ID, Platform, strconv.Itoa(doc.Move.PartnerID.ID))
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	mapping, err := platformmapping.GetMapping(db, business.ID, Platform)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	var references []firs_models.DocumentReference
+	if doc.Move.IsRefund() && doc.Move.ReversedEntryID.ID != 0 {
+		references = billingReferences(db, business.ID, doc.Move.ReversedEntryID.ID)
+	}
+
+	return converter.ConvertOdooToFIRS(doc, business, irn, catalogue, buyer, mapping, references)
+}
+
+func (o *Connector) AuthURL(state string) (string, error) {
+	return "", fmt.Errorf("%w: odoo is connected with the API key in the platform configs", connector.ErrNotSupported)
+}
+
+func (o *Connector) ExchangeCode(code, orgID string, callback map[string]string) (*connector.Tokens, error) {
+	return nil, fmt.Errorf("%w: odoo is connected with the API key in the platform configs", connector.ErrNotSupported)
+}
+
+func (o *Connector) RefreshTokens(token *models.TokenManager) (*connector.Tokens, error) {
+	return nil, fmt.Errorf("%w: odoo is connected with the API key in the platform configs", connector.ErrNotSupported)
+}
+
+// WriteBackIRN writes the IRN and QR code to the custom fields of the invoice the mapping names, x_firs_irn and x_firs_qr by default
+func (o *Connector) WriteBackIRN(db *gorm.DB, account connector.Account, externalID, irn, qrCode string) error {
+	return o.writeBack(db, account, externalID, func(mapping *models.PlatformMapping) map[string]string {
+		return map[string]string{
+			fieldName(mapping.IRNField, odoo.DefaultIRNField): irn,
+			fieldName(mapping.QRField, odoo.DefaultQRField):   qrCode,
+		}
+	})
+}
+
+// WriteBackStatus writes the FIRS processing status and its last error to the custom fields of the invoice the
+// mapping names, x_firs_status and x_firs_error by default
+func (o *Connector) WriteBackStatus(db *gorm.DB, account connector.Account, externalID, status, firsError string) error {
+	return o.writeBack(db, account, externalID, func(mapping *models.PlatformMapping) map[string]string {
+		return map[string]string{
+			fieldName(mapping.StatusField, odoo.DefaultStatusField): status,
+			fieldName(mapping.ErrorField, odoo.DefaultErrorField):   firsError,
+		}
+	})
+}
+
+func (o *Connector) UploadAttachment(db *gorm.DB, account connector.Account, externalID, filename, contentType string, data []byte) error {
+	id, err := moveID(externalID)
+	if err != nil {
+		return err
+	}
+	client, err := NewClient(account.Config)
+	if err != nil {
+		return err
+	}
+	return client.AttachToMove(id, filename, contentType, data)
+}
+
+// PollInvoices pulls the page of customer invoices and credit notes written since the query time, or dated in its
+// range. Cancelled invoices are only voided once, and not at all when they were never received.
+func (o *Connector) PollInvoices(db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, business *models.Business,
+	account connector.Account, query connector.PollQuery) (*connector.PollPage, error) {
+
+	client, err := NewClient(account.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &movePoll{
+		client: client,
+		logger: logger,
+		awaitingVoid: func(move odoo.Move) (bool, error) {
+			return awaitsVoid(db, business.ID, move)
+		},
+		process: func(platformInvoice connector.Invoice) (bool, error) {
+			resp, _, err := webhooks.ProcessPlatformInvoice(o, platformInvoice, db, logger, firsKeys, business, account.Config)
+			return resp != nil && resp.Updated, err
+		},
+	}
+	return p.page(query)
+}
+
+// movePoll takes the moves of one poll through the FIRS steps
+type movePoll struct {
+	client       *odoo.Client
+	logger       *utility.Logger
+	awaitingVoid func(move odoo.Move) (bool, error)
+	process      func(platformInvoice connector.Invoice) (bool, error)
+}
+
+func (p *movePoll) page(query connector.PollQuery) (*connector.PollPage, error) {
+	search := odoo.SearchMovesQuery{
+		DateFrom: query.DateFrom,
+		DateTo:   query.DateTo,
+		Limit:    query.PerPage,
+		Offset:   (query.Page - 1) * query.PerPage,
+	}
+	if !query.ModifiedSince.IsZero() {
+		since := query.ModifiedSince
+		search.WrittenSince = &since
+	}
+
+	moves, err := p.client.SearchMoves(search)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &connector.PollPage{More: len(moves) == query.PerPage}
+	for _, move := range moves {
+		polled := connector.PolledInvoice{Number: move.Number()}
+		if written, err := time.Parse(odoo.TimeLayout, string(move.WriteDate)); err == nil {
+			polled.Modified = written
+		}
+		polled.Changed, polled.Err = p.pull(move)
+		page.Invoices = append(page.Invoices, polled)
+	}
+	return page, nil
+}
+
+// pull reads the rest of the move and hands it to the platform invoice processing
+func (p *movePoll) pull(move odoo.Move) (bool, error) {
+	if move.State == odoo.StateCancel {
+		awaiting, err := p.awaitingVoid(move)
+		if err != nil || !awaiting {
+			return false, err
+		}
+	}
+
+	platformInvoice, skip, err := ReadInvoice(p.client, move)
+	if err != nil {
+		return false, err
+	}
+	if skip != "" {
+		p.logger.Info("Skipping Odoo move", zap.Int("id", move.ID), zap.String("reason", skip))
+		return false, nil
+	}
+	return p.process(platformInvoice)
+}
+
+// awaitsVoid reports whether a cancelled move was received and has not been voided yet
+func awaitsVoid(db *gorm.DB, businessID string, move odoo.Move) (bool, error) {
+	existing, err := repository.FindInvoiceByPlatformID(inst.InitDB(db, true), businessID, Platform, strconv.Itoa(move.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var metadata models.PlatformMetadata
+	_ = json.Unmarshal([]byte(existing.PlatformMetadata), &metadata)
+	return metadata[Platform].Status != odoo.StateCancel, nil
+}
+
+func (o *Connector) writeBack(db *gorm.DB, account connector.Account, externalID string, fields func(*models.PlatformMapping) map[string]string) error {
+	id, err := moveID(externalID)
+	if err != nil {
+		return err
+	}
+	mapping, err := platformmapping.GetMapping(db, account.BusinessID, Platform)
+	if err != nil {
+		return err
+	}
+	client, err := NewClient(account.Config)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	for name, value := range fields(mapping) {
+		values[name] = value
+	}
+	return client.WriteMove(id, values)
+}
+
+// fieldName is the custom field the mapping names, or the default field
+func fieldName(mapped, fallback string) string {
+	if mapped != "" {
+		return mapped
+	}
+	return fallback
+}
+
+// NewClient returns a client of the Odoo database the account configures
+func NewClient(accConfig models.AccountingPlatformConfig) (*odoo.Client, error) {
+	return odoo.NewClient(accConfig.BaseURL, accConfig.OrgID, accConfig.Username, string(accConfig.APIKey))
+}
+
+// ReadInvoice reads the lines, taxes, customer and products of a customer invoice or credit note. It names the reason
+// when the move is not one to send to FIRS: drafts, and credit notes that were cancelled.
+func ReadInvoice(client *odoo.Client, move odoo.Move) (connector.Invoice, string, error) {
+	switch {
+	case move.MoveType != odoo.MoveTypeInvoice && move.MoveType != odoo.MoveTypeRefund:
+		return connector.Invoice{}, "not a customer invoice", nil
+	case move.State == odoo.StateDraft:
+		return connector.Invoice{}, "not posted yet", nil
+	case move.IsRefund() && move.State == odoo.StateCancel:
+		return connector.Invoice{}, "cancelled credit notes are not reversed", nil
+	}
+
+	doc := odoo.InvoiceDocument{Move: move}
+
+	lines, err := client.GetMoveLines(move.InvoiceLineIDs)
+	if err != nil {
+		return connector.Invoice{}, "", err
+	}
+	doc.Lines = lines
+
+	var taxIDs, productIDs []int
+	seenTaxes, seenProducts := map[int]bool{}, map[int]bool{}
+	for _, line := range lines {
+		for _, id := range line.TaxIDs {
+			if !seenTaxes[id] {
+				seenTaxes[id] = true
+				taxIDs = append(taxIDs, id)
+			}
+		}
+		if id := line.ProductID.ID; id != 0 && !seenProducts[id] {
+			seenProducts[id] = true
+			productIDs = append(productIDs, id)
+		}
+	}
+	sort.Ints(taxIDs)
+	sort.Ints(productIDs)
+
+	if doc.Taxes, err = client.GetTaxes(taxIDs); err != nil {
+		return connector.Invoice{}, "", err
+	}
+	if doc.Products, err = client.GetProducts(productIDs); err != nil {
+		return connector.Invoice{}, "", err
+	}
+	if move.PartnerID.ID != 0 {
+		if doc.Partner, err = client.GetPartner(move.PartnerID.ID); err != nil {
+			return connector.Invoice{}, "", err
+		}
+	}
+
+	data, err := json.Marshal(storedDocument(doc))
+	if err != nil {
+		return connector.Invoice{}, "", err
+	}
+
+	// a credit note or reversal settling an invoice is no payment, their balance is left whole so the change is
+	// handled as an update
+	balance := move.AmountResidual
+	if move.IsRefund() || move.PaymentState == odoo.PaymentStateReversed {
+		balance = move.AmountTotal
+	}
+
+	return connector.Invoice{
+		ExternalID: strconv.Itoa(move.ID),
+		Number:     move.Number(),
+		Status:     invoiceStatus(move),
+		Total:      move.AmountTotal,
+		Balance:    balance,
+		Currency:   move.CurrencyID.Name,
+		Voided:     move.State == odoo.StateCancel,
+		Data:       data,
+	}, "", nil
+}
+
+// storedDocument clears the parts of the move the write-back and payments change, so neither is taken for an edit
+// of the invoice when the sync pulls the move again
+func storedDocument(doc odoo.InvoiceDocument) odoo.InvoiceDocument {
+	doc.Move.WriteDate = ""
+	doc.Move.PaymentState = ""
+	doc.Move.AmountResidual = 0
+	return doc
+}
+
+// invoiceStatus is the state of the move, or its payment state once it is posted. Invoices Odoo has reconciled but
+// not yet matched to a bank statement already count as paid.
+func invoiceStatus(move odoo.Move) string {
+	if move.State != odoo.StatePosted || move.PaymentState == "" {
+		return move.State
+	}
+	if move.PaymentState == odoo.PaymentStateInPayment {
+		return odoo.PaymentStatePaid
+	}
+	return string(move.PaymentState)
+}
+
+func moveID(externalID string) (int, error) {
+	id, err := strconv.Atoi(externalID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid odoo invoice id %q", externalID)
+	}
+	return id, nil
+}
+
+// billingReferences refers a credit note to the invoice it reverses when FIRS issued an IRN for it
+func billingReferences(db *gorm.DB, businessID string, reversedID int) []firs_models.DocumentReference {
+	pdb := inst.InitDB(db, true)
+
+	original, err := repository.FindInvoiceByPlatformID(pdb, businessID, Platform, strconv.Itoa(reversedID))
+	if err != nil || original.IRN == "" {
+		return nil
+	}
+	var doc odoo.InvoiceDocument
+	if err := json.Unmarshal(original.InvoiceData, &doc); err != nil || doc.Move.InvoiceDate == "" {
+		return nil
+	}
+	return []firs_models.DocumentReference{{IRN: original.IRN, IssueDate: string(doc.Move.InvoiceDate)}}
+}
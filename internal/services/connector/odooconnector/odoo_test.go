@@ -0,0 +1,249 @@
+package odooconnector
+
+import (
+	"einvoice-access-point/external/odoo"
+	"einvoice-access-point/internal/services/connector"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// odooStandIn answers the JSON-RPC calls of the connector from fixed records of each model
+type odooStandIn struct {
+	records  map[string][]map[string]interface{}
+	searches []map[string]interface{} // keyword arguments of each account.move search
+}
+
+func (o *odooStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     int64 `json:"id"`
+		Params struct {
+			Service string            `json:"service"`
+			Method  string            `json:"method"`
+			Args    []json.RawMessage `json:"args"`
+		} `json:"params"`
+	}
+	if r.URL.Path != "/jsonrpc" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reply := func(result interface{}) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}
+
+	args := req.Params.Args
+	if req.Params.Service == "common" && req.Params.Method == "authenticate" {
+		var database, login, key string
+		_ = json.Unmarshal(args[0], &database)
+		_ = json.Unmarshal(args[1], &login)
+		_ = json.Unmarshal(args[2], &key)
+		if database == "acme" && login == "firs@acme.ng" && key == "api-key" {
+			reply(7)
+		} else {
+			reply(false)
+		}
+		return
+	}
+
+	var model, method string
+	var positional []json.RawMessage
+	var kwargs map[string]interface{}
+	_ = json.Unmarshal(args[3], &model)
+	_ = json.Unmarshal(args[4], &method)
+	_ = json.Unmarshal(args[5], &positional)
+	_ = json.Unmarshal(args[6], &kwargs)
+
+	switch method {
+	case "fields_get":
+		fields := map[string]interface{}{}
+		for _, record := range o.records[model] {
+			for name := range record {
+				fields[name] = map[string]string{"type": "char"}
+			}
+		}
+		reply(fields)
+	case "search_read":
+		o.searches = append(o.searches, kwargs)
+		moves := o.records[model]
+		offset, limit := int(kwargs["offset"].(float64)), int(kwargs["limit"].(float64))
+		if offset > len(moves) {
+			offset = len(moves)
+		}
+		if offset+limit < len(moves) {
+			moves = moves[offset : offset+limit]
+		} else {
+			moves = moves[offset:]
+		}
+		reply(moves)
+	case "read":
+		var ids []int
+		_ = json.Unmarshal(positional[0], &ids)
+		var found []map[string]interface{}
+		for _, id := range ids {
+			for _, record := range o.records[model] {
+				if int(record["id"].(int)) == id {
+					found = append(found, record)
+				}
+			}
+		}
+		reply(found)
+	default:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": req.ID,
+			"error": map[string]interface{}{"code": 200, "message": "Odoo Server Error", "data": map[string]string{"name": "builtins.NotImplementedError", "message": method}},
+		})
+	}
+}
+
+func move(id int, name, state, written string, lines ...int) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id, "name": name, "move_type": odoo.MoveTypeInvoice, "state": state, "payment_state": "not_paid",
+		"ref": false, "invoice_origin": false, "invoice_date": "2025-03-01", "invoice_date_due": "2025-03-31",
+		"partner_id": []interface{}{8, "Globex Ltd"}, "currency_id": []interface{}{1, "NGN"}, "invoice_payment_term_id": false,
+		"reversed_entry_id": false, "amount_untaxed": 1000.0, "amount_tax": 75.0, "amount_total": 1075.0,
+		"amount_residual": 1075.0, "invoice_line_ids": lines, "narration": false, "create_date": written, "write_date": written,
+	}
+}
+
+func newStandIn(t *testing.T) (*odooStandIn, *odoo.Client) {
+	t.Helper()
+
+	standIn := &odooStandIn{records: map[string][]map[string]interface{}{
+		odoo.ModelMove: {
+			move(10, "INV/2025/00001", odoo.StatePosted, "2025-03-01 09:01:00", 100),
+			move(11, "INV/2025/00002", odoo.StatePosted, "2025-03-01 09:02:00", 101),
+			move(12, "INV/2025/00003", odoo.StateCancel, "2025-03-01 09:03:00", 102),
+		},
+		odoo.ModelMoveLine: {
+			{"id": 100, "name": "Consulting", "display_type": "product", "product_id": []interface{}{5, "Consulting"}, "product_uom_id": []interface{}{1, "Units"},
+				"quantity": 2.0, "price_unit": 500.0, "discount": 0.0, "price_subtotal": 1000.0, "price_total": 1075.0, "tax_ids": []int{3}},
+			{"id": 101, "name": "Support", "display_type": "product", "product_id": false, "product_uom_id": false,
+				"quantity": 1.0, "price_unit": 1000.0, "discount": 0.0, "price_subtotal": 1000.0, "price_total": 1075.0, "tax_ids": []int{3}},
+		},
+		odoo.ModelTax:     {{"id": 3, "name": "VAT 7.5%", "amount": 7.5, "amount_type": "percent", "price_include": false}},
+		odoo.ModelPartner: {{"id": 8, "name": "Globex Ltd", "vat": "12345678-0001", "email": "ap@globex.ng", "country_code": "NG"}},
+		// the product has no hs_code field, as in databases without the delivery module
+		odoo.ModelProduct: {{"id": 5, "name": "Consulting", "default_code": "CONS", "categ_id": []interface{}{1, "Services"}}},
+	}}
+
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	client, err := odoo.NewClient(server.URL+"/", "acme", "firs@acme.ng", "api-key")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return standIn, client
+}
+
+func TestPollPage(t *testing.T) {
+	standIn, client := newStandIn(t)
+
+	var received []connector.Invoice
+	p := &movePoll{
+		client: client,
+		awaitingVoid: func(move odoo.Move) (bool, error) {
+			return false, nil // the cancelled invoice was never received
+		},
+		process: func(platformInvoice connector.Invoice) (bool, error) {
+			received = append(received, platformInvoice)
+			if platformInvoice.Number == "INV/2025/00002" {
+				return false, errors.New("firs unavailable")
+			}
+			return true, nil
+		},
+	}
+
+	since := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	page, err := p.page(connector.PollQuery{ModifiedSince: since, Page: 1, PerPage: 3})
+	if err != nil {
+		t.Fatalf("page() error = %v", err)
+	}
+
+	if len(standIn.searches) != 1 || standIn.searches[0]["offset"].(float64) != 0 || standIn.searches[0]["order"] != "write_date asc, id asc" {
+		t.Errorf("searches = %v, want the first page by write date", standIn.searches)
+	}
+	if !page.More {
+		t.Errorf("More = false, want another page after a full one")
+	}
+
+	want := []struct {
+		number  string
+		changed bool
+		failed  bool
+	}{
+		{"INV/2025/00001", true, false},
+		{"INV/2025/00002", false, true},
+		{"INV/2025/00003", false, false},
+	}
+	if len(page.Invoices) != len(want) {
+		t.Fatalf("page has %d invoices, want %d", len(page.Invoices), len(want))
+	}
+	for i, w := range want {
+		polled := page.Invoices[i]
+		if polled.Number != w.number || polled.Changed != w.changed || (polled.Err != nil) != w.failed {
+			t.Errorf("invoice %d = %+v, want %s changed %v failed %v", i, polled, w.number, w.changed, w.failed)
+		}
+		if !polled.Modified.Equal(since.Add(time.Duration(i+1) * time.Minute)) {
+			t.Errorf("invoice %d modified %v, want its write date", i, polled.Modified)
+		}
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("processed %d invoices, want the two posted ones", len(received))
+	}
+	invoice := received[0]
+	if invoice.ExternalID != "10" || invoice.Status != "not_paid" || invoice.Total != 1075 || invoice.Balance != 1075 || invoice.Currency != "NGN" {
+		t.Errorf("invoice = %+v, want the amounts and state of move 10", invoice)
+	}
+
+	var doc odoo.InvoiceDocument
+	if err := json.Unmarshal(invoice.Data, &doc); err != nil {
+		t.Fatalf("failed to decode invoice data: %v", err)
+	}
+	if len(doc.Lines) != 1 || len(doc.Taxes) != 1 || doc.Partner == nil || doc.Partner.VAT != "12345678-0001" {
+		t.Errorf("document = %+v, want the line, tax and partner of the move", doc)
+	}
+	if len(doc.Products) != 1 || doc.Products[0].DefaultCode != "CONS" {
+		t.Errorf("products = %+v, want the product read without the missing hs_code field", doc.Products)
+	}
+	if doc.Move.WriteDate != "" || doc.Move.AmountResidual != 0 {
+		t.Errorf("stored move keeps write date %q and residual %v, want both cleared", doc.Move.WriteDate, doc.Move.AmountResidual)
+	}
+}
+
+func TestPollPageOffset(t *testing.T) {
+	standIn, client := newStandIn(t)
+
+	p := &movePoll{
+		client:       client,
+		awaitingVoid: func(odoo.Move) (bool, error) { return false, nil },
+		process:      func(connector.Invoice) (bool, error) { return true, nil },
+	}
+
+	page, err := p.page(connector.PollQuery{DateFrom: "2025-03-01", DateTo: "2025-03-31", Page: 2, PerPage: 2})
+	if err != nil {
+		t.Fatalf("page() error = %v", err)
+	}
+
+	if standIn.searches[0]["offset"].(float64) != 2 || standIn.searches[0]["order"] != "invoice_date asc, id asc" {
+		t.Errorf("search = %v, want the second page by invoice date", standIn.searches[0])
+	}
+	if page.More || len(page.Invoices) != 1 || page.Invoices[0].Number != "INV/2025/00003" {
+		t.Errorf("page = %+v, want the last move and no more pages", page)
+	}
+}
+
+func TestPollRejectedAPIKey(t *testing.T) {
+	_, client := newStandIn(t)
+	client.APIKey = "revoked"
+
+	p := &movePoll{client: client}
+	if _, err := p.page(connector.PollQuery{Page: 1, PerPage: 10}); !errors.Is(err, odoo.ErrAuthenticationFailed) {
+		t.Errorf("page() error = %v, want ErrAuthenticationFailed", err)
+	}
+}
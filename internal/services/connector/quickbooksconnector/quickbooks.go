@@ -0,0 +1,390 @@
+package quickbooksconnector
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/quickbooks"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/customer"
+	"einvoice-access-point/internal/services/platformmapping"
+	"einvoice-access-point/internal/services/product"
+	"einvoice-access-point/internal/services/token"
+	"einvoice-access-point/internal/services/webhooks"
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Platform is the key of QuickBooks Online in the business platform configs. The organisation ID is the realm ID of the company.
+const Platform = "quickbooks"
+
+// Labels of the lines the FIRS results are written to in the private note of an invoice, unless the business maps custom fields
+const (
+	irnLabel    = "FIRS IRN"
+	qrLabel     = "FIRS QR"
+	statusLabel = "FIRS status"
+	errorLabel  = "FIRS error"
+)
+
+// Connector connects QuickBooks Online companies. Webhooks only name the changed entities, which are read back
+// from the accounting API before they are processed.
+type Connector struct{}
+
+// New returns the QuickBooks Online connector
+func New() *Connector {
+	return &Connector{}
+}
+
+func (q *Connector) Name() string {
+	return Platform
+}
+
+// ParseWebhook decodes a QuickBooks notification into one webhook per company it carries changes for
+func (q *Connector) ParseWebhook(req connector.WebhookRequest) ([]*connector.Webhook, error) {
+	var payload quickbooks.WebhookPayload
+	if err := json.Unmarshal(req.Body, &payload); err != nil {
+		return nil, err
+	}
+	if len(payload.EventNotifications) == 0 {
+		return nil, errors.New("no event notifications present")
+	}
+
+	webhooks := make([]*connector.Webhook, 0, len(payload.EventNotifications))
+	for i := range payload.EventNotifications {
+		notification := payload.EventNotifications[i]
+		webhooks = append(webhooks, &connector.Webhook{OrgID: notification.RealmID, Payload: &notification})
+	}
+	return webhooks, nil
+}
+
+// VerifyWebhook checks the intuit-signature header against the verifier token of the business, or the one of the app
+// when the business did not set its own. Intuit signs with the verifier token of the app the company is connected to.
+func (q *Connector) VerifyWebhook(req connector.WebhookRequest, accConfig models.AccountingPlatformConfig) error {
+	verifier := string(accConfig.HMACSecret)
+	if verifier == "" {
+		verifier = config.GetConfig().QuickBooks.WebhookVerifier
+	}
+	if !quickbooks.VerifySignature(req.Body, verifier, req.Header("intuit-signature")) {
+		return connector.ErrInvalidSignature
+	}
+	return nil
+}
+
+// HandleWebhook processes the invoices the notification names. A payment is handled through the invoices it was applied to.
+func (q *Connector) HandleWebhook(webhook *connector.Webhook, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (interface{}, *string, error) {
+
+	notification, ok := webhook.Payload.(*quickbooks.EventNotification)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected %s webhook payload %T", Platform, webhook.Payload)
+	}
+
+	qbToken, err := token.GetValidAccessToken(db, Platform, notification.RealmID)
+	if err != nil {
+		errDetails := "failed to get access token"
+		return nil, &errDetails, err
+	}
+	accessToken := string(qbToken.AccessToken)
+
+	responses := []*webhooks.PlatformInvoiceResponse{}
+	for _, entity := range notification.DataChangeEvent.Entities {
+		var invoiceIDs []string
+		switch entity.Name {
+		case "Invoice":
+			invoiceIDs = []string{entity.ID}
+		case "Payment":
+			if entity.Operation == "Delete" {
+				continue
+			}
+			payment, err := quickbooks.GetPayment(accessToken, notification.RealmID, entity.ID)
+			if err != nil {
+				errDetails := "failed to get payment"
+				return nil, &errDetails, err
+			}
+			invoiceIDs = paymentInvoiceIDs(payment)
+		default:
+			logger.Info("Ignoring QuickBooks entity", zap.String("entity", entity.Name), zap.String("id", entity.ID))
+			continue
+		}
+
+		for _, invoiceID := range invoiceIDs {
+			platformInvoice, err := readInvoice(accessToken, notification.RealmID, invoiceID, entity.Operation)
+			if err != nil {
+				errDetails := "failed to get invoice"
+				return nil, &errDetails, err
+			}
+			resp, errDetails, err := webhooks.ProcessPlatformInvoice(q, platformInvoice, db, logger, firsKeys, business, accConfig)
+			if err != nil {
+				return nil, errDetails, err
+			}
+			responses = append(responses, resp)
+		}
+	}
+	return responses, nil, nil
+}
+
+func (q *Connector) Convert(db *gorm.DB, business *models.Business, invoiceData []byte, irn string) (firs_models.InvoiceRequest, []string, error) {
+	var doc quickbooks.InvoiceDocument
+	if err := json.Unmarshal(invoiceData, &doc); err != nil {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("failed to decode %s invoice: %w", Platform, err)
+	}
+
+	var itemIDs []string
+	for _, line := range flattenLines(doc.Invoice.Line) {
+		if line.SalesItemLineDetail != nil {
+			itemIDs = append(itemIDs, line.SalesItemLineDetail.ItemRef.Value)
+		}
+	}
+
+	catalogue, err := product.GetCatalogue(db, business.ID, Platform, itemIDs)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	buyer, err := customer.FindCustomer(db, business.ID, Platform, doc.Invoice.CustomerRef.Value)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	mapping, err := platformmapping.GetMapping(db, business.ID, Platform)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	return converter.ConvertQuickBooksToFIRS(doc, business, irn, catalogue, buyer, mapping)
+}
+
+func (q *Connector) AuthURL(state string) (string, error) {
+	return quickbooks.GenerateAuthURL(state)
+}
+
+// ExchangeCode exchanges the code for the tokens of the company the callback names, which must be the one the business configured
+func (q *Connector) ExchangeCode(code, orgID string, callback map[string]string) (*connector.Tokens, error) {
+	if realmID := callback["realmId"]; realmID != "" && orgID != "" && realmID != orgID {
+		return nil, fmt.Errorf("quickbooks company %s was connected in place of the configured company %s", realmID, orgID)
+	}
+
+	tokens, err := quickbooks.ExchangeCodeForTokens(code)
+	if err != nil {
+		return nil, err
+	}
+	return &connector.Tokens{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+func (q *Connector) RefreshTokens(token *models.TokenManager) (*connector.Tokens, error) {
+	tokens, err := quickbooks.RefreshAccessToken(string(token.RefreshToken))
+	var tokenErr *quickbooks.TokenError
+	if errors.As(err, &tokenErr) && tokenErr.Revoked() {
+		return nil, fmt.Errorf("%w: %s", connector.ErrTokenRevoked, tokenErr.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &connector.Tokens{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// WriteBackIRN writes the IRN and QR code to the custom fields the business maps, or to lines of the private note.
+// QuickBooks caps custom field values at 31 characters, so the QR code is best left to the note.
+func (q *Connector) WriteBackIRN(db *gorm.DB, account connector.Account, externalID, irn, qrCode string) error {
+	return q.writeBack(db, account, externalID, func(mapping *models.PlatformMapping) []field {
+		return []field{
+			{definitionID: mapping.IRNField, label: irnLabel, value: irn},
+			{definitionID: mapping.QRField, label: qrLabel, value: qrCode},
+		}
+	})
+}
+
+// WriteBackStatus writes the FIRS processing status and its last error the same way as the IRN
+func (q *Connector) WriteBackStatus(db *gorm.DB, account connector.Account, externalID, status, firsError string) error {
+	return q.writeBack(db, account, externalID, func(mapping *models.PlatformMapping) []field {
+		return []field{
+			{definitionID: mapping.StatusField, label: statusLabel, value: status},
+			{definitionID: mapping.ErrorField, label: errorLabel, value: firsError},
+		}
+	})
+}
+
+func (q *Connector) UploadAttachment(db *gorm.DB, account connector.Account, externalID, filename, contentType string, data []byte) error {
+	qbToken, err := token.GetValidAccessToken(db, Platform, account.Config.OrgID)
+	if err != nil {
+		return err
+	}
+	return quickbooks.UploadInvoiceAttachment(string(qbToken.AccessToken), account.Config.OrgID, externalID, filename, contentType, data)
+}
+
+func (q *Connector) PollInvoices(db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, business *models.Business,
+	account connector.Account, query connector.PollQuery) (*connector.PollPage, error) {
+	return nil, fmt.Errorf("%w: quickbooks invoices are received by webhook", connector.ErrNotSupported)
+}
+
+// field is a value written back to an invoice, to the custom field with the definition ID when one is mapped and to the
+// line of the private note with the label otherwise
+type field struct {
+	definitionID string
+	label        string
+	value        string
+}
+
+// writeBack applies the fields the mapping of the business resolves to the invoice
+func (q *Connector) writeBack(db *gorm.DB, account connector.Account, externalID string, fields func(*models.PlatformMapping) []field) error {
+	mapping, err := platformmapping.GetMapping(db, account.BusinessID, Platform)
+	if err != nil {
+		return err
+	}
+	qbToken, err := token.GetValidAccessToken(db, Platform, account.Config.OrgID)
+	if err != nil {
+		return err
+	}
+	return updateInvoice(string(qbToken.AccessToken), account.Config.OrgID, externalID, fields(mapping))
+}
+
+// updateInvoice reads the invoice for its sync token and note and applies the fields as a sparse update
+func updateInvoice(accessToken, realmID, externalID string, fields []field) error {
+	qbInvoice, err := quickbooks.GetInvoice(accessToken, realmID, externalID)
+	if err != nil {
+		return err
+	}
+
+	update := quickbooks.InvoiceUpdate{ID: qbInvoice.ID, SyncToken: qbInvoice.SyncToken}
+	note := qbInvoice.PrivateNote
+	for _, f := range fields {
+		if f.definitionID != "" {
+			update.CustomField = append(update.CustomField, quickbooks.CustomField{DefinitionID: f.definitionID, Type: "StringType", StringValue: f.value})
+			continue
+		}
+		note = setNoteLine(note, f.label, f.value)
+	}
+	if note != qbInvoice.PrivateNote {
+		update.PrivateNote = &note
+	}
+	if update.PrivateNote == nil && len(update.CustomField) == 0 {
+		return nil
+	}
+	return quickbooks.UpdateInvoice(accessToken, realmID, update)
+}
+
+// setNoteLine replaces the line of the note starting with the label, appending it when the note has none and
+// dropping it when the value is blank
+func setNoteLine(note, label, value string) string {
+	prefix := label + ":"
+	var lines []string
+	replaced := false
+	for _, line := range strings.Split(note, "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+			continue
+		}
+		if !replaced && value != "" {
+			lines = append(lines, prefix+" "+value)
+		}
+		replaced = true
+	}
+	if !replaced && value != "" {
+		lines = append(lines, prefix+" "+value)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// readInvoice reads the invoice and its customer. A deleted invoice can no longer be read and is handled as voided
+// from the data stored when it was received.
+func readInvoice(accessToken, realmID, invoiceID, operation string) (connector.Invoice, error) {
+	if operation == "Delete" {
+		return connector.Invoice{ExternalID: invoiceID, Status: "deleted", Voided: true}, nil
+	}
+
+	qbInvoice, err := quickbooks.GetInvoice(accessToken, realmID, invoiceID)
+	if err != nil {
+		return connector.Invoice{}, err
+	}
+	doc := quickbooks.InvoiceDocument{Invoice: *qbInvoice}
+	if qbInvoice.CustomerRef.Value != "" {
+		qbCustomer, err := quickbooks.GetCustomer(accessToken, realmID, qbInvoice.CustomerRef.Value)
+		if err != nil {
+			return connector.Invoice{}, err
+		}
+		doc.Customer = qbCustomer
+	}
+
+	data, err := json.Marshal(storedDocument(doc))
+	if err != nil {
+		return connector.Invoice{}, err
+	}
+
+	voided := operation == "Void"
+	return connector.Invoice{
+		ExternalID: qbInvoice.ID,
+		Number:     qbInvoice.DocNumber,
+		Status:     invoiceStatus(*qbInvoice, voided),
+		Total:      qbInvoice.TotalAmt,
+		Balance:    qbInvoice.Balance,
+		Currency:   qbInvoice.CurrencyRef.Value,
+		Voided:     voided,
+		Data:       data,
+	}, nil
+}
+
+// storedDocument clears the parts of the invoice the write-back changes, so writing the IRN or status back
+// is not taken for an edit of the invoice when QuickBooks notifies the update
+func storedDocument(doc quickbooks.InvoiceDocument) quickbooks.InvoiceDocument {
+	doc.Invoice.SyncToken = ""
+	doc.Invoice.PrivateNote = ""
+	doc.Invoice.CustomField = nil
+	doc.Invoice.MetaData.LastUpdatedTime = ""
+	return doc
+}
+
+// invoiceStatus describes the invoice by its balance, QuickBooks has no status field
+func invoiceStatus(qbInvoice quickbooks.Invoice, voided bool) string {
+	switch {
+	case voided:
+		return "voided"
+	case qbInvoice.TotalAmt > 0 && qbInvoice.Balance <= 0:
+		return "paid"
+	case qbInvoice.Balance < qbInvoice.TotalAmt:
+		return "partially_paid"
+	}
+	return "open"
+}
+
+// paymentInvoiceIDs lists the invoices a payment was applied to
+func paymentInvoiceIDs(payment *quickbooks.Payment) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, line := range payment.Line {
+		for _, txn := range line.LinkedTxn {
+			if txn.TxnType == "Invoice" && !seen[txn.TxnID] {
+				seen[txn.TxnID] = true
+				ids = append(ids, txn.TxnID)
+			}
+		}
+	}
+	return ids
+}
+
+// flattenLines lists the lines of the invoice with the lines of bundles in place of the bundles
+func flattenLines(lines []quickbooks.Line) []quickbooks.Line {
+	var flat []quickbooks.Line
+	for _, line := range lines {
+		if line.GroupLineDetail != nil {
+			flat = append(flat, flattenLines(line.GroupLineDetail.Line)...)
+			continue
+		}
+		flat = append(flat, line)
+	}
+	return flat
+}
@@ -0,0 +1,202 @@
+package quickbooksconnector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"einvoice-access-point/external/quickbooks"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/pkg/common"
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// quickbooksStandIn serves the invoice and customer reads and the invoice updates of one company of the accounting API
+type quickbooksStandIn struct {
+	invoice  quickbooks.Invoice
+	customer quickbooks.Customer
+	updates  []quickbooks.InvoiceUpdate
+}
+
+func (s *quickbooksStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-token" || r.URL.Query().Get("minorversion") != quickbooks.MINOR_VERSION {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	fault := func() {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"Fault":{"Error":[{"Message":"Object Not Found","Detail":"Object Not Found : Something you're trying to use has been made inactive or is not found.","code":"610"}],"type":"ValidationFault"}}`))
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v3/company/9130/invoice/"+s.invoice.ID:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Invoice": s.invoice})
+	case r.Method == http.MethodGet && r.URL.Path == "/v3/company/9130/customer/"+s.customer.ID:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Customer": s.customer})
+	case r.Method == http.MethodPost && r.URL.Path == "/v3/company/9130/invoice":
+		var update quickbooks.InvoiceUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil || update.SyncToken != s.invoice.SyncToken {
+			fault()
+			return
+		}
+		s.updates = append(s.updates, update)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Invoice": s.invoice})
+	default:
+		fault()
+	}
+}
+
+func newStandIn(t *testing.T) *quickbooksStandIn {
+	t.Helper()
+
+	standIn := &quickbooksStandIn{
+		invoice: quickbooks.Invoice{
+			ID: "130", SyncToken: "3", DocNumber: "1037", TxnDate: "2025-03-01", DueDate: "2025-03-31",
+			CurrencyRef: quickbooks.Ref{Value: "NGN"}, CustomerRef: quickbooks.Ref{Value: "58", Name: "Globex Nigeria Ltd"},
+			Line: []quickbooks.Line{{
+				ID: "1", LineNum: 1, Amount: 400000, DetailType: quickbooks.SalesItemLine,
+				SalesItemLineDetail: &quickbooks.SalesItemLineDetail{ItemRef: quickbooks.Ref{Value: "11"}, UnitPrice: 200000, Qty: 2, TaxCodeRef: &quickbooks.Ref{Value: "5"}},
+			}},
+			TxnTaxDetail: quickbooks.TxnTaxDetail{TotalTax: 30000},
+			TotalAmt:     430000,
+			Balance:      130000,
+			PrivateNote:  "Deliver to gate 2\nFIRS status: failed",
+			CustomField:  []quickbooks.CustomField{{DefinitionID: "1", Type: "StringType", StringValue: "old"}},
+			MetaData:     quickbooks.MetaData{CreateTime: "2025-03-01T09:00:00-08:00", LastUpdatedTime: "2025-03-02T10:00:00-08:00"},
+		},
+		customer: quickbooks.Customer{ID: "58", DisplayName: "Globex Nigeria Ltd", PrimaryEmailAddr: &quickbooks.EmailAddress{Address: "accounts@globex.ng"}},
+	}
+
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	previous := config.Config
+	config.Config = &config.Configuration{QuickBooks: config.QUICKBOOKS{ApiUrl: server.URL + "/", WebhookVerifier: "app-verifier"}}
+	t.Cleanup(func() { config.Config = previous })
+
+	return standIn
+}
+
+func sign(body []byte, verifier string) string {
+	hash := hmac.New(sha256.New, []byte(verifier))
+	hash.Write(body)
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	newStandIn(t)
+	body := []byte(`{"eventNotifications":[{"realmId":"9130","dataChangeEvent":{"entities":[{"name":"Invoice","id":"130","operation":"Update"}]}}]}`)
+
+	tests := []struct {
+		name      string
+		verifier  string // of the business, blank to use the one of the app
+		signature string
+		wantErr   bool
+	}{
+		{"business verifier", "business-verifier", sign(body, "business-verifier"), false},
+		{"app verifier", "", sign(body, "app-verifier"), false},
+		{"app verifier when the business has its own", "business-verifier", sign(body, "app-verifier"), true},
+		{"tampered body", "", sign(append(body, ' '), "app-verifier"), true},
+		{"missing signature", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := connector.WebhookRequest{Body: body, Headers: map[string][]string{"Intuit-Signature": {tt.signature}}}
+			accConfig := models.AccountingPlatformConfig{HMACSecret: common.EncryptedString(tt.verifier)}
+
+			err := New().VerifyWebhook(req, accConfig)
+			if tt.wantErr != errors.Is(err, connector.ErrInvalidSignature) || (!tt.wantErr && err != nil) {
+				t.Errorf("VerifyWebhook() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadInvoice(t *testing.T) {
+	newStandIn(t)
+
+	platformInvoice, err := readInvoice("access-token", "9130", "130", "Update")
+	if err != nil {
+		t.Fatalf("readInvoice() error = %v", err)
+	}
+	if platformInvoice.ExternalID != "130" || platformInvoice.Number != "1037" || platformInvoice.Status != "partially_paid" ||
+		platformInvoice.Total != 430000 || platformInvoice.Balance != 130000 || platformInvoice.Currency != "NGN" || platformInvoice.Voided {
+		t.Errorf("invoice = %+v, want the partially paid invoice 1037", platformInvoice)
+	}
+
+	var doc quickbooks.InvoiceDocument
+	if err := json.Unmarshal(platformInvoice.Data, &doc); err != nil {
+		t.Fatalf("failed to decode invoice data: %v", err)
+	}
+	if doc.Customer == nil || doc.Customer.PrimaryEmailAddr.Address != "accounts@globex.ng" {
+		t.Errorf("customer = %+v, want the customer the invoice refers to", doc.Customer)
+	}
+	if len(doc.Invoice.Line) != 1 || doc.Invoice.Line[0].SalesItemLineDetail.Qty != 2 {
+		t.Errorf("lines = %+v, want the sales line of the invoice", doc.Invoice.Line)
+	}
+	if doc.Invoice.SyncToken != "" || doc.Invoice.PrivateNote != "" || doc.Invoice.CustomField != nil || doc.Invoice.MetaData.LastUpdatedTime != "" {
+		t.Errorf("stored invoice = %+v, want the parts the write-back changes cleared", doc.Invoice)
+	}
+}
+
+func TestReadInvoiceDeletedOrMissing(t *testing.T) {
+	newStandIn(t)
+
+	deleted, err := readInvoice("access-token", "9130", "131", "Delete")
+	if err != nil || !deleted.Voided || deleted.ExternalID != "131" {
+		t.Errorf("readInvoice() = %+v, %v, want a voided invoice without reading it", deleted, err)
+	}
+
+	if _, err := readInvoice("access-token", "9130", "131", "Update"); err == nil || !strings.Contains(err.Error(), "Object Not Found") {
+		t.Errorf("readInvoice() error = %v, want the fault of the missing invoice", err)
+	}
+}
+
+func TestUpdateInvoice(t *testing.T) {
+	standIn := newStandIn(t)
+
+	// The IRN goes to a mapped custom field and the QR code to the note, the blank error is dropped from it
+	err := updateInvoice("access-token", "9130", "130", []field{
+		{definitionID: "1", label: irnLabel, value: "INV1037-94ND90NR-20250301"},
+		{label: qrLabel, value: "qr-code"},
+		{label: statusLabel, value: "submitted"},
+		{label: errorLabel, value: ""},
+	})
+	if err != nil {
+		t.Fatalf("updateInvoice() error = %v", err)
+	}
+
+	if len(standIn.updates) != 1 {
+		t.Fatalf("sent %d updates, want 1", len(standIn.updates))
+	}
+	update := standIn.updates[0]
+	if update.ID != "130" || !update.Sparse {
+		t.Errorf("update = %+v, want a sparse update of invoice 130", update)
+	}
+	wantNote := "Deliver to gate 2\nFIRS status: submitted\nFIRS QR: qr-code"
+	if update.PrivateNote == nil || *update.PrivateNote != wantNote {
+		t.Errorf("note = %v, want %q", update.PrivateNote, wantNote)
+	}
+	if len(update.CustomField) != 1 || update.CustomField[0].DefinitionID != "1" || update.CustomField[0].StringValue != "INV1037-94ND90NR-20250301" {
+		t.Errorf("custom fields = %+v, want the IRN in field 1", update.CustomField)
+	}
+}
+
+func TestUpdateInvoiceUnchanged(t *testing.T) {
+	standIn := newStandIn(t)
+
+	if err := updateInvoice("access-token", "9130", "130", []field{{label: statusLabel, value: "failed"}}); err != nil {
+		t.Fatalf("updateInvoice() error = %v", err)
+	}
+	if len(standIn.updates) != 0 {
+		t.Errorf("sent %d updates, want none for a note that already holds the value", len(standIn.updates))
+	}
+}
@@ -0,0 +1,412 @@
+package xeroconnector
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/xero"
+	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/customer"
+	"einvoice-access-point/internal/services/platformmapping"
+	"einvoice-access-point/internal/services/product"
+	"einvoice-access-point/internal/services/token"
+	"einvoice-access-point/internal/services/webhooks"
+	"einvoice-access-point/pkg/config"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Platform is the key of Xero in the business platform configs. The organisation ID is the tenant ID of the organisation.
+const Platform = "xero"
+
+// ReferenceField is the field name a mapping sets to write a result to the reference of the invoice.
+// Results without a mapped field are added to the history notes of the invoice.
+const ReferenceField = "Reference"
+
+// Labels of the lines the FIRS results are written under in the history notes of an invoice
+const (
+	irnLabel    = "FIRS IRN"
+	qrLabel     = "FIRS QR"
+	statusLabel = "FIRS status"
+	errorLabel  = "FIRS error"
+)
+
+// Connector connects Xero organisations. Webhooks only name the changed invoices and credit notes, which are read
+// back from the accounting API before they are processed.
+type Connector struct{}
+
+// New returns the Xero connector
+func New() *Connector {
+	return &Connector{}
+}
+
+func (x *Connector) Name() string {
+	return Platform
+}
+
+// ParseWebhook decodes a Xero webhook into one webhook per tenant it carries events for. The intent to receive Xero
+// sends when the webhook is set up has no events and no tenant to verify it for, so it is checked against the
+// webhook key of the app here.
+func (x *Connector) ParseWebhook(req connector.WebhookRequest) ([]*connector.Webhook, error) {
+	var payload xero.WebhookPayload
+	if err := json.Unmarshal(req.Body, &payload); err != nil {
+		return nil, err
+	}
+
+	if len(payload.Events) == 0 {
+		if !xero.VerifySignature(req.Body, config.GetConfig().Xero.WebhookKey, req.Header("x-xero-signature")) {
+			return nil, connector.ErrInvalidSignature
+		}
+		return nil, nil
+	}
+
+	var webhooks []*connector.Webhook
+	byTenant := map[string]*xero.TenantEvents{}
+	for _, event := range payload.Events {
+		tenant, ok := byTenant[event.TenantID]
+		if !ok {
+			tenant = &xero.TenantEvents{TenantID: event.TenantID}
+			byTenant[event.TenantID] = tenant
+			webhooks = append(webhooks, &connector.Webhook{OrgID: event.TenantID, Payload: tenant})
+		}
+		tenant.Events = append(tenant.Events, event)
+	}
+	return webhooks, nil
+}
+
+// VerifyWebhook checks the x-xero-signature header against the webhook key of the business, or the one of the app
+// when the business did not set its own. Xero signs with the webhook key of the app the organisation is connected to.
+func (x *Connector) VerifyWebhook(req connector.WebhookRequest, accConfig models.AccountingPlatformConfig) error {
+	key := string(accConfig.HMACSecret)
+	if key == "" {
+		key = config.GetConfig().Xero.WebhookKey
+	}
+	if !xero.VerifySignature(req.Body, key, req.Header("x-xero-signature")) {
+		return connector.ErrInvalidSignature
+	}
+	return nil
+}
+
+// HandleWebhook processes the sales invoices and credit notes the events name. Drafts are left until they are approved.
+func (x *Connector) HandleWebhook(webhook *connector.Webhook, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (interface{}, *string, error) {
+
+	tenant, ok := webhook.Payload.(*xero.TenantEvents)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected %s webhook payload %T", Platform, webhook.Payload)
+	}
+
+	xeroToken, err := token.GetValidAccessToken(db, Platform, tenant.TenantID)
+	if err != nil {
+		errDetails := "failed to get access token"
+		return nil, &errDetails, err
+	}
+	accessToken := string(xeroToken.AccessToken)
+
+	responses := []*webhooks.PlatformInvoiceResponse{}
+	seen := map[string]bool{}
+	for _, event := range tenant.Events {
+		if event.EventCategory != xero.EventCategoryInvoice && event.EventCategory != xero.EventCategoryCreditNote {
+			continue
+		}
+		if seen[event.ResourceID] {
+			continue
+		}
+		seen[event.ResourceID] = true
+
+		platformInvoice, skip, err := readInvoice(accessToken, tenant.TenantID, event.ResourceID, event.EventCategory == xero.EventCategoryCreditNote)
+		if err != nil {
+			errDetails := "failed to get invoice"
+			return nil, &errDetails, err
+		}
+		if skip != "" {
+			logger.Info("Skipping Xero document", zap.String("id", event.ResourceID), zap.String("reason", skip))
+			continue
+		}
+
+		resp, errDetails, err := webhooks.ProcessPlatformInvoice(x, platformInvoice, db, logger, firsKeys, business, accConfig)
+		if err != nil {
+			return nil, errDetails, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil, nil
+}
+
+func (x *Connector) Convert(db *gorm.DB, business *models.Business, invoiceData []byte, irn string) (firs_models.InvoiceRequest, []string, error) {
+	var doc xero.InvoiceDocument
+	if err := json.Unmarshal(invoiceData, &doc); err != nil {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("failed to decode %s invoice: %w", Platform, err)
+	}
+
+	var itemCodes []string
+	for _, item := range doc.Invoice.LineItems {
+		if item.ItemCode != "" {
+			itemCodes = append(itemCodes, item.ItemCode)
+		} else if item.Item != nil && item.Item.Code != "" {
+			itemCodes = append(itemCodes, item.Item.Code)
+		}
+	}
+
+	catalogue, err := product.GetCatalogue(db, business.ID, Platform, itemCodes)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	buyer, err := customer.FindCustomer(db, business.ID, Platform, doc.Invoice.Contact.ContactID)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	mapping, err := platformmapping.GetMapping(db, business.ID, Platform)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	var references []firs_models.DocumentReference
+	if doc.Invoice.IsCreditNote() {
+		references = billingReferences(db, business.ID, doc.Invoice.Allocations)
+	}
+
+	return converter.ConvertXeroToFIRS(doc, business, irn, catalogue, buyer, mapping, references)
+}
+
+func (x *Connector) AuthURL(state string) (string, error) {
+	return xero.GenerateAuthURL(state)
+}
+
+// ExchangeCode exchanges the code for the tokens of the user and selects the configured tenant among the
+// organisations the user granted access to
+func (x *Connector) ExchangeCode(code, orgID string, callback map[string]string) (*connector.Tokens, error) {
+	tokens, err := xero.ExchangeCodeForTokens(code)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := xero.GetConnections(tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	var granted []string
+	for _, connection := range connections {
+		if connection.TenantID == orgID {
+			return &connector.Tokens{
+				AccessToken:  tokens.AccessToken,
+				RefreshToken: tokens.RefreshToken,
+				ExpiresIn:    tokens.ExpiresIn,
+			}, nil
+		}
+		granted = append(granted, fmt.Sprintf("%s (%s)", connection.TenantName, connection.TenantID))
+	}
+	return nil, fmt.Errorf("xero organisation %s was not among the organisations access was granted to: %s", orgID, strings.Join(granted, ", "))
+}
+
+func (x *Connector) RefreshTokens(token *models.TokenManager) (*connector.Tokens, error) {
+	tokens, err := xero.RefreshAccessToken(string(token.RefreshToken))
+	var tokenErr *xero.TokenError
+	if errors.As(err, &tokenErr) && tokenErr.Revoked() {
+		return nil, fmt.Errorf("%w: %s", connector.ErrTokenRevoked, tokenErr.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &connector.Tokens{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// WriteBackIRN adds the IRN and QR code to the history notes of the invoice, or writes the IRN to its reference
+// when the business maps it there. Xero keeps 255 characters of a reference, too few for the QR code.
+func (x *Connector) WriteBackIRN(db *gorm.DB, account connector.Account, externalID, irn, qrCode string) error {
+	return x.writeBack(db, account, externalID, func(mapping *models.PlatformMapping) []field {
+		return []field{
+			{name: mapping.IRNField, label: irnLabel, value: irn},
+			{name: mapping.QRField, label: qrLabel, value: qrCode},
+		}
+	})
+}
+
+// WriteBackStatus adds the FIRS processing status and its last error to the history notes of the invoice
+func (x *Connector) WriteBackStatus(db *gorm.DB, account connector.Account, externalID, status, firsError string) error {
+	return x.writeBack(db, account, externalID, func(mapping *models.PlatformMapping) []field {
+		return []field{
+			{name: mapping.StatusField, label: statusLabel, value: status},
+			{name: mapping.ErrorField, label: errorLabel, value: firsError},
+		}
+	})
+}
+
+func (x *Connector) UploadAttachment(db *gorm.DB, account connector.Account, externalID, filename, contentType string, data []byte) error {
+	creditNote, err := isCreditNote(db, account.BusinessID, externalID)
+	if err != nil {
+		return err
+	}
+	xeroToken, err := token.GetValidAccessToken(db, Platform, account.Config.OrgID)
+	if err != nil {
+		return err
+	}
+	return xero.UploadAttachment(string(xeroToken.AccessToken), account.Config.OrgID, externalID, creditNote, filename, contentType, data)
+}
+
+func (x *Connector) PollInvoices(db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, business *models.Business,
+	account connector.Account, query connector.PollQuery) (*connector.PollPage, error) {
+	return nil, fmt.Errorf("%w: xero invoices are received by webhook", connector.ErrNotSupported)
+}
+
+// field is a result written back to an invoice, to its reference when the mapping names that field and to a line of
+// a history note otherwise
+type field struct {
+	name  string
+	label string
+	value string
+}
+
+func (x *Connector) writeBack(db *gorm.DB, account connector.Account, externalID string, fields func(*models.PlatformMapping) []field) error {
+	mapping, err := platformmapping.GetMapping(db, account.BusinessID, Platform)
+	if err != nil {
+		return err
+	}
+	creditNote, err := isCreditNote(db, account.BusinessID, externalID)
+	if err != nil {
+		return err
+	}
+	xeroToken, err := token.GetValidAccessToken(db, Platform, account.Config.OrgID)
+	if err != nil {
+		return err
+	}
+	accessToken := string(xeroToken.AccessToken)
+
+	var notes []string
+	for _, f := range fields(mapping) {
+		if strings.EqualFold(f.name, ReferenceField) {
+			if err := xero.UpdateReference(accessToken, account.Config.OrgID, externalID, creditNote, f.value); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.value != "" {
+			notes = append(notes, f.label+": "+f.value)
+		}
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+	return xero.AddHistoryNote(accessToken, account.Config.OrgID, externalID, creditNote, strings.Join(notes, "\n"))
+}
+
+// readInvoice reads the invoice or credit note and its contact. It names the reason when the document is not one to
+// send to FIRS: purchase documents, drafts awaiting approval and deleted drafts.
+func readInvoice(accessToken, tenantID, id string, creditNote bool) (connector.Invoice, string, error) {
+	inv, err := xero.GetInvoice(accessToken, tenantID, id, creditNote)
+	if err != nil {
+		return connector.Invoice{}, "", err
+	}
+
+	switch {
+	case inv.Type != xero.TypeSalesInvoice && inv.Type != xero.TypeSalesCreditNote:
+		return connector.Invoice{}, "not a sales document", nil
+	case inv.Status == xero.StatusDraft || inv.Status == xero.StatusSubmitted:
+		return connector.Invoice{}, "not approved yet", nil
+	case inv.Status == xero.StatusDeleted:
+		// only drafts can be deleted, and drafts are never sent to FIRS
+		return connector.Invoice{}, "deleted draft", nil
+	case inv.IsCreditNote() && inv.Status == xero.StatusVoided:
+		return connector.Invoice{}, "voided credit notes are not reversed", nil
+	}
+
+	doc := xero.InvoiceDocument{Invoice: *inv}
+	if inv.Contact.ContactID != "" {
+		contact, err := xero.GetContact(accessToken, tenantID, inv.Contact.ContactID)
+		if err != nil {
+			return connector.Invoice{}, "", err
+		}
+		doc.Contact = contact
+	}
+
+	data, err := json.Marshal(storedDocument(doc))
+	if err != nil {
+		return connector.Invoice{}, "", err
+	}
+
+	// allocating a credit note is no payment, its balance is left whole so status changes are handled as updates
+	balance := inv.AmountDue
+	if inv.IsCreditNote() {
+		balance = inv.Total
+	}
+
+	return connector.Invoice{
+		ExternalID: inv.ID(),
+		Number:     inv.Number(),
+		Status:     invoiceStatus(*inv),
+		Total:      inv.Total,
+		Balance:    balance,
+		Currency:   inv.CurrencyCode,
+		Voided:     inv.Status == xero.StatusVoided,
+		Data:       data,
+	}, "", nil
+}
+
+// storedDocument clears the parts of the invoice the write-back and payments change, so neither is taken for an
+// edit of the invoice when Xero notifies the update
+func storedDocument(doc xero.InvoiceDocument) xero.InvoiceDocument {
+	doc.Invoice.Reference = ""
+	doc.Invoice.UpdatedDateUTC = ""
+	doc.Invoice.AmountPaid = 0
+	return doc
+}
+
+// invoiceStatus is the Xero status, with partly paid invoices told apart from unpaid ones so a payment changes the status
+func invoiceStatus(inv xero.Invoice) string {
+	if inv.Status == xero.StatusAuthorised && !inv.IsCreditNote() && inv.AmountPaid > 0 {
+		return "partially_paid"
+	}
+	return strings.ToLower(inv.Status)
+}
+
+// isCreditNote reports whether the stored document of the Xero ID is a credit note, which Xero serves from its own endpoints
+func isCreditNote(db *gorm.DB, businessID, externalID string) (bool, error) {
+	pdb := inst.InitDB(db, true)
+
+	stored, err := repository.FindInvoiceByPlatformID(pdb, businessID, Platform, externalID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up xero document %s: %w", externalID, err)
+	}
+	var doc xero.InvoiceDocument
+	if err := json.Unmarshal(stored.InvoiceData, &doc); err != nil {
+		return false, err
+	}
+	return doc.Invoice.IsCreditNote(), nil
+}
+
+// billingReferences refers a credit note to the invoices it was allocated to that FIRS issued an IRN for
+func billingReferences(db *gorm.DB, businessID string, allocations []xero.Allocation) []firs_models.DocumentReference {
+	pdb := inst.InitDB(db, true)
+
+	var references []firs_models.DocumentReference
+	for _, allocation := range allocations {
+		original, err := repository.FindInvoiceByPlatformID(pdb, businessID, Platform, allocation.Invoice.InvoiceID)
+		if err != nil || original.IRN == "" {
+			continue
+		}
+		var doc xero.InvoiceDocument
+		if err := json.Unmarshal(original.InvoiceData, &doc); err != nil {
+			continue
+		}
+		issueDate := doc.Invoice.DateString
+		if len(issueDate) > 10 {
+			issueDate = issueDate[:10]
+		}
+		references = append(references, firs_models.DocumentReference{IRN: original.IRN, IssueDate: issueDate})
+	}
+	return references
+}
@@ -0,0 +1,308 @@
+package xeroconnector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/xero"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/pkg/common"
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// xeroStandIn serves the invoice, credit note and contact reads of one tenant of the accounting API
+type xeroStandIn struct {
+	invoices    map[string]xero.Invoice
+	creditNotes map[string]xero.Invoice
+	contact     xero.Contact
+}
+
+func (s *xeroStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-token" || r.Header.Get("Xero-tenant-id") != "tenant-1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const base = "/api.xro/2.0/"
+	for id, invoice := range s.invoices {
+		if r.URL.Path == base+"Invoices/"+id {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"Invoices": []xero.Invoice{invoice}})
+			return
+		}
+	}
+	for id, creditNote := range s.creditNotes {
+		if r.URL.Path == base+"CreditNotes/"+id {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"CreditNotes": []xero.Invoice{creditNote}})
+			return
+		}
+	}
+	if r.URL.Path == base+"Contacts/"+s.contact.ContactID {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Contacts": []xero.Contact{s.contact}})
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func newStandIn(t *testing.T) *xeroStandIn {
+	t.Helper()
+
+	standIn := &xeroStandIn{
+		invoices:    map[string]xero.Invoice{},
+		creditNotes: map[string]xero.Invoice{},
+		contact:     xero.Contact{ContactID: "c-58", Name: "Globex Nigeria Ltd", EmailAddress: "accounts@globex.ng"},
+	}
+
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	previous := config.Config
+	config.Config = &config.Configuration{Xero: config.XERO{ApiUrl: server.URL, WebhookKey: "app-key"}}
+	t.Cleanup(func() { config.Config = previous })
+
+	return standIn
+}
+
+func sign(body []byte, key string) string {
+	hash := hmac.New(sha256.New, []byte(key))
+	hash.Write(body)
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func signedRequest(body []byte, signature string) connector.WebhookRequest {
+	return connector.WebhookRequest{Body: body, Headers: map[string][]string{"X-Xero-Signature": {signature}}}
+}
+
+func TestParseWebhookIntentToReceive(t *testing.T) {
+	newStandIn(t)
+	body := []byte(`{"events":[],"firstEventSequence":0,"lastEventSequence":0,"entropy":"S0MEENTR0PY"}`)
+
+	webhooks, err := New().ParseWebhook(signedRequest(body, sign(body, "app-key")))
+	if err != nil || len(webhooks) != 0 {
+		t.Errorf("ParseWebhook() = %v, %v, want the intent to receive accepted without webhooks", webhooks, err)
+	}
+
+	// Xero checks that a wrongly signed intent to receive is refused before it enables the webhook
+	if _, err := New().ParseWebhook(signedRequest(body, sign(body, "other-key"))); !errors.Is(err, connector.ErrInvalidSignature) {
+		t.Errorf("ParseWebhook() error = %v, want %v", err, connector.ErrInvalidSignature)
+	}
+}
+
+func TestParseWebhookByTenant(t *testing.T) {
+	newStandIn(t)
+	body := []byte(`{"events":[
+		{"resourceId":"inv-1","eventCategory":"INVOICE","eventType":"UPDATE","tenantId":"tenant-1"},
+		{"resourceId":"inv-2","eventCategory":"INVOICE","eventType":"CREATE","tenantId":"tenant-2"},
+		{"resourceId":"cn-1","eventCategory":"CREDITNOTE","eventType":"CREATE","tenantId":"tenant-1"}
+	]}`)
+
+	// Events are not verified here, each tenant is verified with the key of its business
+	webhooks, err := New().ParseWebhook(signedRequest(body, ""))
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	if len(webhooks) != 2 || webhooks[0].OrgID != "tenant-1" || webhooks[1].OrgID != "tenant-2" {
+		t.Fatalf("webhooks = %+v, want one per tenant", webhooks)
+	}
+	if events := webhooks[0].Payload.(*xero.TenantEvents).Events; len(events) != 2 || events[1].ResourceID != "cn-1" {
+		t.Errorf("events of tenant-1 = %+v, want inv-1 and cn-1", events)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	newStandIn(t)
+	body := []byte(`{"events":[{"resourceId":"inv-1","eventCategory":"INVOICE","eventType":"UPDATE","tenantId":"tenant-1"}]}`)
+
+	tests := []struct {
+		name      string
+		key       string // of the business, blank to use the one of the app
+		signature string
+		wantErr   bool
+	}{
+		{"business key", "business-key", sign(body, "business-key"), false},
+		{"app key", "", sign(body, "app-key"), false},
+		{"app key when the business has its own", "business-key", sign(body, "app-key"), true},
+		{"bad signature", "", sign(body, "other-key"), true},
+		{"tampered body", "", sign(append(body, ' '), "app-key"), true},
+		{"missing signature", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accConfig := models.AccountingPlatformConfig{HMACSecret: common.EncryptedString(tt.key)}
+
+			err := New().VerifyWebhook(signedRequest(body, tt.signature), accConfig)
+			if tt.wantErr != errors.Is(err, connector.ErrInvalidSignature) || (!tt.wantErr && err != nil) {
+				t.Errorf("VerifyWebhook() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadCreditNote(t *testing.T) {
+	standIn := newStandIn(t)
+	standIn.creditNotes["cn-1"] = xero.Invoice{
+		Type: xero.TypeSalesCreditNote, CreditNoteID: "cn-1", CreditNoteNumber: "CN-0007", Reference: "FIRS IRN",
+		Contact: xero.ContactRef{ContactID: "c-58"}, DateString: "2025-03-10T00:00:00", Status: xero.StatusAuthorised,
+		Total: 10750, AmountDue: 0, CurrencyCode: "NGN", UpdatedDateUTC: "/Date(1741600000000+0000)/",
+		Allocations: []xero.Allocation{{Invoice: xero.InvoiceRef{InvoiceID: "inv-1"}, Amount: 10750}},
+	}
+
+	platformInvoice, skip, err := readInvoice("access-token", "tenant-1", "cn-1", true)
+	if err != nil || skip != "" {
+		t.Fatalf("readInvoice() = %q, %v, want the credit note", skip, err)
+	}
+	// An allocated credit note keeps its whole balance so the allocation is not taken for a payment
+	if platformInvoice.ExternalID != "cn-1" || platformInvoice.Number != "CN-0007" || platformInvoice.Status != "authorised" ||
+		platformInvoice.Balance != 10750 || platformInvoice.Voided {
+		t.Errorf("invoice = %+v, want the authorised credit note CN-0007 at its whole balance", platformInvoice)
+	}
+
+	var doc xero.InvoiceDocument
+	if err := json.Unmarshal(platformInvoice.Data, &doc); err != nil {
+		t.Fatalf("failed to decode invoice data: %v", err)
+	}
+	if !doc.Invoice.IsCreditNote() || len(doc.Invoice.Allocations) != 1 {
+		t.Errorf("stored document = %+v, want the credit note with its allocation", doc.Invoice)
+	}
+	if doc.Contact == nil || doc.Contact.EmailAddress != "accounts@globex.ng" {
+		t.Errorf("contact = %+v, want the contact the credit note refers to", doc.Contact)
+	}
+	if doc.Invoice.Reference != "" || doc.Invoice.UpdatedDateUTC != "" {
+		t.Errorf("stored document = %+v, want the parts the write-back changes cleared", doc.Invoice)
+	}
+}
+
+func TestReadInvoiceSkipped(t *testing.T) {
+	standIn := newStandIn(t)
+	standIn.invoices["bill-1"] = xero.Invoice{Type: "ACCPAY", InvoiceID: "bill-1", Status: xero.StatusAuthorised}
+	standIn.invoices["inv-2"] = xero.Invoice{Type: xero.TypeSalesInvoice, InvoiceID: "inv-2", Status: xero.StatusDraft}
+	standIn.creditNotes["cn-2"] = xero.Invoice{Type: xero.TypeSalesCreditNote, CreditNoteID: "cn-2", Status: xero.StatusVoided}
+
+	tests := []struct {
+		id         string
+		creditNote bool
+		want       string
+	}{
+		{"bill-1", false, "not a sales document"},
+		{"inv-2", false, "not approved yet"},
+		{"cn-2", true, "voided credit notes are not reversed"},
+	}
+	for _, tt := range tests {
+		if _, skip, err := readInvoice("access-token", "tenant-1", tt.id, tt.creditNote); err != nil || skip != tt.want {
+			t.Errorf("readInvoice(%s) = %q, %v, want skipped as %q", tt.id, skip, err, tt.want)
+		}
+	}
+}
+
+// supplier is a business with a complete supplier profile
+func supplier() *models.Business {
+	return &models.Business{
+		BusinessID:  "8a8e2f7c-3c1d-4a5e-9d2b-6f1e0c4b7a21",
+		CompanyName: "Acme Supplies Ltd",
+		TIN:         "01234567-0001",
+		SupplierProfile: models.SupplierProfile{
+			Email:      "billing@acme.ng",
+			StreetName: "12 Marina Road",
+			CityName:   "Lagos",
+			Country:    "NG",
+		},
+	}
+}
+
+func TestConvertCreditNote(t *testing.T) {
+	doc := xero.InvoiceDocument{Invoice: xero.Invoice{
+		Type: xero.TypeSalesCreditNote, CreditNoteID: "cn-1", CreditNoteNumber: "CN-0007",
+		Contact: xero.ContactRef{ContactID: "c-58"}, DateString: "2025-03-10T00:00:00", Status: xero.StatusAuthorised,
+		LineAmountTypes: xero.LineAmountsExclusive, CurrencyCode: "NGN", TotalTax: 750, Total: 10750,
+		LineItems: []xero.LineItem{{ItemCode: "PAPER", Description: "A4 paper", Quantity: 2, UnitAmount: 5000, TaxType: "OUTPUT2", TaxAmount: 750, LineAmount: 10000}},
+	}}
+	catalogue := map[string]models.Product{"PAPER": {HSCode: "4802.56", ProductCategory: "Paper"}}
+	customer := &models.Customer{Name: "Globex Nigeria Ltd", Email: "accounts@globex.ng", TIN: "98765432-0001", TINVerified: true}
+	mapping := &models.PlatformMapping{TaxCategories: models.TaxMappings{"OUTPUT2": {Category: "STANDARD_VAT"}}}
+	references := []firs_models.DocumentReference{{IRN: "INV1037-94ND90NR-20250301", IssueDate: "2025-03-01"}}
+
+	firsInvoice, warnings, err := converter.ConvertXeroToFIRS(doc, supplier(), "", catalogue, customer, mapping, references)
+	if err != nil {
+		t.Fatalf("ConvertXeroToFIRS() error = %v", err)
+	}
+	if firsInvoice.InvoiceTypeCode != models.CreditNoteTypeCode {
+		t.Errorf("InvoiceTypeCode = %q, want the credit note code %s", firsInvoice.InvoiceTypeCode, models.CreditNoteTypeCode)
+	}
+	if len(firsInvoice.BillingReference) != 1 || firsInvoice.BillingReference[0].IRN != references[0].IRN {
+		t.Errorf("BillingReference = %+v, want the invoice the credit note was allocated to", firsInvoice.BillingReference)
+	}
+	if firsInvoice.PaymentStatus != nil || firsInvoice.DueDate != nil {
+		t.Errorf("credit note has a payment status %v or due date %v, want neither", firsInvoice.PaymentStatus, firsInvoice.DueDate)
+	}
+	if firsInvoice.LegalMonetaryTotal.PayableAmount != 10750 || len(warnings) != 0 {
+		t.Errorf("payable amount = %v with warnings %v, want 10750 without warnings", firsInvoice.LegalMonetaryTotal.PayableAmount, warnings)
+	}
+
+	// Without an allocation the credit note still goes to FIRS, with a warning
+	_, warnings, err = converter.ConvertXeroToFIRS(doc, supplier(), "", catalogue, customer, mapping, nil)
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("ConvertXeroToFIRS() = %v, %v, want a warning about the missing billing reference", warnings, err)
+	}
+}
+
+func TestConvertTaxType(t *testing.T) {
+	override := 5.0
+	mapping := &models.PlatformMapping{TaxCategories: models.TaxMappings{
+		"OUTPUT2":  {Category: "STANDARD_VAT"},
+		"REDUCED1": {Category: "REDUCED_VAT", Percent: &override},
+	}}
+	catalogue := map[string]models.Product{
+		"LAPTOP":  {HSCode: "8471.30", ProductCategory: "Computers"},
+		"SUPPORT": {HSCode: "9983.13", ProductCategory: "IT services"},
+		"PAPER":   {HSCode: "4802.56", ProductCategory: "Paper", TaxCategory: "ZERO_VAT"},
+	}
+	customer := &models.Customer{Name: "Globex Nigeria Ltd", Email: "accounts@globex.ng", TIN: "98765432-0001", TINVerified: true}
+	invoice := func(items ...xero.LineItem) xero.InvoiceDocument {
+		return xero.InvoiceDocument{Invoice: xero.Invoice{
+			Type: xero.TypeSalesInvoice, InvoiceID: "inv-1", InvoiceNumber: "INV-0042", DateString: "2025-03-01T00:00:00",
+			Status: xero.StatusAuthorised, LineAmountTypes: xero.LineAmountsExclusive, CurrencyCode: "NGN", LineItems: items,
+		}}
+	}
+
+	doc := invoice(
+		xero.LineItem{ItemCode: "LAPTOP", Quantity: 1, UnitAmount: 100000, TaxType: "OUTPUT2", TaxAmount: 7500, LineAmount: 100000},
+		xero.LineItem{ItemCode: "SUPPORT", Quantity: 1, UnitAmount: 20000, TaxType: "REDUCED1", TaxAmount: 1000, LineAmount: 20000},
+		xero.LineItem{ItemCode: "PAPER", Quantity: 1, UnitAmount: 5000, TaxType: "NONE", LineAmount: 5000},
+	)
+	doc.Invoice.TotalTax, doc.Invoice.Total, doc.Invoice.AmountDue = 8500, 133500, 133500
+
+	firsInvoice, _, err := converter.ConvertXeroToFIRS(doc, supplier(), "", catalogue, customer, mapping, nil)
+	if err != nil {
+		t.Fatalf("ConvertXeroToFIRS() error = %v", err)
+	}
+	if firsInvoice.InvoiceTypeCode != models.DefaultInvoiceTypeCode {
+		t.Errorf("InvoiceTypeCode = %q, want %s", firsInvoice.InvoiceTypeCode, models.DefaultInvoiceTypeCode)
+	}
+
+	// The mapped category keeps the rate Xero applied unless the mapping overrides it; the untaxed line is left out
+	subtotals := firsInvoice.TaxTotal[0].TaxSubtotal
+	if len(subtotals) != 2 ||
+		subtotals[0].TaxCategory != (firs_models.TaxCategory{ID: "STANDARD_VAT", Percent: 7.5}) || subtotals[0].TaxAmount != 7500 ||
+		subtotals[1].TaxCategory != (firs_models.TaxCategory{ID: "REDUCED_VAT", Percent: 5}) || subtotals[1].TaxAmount != 1000 {
+		t.Errorf("tax subtotals = %+v, want STANDARD_VAT at 7.5 and REDUCED_VAT at 5", subtotals)
+	}
+	if firsInvoice.TaxTotal[0].TaxAmount != 8500 {
+		t.Errorf("tax amount = %v, want 8500", firsInvoice.TaxTotal[0].TaxAmount)
+	}
+
+	// A taxed line whose tax type is not mapped and whose product has no tax category holds the invoice back
+	unmappedDoc := invoice(xero.LineItem{ItemCode: "LAPTOP", Quantity: 1, UnitAmount: 100000, TaxType: "OUTPUT3", TaxAmount: 7500, LineAmount: 100000})
+	_, _, err = converter.ConvertXeroToFIRS(unmappedDoc, supplier(), "", catalogue, customer, mapping, nil)
+	var unmapped *converter.UnmappedTaxesError
+	if !errors.As(err, &unmapped) || len(unmapped.TaxIDs) != 1 || unmapped.TaxIDs[0] != "OUTPUT3" {
+		t.Errorf("ConvertXeroToFIRS() error = %v, want the unmapped tax type OUTPUT3", err)
+	}
+}
@@ -0,0 +1,206 @@
+package zohoconnector
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/platformmapping"
+	"einvoice-access-point/internal/services/token"
+	"einvoice-access-point/internal/services/webhooks"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Connector connects one Zoho product. Zoho Invoice and Zoho Books share the payloads, the OAuth
+// flow and the signature scheme, and differ in scope, API host and the converter applied.
+type Connector struct {
+	api zoho.API
+}
+
+// New returns the connector of the Zoho product
+func New(api zoho.API) *Connector {
+	return &Connector{api: api}
+}
+
+func (z *Connector) Name() string {
+	return z.api.Platform
+}
+
+// ParseWebhook decodes the invoice webhook. Zoho names the organisation in the query of the webhook URL.
+func (z *Connector) ParseWebhook(req connector.WebhookRequest) ([]*connector.Webhook, error) {
+	orgID := req.Query["organisation_id"]
+	if orgID == "" {
+		return nil, errors.New("no organisation ID present")
+	}
+
+	var payload zoho.WebhookPayload
+	if err := json.Unmarshal(req.Body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.EventType == "" {
+		payload.EventType = req.Query["event_type"]
+	}
+
+	return []*connector.Webhook{{OrgID: orgID, Payload: &payload}}, nil
+}
+
+func (z *Connector) VerifyWebhook(req connector.WebhookRequest, accConfig models.AccountingPlatformConfig) error {
+	return webhooks.VerifyZohoSignature(req.Body, req.Header("X-Zoho-Signature"), accConfig)
+}
+
+func (z *Connector) HandleWebhook(webhook *connector.Webhook, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (interface{}, *string, error) {
+
+	payload, ok := webhook.Payload.(*zoho.WebhookPayload)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected %s webhook payload %T", z.api.Platform, webhook.Payload)
+	}
+	return webhooks.HandleZohoEvent(z.api, *payload, db, logger, firsKeys, business, accConfig)
+}
+
+func (z *Connector) Convert(db *gorm.DB, business *models.Business, invoiceData []byte, irn string) (firs_models.InvoiceRequest, []string, error) {
+	var zohoInvoice zoho.Invoice
+	if err := json.Unmarshal(invoiceData, &zohoInvoice); err != nil {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("failed to decode %s invoice: %w", z.api.Platform, err)
+	}
+	return webhooks.ConvertZohoInvoice(db, z.api, business, zohoInvoice, irn)
+}
+
+func (z *Connector) AuthURL(state string) (string, error) {
+	return z.api.GenerateAuthURL(state)
+}
+
+// ExchangeCode exchanges the code at the accounts server the callback names, which is the one of the organisation's datacenter
+func (z *Connector) ExchangeCode(code, orgID string, callback map[string]string) (*connector.Tokens, error) {
+	server, err := zoho.ResolveAccountsServer(callback["accounts-server"])
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := zoho.ExchangeCodeForTokens(server, code)
+	if err != nil {
+		return nil, err
+	}
+	return &connector.Tokens{
+		AccessToken:    tokens.AccessToken,
+		RefreshToken:   tokens.RefreshToken,
+		ExpiresIn:      tokens.ExpiresIn,
+		APIDomain:      tokens.API_Domain,
+		AccountsServer: server,
+	}, nil
+}
+
+func (z *Connector) RefreshTokens(token *models.TokenManager) (*connector.Tokens, error) {
+	accountsServer := token.AccountsServer
+	if accountsServer == "" {
+		accountsServer = zoho.DefaultAccountsServer()
+	}
+
+	tokens, err := zoho.RefreshAccessToken(accountsServer, string(token.RefreshToken))
+	var tokenErr *zoho.TokenError
+	if errors.As(err, &tokenErr) && tokenErr.Revoked() {
+		return nil, fmt.Errorf("%w: %s", connector.ErrTokenRevoked, tokenErr.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &connector.Tokens{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		APIDomain:    tokens.API_Domain,
+	}, nil
+}
+
+func (z *Connector) WriteBackIRN(db *gorm.DB, account connector.Account, externalID, irn, qrCode string) error {
+	zohoToken, mapping, err := z.session(db, account)
+	if err != nil {
+		return err
+	}
+	return z.api.UpdateInvoice(string(zohoToken.AccessToken), zohoToken.APIDomain, externalID, irn, qrCode, account.Config, mapping)
+}
+
+func (z *Connector) WriteBackStatus(db *gorm.DB, account connector.Account, externalID, status, firsError string) error {
+	zohoToken, mapping, err := z.session(db, account)
+	if err != nil {
+		return err
+	}
+	return z.api.UpdateFirsStatus(string(zohoToken.AccessToken), zohoToken.APIDomain, externalID,
+		account.Config.OrgID, status, firsError, mapping)
+}
+
+func (z *Connector) UploadAttachment(db *gorm.DB, account connector.Account, externalID, filename, contentType string, data []byte) error {
+	zohoToken, err := token.GetValidAccessToken(db, z.api.Platform, account.Config.OrgID)
+	if err != nil {
+		return err
+	}
+	return z.api.UploadInvoiceAttachment(string(zohoToken.AccessToken), zohoToken.APIDomain, externalID,
+		account.Config.OrgID, filename, contentType, data, true)
+}
+
+// PollInvoices pulls the page of invoices modified since the query time, or dated in its range, through the same path
+// as the webhook. Drafts are left until they are issued.
+func (z *Connector) PollInvoices(db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, business *models.Business,
+	account connector.Account, query connector.PollQuery) (*connector.PollPage, error) {
+
+	zohoToken, err := token.GetValidAccessToken(db, z.api.Platform, account.Config.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	return z.poll(zohoToken, account.Config.OrgID, query, func(zohoInvoice zoho.Invoice) (bool, error) {
+		return webhooks.ProcessZohoInvoice(z.api, zohoInvoice, db, logger, firsKeys, business, account.Config)
+	})
+}
+
+// poll lists the invoices and hands each issued one, read in full, to process
+func (z *Connector) poll(zohoToken *models.TokenManager, orgID string, query connector.PollQuery,
+	process func(zohoInvoice zoho.Invoice) (bool, error)) (*connector.PollPage, error) {
+
+	list, err := z.api.ListInvoices(string(zohoToken.AccessToken), zohoToken.APIDomain, orgID, zoho.ListInvoicesQuery{
+		LastModifiedTime: query.ModifiedSince,
+		DateStart:        query.DateFrom,
+		DateEnd:          query.DateTo,
+		Page:             query.Page,
+		PerPage:          query.PerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &connector.PollPage{More: list.PageContext.HasMorePage}
+	for _, summary := range list.Invoices {
+		polled := connector.PolledInvoice{Number: summary.InvoiceNumber}
+		if modified, err := time.Parse(zoho.TimeLayout, summary.LastModifiedTime); err == nil {
+			polled.Modified = modified
+		}
+
+		if summary.Status != "draft" {
+			zohoInvoice, err := z.api.GetInvoice(string(zohoToken.AccessToken), zohoToken.APIDomain, orgID, summary.InvoiceID)
+			if err == nil {
+				polled.Changed, err = process(*zohoInvoice)
+			}
+			polled.Err = err
+		}
+		page.Invoices = append(page.Invoices, polled)
+	}
+	return page, nil
+}
+
+// session returns the access token of the organisation and the custom fields the business maps for the product
+func (z *Connector) session(db *gorm.DB, account connector.Account) (*models.TokenManager, *models.PlatformMapping, error) {
+	mapping, err := platformmapping.GetMapping(db, account.BusinessID, z.api.Platform)
+	if err != nil {
+		return nil, nil, err
+	}
+	zohoToken, err := token.GetValidAccessToken(db, z.api.Platform, account.Config.OrgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return zohoToken, mapping, nil
+}
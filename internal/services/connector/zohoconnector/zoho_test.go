@@ -0,0 +1,133 @@
+package zohoconnector
+
+import (
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// zohoStandIn serves the invoice list and invoice reads of the Zoho Invoice API from a fixed list of invoices. The
+// gone invoices are listed but can no longer be read.
+func zohoStandIn(t *testing.T, invoices []zoho.InvoiceSummary, gone ...string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Zoho-oauthtoken access-token" || r.URL.Query().Get("organization_id") != "org-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, "/invoice/v3/invoices")
+		if id == "" {
+			since, _ := time.Parse(zoho.TimeLayout, r.URL.Query().Get("last_modified_time"))
+			var listed []zoho.InvoiceSummary
+			for _, summary := range invoices {
+				modified, _ := time.Parse(zoho.TimeLayout, summary.LastModifiedTime)
+				if !modified.Before(since) {
+					listed = append(listed, summary)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(zoho.InvoiceList{Invoices: listed, PageContext: zoho.PageContext{HasMorePage: r.URL.Query().Get("page") == "1"}})
+			return
+		}
+
+		for _, summary := range invoices {
+			if "/"+summary.InvoiceID == id && !slices.Contains(gone, summary.InvoiceID) {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    0,
+					"invoice": zoho.Invoice{InvoiceID: summary.InvoiceID, InvoiceNumber: summary.InvoiceNumber, Status: summary.Status},
+				})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	previous := config.Config
+	config.Config = &config.Configuration{Zoho: config.ZOHO{ZohoApiUrl: server.URL + "/invoice/v3/invoices"}}
+	t.Cleanup(func() { config.Config = previous })
+
+	return server
+}
+
+func TestPoll(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) string {
+		return start.Add(time.Duration(minutes) * time.Minute).Format(zoho.TimeLayout)
+	}
+	server := zohoStandIn(t, []zoho.InvoiceSummary{
+		{InvoiceID: "1", InvoiceNumber: "INV-001", Status: "sent", LastModifiedTime: at(1)},
+		{InvoiceID: "2", InvoiceNumber: "INV-002", Status: "draft", LastModifiedTime: at(2)},
+		{InvoiceID: "3", InvoiceNumber: "INV-003", Status: "sent", LastModifiedTime: at(3)},
+		{InvoiceID: "9", InvoiceNumber: "INV-009", Status: "paid", LastModifiedTime: at(4)},
+	}, "9")
+	z := New(zoho.InvoiceAPI)
+	zohoToken := &models.TokenManager{AccessToken: "access-token", APIDomain: server.URL}
+
+	var processed []string
+	page, err := z.poll(zohoToken, "org-1", connector.PollQuery{ModifiedSince: start, Page: 1, PerPage: 200}, func(zohoInvoice zoho.Invoice) (bool, error) {
+		processed = append(processed, zohoInvoice.InvoiceNumber)
+		if zohoInvoice.InvoiceNumber == "INV-003" {
+			return false, errors.New("firs unavailable")
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+
+	if strings.Join(processed, ",") != "INV-001,INV-003" {
+		t.Errorf("processed %v, want the issued invoices that could be read", processed)
+	}
+	if !page.More {
+		t.Errorf("More = false, want the page context passed on")
+	}
+	if len(page.Invoices) != 4 {
+		t.Fatalf("page has %d invoices, want 4", len(page.Invoices))
+	}
+
+	want := []struct {
+		number  string
+		changed bool
+		failed  bool
+	}{
+		{"INV-001", true, false},
+		{"INV-002", false, false},
+		{"INV-003", false, true},
+		{"INV-009", false, true},
+	}
+	for i, w := range want {
+		polled := page.Invoices[i]
+		if polled.Number != w.number || polled.Changed != w.changed || (polled.Err != nil) != w.failed {
+			t.Errorf("invoice %d = %+v, want %s changed %v failed %v", i, polled, w.number, w.changed, w.failed)
+		}
+		if !polled.Modified.Equal(start.Add(time.Duration(i+1) * time.Minute)) {
+			t.Errorf("invoice %d modified %v, want the last modified time of the list", i, polled.Modified)
+		}
+	}
+}
+
+func TestPollRejectedToken(t *testing.T) {
+	server := zohoStandIn(t, nil)
+
+	z := New(zoho.InvoiceAPI)
+	zohoToken := &models.TokenManager{AccessToken: "revoked", APIDomain: server.URL}
+
+	_, err := z.poll(zohoToken, "org-1", connector.PollQuery{Page: 1, PerPage: 200}, func(zoho.Invoice) (bool, error) {
+		t.Fatal("no invoice should be processed")
+		return false, nil
+	})
+	if err == nil {
+		t.Errorf("poll() error = nil, want the list failure")
+	}
+}
@@ -0,0 +1,390 @@
+package converter
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/odoo"
+	"einvoice-access-point/internal/services/business"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var odooTags = regexp.MustCompile(`<[^>]*>`)
+
+// ConvertOdooToFIRS converts an Odoo customer invoice or credit note. Lines are classified through the product catalogue
+// by Odoo product ID, falling back to the HS code and category of the product in Odoo; taxes are resolved through the
+// platform mapping by Odoo tax ID. The line and tax amounts are the ones Odoo computed. Credit notes are sent with the
+// credit note type code and refer to the invoice they reverse.
+func ConvertOdooToFIRS(doc odoo.InvoiceDocument, supplier *models.Business, irn string, catalogue map[string]models.Product, customer *models.Customer, mapping *models.PlatformMapping, references []firs_models.DocumentReference) (firs_models.InvoiceRequest, []string, error) {
+	move := doc.Move
+	if customer == nil || !customer.TINVerified {
+		return firs_models.InvoiceRequest{}, nil, &UnverifiedCustomerError{CustomerID: strconv.Itoa(move.PartnerID.ID)}
+	}
+
+	lines := odooLines(doc.Lines)
+	products := odooProducts(doc.Products, catalogue)
+	taxes := map[int]odoo.Tax{}
+	for _, tax := range doc.Taxes {
+		taxes[tax.ID] = tax
+	}
+
+	// Every line must be classified before the invoice can go to FIRS
+	var unmapped []string
+	for _, line := range lines {
+		if _, ok := products[line.ProductID.ID]; !ok {
+			id := string(line.Name)
+			if line.ProductID.ID != 0 {
+				id = strconv.Itoa(line.ProductID.ID)
+			}
+			unmapped = append(unmapped, id)
+		}
+	}
+	if len(unmapped) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedProductsError{ItemIDs: unmapped}
+	}
+
+	// Every taxed line must resolve to a FIRS tax category
+	var unmappedTaxes []string
+	seenTaxes := map[int]bool{}
+	lineTaxes := make([]models.TaxCategoryMapping, len(lines))
+	for i, line := range lines {
+		tax, ok := odooTaxCategory(line, taxes, products[line.ProductID.ID], mapping)
+		if !ok {
+			for _, id := range line.TaxIDs {
+				if !seenTaxes[id] {
+					seenTaxes[id] = true
+					unmappedTaxes = append(unmappedTaxes, strconv.Itoa(id))
+				}
+			}
+		}
+		lineTaxes[i] = tax
+	}
+	if len(unmappedTaxes) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedTaxesError{TaxIDs: unmappedTaxes}
+	}
+
+	issueDate := string(move.InvoiceDate)
+	if issueDate == "" {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("odoo invoice %s has no invoice date", move.Number())
+	}
+	issueTime := "00:00:00"
+	if created := string(move.CreateDate); len(created) >= 19 {
+		issueTime = created[11:19]
+	}
+	currency := move.CurrencyID.Name
+
+	var warnings []string
+	buyer, partnerWarnings := odooCustomerParty(doc.Partner, customer)
+	warnings = append(warnings, partnerWarnings...)
+
+	// Map invoice lines
+	var invoiceLines []firs_models.InvoiceLine
+	var lineTotal float64
+	for i, line := range lines {
+		invoiceLine, lineWarnings := odooInvoiceLine(currency, line, lineTaxes[i], products[line.ProductID.ID])
+		invoiceLines = append(invoiceLines, invoiceLine)
+		warnings = append(warnings, lineWarnings...)
+		lineTotal += invoiceLine.LineExtensionAmount
+	}
+
+	taxTotal, subtotals := odooTaxSubtotals(lines, lineTaxes)
+	if move.AmountTax > 0 {
+		taxTotal = move.AmountTax
+	}
+
+	taxInclusive := lineTotal + taxTotal
+	if !amountsEqual(taxInclusive, move.AmountTotal) {
+		warnings = append(warnings, fmt.Sprintf("computed payable amount %.2f differs from the Odoo total %.2f, the Odoo total was used", taxInclusive, move.AmountTotal))
+	}
+
+	firsInvoice := firs_models.InvoiceRequest{
+		BusinessID:              supplier.BusinessID,
+		IRN:                     &irn,
+		IssueDate:               issueDate,
+		IssueTime:               &issueTime,
+		InvoiceTypeCode:         mapping.DocumentTypeCode(),
+		TaxPointDate:            &issueDate,
+		DocumentCurrencyCode:    currency,
+		TaxCurrencyCode:         &currency,
+		AccountingCustomerParty: buyer,
+		TaxTotal: []firs_models.TaxTotal{
+			{
+				TaxAmount:   roundAmount(taxTotal),
+				TaxSubtotal: subtotals,
+			},
+		},
+		LegalMonetaryTotal: firs_models.LegalMonetaryTotal{
+			LineExtensionAmount: roundAmount(lineTotal),
+			TaxExclusiveAmount:  roundAmount(lineTotal),
+			TaxInclusiveAmount:  roundAmount(taxInclusive),
+			PayableAmount:       move.AmountTotal,
+		},
+		InvoiceLine: invoiceLines,
+	}
+	if note := odooPlainText(string(move.Narration)); note != "" {
+		firsInvoice.Note = &note
+	}
+
+	if move.IsRefund() {
+		firsInvoice.InvoiceTypeCode = models.CreditNoteTypeCode
+		firsInvoice.BillingReference = references
+		if len(references) == 0 {
+			warnings = append(warnings, "credit note does not reverse an invoice FIRS signed, it was sent without a billing reference")
+		}
+	} else {
+		// payments are not kept in the stored move, they are sent to FIRS as Odoo records them
+		paymentStatus := "PENDING"
+		firsInvoice.PaymentStatus = &paymentStatus
+
+		dueDate := string(move.InvoiceDateDue)
+		if dueDate != "" {
+			firsInvoice.DueDate = &dueDate
+		}
+		if terms := move.PaymentTermID.Name; terms != "" {
+			firsInvoice.PaymentTermsNote = &terms
+			code, ok := mapping.PaymentMeansCode(strconv.Itoa(move.PaymentTermID.ID))
+			if !ok {
+				code, ok = mapping.PaymentMeansCode(terms)
+			}
+			if ok {
+				firsInvoice.PaymentMeans = []firs_models.PaymentMeans{{PaymentMeansCode: code, PaymentDueDate: dueDate}}
+			}
+		}
+	}
+
+	if err := business.ApplySupplierProfile(&firsInvoice, supplier); err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	return firsInvoice, warnings, nil
+}
+
+// odooPlainText turns an html field such as the terms of an invoice into plain text
+func odooPlainText(value string) string {
+	text := odooTags.ReplaceAllString(strings.ReplaceAll(value, "</p>", "</p>\n"), "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// odooLines keeps the product lines, dropping sections and notes
+func odooLines(lines []odoo.MoveLine) []odoo.MoveLine {
+	var productLines []odoo.MoveLine
+	for _, line := range lines {
+		if line.DisplayType != "" && line.DisplayType != odoo.DisplayTypeProduct {
+			continue
+		}
+		productLines = append(productLines, line)
+	}
+	return productLines
+}
+
+// odooProducts resolves the FIRS classification of the products on the invoice by Odoo product ID. The product
+// catalogue wins; products missing from it are classified by the HS code and category they have in Odoo.
+func odooProducts(items []odoo.Product, catalogue map[string]models.Product) map[int]models.Product {
+	products := map[int]models.Product{}
+	for _, p := range items {
+		if product, ok := catalogue[strconv.Itoa(p.ID)]; ok {
+			products[p.ID] = product
+			continue
+		}
+		if p.HSCode == "" {
+			continue
+		}
+		category := p.CategID.Name
+		if i := strings.LastIndex(category, " / "); i >= 0 {
+			category = category[i+3:]
+		}
+		products[p.ID] = models.Product{
+			PlatformItemID:  strconv.Itoa(p.ID),
+			Name:            string(p.Name),
+			HSCode:          string(p.HSCode),
+			ProductCategory: category,
+		}
+	}
+	return products
+}
+
+// odooTaxRate is the rate Odoo applied to the line, worked out from the tax it charged on the net amount
+func odooTaxRate(line odoo.MoveLine) float64 {
+	tax := line.PriceTotal - line.PriceSubtotal
+	if line.PriceSubtotal == 0 || tax == 0 {
+		return 0
+	}
+	return roundAmount(tax / line.PriceSubtotal * 100)
+}
+
+// odooTaxCategory resolves the FIRS tax category of a line. The first tax of the line found in the mapping is used,
+// keeping the rate Odoo applied unless the mapping overrides it; otherwise the product's default tax category is used.
+// It reports false for a taxed line that neither the mapping nor the product can categorise.
+func odooTaxCategory(line odoo.MoveLine, taxes map[int]odoo.Tax, product models.Product, mapping *models.PlatformMapping) (models.TaxCategoryMapping, bool) {
+	rate := odooTaxRate(line)
+	for _, id := range line.TaxIDs {
+		tax, ok := mapping.TaxCategory(strconv.Itoa(id))
+		if !ok {
+			continue
+		}
+		if tax.Percent == nil {
+			if odooTax, ok := taxes[id]; ok && odooTax.AmountType == "percent" && len(line.TaxIDs) == 1 {
+				rate = odooTax.Amount
+			}
+			tax.Percent = &rate
+		}
+		return tax, true
+	}
+
+	if product.TaxCategory != "" {
+		return models.TaxCategoryMapping{Category: product.TaxCategory, Percent: &rate}, true
+	}
+	return models.TaxCategoryMapping{}, len(line.TaxIDs) == 0 || rate == 0
+}
+
+// odooInvoiceLine maps an Odoo product line at its net amount. The price is quoted per single unit before the discount
+// and without tax, worked back from the subtotal Odoo computed so tax-inclusive prices come out net.
+func odooInvoiceLine(currency string, line odoo.MoveLine, tax models.TaxCategoryMapping, product models.Product) (firs_models.InvoiceLine, []string) {
+	var warnings []string
+
+	qty := line.Quantity
+	if qty == 0 {
+		qty = 1
+	}
+	quantity := int(math.Round(qty))
+	if quantity < 1 {
+		quantity = 1
+	}
+	if float64(quantity) != qty {
+		warnings = append(warnings, fmt.Sprintf("line %s quantity %g was rounded to %d", odooLineName(line), qty, quantity))
+	}
+
+	gross := line.PriceSubtotal
+	if line.Discount > 0 && line.Discount < 100 {
+		gross = line.PriceSubtotal / (1 - line.Discount/100)
+	} else if line.Discount >= 100 {
+		gross = line.PriceUnit * qty
+	}
+	discountAmount := gross - line.PriceSubtotal
+
+	priceUnit := currency + " per 1"
+	if product.UnitOfMeasure != "" {
+		priceUnit = currency + " per " + product.UnitOfMeasure
+	} else if line.ProductUomID.Name != "" {
+		priceUnit = currency + " per " + line.ProductUomID.Name
+	}
+
+	return firs_models.InvoiceLine{
+		HSNCode:             product.HSCode,
+		ProductCategory:     product.ProductCategory,
+		DiscountAmount:      roundAmount(discountAmount),
+		DiscountRate:        roundAmount(line.Discount),
+		InvoicedQuantity:    quantity,
+		LineExtensionAmount: roundAmount(line.PriceSubtotal),
+		Item: firs_models.Item{
+			Name:        odooLineName(line),
+			Description: string(line.Name),
+		},
+		Price: firs_models.Price{
+			PriceAmount:  roundAmount(gross / qty),
+			BaseQuantity: 1,
+			PriceUnit:    priceUnit,
+		},
+	}, warnings
+}
+
+// odooLineName is the product of the line, or its label for lines without a product
+func odooLineName(line odoo.MoveLine) string {
+	if line.ProductID.Name != "" {
+		return line.ProductID.Name
+	}
+	return string(line.Name)
+}
+
+// odooTaxSubtotals groups the taxed lines by tax category and rate, with the tax amounts Odoo computed
+func odooTaxSubtotals(lines []odoo.MoveLine, taxes []models.TaxCategoryMapping) (float64, []firs_models.TaxSubtotal) {
+	var total float64
+	var subtotals []firs_models.TaxSubtotal
+	index := map[string]int{}
+
+	for i, line := range lines {
+		tax := taxes[i]
+		if tax.Percent == nil || *tax.Percent <= 0 {
+			continue
+		}
+		taxable := line.PriceSubtotal
+		amount := line.PriceTotal - line.PriceSubtotal
+		total += amount
+
+		key := fmt.Sprintf("%s/%g", tax.Category, *tax.Percent)
+		if j, ok := index[key]; ok {
+			subtotals[j].TaxableAmount = roundAmount(subtotals[j].TaxableAmount + taxable)
+			subtotals[j].TaxAmount = roundAmount(subtotals[j].TaxAmount + amount)
+			continue
+		}
+		index[key] = len(subtotals)
+		subtotals = append(subtotals, firs_models.TaxSubtotal{
+			TaxableAmount: roundAmount(taxable),
+			TaxAmount:     roundAmount(amount),
+			TaxCategory: firs_models.TaxCategory{
+				ID:      tax.Category,
+				Percent: *tax.Percent,
+			},
+		})
+	}
+	return total, subtotals
+}
+
+// odooCustomerParty builds the buyer from the directory entry, falling back to the Odoo partner for missing contact details
+func odooCustomerParty(partner *odoo.Partner, customer *models.Customer) (firs_models.Party, []string) {
+	var warnings []string
+
+	name := customer.Name
+	email := customer.Email
+	if email == "" && partner != nil {
+		email = string(partner.Email)
+	}
+	if email == "" {
+		warnings = append(warnings, "buyer has no email address in the customer directory or on the Odoo partner")
+	}
+
+	telephone := customer.Telephone
+	if telephone == "" && partner != nil {
+		phone := partner.Phone
+		if phone == "" {
+			phone = partner.Mobile
+		}
+		if phone != "" {
+			telephone = utility.FormatPhone(string(phone))
+		}
+	}
+
+	address := &firs_models.PostalAddress{
+		StreetName: customer.StreetName,
+		CityName:   customer.CityName,
+		PostalZone: customer.PostalZone,
+		Country:    customer.Country,
+	}
+	if address.StreetName == "" && partner != nil {
+		street := strings.TrimSpace(string(partner.Street) + " " + string(partner.Street2))
+		if street != "" || partner.City != "" || partner.Zip != "" || partner.CountryCode != "" {
+			address = &firs_models.PostalAddress{
+				StreetName: street,
+				CityName:   string(partner.City),
+				PostalZone: string(partner.Zip),
+				Country:    string(partner.CountryCode),
+			}
+		}
+	}
+
+	party := firs_models.Party{
+		PartyName:     &name,
+		TIN:           customer.TIN,
+		Email:         email,
+		PostalAddress: address,
+	}
+	if telephone != "" {
+		party.Telephone = &telephone
+	}
+	return party, warnings
+}
@@ -0,0 +1,339 @@
+package converter
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PEPPOL BIS Billing 3.0 document identifiers
+const (
+	PeppolCustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	PeppolProfileID       = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+)
+
+// defaultPeppolUnitCode is UN/ECE Rec 20 "one", used when the line price unit is not a Rec 20 code
+const defaultPeppolUnitCode = "C62"
+
+// UNCL1001 document type codes allowed by PEPPOL BIS 3.0
+var (
+	peppolInvoiceTypeCodes    = codeList("71 80 82 84 102 218 219 331 380 382 383 386 388 393 395 553 575 623 780 817 870 875 876 877")
+	peppolCreditNoteTypeCodes = codeList("81 83 261 262 296 308 381 396 420 458 532")
+)
+
+// UNCL5305 VAT category codes and the FIRS tax categories they are derived from
+var (
+	peppolTaxCategoryCodes = codeList("S Z E AE K G O L M B")
+	firsTaxCategoryCodes   = map[string]string{
+		"STANDARD_VAT":    "S",
+		"REDUCED_VAT":     "S",
+		"ZERO_VAT":        "Z",
+		"EXEMPTED":        "E",
+		"EXEMPT_VAT":      "E",
+		"OUT_OF_SCOPE":    "O",
+		"REVERSE_CHARGE":  "AE",
+		"INTRA_COMMUNITY": "K",
+		"EXPORT":          "G",
+	}
+)
+
+// UNCL4461 payment means codes
+var peppolPaymentMeansCodes = codeList("1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24 25 26 27 28 29 30 31 32 33 34 35 " +
+	"36 37 38 39 40 41 42 43 44 45 46 47 48 49 50 51 52 53 54 55 56 57 58 59 60 61 62 63 64 65 66 67 68 70 74 75 76 77 78 " +
+	"91 92 93 94 95 96 97 ZZZ")
+
+// ISO 4217 currency codes
+var peppolCurrencyCodes = codeList("AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV BRL BSD BTN BWP BYN BZD " +
+	"CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF " +
+	"GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD " +
+	"MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD " +
+	"RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN " +
+	"UYI UYU UYW UZS VED VES VND VUV WST XAF XAG XAU XBA XBB XBC XBD XCD XDR XOF XPD XPF XPT XSU XUA YER ZAR ZMW ZWL")
+
+// ISO 3166-1 alpha-2 country codes
+var peppolCountryCodes = codeList("AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY " +
+	"BZ CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR GA GB GD GE " +
+	"GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN KP KR " +
+	"KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI " +
+	"NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS " +
+	"ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS XI YE YT ZA ZM ZW")
+
+// peppolEndpointPattern matches a participant identifier written as "scheme:id", e.g. "0088:7300010000001"
+var peppolEndpointPattern = regexp.MustCompile(`^([0-9]{4}):(\S+)$`)
+
+var peppolUnitCodePattern = regexp.MustCompile(`^[A-Z0-9]{2,3}$`)
+
+// PeppolRuleViolation is a failed BIS 3.0 business rule
+type PeppolRuleViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PeppolValidationError lists every business rule the document violates
+type PeppolValidationError struct {
+	Violations []PeppolRuleViolation
+}
+
+func (e *PeppolValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("[%s] %s", v.Rule, v.Message))
+	}
+	return "peppol validation failed: " + strings.Join(parts, "; ")
+}
+
+type peppolValidator struct {
+	violations []PeppolRuleViolation
+}
+
+func (v *peppolValidator) check(ok bool, rule, format string, args ...interface{}) {
+	if !ok {
+		v.violations = append(v.violations, PeppolRuleViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// ConvertFIRSToPeppol renders a FIRS invoice request as a PEPPOL BIS Billing 3.0 document.
+// Seller and buyer endpoints are PEPPOL participant identifiers written as "scheme:id".
+// The document is checked against the BIS business rules before it is returned.
+func ConvertFIRSToPeppol(req firs_models.InvoiceRequest, sellerEndpoint, buyerEndpoint string) ([]byte, error) {
+	if err := ValidatePeppol(req, sellerEndpoint, buyerEndpoint); err != nil {
+		return nil, err
+	}
+
+	doc := buildUBLDocument(req)
+	doc.CustomizationID = PeppolCustomizationID
+	doc.ProfileID = PeppolProfileID
+	doc.UBLVersionID = ""
+
+	doc.AccountingSupplierParty.Party = peppolParty(doc.AccountingSupplierParty.Party, req.AccountingSupplierParty, sellerEndpoint)
+	doc.AccountingCustomerParty.Party = peppolParty(doc.AccountingCustomerParty.Party, req.AccountingCustomerParty, buyerEndpoint)
+
+	for i := range doc.TaxTotal {
+		for j := range doc.TaxTotal[i].TaxSubtotal {
+			category := &doc.TaxTotal[i].TaxSubtotal[j].TaxCategory
+			category.ID = peppolTaxCategory(category.ID)
+			if category.Percent == "" {
+				category.Percent = "0"
+			}
+		}
+	}
+
+	lines := doc.InvoiceLine
+	if doc.XMLName.Local == "CreditNote" {
+		lines = doc.CreditNoteLine
+	}
+	for i := range lines {
+		unitCode := peppolUnitCode(req.InvoiceLine[i].Price.PriceUnit)
+		if lines[i].InvoicedQuantity != nil {
+			lines[i].InvoicedQuantity.UnitCode = unitCode
+		}
+		if lines[i].CreditedQuantity != nil {
+			lines[i].CreditedQuantity.UnitCode = unitCode
+		}
+		lines[i].Price.BaseQuantity.UnitCode = unitCode
+		// Only the HS code uses a UNTDID 7143 list; the FIRS product category has no PEPPOL equivalent
+		lines[i].Item.CommodityClassification = lines[i].Item.CommodityClassification[:1]
+	}
+
+	return encodeUBL(doc)
+}
+
+// ValidatePeppol checks a FIRS invoice request against the PEPPOL BIS 3.0 and EN 16931 business rules
+// that can be evaluated locally
+func ValidatePeppol(req firs_models.InvoiceRequest, sellerEndpoint, buyerEndpoint string) error {
+	v := &peppolValidator{}
+
+	isCreditNote := IsCreditNoteTypeCode(req.InvoiceTypeCode)
+
+	v.check(req.InvoiceNumber != "", "BR-02", "An Invoice shall have an Invoice number")
+	v.check(req.IssueDate != "", "BR-03", "An Invoice shall have an Invoice issue date")
+	if req.IssueDate != "" {
+		_, err := time.Parse("2006-01-02", req.IssueDate)
+		v.check(err == nil, "PEPPOL-EN16931-F001", "Issue date %q must be formatted YYYY-MM-DD", req.IssueDate)
+	}
+	v.check(req.InvoiceTypeCode != "", "BR-04", "An Invoice shall have an Invoice type code")
+	if isCreditNote {
+		v.check(peppolCreditNoteTypeCodes[req.InvoiceTypeCode], "BR-CL-01", "Credit note type code %q is not allowed", req.InvoiceTypeCode)
+	} else if req.InvoiceTypeCode != "" {
+		v.check(peppolInvoiceTypeCodes[req.InvoiceTypeCode], "BR-CL-01", "Invoice type code %q is not allowed", req.InvoiceTypeCode)
+	}
+	v.check(req.DocumentCurrencyCode != "", "BR-05", "An Invoice shall have an Invoice currency code")
+	if req.DocumentCurrencyCode != "" {
+		v.check(peppolCurrencyCodes[req.DocumentCurrencyCode], "BR-CL-04", "Invoice currency code %q is not an ISO 4217 code", req.DocumentCurrencyCode)
+	}
+	if req.TaxCurrencyCode != nil && *req.TaxCurrencyCode != "" {
+		v.check(peppolCurrencyCodes[*req.TaxCurrencyCode], "BR-CL-05", "Tax currency code %q is not an ISO 4217 code", *req.TaxCurrencyCode)
+	}
+
+	supplier, customer := req.AccountingSupplierParty, req.AccountingCustomerParty
+	v.check(deref(supplier.PartyName) != "", "BR-06", "An Invoice shall contain the Seller name")
+	v.check(deref(customer.PartyName) != "", "BR-07", "An Invoice shall contain the Buyer name")
+	v.check(supplier.PostalAddress != nil, "BR-08", "An Invoice shall contain the Seller postal address")
+	if supplier.PostalAddress != nil {
+		v.check(supplier.PostalAddress.Country != "", "BR-09", "The Seller postal address shall contain a Seller country code")
+		if supplier.PostalAddress.Country != "" {
+			v.check(peppolCountryCodes[supplier.PostalAddress.Country], "BR-CL-14", "Seller country code %q is not an ISO 3166-1 code", supplier.PostalAddress.Country)
+		}
+	}
+	v.check(customer.PostalAddress != nil, "BR-10", "An Invoice shall contain the Buyer postal address")
+	if customer.PostalAddress != nil {
+		v.check(customer.PostalAddress.Country != "", "BR-11", "The Buyer postal address shall contain a Buyer country code")
+		if customer.PostalAddress.Country != "" {
+			v.check(peppolCountryCodes[customer.PostalAddress.Country], "BR-CL-14", "Buyer country code %q is not an ISO 3166-1 code", customer.PostalAddress.Country)
+		}
+	}
+	v.check(peppolEndpointPattern.MatchString(sellerEndpoint), "PEPPOL-EN16931-R020", "Seller electronic address MUST be provided as scheme:id")
+	v.check(peppolEndpointPattern.MatchString(buyerEndpoint), "PEPPOL-EN16931-R010", "Buyer electronic address MUST be provided as scheme:id")
+
+	for i, pm := range req.PaymentMeans {
+		v.check(peppolPaymentMeansCodes[pm.PaymentMeansCode], "BR-CL-16", "Payment means %d code %q is not a UNCL4461 code", i+1, pm.PaymentMeansCode)
+	}
+
+	v.check(len(req.InvoiceLine) > 0, "BR-16", "An Invoice shall have at least one Invoice line")
+	var lineTotal float64
+	for i, line := range req.InvoiceLine {
+		n := i + 1
+		lineTotal += line.LineExtensionAmount
+		v.check(line.InvoicedQuantity != 0, "BR-22", "Invoice line %d shall have an Invoiced quantity", n)
+		v.check(line.Item.Name != "", "BR-25", "Invoice line %d shall contain the Item name", n)
+		v.check(line.Price.PriceAmount >= 0, "BR-27", "Invoice line %d Item net price shall not be negative", n)
+	}
+
+	total := req.LegalMonetaryTotal
+	var allowances, charges float64
+	for _, ac := range req.AllowanceCharge {
+		if ac.ChargeIndicator {
+			charges += ac.Amount
+		} else {
+			allowances += ac.Amount
+		}
+	}
+	var taxAmount float64
+	for _, tt := range req.TaxTotal {
+		taxAmount += tt.TaxAmount
+	}
+
+	v.check(amountsEqual(total.LineExtensionAmount, lineTotal), "BR-CO-10",
+		"Sum of Invoice line net amount %.2f must equal the sum of line net amounts %.2f", total.LineExtensionAmount, lineTotal)
+	v.check(amountsEqual(total.TaxExclusiveAmount, total.LineExtensionAmount-allowances+charges), "BR-CO-13",
+		"Invoice total amount without VAT %.2f must equal line total minus allowances plus charges", total.TaxExclusiveAmount)
+	v.check(amountsEqual(total.TaxInclusiveAmount, total.TaxExclusiveAmount+taxAmount), "BR-CO-15",
+		"Invoice total amount with VAT %.2f must equal the amount without VAT plus the VAT total", total.TaxInclusiveAmount)
+	v.check(total.PayableAmount >= 0 || isCreditNote, "BR-CO-25", "Amount due for payment must not be negative")
+
+	for i, tt := range req.TaxTotal {
+		var subtotal float64
+		for j, st := range tt.TaxSubtotal {
+			subtotal += st.TaxAmount
+			code := peppolTaxCategory(st.TaxCategory.ID)
+			v.check(peppolTaxCategoryCodes[code], "BR-CL-18", "Tax total %d subtotal %d category %q has no UNCL5305 code", i+1, j+1, st.TaxCategory.ID)
+			switch code {
+			case "S":
+				v.check(st.TaxCategory.Percent > 0, "BR-S-05", "Standard rated VAT subtotal %d must have a rate greater than zero", j+1)
+			case "Z":
+				v.check(st.TaxCategory.Percent == 0, "BR-Z-05", "Zero rated VAT subtotal %d must have a rate of 0", j+1)
+			case "E":
+				v.check(st.TaxCategory.Percent == 0, "BR-E-05", "Exempt VAT subtotal %d must have a rate of 0", j+1)
+			}
+			if code == "S" || code == "Z" {
+				expected := st.TaxableAmount * st.TaxCategory.Percent / 100
+				v.check(amountsEqual(st.TaxAmount, expected), "BR-CO-17",
+					"VAT subtotal %d amount %.2f must equal taxable amount times rate (%.2f)", j+1, st.TaxAmount, expected)
+			}
+		}
+		if len(tt.TaxSubtotal) > 0 {
+			v.check(amountsEqual(tt.TaxAmount, subtotal), "BR-CO-14",
+				"Invoice total VAT amount %.2f must equal the sum of VAT subtotals %.2f", tt.TaxAmount, subtotal)
+		}
+	}
+
+	if len(v.violations) > 0 {
+		return &PeppolValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+func peppolParty(party ublParty, source firs_models.Party, endpoint string) ublParty {
+	if match := peppolEndpointPattern.FindStringSubmatch(endpoint); match != nil {
+		party.EndpointID = &ublIdentifier{SchemeID: match[1], Value: match[2]}
+	}
+	if party.PartyLegalEntity == nil {
+		party.PartyLegalEntity = &ublPartyLegalEntity{}
+	}
+	party.PartyLegalEntity.RegistrationName = deref(source.PartyName)
+	return party
+}
+
+// peppolTaxCategory maps a FIRS tax category onto its UNCL5305 code, passing through values that already are one
+func peppolTaxCategory(id string) string {
+	if peppolTaxCategoryCodes[id] {
+		return id
+	}
+	if code, ok := firsTaxCategoryCodes[strings.ToUpper(id)]; ok {
+		return code
+	}
+	return id
+}
+
+func peppolUnitCode(priceUnit string) string {
+	if peppolUnitCodePattern.MatchString(priceUnit) {
+		return priceUnit
+	}
+	return defaultPeppolUnitCode
+}
+
+func amountsEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+// DocumentTypeCodes lists the UNCL1001 invoice and credit note type codes accepted as invoice_type_code
+func DocumentTypeCodes() []string {
+	return sortedCodes(peppolInvoiceTypeCodes, peppolCreditNoteTypeCodes)
+}
+
+// IsCreditNoteTypeCode reports whether the UNCL1001 type code is one of a credit note, which is exported as a UBL CreditNote
+func IsCreditNoteTypeCode(code string) bool {
+	return peppolCreditNoteTypeCodes[code]
+}
+
+// InvoiceTypeCodes lists the UNCL1001 invoice type codes, without the credit note codes
+func InvoiceTypeCodes() []string {
+	return sortedCodes(peppolInvoiceTypeCodes)
+}
+
+// TaxCategoryCodes lists the FIRS tax categories
+func TaxCategoryCodes() []string {
+	codes := make(map[string]bool, len(firsTaxCategoryCodes))
+	for code := range firsTaxCategoryCodes {
+		codes[code] = true
+	}
+	return sortedCodes(codes)
+}
+
+// PaymentMeansCodes lists the UNCL4461 payment means codes
+func PaymentMeansCodes() []string {
+	return sortedCodes(peppolPaymentMeansCodes)
+}
+
+func sortedCodes(lists ...map[string]bool) []string {
+	var codes []string
+	for _, list := range lists {
+		for code := range list {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func codeList(codes string) map[string]bool {
+	list := make(map[string]bool)
+	for _, code := range strings.Fields(codes) {
+		list[code] = true
+	}
+	return list
+}
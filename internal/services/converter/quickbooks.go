@@ -0,0 +1,419 @@
+package converter
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/quickbooks"
+	"einvoice-access-point/internal/services/business"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ConvertQuickBooksToFIRS converts a QuickBooks Online invoice. Lines of bundles are flattened, the shipping line
+// becomes a charge and the discount line an allowance. Tax codes resolve to FIRS tax categories through the platform
+// mapping, and the tax total is the one QuickBooks computed. The customer read with the invoice fills in contact
+// details missing from the customer directory.
+func ConvertQuickBooksToFIRS(doc quickbooks.InvoiceDocument, supplier *models.Business, irn string, catalogue map[string]models.Product, customer *models.Customer, mapping *models.PlatformMapping) (firs_models.InvoiceRequest, []string, error) {
+	qb := doc.Invoice
+	if customer == nil || !customer.TINVerified {
+		return firs_models.InvoiceRequest{}, nil, &UnverifiedCustomerError{CustomerID: qb.CustomerRef.Value}
+	}
+
+	items, shipping, discount := quickBooksLines(qb.Line)
+
+	// Every line must be classified before the invoice can go to FIRS
+	var unmapped []string
+	for _, item := range items {
+		if _, ok := catalogue[item.SalesItemLineDetail.ItemRef.Value]; !ok {
+			unmapped = append(unmapped, item.SalesItemLineDetail.ItemRef.Value)
+		}
+	}
+	if len(unmapped) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedProductsError{ItemIDs: unmapped}
+	}
+
+	// Every taxed line must resolve to a FIRS tax category
+	var unmappedTaxes []string
+	seenTaxes := map[string]bool{}
+	invoiceRate := quickBooksTaxRate(qb.TxnTaxDetail)
+	taxes := make([]models.TaxCategoryMapping, len(items))
+	for i, item := range items {
+		tax, ok := quickBooksTaxCategory(item, catalogue[item.SalesItemLineDetail.ItemRef.Value], mapping, invoiceRate)
+		if code := quickBooksTaxCode(item); !ok && !seenTaxes[code] {
+			seenTaxes[code] = true
+			unmappedTaxes = append(unmappedTaxes, code)
+		}
+		taxes[i] = tax
+	}
+	if len(unmappedTaxes) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedTaxesError{TaxIDs: unmappedTaxes}
+	}
+
+	createdTime, err := time.Parse(time.RFC3339, qb.MetaData.CreateTime)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("failed to parse CreateTime: %v", err)
+	}
+	issueTime := createdTime.Format("15:04:05")
+	paymentStatus := "PENDING"
+	if qb.Balance <= 0 && qb.TotalAmt > 0 {
+		paymentStatus = "PAID"
+	}
+
+	var warnings []string
+	buyer, contactWarnings := quickBooksCustomerParty(qb, doc.Customer, customer)
+	warnings = append(warnings, contactWarnings...)
+
+	// Map line items
+	var lines []firs_models.InvoiceLine
+	var lineTotal float64
+	for _, item := range items {
+		line, lineWarnings := quickBooksInvoiceLine(qb.CurrencyRef.Value, item, catalogue[item.SalesItemLineDetail.ItemRef.Value])
+		lines = append(lines, line)
+		warnings = append(warnings, lineWarnings...)
+		lineTotal += item.Amount
+	}
+
+	var allowanceCharges []firs_models.AllowanceCharge
+	if discount > 0 {
+		allowanceCharges = append(allowanceCharges, firs_models.AllowanceCharge{ChargeIndicator: false, Amount: roundAmount(discount)})
+	}
+	if shipping > 0 {
+		allowanceCharges = append(allowanceCharges, firs_models.AllowanceCharge{ChargeIndicator: true, Amount: roundAmount(shipping)})
+	}
+
+	// A discount taken before tax shrinks the taxable amount of every line in proportion
+	taxableShare := 1.0
+	if discount > 0 && qb.ApplyTaxAfterDiscount && lineTotal > 0 {
+		taxableShare = (lineTotal - discount) / lineTotal
+	}
+	taxTotal, subtotals := quickBooksTaxSubtotals(items, taxes, taxableShare)
+	if qb.TxnTaxDetail.TotalTax > 0 {
+		taxTotal = qb.TxnTaxDetail.TotalTax
+	}
+
+	taxExclusive := lineTotal + shipping
+	if qb.ApplyTaxAfterDiscount {
+		taxExclusive -= discount
+	}
+	taxInclusive := taxExclusive + taxTotal
+	payable := taxInclusive
+	if !qb.ApplyTaxAfterDiscount {
+		payable -= discount
+	}
+	if !amountsEqual(payable, qb.TotalAmt) {
+		warnings = append(warnings, fmt.Sprintf("computed payable amount %.2f differs from the QuickBooks total %.2f, the QuickBooks total was used", payable, qb.TotalAmt))
+	}
+
+	firsInvoice := firs_models.InvoiceRequest{
+		BusinessID:              supplier.BusinessID,
+		IRN:                     &irn,
+		IssueDate:               qb.TxnDate,
+		IssueTime:               &issueTime,
+		InvoiceTypeCode:         mapping.DocumentTypeCode(),
+		PaymentStatus:           &paymentStatus,
+		TaxPointDate:            &qb.TxnDate,
+		DocumentCurrencyCode:    qb.CurrencyRef.Value,
+		TaxCurrencyCode:         &qb.CurrencyRef.Value,
+		AccountingCustomerParty: buyer,
+		DeliveryAddress:         quickBooksAddress(qb.ShipAddr),
+		AllowanceCharge:         allowanceCharges,
+		TaxTotal: []firs_models.TaxTotal{
+			{
+				TaxAmount:   roundAmount(taxTotal),
+				TaxSubtotal: subtotals,
+			},
+		},
+		LegalMonetaryTotal: firs_models.LegalMonetaryTotal{
+			LineExtensionAmount: roundAmount(lineTotal),
+			TaxExclusiveAmount:  roundAmount(taxExclusive),
+			TaxInclusiveAmount:  roundAmount(taxInclusive),
+			PayableAmount:       qb.TotalAmt,
+		},
+		InvoiceLine: lines,
+	}
+	if qb.DueDate != "" {
+		firsInvoice.DueDate = &qb.DueDate
+	}
+	if qb.CustomerMemo != nil && qb.CustomerMemo.Value != "" {
+		firsInvoice.Note = &qb.CustomerMemo.Value
+	}
+
+	if qb.SalesTermRef != nil {
+		terms := qb.SalesTermRef.Name
+		if terms == "" {
+			terms = qb.SalesTermRef.Value
+		}
+		firsInvoice.PaymentTermsNote = &terms
+		code, ok := mapping.PaymentMeansCode(qb.SalesTermRef.Value)
+		if !ok {
+			code, ok = mapping.PaymentMeansCode(qb.SalesTermRef.Name)
+		}
+		if ok {
+			firsInvoice.PaymentMeans = []firs_models.PaymentMeans{{PaymentMeansCode: code, PaymentDueDate: qb.DueDate}}
+		}
+	}
+
+	if err := business.ApplySupplierProfile(&firsInvoice, supplier); err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	return firsInvoice, warnings, nil
+}
+
+// quickBooksLines flattens the item lines of the invoice, taking the lines of bundles in their place, and
+// returns them with the shipping charge and the invoice discount. Subtotal and description lines carry no amounts.
+func quickBooksLines(lines []quickbooks.Line) (items []quickbooks.Line, shipping, discount float64) {
+	for _, line := range lines {
+		switch line.DetailType {
+		case quickbooks.SalesItemLine:
+			if line.SalesItemLineDetail == nil {
+				continue
+			}
+			if line.SalesItemLineDetail.ItemRef.Value == quickbooks.ShippingItemID {
+				shipping += line.Amount
+				continue
+			}
+			items = append(items, line)
+		case quickbooks.GroupLine:
+			if line.GroupLineDetail == nil {
+				continue
+			}
+			groupItems, groupShipping, groupDiscount := quickBooksLines(line.GroupLineDetail.Line)
+			items = append(items, groupItems...)
+			shipping += groupShipping
+			discount += groupDiscount
+		case quickbooks.DiscountLine:
+			discount += line.Amount
+		}
+	}
+	return items, shipping, discount
+}
+
+// quickBooksInvoiceLine maps a QuickBooks item line. The price is quoted per single unit.
+func quickBooksInvoiceLine(currency string, item quickbooks.Line, product models.Product) (firs_models.InvoiceLine, []string) {
+	var warnings []string
+	detail := item.SalesItemLineDetail
+
+	qty := detail.Qty
+	if qty == 0 {
+		qty = 1
+	}
+	quantity := int(math.Round(qty))
+	if quantity < 1 {
+		quantity = 1
+	}
+	if float64(quantity) != qty {
+		warnings = append(warnings, fmt.Sprintf("line %s quantity %g was rounded to %d", quickBooksLineName(item), qty, quantity))
+	}
+
+	unitPrice := detail.UnitPrice
+	if unitPrice == 0 && qty > 0 {
+		unitPrice = item.Amount / qty
+	}
+
+	gross := unitPrice * qty
+	discountAmount := detail.DiscountAmt
+	if discountAmount == 0 && detail.DiscountRate > 0 {
+		discountAmount = gross * detail.DiscountRate / 100
+	}
+	if discountAmount == 0 && gross-item.Amount > 0.005 {
+		discountAmount = gross - item.Amount
+	}
+	discountRate := detail.DiscountRate
+	if discountRate == 0 && gross > 0 {
+		discountRate = discountAmount / gross * 100
+	}
+
+	priceUnit := currency + " per 1"
+	if product.UnitOfMeasure != "" {
+		priceUnit = currency + " per " + product.UnitOfMeasure
+	}
+
+	return firs_models.InvoiceLine{
+		HSNCode:             product.HSCode,
+		ProductCategory:     product.ProductCategory,
+		DiscountAmount:      roundAmount(discountAmount),
+		DiscountRate:        roundAmount(discountRate),
+		InvoicedQuantity:    quantity,
+		LineExtensionAmount: item.Amount,
+		Item: firs_models.Item{
+			Name:        quickBooksLineName(item),
+			Description: item.Description,
+		},
+		Price: firs_models.Price{
+			PriceAmount:  unitPrice,
+			BaseQuantity: 1,
+			PriceUnit:    priceUnit,
+		},
+	}, warnings
+}
+
+// quickBooksLineName is the name of the item on the line, or its description when the item reference carries no name
+func quickBooksLineName(item quickbooks.Line) string {
+	if item.SalesItemLineDetail != nil && item.SalesItemLineDetail.ItemRef.Name != "" {
+		return item.SalesItemLineDetail.ItemRef.Name
+	}
+	return item.Description
+}
+
+// quickBooksTaxCode is the tax code applied to the line, blank when the line is not taxed
+func quickBooksTaxCode(item quickbooks.Line) string {
+	ref := item.SalesItemLineDetail.TaxCodeRef
+	if ref == nil || ref.Value == quickbooks.NonTaxableCode {
+		return ""
+	}
+	return ref.Value
+}
+
+// quickBooksTaxRate is the rate of the invoice when QuickBooks applied a single percentage rate to it, and 0 otherwise
+func quickBooksTaxRate(detail quickbooks.TxnTaxDetail) float64 {
+	var rate float64
+	for _, line := range detail.TaxLine {
+		if !line.TaxLineDetail.PercentBased {
+			continue
+		}
+		if rate != 0 && rate != line.TaxLineDetail.TaxPercent {
+			return 0
+		}
+		rate = line.TaxLineDetail.TaxPercent
+	}
+	return rate
+}
+
+// quickBooksTaxCategory resolves the FIRS tax category of a line. The tax code of the line is looked up in the mapping;
+// the rate is the mapped one, else the single rate QuickBooks applied to the invoice, else the product default.
+// A line without a mapped tax code falls back to the product's default tax category. It reports false for a taxed
+// line that neither the mapping nor the product can categorise.
+func quickBooksTaxCategory(item quickbooks.Line, product models.Product, mapping *models.PlatformMapping, invoiceRate float64) (models.TaxCategoryMapping, bool) {
+	code := quickBooksTaxCode(item)
+	taxed := code != ""
+	if taxed {
+		if tax, ok := mapping.TaxCategory(code); ok {
+			if tax.Percent == nil {
+				percent := invoiceRate
+				if percent == 0 {
+					percent = product.TaxPercent
+				}
+				tax.Percent = &percent
+			}
+			return tax, true
+		}
+	}
+
+	if product.TaxCategory != "" {
+		percent := product.TaxPercent
+		if taxed && invoiceRate > 0 {
+			percent = invoiceRate
+		}
+		if !taxed {
+			percent = 0
+		}
+		return models.TaxCategoryMapping{Category: product.TaxCategory, Percent: &percent}, true
+	}
+	return models.TaxCategoryMapping{}, !taxed
+}
+
+// quickBooksTaxSubtotals groups the taxed lines by tax category and rate
+func quickBooksTaxSubtotals(items []quickbooks.Line, taxes []models.TaxCategoryMapping, taxableShare float64) (float64, []firs_models.TaxSubtotal) {
+	var total float64
+	var subtotals []firs_models.TaxSubtotal
+	index := map[string]int{}
+
+	for i, item := range items {
+		tax := taxes[i]
+		if tax.Percent == nil || *tax.Percent <= 0 {
+			continue
+		}
+		taxable := item.Amount * taxableShare
+		amount := taxable * (*tax.Percent / 100)
+		total += amount
+
+		key := fmt.Sprintf("%s/%g", tax.Category, *tax.Percent)
+		if j, ok := index[key]; ok {
+			subtotals[j].TaxableAmount = roundAmount(subtotals[j].TaxableAmount + taxable)
+			subtotals[j].TaxAmount = roundAmount(subtotals[j].TaxAmount + amount)
+			continue
+		}
+		index[key] = len(subtotals)
+		subtotals = append(subtotals, firs_models.TaxSubtotal{
+			TaxableAmount: roundAmount(taxable),
+			TaxAmount:     roundAmount(amount),
+			TaxCategory: firs_models.TaxCategory{
+				ID:      tax.Category,
+				Percent: *tax.Percent,
+			},
+		})
+	}
+	return total, subtotals
+}
+
+// quickBooksCustomerParty builds the buyer from the directory entry, falling back to the invoice and then the
+// QuickBooks customer for missing contact details
+func quickBooksCustomerParty(qb quickbooks.Invoice, qbCustomer *quickbooks.Customer, customer *models.Customer) (firs_models.Party, []string) {
+	var warnings []string
+
+	name := customer.Name
+	email := customer.Email
+	if email == "" && qb.BillEmail != nil {
+		email = qb.BillEmail.Address
+	}
+	if email == "" && qbCustomer != nil && qbCustomer.PrimaryEmailAddr != nil {
+		email = qbCustomer.PrimaryEmailAddr.Address
+	}
+	if email == "" {
+		warnings = append(warnings, "buyer has no email address in the customer directory or on the QuickBooks invoice")
+	}
+
+	telephone := customer.Telephone
+	if telephone == "" && qbCustomer != nil {
+		if qbCustomer.PrimaryPhone != nil && qbCustomer.PrimaryPhone.FreeFormNumber != "" {
+			telephone = utility.FormatPhone(qbCustomer.PrimaryPhone.FreeFormNumber)
+		} else if qbCustomer.Mobile != nil && qbCustomer.Mobile.FreeFormNumber != "" {
+			telephone = utility.FormatPhone(qbCustomer.Mobile.FreeFormNumber)
+		}
+	}
+
+	address := &firs_models.PostalAddress{
+		StreetName: customer.StreetName,
+		CityName:   customer.CityName,
+		PostalZone: customer.PostalZone,
+		Country:    customer.Country,
+	}
+	if address.StreetName == "" {
+		address = quickBooksAddress(qb.BillAddr)
+		if address == nil && qbCustomer != nil {
+			address = quickBooksAddress(qbCustomer.BillAddr)
+		}
+	}
+
+	party := firs_models.Party{
+		PartyName:     &name,
+		TIN:           customer.TIN,
+		Email:         email,
+		PostalAddress: address,
+	}
+	if telephone != "" {
+		party.Telephone = &telephone
+	}
+	return party, warnings
+}
+
+// quickBooksAddress maps a QuickBooks address, returning nil for a missing or empty one
+func quickBooksAddress(a *quickbooks.Address) *firs_models.PostalAddress {
+	if a == nil {
+		return nil
+	}
+	street := strings.TrimSpace(strings.Join([]string{a.Line1, a.Line2, a.Line3}, " "))
+	if street == "" && a.City == "" && a.PostalCode == "" && a.Country == "" {
+		return nil
+	}
+	return &firs_models.PostalAddress{
+		StreetName: street,
+		CityName:   a.City,
+		PostalZone: a.PostalCode,
+		Country:    a.Country,
+	}
+}
@@ -0,0 +1,648 @@
+package converter
+
+import (
+	"bytes"
+	"einvoice-access-point/external/firs_models"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// UBLFieldError is a single mapping failure located by the XPath of the offending node
+type UBLFieldError struct {
+	XPath   string `json:"xpath"`
+	Message string `json:"message"`
+}
+
+// UBLMappingError collects every field that could not be mapped between UBL and FIRS
+type UBLMappingError struct {
+	Errors []UBLFieldError
+}
+
+func (e *UBLMappingError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fmt.Sprintf("%s: %s", fe.XPath, fe.Message))
+	}
+	return "ubl mapping failed: " + strings.Join(parts, "; ")
+}
+
+// ublMapper accumulates field errors while walking a document
+type ublMapper struct {
+	errs []UBLFieldError
+}
+
+func (m *ublMapper) fail(xpath, format string, args ...interface{}) {
+	m.errs = append(m.errs, UBLFieldError{XPath: xpath, Message: fmt.Sprintf(format, args...)})
+}
+
+func (m *ublMapper) required(xpath, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		m.fail(xpath, "is required")
+	}
+	return value
+}
+
+func (m *ublMapper) amount(xpath string, a *ublAmount, required bool) float64 {
+	if a == nil || strings.TrimSpace(a.Value) == "" {
+		if required {
+			m.fail(xpath, "is required")
+		}
+		return 0
+	}
+	return m.number(xpath, a.Value)
+}
+
+func (m *ublMapper) number(xpath, value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		m.fail(xpath, "%q is not a valid number", value)
+		return 0
+	}
+	return f
+}
+
+func (m *ublMapper) quantity(xpath string, q *ublQuantity) int {
+	if q == nil || strings.TrimSpace(q.Value) == "" {
+		m.fail(xpath, "is required")
+		return 0
+	}
+	f := m.number(xpath, q.Value)
+	if f != float64(int(f)) {
+		m.fail(xpath, "fractional quantity %q is not supported", q.Value)
+	}
+	return int(f)
+}
+
+func (m *ublMapper) boolean(xpath, value string) bool {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		m.fail(xpath, "%q is not a valid boolean", value)
+	}
+	return b
+}
+
+// ublTokenReader rewrites UBL namespaces onto the cac/cbc prefixes used by the document structs,
+// so documents decode regardless of the prefixes the sender chose
+type ublTokenReader struct {
+	decoder *xml.Decoder
+}
+
+func (r *ublTokenReader) Token() (xml.Token, error) {
+	t, err := r.decoder.Token()
+	if err != nil {
+		return t, err
+	}
+
+	switch el := t.(type) {
+	case xml.StartElement:
+		el.Name = ublLocalName(el.Name)
+		attrs := el.Attr[:0]
+		for _, a := range el.Attr {
+			if a.Name.Space == "" && a.Name.Local != "xmlns" {
+				attrs = append(attrs, a)
+			}
+		}
+		el.Attr = attrs
+		return el, nil
+	case xml.EndElement:
+		el.Name = ublLocalName(el.Name)
+		return el, nil
+	}
+	return t, nil
+}
+
+func ublLocalName(name xml.Name) xml.Name {
+	switch name.Space {
+	case ublCACNS:
+		return xml.Name{Local: "cac:" + name.Local}
+	case ublCBCNS:
+		return xml.Name{Local: "cbc:" + name.Local}
+	}
+	return xml.Name{Local: name.Local}
+}
+
+// ConvertUBLToFIRS maps a UBL 2.1 Invoice or CreditNote document to a FIRS invoice request.
+// Any field that cannot be mapped is reported in a *UBLMappingError keyed by its XPath.
+func ConvertUBLToFIRS(r io.Reader) (firs_models.InvoiceRequest, error) {
+	var doc ublDocument
+	if err := xml.NewTokenDecoder(&ublTokenReader{decoder: xml.NewDecoder(r)}).Decode(&doc); err != nil {
+		return firs_models.InvoiceRequest{}, fmt.Errorf("invalid ubl xml: %w", err)
+	}
+
+	root := "/" + doc.XMLName.Local
+	if doc.XMLName.Local != "Invoice" && doc.XMLName.Local != "CreditNote" {
+		return firs_models.InvoiceRequest{}, &UBLMappingError{Errors: []UBLFieldError{
+			{XPath: root, Message: "root element must be Invoice or CreditNote"},
+		}}
+	}
+	isCreditNote := doc.XMLName.Local == "CreditNote"
+
+	m := &ublMapper{}
+	req := firs_models.InvoiceRequest{
+		InvoiceNumber:        m.required(root+"/cbc:ID", doc.ID),
+		IssueDate:            m.required(root+"/cbc:IssueDate", doc.IssueDate),
+		DocumentCurrencyCode: m.required(root+"/cbc:DocumentCurrencyCode", doc.DocumentCurrencyCode),
+		IssueTime:            optional(doc.IssueTime),
+		DueDate:              optional(doc.DueDate),
+		Note:                 optional(doc.Note),
+		TaxPointDate:         optional(doc.TaxPointDate),
+		TaxCurrencyCode:      optional(doc.TaxCurrencyCode),
+		AccountingCost:       optional(doc.AccountingCost),
+		BuyerReference:       optional(doc.BuyerReference),
+		IRN:                  optional(doc.UUID),
+	}
+
+	if isCreditNote {
+		req.InvoiceTypeCode = m.required(root+"/cbc:CreditNoteTypeCode", doc.CreditNoteTypeCode)
+	} else {
+		req.InvoiceTypeCode = m.required(root+"/cbc:InvoiceTypeCode", doc.InvoiceTypeCode)
+	}
+
+	if doc.InvoicePeriod != nil {
+		req.InvoiceDeliveryPeriod = &firs_models.InvoiceDeliveryPeriod{
+			StartDate: doc.InvoicePeriod.StartDate,
+			EndDate:   doc.InvoicePeriod.EndDate,
+		}
+	}
+	if doc.OrderReference != nil {
+		req.OrderReference = optional(doc.OrderReference.ID)
+	}
+	for i, ref := range doc.BillingReference {
+		xpath := fmt.Sprintf("%s/cac:BillingReference[%d]/cac:InvoiceDocumentReference", root, i+1)
+		req.BillingReference = append(req.BillingReference, m.documentReference(xpath, &ref.InvoiceDocumentReference))
+	}
+	req.DispatchDocumentReference = m.optionalDocumentReference(root+"/cac:DispatchDocumentReference", doc.DispatchDocumentReference)
+	req.ReceiptDocumentReference = m.optionalDocumentReference(root+"/cac:ReceiptDocumentReference", doc.ReceiptDocumentReference)
+	req.OriginatorDocumentReference = m.optionalDocumentReference(root+"/cac:OriginatorDocumentReference", doc.OriginatorDocumentReference)
+	req.ContractDocumentReference = m.optionalDocumentReference(root+"/cac:ContractDocumentReference", doc.ContractDocumentReference)
+	for i := range doc.AdditionalDocumentReference {
+		xpath := fmt.Sprintf("%s/cac:AdditionalDocumentReference[%d]", root, i+1)
+		req.AdditionalDocumentReference = append(req.AdditionalDocumentReference, m.documentReference(xpath, &doc.AdditionalDocumentReference[i]))
+	}
+
+	if doc.AccountingSupplierParty == nil {
+		m.fail(root+"/cac:AccountingSupplierParty", "is required")
+	} else {
+		req.AccountingSupplierParty = m.party(root+"/cac:AccountingSupplierParty/cac:Party", &doc.AccountingSupplierParty.Party)
+	}
+	if doc.AccountingCustomerParty == nil {
+		m.fail(root+"/cac:AccountingCustomerParty", "is required")
+	} else {
+		req.AccountingCustomerParty = m.party(root+"/cac:AccountingCustomerParty/cac:Party", &doc.AccountingCustomerParty.Party)
+	}
+	if doc.PayeeParty != nil {
+		payee := m.party(root+"/cac:PayeeParty", doc.PayeeParty)
+		req.PayeeParty = &payee
+	}
+	if doc.TaxRepresentativeParty != nil {
+		representative := m.party(root+"/cac:TaxRepresentativeParty", doc.TaxRepresentativeParty)
+		req.TaxRepresentativeParty = &representative
+	}
+
+	if doc.Delivery != nil {
+		req.ActualDeliveryDate = optional(doc.Delivery.ActualDeliveryDate)
+		if doc.Delivery.DeliveryLocation != nil {
+			req.DeliveryAddress = fromUBLPostalAddress(doc.Delivery.DeliveryLocation.Address)
+		}
+	}
+	for i, pm := range doc.PaymentMeans {
+		xpath := fmt.Sprintf("%s/cac:PaymentMeans[%d]", root, i+1)
+		req.PaymentMeans = append(req.PaymentMeans, firs_models.PaymentMeans{
+			PaymentMeansCode: m.required(xpath+"/cbc:PaymentMeansCode", pm.PaymentMeansCode),
+			PaymentDueDate:   pm.PaymentDueDate,
+		})
+	}
+	if doc.PaymentTerms != nil {
+		req.PaymentTermsNote = optional(doc.PaymentTerms.Note)
+	}
+	for i, ac := range doc.AllowanceCharge {
+		xpath := fmt.Sprintf("%s/cac:AllowanceCharge[%d]", root, i+1)
+		req.AllowanceCharge = append(req.AllowanceCharge, firs_models.AllowanceCharge{
+			ChargeIndicator: m.boolean(xpath+"/cbc:ChargeIndicator", ac.ChargeIndicator),
+			Amount:          m.amount(xpath+"/cbc:Amount", ac.Amount, true),
+		})
+	}
+
+	for i, tt := range doc.TaxTotal {
+		xpath := fmt.Sprintf("%s/cac:TaxTotal[%d]", root, i+1)
+		total := firs_models.TaxTotal{TaxAmount: m.amount(xpath+"/cbc:TaxAmount", tt.TaxAmount, true)}
+		for j, st := range tt.TaxSubtotal {
+			subXPath := fmt.Sprintf("%s/cac:TaxSubtotal[%d]", xpath, j+1)
+			total.TaxSubtotal = append(total.TaxSubtotal, firs_models.TaxSubtotal{
+				TaxableAmount: m.amount(subXPath+"/cbc:TaxableAmount", st.TaxableAmount, true),
+				TaxAmount:     m.amount(subXPath+"/cbc:TaxAmount", st.TaxAmount, true),
+				TaxCategory: firs_models.TaxCategory{
+					ID:      m.required(subXPath+"/cac:TaxCategory/cbc:ID", st.TaxCategory.ID),
+					Percent: m.number(subXPath+"/cac:TaxCategory/cbc:Percent", st.TaxCategory.Percent),
+				},
+			})
+		}
+		req.TaxTotal = append(req.TaxTotal, total)
+	}
+
+	if doc.LegalMonetaryTotal == nil {
+		m.fail(root+"/cac:LegalMonetaryTotal", "is required")
+	} else {
+		xpath := root + "/cac:LegalMonetaryTotal"
+		req.LegalMonetaryTotal = firs_models.LegalMonetaryTotal{
+			LineExtensionAmount: m.amount(xpath+"/cbc:LineExtensionAmount", doc.LegalMonetaryTotal.LineExtensionAmount, true),
+			TaxExclusiveAmount:  m.amount(xpath+"/cbc:TaxExclusiveAmount", doc.LegalMonetaryTotal.TaxExclusiveAmount, true),
+			TaxInclusiveAmount:  m.amount(xpath+"/cbc:TaxInclusiveAmount", doc.LegalMonetaryTotal.TaxInclusiveAmount, true),
+			PayableAmount:       m.amount(xpath+"/cbc:PayableAmount", doc.LegalMonetaryTotal.PayableAmount, true),
+		}
+	}
+
+	lines, lineElement, quantityElement := doc.InvoiceLine, "cac:InvoiceLine", "cbc:InvoicedQuantity"
+	if isCreditNote {
+		lines, lineElement, quantityElement = doc.CreditNoteLine, "cac:CreditNoteLine", "cbc:CreditedQuantity"
+	}
+	if len(lines) == 0 {
+		m.fail(root+"/"+lineElement, "at least one line is required")
+	}
+	for i := range lines {
+		xpath := fmt.Sprintf("%s/%s[%d]", root, lineElement, i+1)
+		req.InvoiceLine = append(req.InvoiceLine, m.line(xpath, quantityElement, &lines[i], isCreditNote))
+	}
+
+	if len(m.errs) > 0 {
+		return firs_models.InvoiceRequest{}, &UBLMappingError{Errors: m.errs}
+	}
+	return req, nil
+}
+
+func (m *ublMapper) documentReference(xpath string, ref *ublDocumentReference) firs_models.DocumentReference {
+	return firs_models.DocumentReference{
+		IRN:       m.required(xpath+"/cbc:ID", ref.ID),
+		IssueDate: ref.IssueDate,
+	}
+}
+
+func (m *ublMapper) optionalDocumentReference(xpath string, ref *ublDocumentReference) *firs_models.DocumentReference {
+	if ref == nil {
+		return nil
+	}
+	mapped := m.documentReference(xpath, ref)
+	return &mapped
+}
+
+func (m *ublMapper) party(xpath string, p *ublParty) firs_models.Party {
+	party := firs_models.Party{}
+
+	if p.PartyName != nil {
+		party.PartyName = optional(p.PartyName.Name)
+	} else if p.PartyLegalEntity != nil {
+		party.PartyName = optional(p.PartyLegalEntity.RegistrationName)
+	}
+
+	if p.PartyTaxScheme == nil {
+		m.fail(xpath+"/cac:PartyTaxScheme/cbc:CompanyID", "is required")
+	} else {
+		party.TIN = m.required(xpath+"/cac:PartyTaxScheme/cbc:CompanyID", p.PartyTaxScheme.CompanyID)
+	}
+
+	if p.Contact == nil {
+		m.fail(xpath+"/cac:Contact/cbc:ElectronicMail", "is required")
+	} else {
+		party.Email = m.required(xpath+"/cac:Contact/cbc:ElectronicMail", p.Contact.ElectronicMail)
+		party.Telephone = optional(p.Contact.Telephone)
+	}
+
+	if p.PartyLegalEntity != nil {
+		party.BusinessDescription = optional(p.PartyLegalEntity.CompanyLegalForm)
+	}
+
+	party.PostalAddress = fromUBLPostalAddress(p.PostalAddress)
+
+	return party
+}
+
+func fromUBLPostalAddress(a *ublPostalAddress) *firs_models.PostalAddress {
+	if a == nil {
+		return nil
+	}
+	address := &firs_models.PostalAddress{
+		StreetName: a.StreetName,
+		CityName:   a.CityName,
+		PostalZone: a.PostalZone,
+	}
+	if a.Country != nil {
+		address.Country = a.Country.IdentificationCode
+	}
+	return address
+}
+
+func (m *ublMapper) line(xpath, quantityElement string, l *ublLine, isCreditNote bool) firs_models.InvoiceLine {
+	quantity := l.InvoicedQuantity
+	if isCreditNote {
+		quantity = l.CreditedQuantity
+	}
+
+	line := firs_models.InvoiceLine{
+		InvoicedQuantity:    m.quantity(xpath+"/"+quantityElement, quantity),
+		LineExtensionAmount: m.amount(xpath+"/cbc:LineExtensionAmount", l.LineExtensionAmount, true),
+		Item: firs_models.Item{
+			Name:        m.required(xpath+"/cac:Item/cbc:Name", l.Item.Name),
+			Description: l.Item.Description,
+		},
+	}
+	if l.Item.SellersItemIdentification != nil {
+		line.Item.SellersItemIdentification = optional(l.Item.SellersItemIdentification.ID)
+	}
+
+	for _, cc := range l.Item.CommodityClassification {
+		switch cc.ItemClassificationCode.ListID {
+		case ublProductCategoryListID:
+			line.ProductCategory = strings.TrimSpace(cc.ItemClassificationCode.Value)
+		default:
+			if line.HSNCode == "" {
+				line.HSNCode = strings.TrimSpace(cc.ItemClassificationCode.Value)
+			}
+		}
+	}
+	if line.HSNCode == "" {
+		m.fail(xpath+"/cac:Item/cac:CommodityClassification/cbc:ItemClassificationCode", "an HS code classification is required")
+	}
+	if line.ProductCategory == "" {
+		m.fail(xpath+"/cac:Item/cac:CommodityClassification/cbc:ItemClassificationCode[@listID='"+ublProductCategoryListID+"']", "a product category classification is required")
+	}
+
+	for i, ac := range l.AllowanceCharge {
+		acXPath := fmt.Sprintf("%s/cac:AllowanceCharge[%d]", xpath, i+1)
+		rate := m.number(acXPath+"/cbc:MultiplierFactorNumeric", ac.MultiplierFactorNumeric)
+		amount := m.amount(acXPath+"/cbc:Amount", ac.Amount, true)
+		if m.boolean(acXPath+"/cbc:ChargeIndicator", ac.ChargeIndicator) {
+			line.FeeRate, line.FeeAmount = rate, amount
+		} else {
+			line.DiscountRate, line.DiscountAmount = rate, amount
+		}
+	}
+
+	if l.Price == nil {
+		m.fail(xpath+"/cac:Price", "is required")
+	} else {
+		line.Price.PriceAmount = m.amount(xpath+"/cac:Price/cbc:PriceAmount", l.Price.PriceAmount, true)
+		line.Price.BaseQuantity = 1
+		if l.Price.BaseQuantity != nil {
+			line.Price.BaseQuantity = m.quantity(xpath+"/cac:Price/cbc:BaseQuantity", l.Price.BaseQuantity)
+			line.Price.PriceUnit = l.Price.BaseQuantity.UnitCode
+		}
+	}
+
+	return line
+}
+
+// ConvertFIRSToUBL renders a FIRS invoice request as a UBL 2.1 document. Credit notes
+// (any UNCL1001 credit note type code, such as 381 or 396) are emitted as a CreditNote, everything else as an Invoice.
+func ConvertFIRSToUBL(req firs_models.InvoiceRequest) ([]byte, error) {
+	if req.InvoiceNumber == "" {
+		return nil, errors.New("invoice number is required for ubl export")
+	}
+	return encodeUBL(buildUBLDocument(req))
+}
+
+func buildUBLDocument(req firs_models.InvoiceRequest) ublDocument {
+	currency := req.DocumentCurrencyCode
+	isCreditNote := IsCreditNoteTypeCode(req.InvoiceTypeCode)
+
+	doc := ublDocument{
+		XMLName:              xml.Name{Local: "Invoice"},
+		XMLNS:                ublInvoiceNS,
+		XMLNSCAC:             ublCACNS,
+		XMLNSCBC:             ublCBCNS,
+		UBLVersionID:         "2.1",
+		ID:                   req.InvoiceNumber,
+		UUID:                 deref(req.IRN),
+		IssueDate:            req.IssueDate,
+		IssueTime:            deref(req.IssueTime),
+		Note:                 deref(req.Note),
+		TaxPointDate:         deref(req.TaxPointDate),
+		DocumentCurrencyCode: currency,
+		TaxCurrencyCode:      deref(req.TaxCurrencyCode),
+		AccountingCost:       deref(req.AccountingCost),
+		BuyerReference:       deref(req.BuyerReference),
+		AccountingSupplierParty: &ublPartyWrapper{
+			Party: toUBLParty(req.AccountingSupplierParty),
+		},
+		AccountingCustomerParty: &ublPartyWrapper{
+			Party: toUBLParty(req.AccountingCustomerParty),
+		},
+		LegalMonetaryTotal: &ublLegalMonetaryTotal{
+			LineExtensionAmount: ublMoney(req.LegalMonetaryTotal.LineExtensionAmount, currency),
+			TaxExclusiveAmount:  ublMoney(req.LegalMonetaryTotal.TaxExclusiveAmount, currency),
+			TaxInclusiveAmount:  ublMoney(req.LegalMonetaryTotal.TaxInclusiveAmount, currency),
+			PayableAmount:       ublMoney(req.LegalMonetaryTotal.PayableAmount, currency),
+		},
+	}
+
+	if isCreditNote {
+		doc.XMLName = xml.Name{Local: "CreditNote"}
+		doc.XMLNS = ublCreditNoteNS
+		doc.CreditNoteTypeCode = req.InvoiceTypeCode
+	} else {
+		doc.InvoiceTypeCode = req.InvoiceTypeCode
+		doc.DueDate = deref(req.DueDate)
+	}
+
+	if req.InvoiceDeliveryPeriod != nil {
+		doc.InvoicePeriod = &ublPeriod{StartDate: req.InvoiceDeliveryPeriod.StartDate, EndDate: req.InvoiceDeliveryPeriod.EndDate}
+	}
+	if req.OrderReference != nil && *req.OrderReference != "" {
+		doc.OrderReference = &ublOrderReference{ID: *req.OrderReference}
+	}
+	for _, ref := range req.BillingReference {
+		doc.BillingReference = append(doc.BillingReference, ublBillingReference{InvoiceDocumentReference: toUBLDocumentReference(ref)})
+	}
+	doc.DispatchDocumentReference = toOptionalUBLDocumentReference(req.DispatchDocumentReference)
+	doc.ReceiptDocumentReference = toOptionalUBLDocumentReference(req.ReceiptDocumentReference)
+	doc.OriginatorDocumentReference = toOptionalUBLDocumentReference(req.OriginatorDocumentReference)
+	doc.ContractDocumentReference = toOptionalUBLDocumentReference(req.ContractDocumentReference)
+	for _, ref := range req.AdditionalDocumentReference {
+		doc.AdditionalDocumentReference = append(doc.AdditionalDocumentReference, toUBLDocumentReference(ref))
+	}
+
+	if req.PayeeParty != nil {
+		payee := toUBLParty(*req.PayeeParty)
+		doc.PayeeParty = &payee
+	}
+	if req.TaxRepresentativeParty != nil {
+		representative := toUBLParty(*req.TaxRepresentativeParty)
+		doc.TaxRepresentativeParty = &representative
+	}
+	if deref(req.ActualDeliveryDate) != "" || req.DeliveryAddress != nil {
+		doc.Delivery = &ublDelivery{ActualDeliveryDate: deref(req.ActualDeliveryDate)}
+		if req.DeliveryAddress != nil {
+			doc.Delivery.DeliveryLocation = &ublDeliveryLocation{Address: toUBLPostalAddress(req.DeliveryAddress)}
+		}
+	}
+	for _, pm := range req.PaymentMeans {
+		doc.PaymentMeans = append(doc.PaymentMeans, ublPaymentMeans{PaymentMeansCode: pm.PaymentMeansCode, PaymentDueDate: pm.PaymentDueDate})
+	}
+	if req.PaymentTermsNote != nil && *req.PaymentTermsNote != "" {
+		doc.PaymentTerms = &ublPaymentTerms{Note: *req.PaymentTermsNote}
+	}
+	for _, ac := range req.AllowanceCharge {
+		doc.AllowanceCharge = append(doc.AllowanceCharge, ublAllowanceCharge{
+			ChargeIndicator: strconv.FormatBool(ac.ChargeIndicator),
+			Amount:          ublMoney(ac.Amount, currency),
+		})
+	}
+
+	for _, tt := range req.TaxTotal {
+		total := ublTaxTotal{TaxAmount: ublMoney(tt.TaxAmount, currency)}
+		for _, st := range tt.TaxSubtotal {
+			total.TaxSubtotal = append(total.TaxSubtotal, ublTaxSubtotal{
+				TaxableAmount: ublMoney(st.TaxableAmount, currency),
+				TaxAmount:     ublMoney(st.TaxAmount, currency),
+				TaxCategory: ublTaxCategory{
+					ID:        st.TaxCategory.ID,
+					Percent:   ublDecimal(st.TaxCategory.Percent),
+					TaxScheme: ublTaxScheme{ID: "VAT"},
+				},
+			})
+		}
+		doc.TaxTotal = append(doc.TaxTotal, total)
+	}
+
+	for i, l := range req.InvoiceLine {
+		line := toUBLLine(i+1, l, currency, isCreditNote)
+		if isCreditNote {
+			doc.CreditNoteLine = append(doc.CreditNoteLine, line)
+		} else {
+			doc.InvoiceLine = append(doc.InvoiceLine, line)
+		}
+	}
+
+	return doc
+}
+
+func encodeUBL(doc ublDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode ubl document: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func toUBLParty(p firs_models.Party) ublParty {
+	party := ublParty{
+		PartyTaxScheme: &ublPartyTaxScheme{CompanyID: p.TIN, TaxScheme: ublTaxScheme{ID: "VAT"}},
+		Contact:        &ublContact{ElectronicMail: p.Email, Telephone: deref(p.Telephone)},
+	}
+	if name := deref(p.PartyName); name != "" {
+		party.PartyName = &ublPartyName{Name: name}
+	}
+	if description := deref(p.BusinessDescription); description != "" {
+		party.PartyLegalEntity = &ublPartyLegalEntity{RegistrationName: deref(p.PartyName), CompanyLegalForm: description}
+	}
+	party.PostalAddress = toUBLPostalAddress(p.PostalAddress)
+	return party
+}
+
+func toUBLPostalAddress(a *firs_models.PostalAddress) *ublPostalAddress {
+	if a == nil {
+		return nil
+	}
+	address := &ublPostalAddress{
+		StreetName: a.StreetName,
+		CityName:   a.CityName,
+		PostalZone: a.PostalZone,
+	}
+	if a.Country != "" {
+		address.Country = &ublCountry{IdentificationCode: a.Country}
+	}
+	return address
+}
+
+func toUBLDocumentReference(ref firs_models.DocumentReference) ublDocumentReference {
+	return ublDocumentReference{ID: ref.IRN, IssueDate: ref.IssueDate}
+}
+
+func toOptionalUBLDocumentReference(ref *firs_models.DocumentReference) *ublDocumentReference {
+	if ref == nil {
+		return nil
+	}
+	mapped := toUBLDocumentReference(*ref)
+	return &mapped
+}
+
+func toUBLLine(id int, l firs_models.InvoiceLine, currency string, isCreditNote bool) ublLine {
+	quantity := &ublQuantity{Value: strconv.Itoa(l.InvoicedQuantity)}
+	line := ublLine{
+		ID:                  strconv.Itoa(id),
+		LineExtensionAmount: ublMoney(l.LineExtensionAmount, currency),
+		Item: ublItem{
+			Name:        l.Item.Name,
+			Description: l.Item.Description,
+			CommodityClassification: []ublCommodityClassification{
+				{ItemClassificationCode: ublClassificationCode{Value: l.HSNCode, ListID: ublHSCodeListID}},
+				{ItemClassificationCode: ublClassificationCode{Value: l.ProductCategory, ListID: ublProductCategoryListID}},
+			},
+		},
+		Price: &ublPrice{
+			PriceAmount:  ublMoney(l.Price.PriceAmount, currency),
+			BaseQuantity: &ublQuantity{Value: strconv.Itoa(l.Price.BaseQuantity), UnitCode: l.Price.PriceUnit},
+		},
+	}
+	if isCreditNote {
+		line.CreditedQuantity = quantity
+	} else {
+		line.InvoicedQuantity = quantity
+	}
+
+	if l.Item.SellersItemIdentification != nil && *l.Item.SellersItemIdentification != "" {
+		line.Item.SellersItemIdentification = &ublItemIdentification{ID: *l.Item.SellersItemIdentification}
+	}
+	if l.DiscountAmount != 0 || l.DiscountRate != 0 {
+		line.AllowanceCharge = append(line.AllowanceCharge, ublAllowanceCharge{
+			ChargeIndicator:         "false",
+			MultiplierFactorNumeric: ublDecimal(l.DiscountRate),
+			Amount:                  ublMoney(l.DiscountAmount, currency),
+		})
+	}
+	if l.FeeAmount != 0 || l.FeeRate != 0 {
+		line.AllowanceCharge = append(line.AllowanceCharge, ublAllowanceCharge{
+			ChargeIndicator:         "true",
+			MultiplierFactorNumeric: ublDecimal(l.FeeRate),
+			Amount:                  ublMoney(l.FeeAmount, currency),
+		})
+	}
+	return line
+}
+
+func ublMoney(value float64, currency string) *ublAmount {
+	return &ublAmount{Value: strconv.FormatFloat(value, 'f', 2, 64), CurrencyID: currency}
+}
+
+func ublDecimal(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func optional(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,227 @@
+package converter
+
+import "encoding/xml"
+
+// UBL 2.1 namespaces
+const (
+	ublInvoiceNS    = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	ublCreditNoteNS = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+	ublCACNS        = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	ublCBCNS        = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+// Item classification list IDs used to carry the FIRS HS code and product category
+const (
+	ublHSCodeListID          = "HS"
+	ublProductCategoryListID = "CATEGORY"
+)
+
+// ublDocument covers both the Invoice and CreditNote roots. Element names carry the cac/cbc
+// prefixes so the document marshals as conventional UBL; decoding maps the namespaces back
+// onto these prefixes.
+type ublDocument struct {
+	XMLName                     xml.Name
+	XMLNS                       string                 `xml:"xmlns,attr,omitempty"`
+	XMLNSCAC                    string                 `xml:"xmlns:cac,attr,omitempty"`
+	XMLNSCBC                    string                 `xml:"xmlns:cbc,attr,omitempty"`
+	UBLVersionID                string                 `xml:"cbc:UBLVersionID,omitempty"`
+	CustomizationID             string                 `xml:"cbc:CustomizationID,omitempty"`
+	ProfileID                   string                 `xml:"cbc:ProfileID,omitempty"`
+	ID                          string                 `xml:"cbc:ID"`
+	UUID                        string                 `xml:"cbc:UUID,omitempty"`
+	IssueDate                   string                 `xml:"cbc:IssueDate"`
+	IssueTime                   string                 `xml:"cbc:IssueTime,omitempty"`
+	DueDate                     string                 `xml:"cbc:DueDate,omitempty"`
+	InvoiceTypeCode             string                 `xml:"cbc:InvoiceTypeCode,omitempty"`
+	CreditNoteTypeCode          string                 `xml:"cbc:CreditNoteTypeCode,omitempty"`
+	Note                        string                 `xml:"cbc:Note,omitempty"`
+	TaxPointDate                string                 `xml:"cbc:TaxPointDate,omitempty"`
+	DocumentCurrencyCode        string                 `xml:"cbc:DocumentCurrencyCode"`
+	TaxCurrencyCode             string                 `xml:"cbc:TaxCurrencyCode,omitempty"`
+	AccountingCost              string                 `xml:"cbc:AccountingCost,omitempty"`
+	BuyerReference              string                 `xml:"cbc:BuyerReference,omitempty"`
+	InvoicePeriod               *ublPeriod             `xml:"cac:InvoicePeriod,omitempty"`
+	OrderReference              *ublOrderReference     `xml:"cac:OrderReference,omitempty"`
+	BillingReference            []ublBillingReference  `xml:"cac:BillingReference,omitempty"`
+	DispatchDocumentReference   *ublDocumentReference  `xml:"cac:DispatchDocumentReference,omitempty"`
+	ReceiptDocumentReference    *ublDocumentReference  `xml:"cac:ReceiptDocumentReference,omitempty"`
+	OriginatorDocumentReference *ublDocumentReference  `xml:"cac:OriginatorDocumentReference,omitempty"`
+	ContractDocumentReference   *ublDocumentReference  `xml:"cac:ContractDocumentReference,omitempty"`
+	AdditionalDocumentReference []ublDocumentReference `xml:"cac:AdditionalDocumentReference,omitempty"`
+	AccountingSupplierParty     *ublPartyWrapper       `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty     *ublPartyWrapper       `xml:"cac:AccountingCustomerParty"`
+	PayeeParty                  *ublParty              `xml:"cac:PayeeParty,omitempty"`
+	TaxRepresentativeParty      *ublParty              `xml:"cac:TaxRepresentativeParty,omitempty"`
+	Delivery                    *ublDelivery           `xml:"cac:Delivery,omitempty"`
+	PaymentMeans                []ublPaymentMeans      `xml:"cac:PaymentMeans,omitempty"`
+	PaymentTerms                *ublPaymentTerms       `xml:"cac:PaymentTerms,omitempty"`
+	AllowanceCharge             []ublAllowanceCharge   `xml:"cac:AllowanceCharge,omitempty"`
+	TaxTotal                    []ublTaxTotal          `xml:"cac:TaxTotal,omitempty"`
+	LegalMonetaryTotal          *ublLegalMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLine                 []ublLine              `xml:"cac:InvoiceLine,omitempty"`
+	CreditNoteLine              []ublLine              `xml:"cac:CreditNoteLine,omitempty"`
+}
+
+type ublAmount struct {
+	Value      string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr,omitempty"`
+}
+
+type ublQuantity struct {
+	Value    string `xml:",chardata"`
+	UnitCode string `xml:"unitCode,attr,omitempty"`
+}
+
+type ublPeriod struct {
+	StartDate string `xml:"cbc:StartDate,omitempty"`
+	EndDate   string `xml:"cbc:EndDate,omitempty"`
+}
+
+type ublOrderReference struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublBillingReference struct {
+	InvoiceDocumentReference ublDocumentReference `xml:"cac:InvoiceDocumentReference"`
+}
+
+type ublDocumentReference struct {
+	ID        string `xml:"cbc:ID"`
+	IssueDate string `xml:"cbc:IssueDate,omitempty"`
+}
+
+type ublPartyWrapper struct {
+	Party ublParty `xml:"cac:Party"`
+}
+
+type ublParty struct {
+	EndpointID       *ublIdentifier       `xml:"cbc:EndpointID,omitempty"`
+	PartyName        *ublPartyName        `xml:"cac:PartyName,omitempty"`
+	PostalAddress    *ublPostalAddress    `xml:"cac:PostalAddress,omitempty"`
+	PartyTaxScheme   *ublPartyTaxScheme   `xml:"cac:PartyTaxScheme,omitempty"`
+	PartyLegalEntity *ublPartyLegalEntity `xml:"cac:PartyLegalEntity,omitempty"`
+	Contact          *ublContact          `xml:"cac:Contact,omitempty"`
+}
+
+type ublIdentifier struct {
+	Value    string `xml:",chardata"`
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+}
+
+type ublPartyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+type ublPostalAddress struct {
+	StreetName string      `xml:"cbc:StreetName,omitempty"`
+	CityName   string      `xml:"cbc:CityName,omitempty"`
+	PostalZone string      `xml:"cbc:PostalZone,omitempty"`
+	Country    *ublCountry `xml:"cac:Country,omitempty"`
+}
+
+type ublCountry struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+}
+
+type ublPartyTaxScheme struct {
+	CompanyID string       `xml:"cbc:CompanyID"`
+	TaxScheme ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublTaxScheme struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublPartyLegalEntity struct {
+	RegistrationName string `xml:"cbc:RegistrationName,omitempty"`
+	CompanyLegalForm string `xml:"cbc:CompanyLegalForm,omitempty"`
+}
+
+type ublContact struct {
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+type ublDelivery struct {
+	ActualDeliveryDate string               `xml:"cbc:ActualDeliveryDate,omitempty"`
+	DeliveryLocation   *ublDeliveryLocation `xml:"cac:DeliveryLocation,omitempty"`
+}
+
+type ublDeliveryLocation struct {
+	Address *ublPostalAddress `xml:"cac:Address,omitempty"`
+}
+
+type ublPaymentMeans struct {
+	PaymentMeansCode string `xml:"cbc:PaymentMeansCode"`
+	PaymentDueDate   string `xml:"cbc:PaymentDueDate,omitempty"`
+}
+
+type ublPaymentTerms struct {
+	Note string `xml:"cbc:Note"`
+}
+
+type ublAllowanceCharge struct {
+	ChargeIndicator         string     `xml:"cbc:ChargeIndicator"`
+	MultiplierFactorNumeric string     `xml:"cbc:MultiplierFactorNumeric,omitempty"`
+	Amount                  *ublAmount `xml:"cbc:Amount"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount   *ublAmount       `xml:"cbc:TaxAmount"`
+	TaxSubtotal []ublTaxSubtotal `xml:"cac:TaxSubtotal,omitempty"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount *ublAmount     `xml:"cbc:TaxableAmount"`
+	TaxAmount     *ublAmount     `xml:"cbc:TaxAmount"`
+	TaxCategory   ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	ID        string       `xml:"cbc:ID"`
+	Percent   string       `xml:"cbc:Percent,omitempty"`
+	TaxScheme ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublLegalMonetaryTotal struct {
+	LineExtensionAmount *ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  *ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  *ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       *ublAmount `xml:"cbc:PayableAmount"`
+}
+
+// ublLine is used for both InvoiceLine and CreditNoteLine
+type ublLine struct {
+	ID                  string               `xml:"cbc:ID"`
+	InvoicedQuantity    *ublQuantity         `xml:"cbc:InvoicedQuantity,omitempty"`
+	CreditedQuantity    *ublQuantity         `xml:"cbc:CreditedQuantity,omitempty"`
+	LineExtensionAmount *ublAmount           `xml:"cbc:LineExtensionAmount"`
+	AllowanceCharge     []ublAllowanceCharge `xml:"cac:AllowanceCharge,omitempty"`
+	Item                ublItem              `xml:"cac:Item"`
+	Price               *ublPrice            `xml:"cac:Price"`
+}
+
+type ublItem struct {
+	Description               string                       `xml:"cbc:Description,omitempty"`
+	Name                      string                       `xml:"cbc:Name"`
+	SellersItemIdentification *ublItemIdentification       `xml:"cac:SellersItemIdentification,omitempty"`
+	CommodityClassification   []ublCommodityClassification `xml:"cac:CommodityClassification,omitempty"`
+}
+
+type ublItemIdentification struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublCommodityClassification struct {
+	ItemClassificationCode ublClassificationCode `xml:"cbc:ItemClassificationCode"`
+}
+
+type ublClassificationCode struct {
+	Value  string `xml:",chardata"`
+	ListID string `xml:"listID,attr,omitempty"`
+}
+
+type ublPrice struct {
+	PriceAmount  *ublAmount   `xml:"cbc:PriceAmount"`
+	BaseQuantity *ublQuantity `xml:"cbc:BaseQuantity,omitempty"`
+}
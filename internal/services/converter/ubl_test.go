@@ -0,0 +1,31 @@
+package converter
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"testing"
+)
+
+func TestBuildUBLDocumentCreditNote(t *testing.T) {
+	tests := []struct {
+		typeCode string
+		root     string
+	}{
+		{"380", "Invoice"},
+		{"381", "CreditNote"},
+		{"396", "CreditNote"},
+		{"261", "CreditNote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeCode, func(t *testing.T) {
+			doc := buildUBLDocument(firs_models.InvoiceRequest{InvoiceNumber: "INV-001", InvoiceTypeCode: tt.typeCode, DocumentCurrencyCode: "NGN"})
+
+			if doc.XMLName.Local != tt.root {
+				t.Errorf("root element = %q, want %q", doc.XMLName.Local, tt.root)
+			}
+			if tt.root == "CreditNote" && (doc.CreditNoteTypeCode != tt.typeCode || doc.InvoiceTypeCode != "") {
+				t.Errorf("type codes = invoice %q credit note %q, want credit note %q", doc.InvoiceTypeCode, doc.CreditNoteTypeCode, tt.typeCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package converter
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/xero"
+	"einvoice-access-point/internal/services/business"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ConvertXeroToFIRS converts a Xero sales invoice or credit note. Lines are resolved through the product catalogue by
+// item code and Xero tax types through the platform mapping; the line and tax amounts are the ones Xero computed, with
+// tax-inclusive lines brought back to their net amount. Credit notes are sent with the credit note type code and refer
+// to the invoices they were allocated to.
+func ConvertXeroToFIRS(doc xero.InvoiceDocument, supplier *models.Business, irn string, catalogue map[string]models.Product, customer *models.Customer, mapping *models.PlatformMapping, references []firs_models.DocumentReference) (firs_models.InvoiceRequest, []string, error) {
+	inv := doc.Invoice
+	if customer == nil || !customer.TINVerified {
+		return firs_models.InvoiceRequest{}, nil, &UnverifiedCustomerError{CustomerID: inv.Contact.ContactID}
+	}
+
+	items := xeroLines(inv.LineItems)
+
+	// Every line must be classified before the invoice can go to FIRS
+	var unmapped []string
+	for _, item := range items {
+		code := xeroItemCode(item)
+		if _, ok := catalogue[code]; !ok || code == "" {
+			if code == "" {
+				code = item.Description
+			}
+			unmapped = append(unmapped, code)
+		}
+	}
+	if len(unmapped) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedProductsError{ItemIDs: unmapped}
+	}
+
+	// Every taxed line must resolve to a FIRS tax category
+	var unmappedTaxes []string
+	seenTaxes := map[string]bool{}
+	taxes := make([]models.TaxCategoryMapping, len(items))
+	for i, item := range items {
+		tax, ok := xeroTaxCategory(inv.LineAmountTypes, item, catalogue[xeroItemCode(item)], mapping)
+		if !ok && !seenTaxes[item.TaxType] {
+			seenTaxes[item.TaxType] = true
+			unmappedTaxes = append(unmappedTaxes, item.TaxType)
+		}
+		taxes[i] = tax
+	}
+	if len(unmappedTaxes) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedTaxesError{TaxIDs: unmappedTaxes}
+	}
+
+	issueDate, issueTime := xeroDate(inv.DateString)
+	if issueDate == "" {
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("xero document %s has no date", inv.Number())
+	}
+
+	var warnings []string
+	buyer, contactWarnings := xeroCustomerParty(doc.Contact, customer)
+	warnings = append(warnings, contactWarnings...)
+
+	// Map line items
+	var lines []firs_models.InvoiceLine
+	var lineTotal float64
+	for i, item := range items {
+		line, lineWarnings := xeroInvoiceLine(inv.CurrencyCode, inv.LineAmountTypes, item, taxes[i], catalogue[xeroItemCode(item)])
+		lines = append(lines, line)
+		warnings = append(warnings, lineWarnings...)
+		lineTotal += line.LineExtensionAmount
+	}
+
+	taxTotal, subtotals := xeroTaxSubtotals(inv.LineAmountTypes, items, taxes)
+	if inv.TotalTax > 0 {
+		taxTotal = inv.TotalTax
+	}
+
+	taxInclusive := lineTotal + taxTotal
+	if !amountsEqual(taxInclusive, inv.Total) {
+		warnings = append(warnings, fmt.Sprintf("computed payable amount %.2f differs from the Xero total %.2f, the Xero total was used", taxInclusive, inv.Total))
+	}
+
+	firsInvoice := firs_models.InvoiceRequest{
+		BusinessID:              supplier.BusinessID,
+		IRN:                     &irn,
+		IssueDate:               issueDate,
+		IssueTime:               &issueTime,
+		InvoiceTypeCode:         mapping.DocumentTypeCode(),
+		TaxPointDate:            &issueDate,
+		DocumentCurrencyCode:    inv.CurrencyCode,
+		TaxCurrencyCode:         &inv.CurrencyCode,
+		AccountingCustomerParty: buyer,
+		TaxTotal: []firs_models.TaxTotal{
+			{
+				TaxAmount:   roundAmount(taxTotal),
+				TaxSubtotal: subtotals,
+			},
+		},
+		LegalMonetaryTotal: firs_models.LegalMonetaryTotal{
+			LineExtensionAmount: roundAmount(lineTotal),
+			TaxExclusiveAmount:  roundAmount(lineTotal),
+			TaxInclusiveAmount:  roundAmount(taxInclusive),
+			PayableAmount:       inv.Total,
+		},
+		InvoiceLine: lines,
+	}
+
+	if inv.IsCreditNote() {
+		firsInvoice.InvoiceTypeCode = models.CreditNoteTypeCode
+		firsInvoice.BillingReference = references
+		if len(references) == 0 {
+			warnings = append(warnings, "credit note is not allocated to an invoice FIRS signed, it was sent without a billing reference")
+		}
+	} else {
+		paymentStatus := "PENDING"
+		if inv.Status == xero.StatusPaid || (inv.AmountDue <= 0 && inv.Total > 0) {
+			paymentStatus = "PAID"
+		}
+		firsInvoice.PaymentStatus = &paymentStatus
+		if dueDate, _ := xeroDate(inv.DueDateString); dueDate != "" {
+			firsInvoice.DueDate = &dueDate
+		}
+	}
+
+	if err := business.ApplySupplierProfile(&firsInvoice, supplier); err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	return firsInvoice, warnings, nil
+}
+
+// xeroLines drops the lines that only carry text
+func xeroLines(items []xero.LineItem) []xero.LineItem {
+	var lines []xero.LineItem
+	for _, item := range items {
+		if xeroItemCode(item) == "" && item.LineAmount == 0 && item.Quantity == 0 {
+			continue
+		}
+		lines = append(lines, item)
+	}
+	return lines
+}
+
+// xeroItemCode is the code of the item on the line, which the product catalogue is keyed by
+func xeroItemCode(item xero.LineItem) string {
+	if item.ItemCode != "" {
+		return item.ItemCode
+	}
+	if item.Item != nil {
+		return item.Item.Code
+	}
+	return ""
+}
+
+// xeroNetAmount is the line amount without tax
+func xeroNetAmount(lineAmountTypes string, item xero.LineItem) float64 {
+	if lineAmountTypes == xero.LineAmountsInclusive {
+		return item.LineAmount - item.TaxAmount
+	}
+	return item.LineAmount
+}
+
+// xeroTaxRate is the rate Xero applied to the line, worked out from the tax it charged on the net amount
+func xeroTaxRate(lineAmountTypes string, item xero.LineItem) float64 {
+	net := xeroNetAmount(lineAmountTypes, item)
+	if net == 0 || item.TaxAmount == 0 {
+		return 0
+	}
+	return roundAmount(item.TaxAmount / net * 100)
+}
+
+// xeroTaxCategory resolves the FIRS tax category of a line. The Xero tax type of the line is looked up in the mapping,
+// keeping the rate Xero applied unless the mapping overrides it; otherwise the product's default tax category is used.
+// It reports false for a taxed line that neither the mapping nor the product can categorise.
+func xeroTaxCategory(lineAmountTypes string, item xero.LineItem, product models.Product, mapping *models.PlatformMapping) (models.TaxCategoryMapping, bool) {
+	rate := xeroTaxRate(lineAmountTypes, item)
+	if tax, ok := mapping.TaxCategory(item.TaxType); ok {
+		if tax.Percent == nil {
+			tax.Percent = &rate
+		}
+		return tax, true
+	}
+
+	if product.TaxCategory != "" {
+		return models.TaxCategoryMapping{Category: product.TaxCategory, Percent: &rate}, true
+	}
+	return models.TaxCategoryMapping{}, item.TaxAmount == 0
+}
+
+// xeroInvoiceLine maps a Xero line item at its net amount. The price is quoted per single unit without tax.
+func xeroInvoiceLine(currency, lineAmountTypes string, item xero.LineItem, tax models.TaxCategoryMapping, product models.Product) (firs_models.InvoiceLine, []string) {
+	var warnings []string
+
+	qty := item.Quantity
+	if qty == 0 {
+		qty = 1
+	}
+	quantity := int(math.Round(qty))
+	if quantity < 1 {
+		quantity = 1
+	}
+	if float64(quantity) != qty {
+		warnings = append(warnings, fmt.Sprintf("line %s quantity %g was rounded to %d", xeroLineName(item), qty, quantity))
+	}
+
+	unitPrice := item.UnitAmount
+	if lineAmountTypes == xero.LineAmountsInclusive && tax.Percent != nil && *tax.Percent > 0 {
+		unitPrice = unitPrice / (1 + *tax.Percent/100)
+	}
+
+	net := xeroNetAmount(lineAmountTypes, item)
+	gross := unitPrice * qty
+	discountAmount := item.DiscountAmount
+	if discountAmount == 0 && item.DiscountRate > 0 {
+		discountAmount = gross * item.DiscountRate / 100
+	}
+	discountRate := item.DiscountRate
+	if discountRate == 0 && gross > 0 {
+		discountRate = discountAmount / gross * 100
+	}
+
+	priceUnit := currency + " per 1"
+	if product.UnitOfMeasure != "" {
+		priceUnit = currency + " per " + product.UnitOfMeasure
+	}
+
+	return firs_models.InvoiceLine{
+		HSNCode:             product.HSCode,
+		ProductCategory:     product.ProductCategory,
+		DiscountAmount:      roundAmount(discountAmount),
+		DiscountRate:        roundAmount(discountRate),
+		InvoicedQuantity:    quantity,
+		LineExtensionAmount: roundAmount(net),
+		Item: firs_models.Item{
+			Name:        xeroLineName(item),
+			Description: item.Description,
+		},
+		Price: firs_models.Price{
+			PriceAmount:  roundAmount(unitPrice),
+			BaseQuantity: 1,
+			PriceUnit:    priceUnit,
+		},
+	}, warnings
+}
+
+// xeroLineName is the name of the item on the line, or its description for lines without an item
+func xeroLineName(item xero.LineItem) string {
+	if item.Item != nil && item.Item.Name != "" {
+		return item.Item.Name
+	}
+	return item.Description
+}
+
+// xeroTaxSubtotals groups the taxed lines by tax category and rate, with the tax amounts Xero computed
+func xeroTaxSubtotals(lineAmountTypes string, items []xero.LineItem, taxes []models.TaxCategoryMapping) (float64, []firs_models.TaxSubtotal) {
+	var total float64
+	var subtotals []firs_models.TaxSubtotal
+	index := map[string]int{}
+
+	for i, item := range items {
+		tax := taxes[i]
+		if tax.Percent == nil || *tax.Percent <= 0 {
+			continue
+		}
+		taxable := xeroNetAmount(lineAmountTypes, item)
+		amount := item.TaxAmount
+		total += amount
+
+		key := fmt.Sprintf("%s/%g", tax.Category, *tax.Percent)
+		if j, ok := index[key]; ok {
+			subtotals[j].TaxableAmount = roundAmount(subtotals[j].TaxableAmount + taxable)
+			subtotals[j].TaxAmount = roundAmount(subtotals[j].TaxAmount + amount)
+			continue
+		}
+		index[key] = len(subtotals)
+		subtotals = append(subtotals, firs_models.TaxSubtotal{
+			TaxableAmount: roundAmount(taxable),
+			TaxAmount:     roundAmount(amount),
+			TaxCategory: firs_models.TaxCategory{
+				ID:      tax.Category,
+				Percent: *tax.Percent,
+			},
+		})
+	}
+	return total, subtotals
+}
+
+// xeroCustomerParty builds the buyer from the directory entry, falling back to the Xero contact for missing contact details
+func xeroCustomerParty(contact *xero.Contact, customer *models.Customer) (firs_models.Party, []string) {
+	var warnings []string
+
+	name := customer.Name
+	email := customer.Email
+	if email == "" && contact != nil {
+		email = contact.EmailAddress
+	}
+	if email == "" {
+		warnings = append(warnings, "buyer has no email address in the customer directory or on the Xero contact")
+	}
+
+	telephone := customer.Telephone
+	if telephone == "" && contact != nil {
+		if phone := xeroPhone(contact.Phones); phone != "" {
+			telephone = utility.FormatPhone(phone)
+		}
+	}
+
+	address := &firs_models.PostalAddress{
+		StreetName: customer.StreetName,
+		CityName:   customer.CityName,
+		PostalZone: customer.PostalZone,
+		Country:    customer.Country,
+	}
+	if address.StreetName == "" && contact != nil {
+		address = xeroAddress(contact.Addresses)
+	}
+
+	party := firs_models.Party{
+		PartyName:     &name,
+		TIN:           customer.TIN,
+		Email:         email,
+		PostalAddress: address,
+	}
+	if telephone != "" {
+		party.Telephone = &telephone
+	}
+	return party, warnings
+}
+
+// xeroPhone is the default phone of the contact, then their mobile, in international form when Xero has the country code
+func xeroPhone(phones []xero.Phone) string {
+	for _, phoneType := range []string{"DEFAULT", "MOBILE"} {
+		for _, phone := range phones {
+			if phone.PhoneType != phoneType || phone.PhoneNumber == "" {
+				continue
+			}
+			number := strings.ReplaceAll(phone.PhoneAreaCode+phone.PhoneNumber, " ", "")
+			if code := strings.TrimPrefix(phone.PhoneCountryCode, "+"); code != "" {
+				return "+" + code + strings.TrimPrefix(number, "0")
+			}
+			return number
+		}
+	}
+	return ""
+}
+
+// xeroAddress maps the street address of the contact, then its postal address, returning nil when both are empty
+func xeroAddress(addresses []xero.Address) *firs_models.PostalAddress {
+	for _, addressType := range []string{"STREET", "POBOX"} {
+		for _, a := range addresses {
+			if a.AddressType != addressType {
+				continue
+			}
+			street := strings.TrimSpace(strings.Join([]string{a.AddressLine1, a.AddressLine2, a.AddressLine3, a.AddressLine4}, " "))
+			if street == "" && a.City == "" && a.PostalCode == "" && a.Country == "" {
+				continue
+			}
+			return &firs_models.PostalAddress{
+				StreetName: street,
+				CityName:   a.City,
+				PostalZone: a.PostalCode,
+				Country:    a.Country,
+			}
+		}
+	}
+	return nil
+}
+
+// xeroDate splits a Xero date string such as 2026-10-01T00:00:00 into the date and the time of day
+func xeroDate(value string) (string, string) {
+	if len(value) < 10 {
+		return "", ""
+	}
+	date, clock := value[:10], "00:00:00"
+	if len(value) >= 19 && value[10] == 'T' {
+		clock = value[11:19]
+	}
+	return date, clock
+}
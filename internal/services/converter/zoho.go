@@ -3,111 +3,386 @@ package converter
 import (
 	"einvoice-access-point/external/firs_models"
 	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/business"
+	"einvoice-access-point/pkg/models"
 	"einvoice-access-point/pkg/utility"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
-// ConvertZohoToFIRS converts a Zoho invoice to FIRS invoice format
-func ConvertZohoToFIRS(zoho zoho.Invoice, organizationID, organizationName, irn string) (firs_models.InvoiceRequest, error) {
+// UnmappedProductsError lists the line items that have no entry in the product catalogue
+type UnmappedProductsError struct {
+	ItemIDs []string
+}
+
+func (e *UnmappedProductsError) Error() string {
+	return fmt.Sprintf("line items without a product catalogue mapping: %s", strings.Join(e.ItemIDs, ", "))
+}
+
+// UnmappedTaxesError lists the platform taxes that have no FIRS tax category in the mapping and no product default
+type UnmappedTaxesError struct {
+	TaxIDs []string
+}
+
+func (e *UnmappedTaxesError) Error() string {
+	return fmt.Sprintf("taxes without a FIRS tax category mapping: %s", strings.Join(e.TaxIDs, ", "))
+}
+
+// UnverifiedCustomerError is returned when the buyer is missing from the customer directory or its TIN failed verification
+type UnverifiedCustomerError struct {
+	CustomerID string
+}
+
+func (e *UnverifiedCustomerError) Error() string {
+	return fmt.Sprintf("customer %s has no verified TIN in the customer directory", e.CustomerID)
+}
+
+// ConvertZohoToFIRS converts a Zoho invoice to FIRS invoice format, resolving each line through the product catalogue
+// and the buyer through the customer directory. The supplier party comes from the business supplier profile, and the
+// platform mapping of the business decides the tax categories, payment means and invoice type code.
+// Values that had to be adjusted to fit FIRS, such as fractional quantities, are returned as warnings.
+func ConvertZohoToFIRS(zoho zoho.Invoice, supplier *models.Business, irn string, catalogue map[string]models.Product, customer *models.Customer, mapping *models.PlatformMapping) (firs_models.InvoiceRequest, []string, error) {
+	if customer == nil || !customer.TINVerified {
+		return firs_models.InvoiceRequest{}, nil, &UnverifiedCustomerError{CustomerID: zoho.CustomerID}
+	}
+
+	// Every line must be classified before the invoice can go to FIRS
+	var unmapped []string
+	for _, item := range zoho.LineItems {
+		if _, ok := catalogue[item.ItemID]; !ok {
+			unmapped = append(unmapped, item.ItemID)
+		}
+	}
+	if len(unmapped) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedProductsError{ItemIDs: unmapped}
+	}
+
+	// Every taxed line must resolve to a FIRS tax category
+	var unmappedTaxes []string
+	seenTaxes := map[string]bool{}
+	taxes := make([]models.TaxCategoryMapping, len(zoho.LineItems))
+	for i, item := range zoho.LineItems {
+		tax, ok := lineTaxCategory(item, catalogue[item.ItemID], mapping)
+		if !ok && !seenTaxes[*item.TaxID] {
+			seenTaxes[*item.TaxID] = true
+			unmappedTaxes = append(unmappedTaxes, *item.TaxID)
+		}
+		taxes[i] = tax
+	}
+	if len(unmappedTaxes) > 0 {
+		return firs_models.InvoiceRequest{}, nil, &UnmappedTaxesError{TaxIDs: unmappedTaxes}
+	}
+
 	// Parse created time to extract issue time
 	createdTime, err := time.Parse("2006-01-02T15:04:05-0700", zoho.CreatedTime)
 	if err != nil {
-		return firs_models.InvoiceRequest{}, fmt.Errorf("failed to parse created_time: %v", err)
+		return firs_models.InvoiceRequest{}, nil, fmt.Errorf("failed to parse created_time: %v", err)
 	}
 	issueTime := createdTime.Format("15:04:05")
-	contactPhone := utility.FormatPhone(zoho.ContactPersonsDetails[0].Phone)
 	zohotatus := mapStatus(zoho.Status)
 
-	// Calculate tax totals
-	var taxTotal float64
-	var taxSubtotals []firs_models.TaxSubtotal
+	var warnings []string
+	buyer, contactWarnings := customerParty(zoho, customer)
+	warnings = append(warnings, contactWarnings...)
+
+	// Map line items
+	var lines []firs_models.InvoiceLine
+	var lineTotal float64
 	for _, item := range zoho.LineItems {
-		if item.TaxPercentage > 0 {
-			taxAmount := item.ItemTotal * (item.TaxPercentage / 100)
-			taxTotal += taxAmount
-			taxSubtotals = append(taxSubtotals, firs_models.TaxSubtotal{
-				TaxableAmount: item.ItemTotal,
-				TaxAmount:     taxAmount,
-				TaxCategory: firs_models.TaxCategory{
-					ID:      *item.TaxID,
-					Percent: item.TaxPercentage,
-				},
-			})
-		}
+		line, lineWarnings := invoiceLine(zoho.CurrencyCode, item, catalogue[item.ItemID])
+		lines = append(lines, line)
+		warnings = append(warnings, lineWarnings...)
+		lineTotal += item.ItemTotal
+	}
+
+	// Invoice level discounts, shipping and adjustments
+	discount := invoiceDiscount(zoho, lineTotal)
+	var allowanceCharges []firs_models.AllowanceCharge
+	if discount > 0 {
+		allowanceCharges = append(allowanceCharges, firs_models.AllowanceCharge{ChargeIndicator: false, Amount: roundAmount(discount)})
+	}
+	if zoho.ShippingCharge > 0 {
+		allowanceCharges = append(allowanceCharges, firs_models.AllowanceCharge{ChargeIndicator: true, Amount: roundAmount(zoho.ShippingCharge)})
+	}
+	if zoho.Adjustment != 0 {
+		allowanceCharges = append(allowanceCharges, firs_models.AllowanceCharge{ChargeIndicator: zoho.Adjustment > 0, Amount: roundAmount(math.Abs(zoho.Adjustment))})
+	}
+
+	// A discount taken before tax shrinks the taxable amount of every line in proportion
+	taxableShare := 1.0
+	if discount > 0 && zoho.IsDiscountBeforeTax && lineTotal > 0 {
+		taxableShare = (lineTotal - discount) / lineTotal
+	}
+	taxTotal, taxSubtotals := taxSubtotals(zoho.LineItems, taxes, taxableShare)
+
+	taxExclusive := lineTotal + zoho.ShippingCharge
+	if zoho.IsDiscountBeforeTax {
+		taxExclusive -= discount
+	}
+	taxInclusive := taxExclusive + taxTotal
+	payable := taxInclusive + zoho.Adjustment
+	if !zoho.IsDiscountBeforeTax {
+		payable -= discount
+	}
+	if !amountsEqual(payable, zoho.Total) {
+		warnings = append(warnings, fmt.Sprintf("computed payable amount %.2f differs from the Zoho total %.2f, the Zoho total was used", payable, zoho.Total))
 	}
 
 	// Map Zoho invoice to FIRS invoice
 	firsInvoice := firs_models.InvoiceRequest{
-		BusinessID:           organizationID, // Using organization_id as business_id
-		IRN:                  &irn,
-		IssueDate:            zoho.Date,
-		DueDate:              &zoho.DueDate,
-		IssueTime:            &issueTime,
-		InvoiceTypeCode:      "381", // Default invoice type code
-		PaymentStatus:        &zohotatus,
-		Note:                 &zoho.Notes,
-		TaxPointDate:         &zoho.Date, // Using invoice_date as tax_point_date
-		DocumentCurrencyCode: zoho.CurrencyCode,
-		TaxCurrencyCode:      &zoho.CurrencyCode,
-		AccountingSupplierParty: firs_models.Party{
-			PartyName: &organizationName,
-			TIN:       "TIN-UNKNOWN",          // Placeholder, as TIN is not provided in Zoho data
-			Email:     "supplier@example.com", // Placeholder, as supplier email is not provided                  // Optional, not provided in Zoho data
-			PostalAddress: &firs_models.PostalAddress{
-				StreetName: "test adress", // Not provided in Zoho data
-				CityName:   "amac",
-				PostalZone: "19001",
-				Country:    "NG",
-			},
-		},
-		AccountingCustomerParty: firs_models.Party{
-			PartyName: &zoho.CustomerName,
-			TIN:       "TIN-" + zoho.CustomerID, // Using customer_id as part of TIN
-			Email:     zoho.Email,
-			Telephone: &contactPhone,
-			PostalAddress: &firs_models.PostalAddress{
-				StreetName: zoho.BillingAddress.Address,
-				CityName:   zoho.BillingAddress.City,
-				PostalZone: zoho.BillingAddress.Zip,
-				Country:    zoho.BillingAddress.CountryCode,
-			},
-		},
-		PaymentTermsNote: &zoho.Terms,
+		BusinessID:              supplier.BusinessID,
+		IRN:                     &irn,
+		IssueDate:               zoho.Date,
+		DueDate:                 &zoho.DueDate,
+		IssueTime:               &issueTime,
+		InvoiceTypeCode:         mapping.DocumentTypeCode(),
+		PaymentStatus:           &zohotatus,
+		Note:                    &zoho.Notes,
+		TaxPointDate:            &zoho.Date, // Using invoice_date as tax_point_date
+		DocumentCurrencyCode:    zoho.CurrencyCode,
+		TaxCurrencyCode:         &zoho.CurrencyCode,
+		AccountingCustomerParty: buyer,
+		DeliveryAddress:         postalAddress(zoho.ShippingAddress),
+		PaymentTermsNote:        &zoho.Terms,
+		AllowanceCharge:         allowanceCharges,
 		TaxTotal: []firs_models.TaxTotal{
 			{
-				TaxAmount:   taxTotal,
+				TaxAmount:   roundAmount(taxTotal),
 				TaxSubtotal: taxSubtotals,
 			},
 		},
 		LegalMonetaryTotal: firs_models.LegalMonetaryTotal{
-			LineExtensionAmount: zoho.Total - taxTotal,
-			TaxExclusiveAmount:  zoho.Total - taxTotal,
-			TaxInclusiveAmount:  zoho.Total,
+			LineExtensionAmount: roundAmount(lineTotal),
+			TaxExclusiveAmount:  roundAmount(taxExclusive),
+			TaxInclusiveAmount:  roundAmount(taxInclusive),
 			PayableAmount:       zoho.Total,
 		},
+		InvoiceLine: lines,
 	}
 
-	// Map line items
-	for _, item := range zoho.LineItems {
-		firsInvoice.InvoiceLine = append(firsInvoice.InvoiceLine, firs_models.InvoiceLine{
-			HSNCode:             item.ItemID, // Using item_id as HSN code
-			ProductCategory:     "General",   // Placeholder, as category is not provided
-			InvoicedQuantity:    int(item.Quantity),
-			LineExtensionAmount: item.ItemTotal,
-			Item: firs_models.Item{
-				Name:        item.Name,
-				Description: item.Description,
-			},
-			Price: firs_models.Price{
-				PriceAmount:  item.Rate,
-				BaseQuantity: int(item.Quantity),
-				PriceUnit:    zoho.CurrencyCode + " per 1",
+	if code, ok := mapping.PaymentMeansCode(zoho.PaymentTermsLabel); ok {
+		firsInvoice.PaymentMeans = []firs_models.PaymentMeans{{PaymentMeansCode: code, PaymentDueDate: zoho.DueDate}}
+	}
+
+	if err := business.ApplySupplierProfile(&firsInvoice, supplier); err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	return firsInvoice, warnings, nil
+}
+
+// invoiceLine maps a Zoho line item. The price is quoted per single unit and the line discount is reported
+// both as an amount and as a rate of the undiscounted line.
+func invoiceLine(currency string, item zoho.LineItem, product models.Product) (firs_models.InvoiceLine, []string) {
+	var warnings []string
+
+	quantity := int(math.Round(item.Quantity))
+	if quantity < 1 {
+		quantity = 1
+	}
+	if float64(quantity) != item.Quantity {
+		warnings = append(warnings, fmt.Sprintf("line %s quantity %g was rounded to %d", item.Name, item.Quantity, quantity))
+	}
+
+	gross := item.Rate * item.Quantity
+	discountAmount := item.DiscountAmount
+	if discountAmount == 0 {
+		discountAmount = item.Discount.Amount(gross)
+	}
+	if discountAmount == 0 && gross-item.ItemTotal > 0.005 {
+		discountAmount = gross - item.ItemTotal
+	}
+	var discountRate float64
+	switch {
+	case item.Discount.Percent:
+		discountRate = item.Discount.Value
+	case gross > 0:
+		discountRate = discountAmount / gross * 100
+	}
+
+	priceUnit := currency + " per 1"
+	if product.UnitOfMeasure != "" {
+		priceUnit = currency + " per " + product.UnitOfMeasure
+	}
+
+	return firs_models.InvoiceLine{
+		HSNCode:             product.HSCode,
+		ProductCategory:     product.ProductCategory,
+		DiscountAmount:      roundAmount(discountAmount),
+		DiscountRate:        roundAmount(discountRate),
+		InvoicedQuantity:    quantity,
+		LineExtensionAmount: item.ItemTotal,
+		Item: firs_models.Item{
+			Name:        item.Name,
+			Description: item.Description,
+		},
+		Price: firs_models.Price{
+			PriceAmount:  item.Rate,
+			BaseQuantity: 1,
+			PriceUnit:    priceUnit,
+		},
+	}, warnings
+}
+
+// invoiceDiscount is the invoice level discount. Item level discounts are already taken off the line totals.
+func invoiceDiscount(zoho zoho.Invoice, lineTotal float64) float64 {
+	if zoho.DiscountType == "item_level" {
+		return 0
+	}
+	if zoho.DiscountAmount > 0 {
+		return zoho.DiscountAmount
+	}
+	return zoho.Discount.Amount(lineTotal)
+}
+
+// taxSubtotals groups the taxed lines by tax category and rate
+func taxSubtotals(items []zoho.LineItem, taxes []models.TaxCategoryMapping, taxableShare float64) (float64, []firs_models.TaxSubtotal) {
+	var total float64
+	var subtotals []firs_models.TaxSubtotal
+	index := map[string]int{}
+
+	for i, item := range items {
+		tax := taxes[i]
+		if tax.Percent == nil || *tax.Percent <= 0 {
+			continue
+		}
+		taxable := item.ItemTotal * taxableShare
+		amount := taxable * (*tax.Percent / 100)
+		total += amount
+
+		key := fmt.Sprintf("%s/%g", tax.Category, *tax.Percent)
+		if j, ok := index[key]; ok {
+			subtotals[j].TaxableAmount = roundAmount(subtotals[j].TaxableAmount + taxable)
+			subtotals[j].TaxAmount = roundAmount(subtotals[j].TaxAmount + amount)
+			continue
+		}
+		index[key] = len(subtotals)
+		subtotals = append(subtotals, firs_models.TaxSubtotal{
+			TaxableAmount: roundAmount(taxable),
+			TaxAmount:     roundAmount(amount),
+			TaxCategory: firs_models.TaxCategory{
+				ID:      tax.Category,
+				Percent: *tax.Percent,
 			},
 		})
 	}
+	return total, subtotals
+}
 
-	return firsInvoice, nil
+// customerParty builds the buyer from the directory entry, falling back to the Zoho invoice and its primary contact
+// person for missing contact details
+func customerParty(zoho zoho.Invoice, customer *models.Customer) (firs_models.Party, []string) {
+	var warnings []string
+	contact := primaryContact(zoho.ContactPersonsDetails)
+
+	name := customer.Name
+	email := customer.Email
+	if email == "" {
+		email = zoho.Email
+	}
+	if email == "" && contact != nil {
+		email = contact.Email
+	}
+	if email == "" {
+		warnings = append(warnings, "buyer has no email address in the customer directory or on the Zoho invoice")
+	}
+
+	telephone := customer.Telephone
+	if telephone == "" {
+		if phone := zohoPhone(zoho, contact); phone != "" {
+			telephone = utility.FormatPhone(phone)
+		}
+	}
+
+	address := &firs_models.PostalAddress{
+		StreetName: customer.StreetName,
+		CityName:   customer.CityName,
+		PostalZone: customer.PostalZone,
+		Country:    customer.Country,
+	}
+	if address.StreetName == "" {
+		address = postalAddress(zoho.BillingAddress)
+	}
+
+	party := firs_models.Party{
+		PartyName:     &name,
+		TIN:           customer.TIN,
+		Email:         email,
+		PostalAddress: address,
+	}
+	if telephone != "" {
+		party.Telephone = &telephone
+	}
+	return party, warnings
+}
+
+// primaryContact returns the contact person marked primary, or the first one, or nil when the invoice has none
+func primaryContact(persons []zoho.ContactPerson) *zoho.ContactPerson {
+	for i := range persons {
+		if persons[i].IsPrimaryContact {
+			return &persons[i]
+		}
+	}
+	if len(persons) > 0 {
+		return &persons[0]
+	}
+	return nil
+}
+
+// zohoPhone is the phone of the contact person, then their mobile, then the phone on the billing address
+func zohoPhone(zoho zoho.Invoice, contact *zoho.ContactPerson) string {
+	if contact != nil && contact.Phone != "" {
+		return contact.Phone
+	}
+	if contact != nil && contact.Mobile != "" {
+		return contact.Mobile
+	}
+	return zoho.BillingAddress.Phone
+}
+
+// postalAddress maps a Zoho address, returning nil for an empty one
+func postalAddress(a zoho.Address) *firs_models.PostalAddress {
+	street := strings.TrimSpace(strings.Join([]string{a.Address, a.Street2}, " "))
+	if street == "" && a.City == "" && a.Zip == "" && a.CountryCode == "" {
+		return nil
+	}
+	return &firs_models.PostalAddress{
+		StreetName: street,
+		CityName:   a.City,
+		PostalZone: a.Zip,
+		Country:    a.CountryCode,
+	}
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// lineTaxCategory resolves the FIRS tax category of a line. The tax applied in Zoho is looked up in the mapping,
+// keeping the Zoho rate unless the mapping overrides it; otherwise the product's default tax category is used.
+// It reports false for a line taxed in Zoho that neither the mapping nor the product can categorise.
+func lineTaxCategory(item zoho.LineItem, product models.Product, mapping *models.PlatformMapping) (models.TaxCategoryMapping, bool) {
+	taxed := item.TaxPercentage > 0 && item.TaxID != nil && *item.TaxID != ""
+	if taxed {
+		if tax, ok := mapping.TaxCategory(*item.TaxID); ok {
+			if tax.Percent == nil {
+				percent := item.TaxPercentage
+				tax.Percent = &percent
+			}
+			return tax, true
+		}
+	}
+
+	if product.TaxCategory != "" {
+		percent := product.TaxPercent
+		if taxed {
+			percent = item.TaxPercentage
+		}
+		return models.TaxCategoryMapping{Category: product.TaxCategory, Percent: &percent}, true
+	}
+	return models.TaxCategoryMapping{}, !taxed
 }
 
 // mapStatus maps Zoho invoice status to FIRS payment status
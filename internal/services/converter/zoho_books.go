@@ -0,0 +1,29 @@
+package converter
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/pkg/models"
+)
+
+// ConvertZohoBooksToFIRS converts a Zoho Books invoice. Lines, parties, discounts and charges are mapped as for
+// Zoho Invoice; the tax total comes from the tax_total Books computes, which already accounts for tax-inclusive
+// pricing, and the Books order number becomes the order reference.
+func ConvertZohoBooksToFIRS(books zoho.Invoice, supplier *models.Business, irn string, catalogue map[string]models.Product, customer *models.Customer, mapping *models.PlatformMapping) (firs_models.InvoiceRequest, []string, error) {
+	firsInvoice, warnings, err := ConvertZohoToFIRS(books, supplier, irn, catalogue, customer, mapping)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	if books.ReferenceNumber != "" {
+		reference := books.ReferenceNumber
+		firsInvoice.OrderReference = &reference
+	}
+
+	if books.TaxTotal > 0 && len(firsInvoice.TaxTotal) > 0 {
+		firsInvoice.TaxTotal[0].TaxAmount = books.TaxTotal
+		firsInvoice.LegalMonetaryTotal.TaxInclusiveAmount = roundAmount(firsInvoice.LegalMonetaryTotal.TaxExclusiveAmount + books.TaxTotal)
+	}
+
+	return firsInvoice, warnings, nil
+}
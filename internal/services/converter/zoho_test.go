@@ -0,0 +1,145 @@
+package converter
+
+import (
+	"bytes"
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/pkg/models"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the converter output")
+
+// zohoGolden is the expected conversion of a Zoho invoice
+type zohoGolden struct {
+	Invoice  firs_models.InvoiceRequest `json:"invoice"`
+	Warnings []string                   `json:"warnings"`
+}
+
+// TestConvertZohoToFIRSGolden converts each Zoho invoice in testdata/zoho and compares the result with the golden
+// file next to it. Run with -update to rewrite the golden files after an intended change.
+func TestConvertZohoToFIRSGolden(t *testing.T) {
+	payloads, err := filepath.Glob(filepath.Join("testdata", "zoho", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vat := 7.5
+	supplier := &models.Business{
+		BusinessID:  "8a8e2f7c-3c1d-4a5e-9d2b-6f1e0c4b7a21",
+		CompanyName: "Acme Supplies Ltd",
+		TIN:         "01234567-0001",
+		PhoneNumber: "08031234567",
+		SupplierProfile: models.SupplierProfile{
+			Email:            "billing@acme.ng",
+			StreetName:       "12 Marina Road",
+			CityName:         "Lagos",
+			Country:          "NG",
+			PaymentMeansCode: "30",
+		},
+	}
+	catalogue := map[string]models.Product{
+		"460000000038001": {HSCode: "8471.30", ProductCategory: "Computers", UnitOfMeasure: "unit", TaxCategory: "STANDARD_VAT", TaxPercent: vat},
+		"460000000038013": {HSCode: "9983.13", ProductCategory: "IT services", TaxCategory: "STANDARD_VAT", TaxPercent: vat},
+		"460000000038025": {HSCode: "4802.56", ProductCategory: "Paper", UnitOfMeasure: "ream", TaxCategory: "ZERO_VAT"},
+	}
+	// The directory entry only holds the verified TIN, so the contact details come from the Zoho invoice
+	customer := &models.Customer{Name: "Globex Nigeria Ltd", TIN: "98765432-0001", TINVerified: true}
+	mapping := &models.PlatformMapping{
+		TaxCategories: models.TaxMappings{"460000000054003": {Category: "STANDARD_VAT"}},
+		PaymentMeans:  models.PaymentMeansMappings{"Net 30": "42"},
+	}
+
+	for _, payload := range payloads {
+		if strings.HasSuffix(payload, ".golden.json") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(payload), ".json")
+
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var invoice zoho.Invoice
+			if err := json.Unmarshal(data, &invoice); err != nil {
+				t.Fatalf("failed to decode %s: %v", payload, err)
+			}
+
+			firsInvoice, warnings, err := ConvertZohoToFIRS(invoice, supplier, "INV"+invoice.InvoiceNumber+"-94ND90NR-20250301", catalogue, customer, mapping)
+			if err != nil {
+				t.Fatalf("ConvertZohoToFIRS() error = %v", err)
+			}
+
+			got, err := json.MarshalIndent(zohoGolden{Invoice: firsInvoice, Warnings: warnings}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "zoho", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read the golden file, run the test with -update to create it: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("conversion of %s differs from %s:\n%s", payload, golden, got)
+			}
+		})
+	}
+}
+
+func TestConvertZohoToFIRSUnmappedTax(t *testing.T) {
+	taxID := "460000000054099"
+	invoice := zoho.Invoice{
+		CustomerID: "460000000026049",
+		LineItems:  []zoho.LineItem{{ItemID: "460000000038099", TaxID: &taxID, TaxPercentage: 5}},
+	}
+	catalogue := map[string]models.Product{"460000000038099": {HSCode: "9983.13", ProductCategory: "IT services"}}
+	customer := &models.Customer{TIN: "98765432-0001", TINVerified: true}
+
+	_, _, err := ConvertZohoToFIRS(invoice, &models.Business{}, "", catalogue, customer, nil)
+	unmapped, ok := err.(*UnmappedTaxesError)
+	if !ok || len(unmapped.TaxIDs) != 1 || unmapped.TaxIDs[0] != taxID {
+		t.Errorf("ConvertZohoToFIRS() error = %v, want the unmapped tax %s", err, taxID)
+	}
+}
+
+// TestConvertZohoToFIRSDefaultTypeCode pins the type code of invoices without a mapping, every existing Zoho
+// invoice is sent to FIRS with it
+func TestConvertZohoToFIRSDefaultTypeCode(t *testing.T) {
+	invoice := zoho.Invoice{
+		CustomerID:  "460000000026049",
+		Date:        "2025-03-05",
+		DueDate:     "2025-03-20",
+		CreatedTime: "2025-03-05T16:45:00+0100",
+		LineItems:   []zoho.LineItem{{ItemID: "460000000038013", Rate: 100, Quantity: 1, ItemTotal: 100}},
+	}
+	catalogue := map[string]models.Product{"460000000038013": {HSCode: "9983.13", ProductCategory: "IT services", TaxCategory: "ZERO_VAT"}}
+	customer := &models.Customer{TIN: "98765432-0001", TINVerified: true}
+	supplier := &models.Business{
+		CompanyName:     "Acme Supplies Ltd",
+		TIN:             "01234567-0001",
+		SupplierProfile: models.SupplierProfile{StreetName: "12 Marina Road", CityName: "Lagos", Country: "NG"},
+	}
+
+	for _, mapping := range []*models.PlatformMapping{nil, {}} {
+		firsInvoice, _, err := ConvertZohoToFIRS(invoice, supplier, "", catalogue, customer, mapping)
+		if err != nil {
+			t.Fatalf("ConvertZohoToFIRS() error = %v", err)
+		}
+		if firsInvoice.InvoiceTypeCode != "381" {
+			t.Errorf("InvoiceTypeCode = %q, want the default 381", firsInvoice.InvoiceTypeCode)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package customer
+
+import (
+	repository "einvoice-access-point/internal/repository/customer"
+	"einvoice-access-point/internal/services/entity"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// TINReverificationAge is how long a TIN check stays valid before the customer is checked again
+	TINReverificationAge = 30 * 24 * time.Hour
+	// TINReverificationInterval is how often the background job looks for new and stale customers
+	TINReverificationInterval = 10 * time.Minute
+	// TINRetryAge is how long a customer whose check could not be completed waits before it is checked again
+	TINRetryAge = 24 * time.Hour
+
+	reverificationBatchSize = 100
+)
+
+var ErrCustomerNotFound = errors.New("customer not found")
+
+func GetCustomers(db *gorm.DB, businessID, platform string) ([]models.Customer, error) {
+	pdb := inst.InitDB(db, true)
+	return repository.FindCustomersByBusiness(pdb, businessID, platform)
+}
+
+// ImportCustomers upserts the customers into the business directory. Their TINs are checked with FIRS by the
+// reverification job, which takes new customers and customers whose TIN changed first.
+func ImportCustomers(db *gorm.DB, businessID string, req []models.CustomerRequest) ([]models.Customer, error) {
+	pdb := inst.InitDB(db, true)
+
+	customers := make([]models.Customer, 0, len(req))
+	for _, c := range req {
+		customer := models.Customer{
+			BusinessID:         businessID,
+			Platform:           strings.ToLower(c.Platform),
+			PlatformCustomerID: c.PlatformCustomerID,
+			Name:               c.Name,
+			TIN:                strings.TrimSpace(c.TIN),
+			Email:              c.Email,
+			Telephone:          utility.FormatPhone(c.Telephone),
+			StreetName:         c.StreetName,
+			CityName:           c.CityName,
+			PostalZone:         c.PostalZone,
+			Country:            strings.ToUpper(c.Country),
+			PeppolEndpoint:     c.PeppolEndpoint,
+		}
+		customers = append(customers, customer)
+	}
+
+	if err := repository.UpsertCustomers(pdb, customers); err != nil {
+		return nil, fmt.Errorf("failed to import customers: %w", err)
+	}
+
+	// Read the customers back so existing ones are returned with their verification
+	return repository.FindCustomersByPlatformIDs(pdb, businessID, customers)
+}
+
+// VerifyCustomer re-runs the TIN check of a single customer
+func VerifyCustomer(db *gorm.DB, businessID, customerID string) (*models.Customer, error) {
+	pdb := inst.InitDB(db, true)
+
+	customer, err := repository.FindCustomer(pdb, businessID, customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+
+	verifyTIN(customer)
+	if err := repository.UpdateVerification(pdb, customer); err != nil {
+		return nil, fmt.Errorf("failed to save verification: %w", err)
+	}
+
+	return customer, nil
+}
+
+func DeleteCustomer(db *gorm.DB, businessID, customerID string) error {
+	pdb := inst.InitDB(db, true)
+
+	affected, err := repository.DeleteCustomer(pdb, businessID, customerID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCustomerNotFound
+	}
+	return nil
+}
+
+// FindCustomer looks up a customer by its platform ID, returning nil when it is not in the directory
+func FindCustomer(db *gorm.DB, businessID, platform, platformCustomerID string) (*models.Customer, error) {
+	pdb := inst.InitDB(db, true)
+
+	customer, err := repository.FindCustomerByPlatformID(pdb, businessID, platform, platformCustomerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return customer, nil
+}
+
+// ReverifyCustomers re-checks a batch of customers whose TIN verification is older than TINReverificationAge
+func ReverifyCustomers(db *gorm.DB) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	now := time.Now()
+	customers, err := repository.FindCustomersDueForVerification(pdb, now.Add(-TINReverificationAge), now.Add(-TINRetryAge), reverificationBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range customers {
+		verifyTIN(&customers[i])
+		if err := repository.UpdateVerification(pdb, &customers[i]); err != nil {
+			return i, fmt.Errorf("failed to save verification of customer %s: %w", customers[i].ID, err)
+		}
+	}
+
+	return len(customers), nil
+}
+
+// StartTINReverification runs ReverifyCustomers every TINReverificationInterval until the process exits
+func StartTINReverification(db *gorm.DB, logger *utility.Logger) {
+	go func() {
+		ticker := time.NewTicker(TINReverificationInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ReverifyCustomers(db)
+			if err != nil {
+				logger.Error("customer tin reverification failed", err)
+				continue
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("reverified tin of %d customers", count))
+			}
+		}
+	}()
+}
+
+// verifyTIN checks the customer TIN with FIRS and records the outcome on the customer.
+// A failed check leaves the verification time untouched so the customer is retried after TINRetryAge. Only a
+// rejection of the TIN by FIRS unverifies the customer; a check FIRS could not complete keeps the last outcome.
+func verifyTIN(customer *models.Customer) {
+	now := time.Now()
+	customer.LastAttemptAt = &now
+
+	_, errDetails, err := entity.VerifyTin(customer.TIN)
+	if err != nil {
+		if errors.Is(err, entity.ErrTINRejected) {
+			customer.TINVerified = false
+		}
+		customer.VerificationError = err.Error()
+		if errDetails != nil && *errDetails != "" {
+			customer.VerificationError = *errDetails
+		}
+		return
+	}
+
+	customer.TINVerified = true
+	customer.TINVerifiedAt = &now
+	customer.VerificationError = ""
+}
+
+// ParseCustomersCSV reads a customer CSV whose header row uses the customer request field names
+func ParseCustomersCSV(r io.Reader) ([]models.CustomerRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"platform", "platform_customer_id", "name", "tin"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %s", required)
+		}
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var customers []models.CustomerRequest
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		customers = append(customers, models.CustomerRequest{
+			Platform:           value(record, "platform"),
+			PlatformCustomerID: value(record, "platform_customer_id"),
+			Name:               value(record, "name"),
+			TIN:                value(record, "tin"),
+			Email:              value(record, "email"),
+			Telephone:          value(record, "telephone"),
+			StreetName:         value(record, "street_name"),
+			CityName:           value(record, "city_name"),
+			PostalZone:         value(record, "postal_zone"),
+			Country:            value(record, "country"),
+			PeppolEndpoint:     value(record, "peppol_endpoint"),
+		})
+	}
+
+	return customers, nil
+}
@@ -0,0 +1,64 @@
+package customer
+
+import (
+	"einvoice-access-point/pkg/config"
+	"einvoice-access-point/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// firsStandIn answers TIN checks of the FIRS API with the status and body given
+func firsStandIn(t *testing.T, status int, body string) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/utilities/verify-tin/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	previous := config.Config
+	config.Config = &config.Configuration{Firs: config.FIRS{FirsApiUrl: server.URL + "/api/v1"}}
+	t.Cleanup(func() { config.Config = previous })
+}
+
+func TestVerifyTIN(t *testing.T) {
+	verifiedAt := time.Now().Add(-45 * 24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantVerified bool
+		wantError    string
+	}{
+		{"verified", http.StatusOK, `{"code":200,"data":{"tin":"01234567-0001"}}`, true, ""},
+		{"rejected", http.StatusNotFound, `{"code":404,"message":"TIN not found"}`, false, "tin was rejected by FIRS: api error 404: TIN not found"},
+		{"outage", http.StatusServiceUnavailable, `{"code":503,"message":"Service unavailable"}`, true, "failed to parse FIRS API response: api error 503: Service unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firsStandIn(t, tt.status, tt.body)
+			customer := models.Customer{TIN: "01234567-0001", TINVerified: true, TINVerifiedAt: &verifiedAt}
+
+			verifyTIN(&customer)
+
+			if customer.TINVerified != tt.wantVerified || customer.VerificationError != tt.wantError {
+				t.Errorf("verified = %v error %q, want %v error %q", customer.TINVerified, customer.VerificationError, tt.wantVerified, tt.wantError)
+			}
+			if customer.LastAttemptAt == nil || time.Since(*customer.LastAttemptAt) > time.Minute {
+				t.Errorf("last attempt = %v, want the time of the check", customer.LastAttemptAt)
+			}
+			if tt.wantError != "" && !customer.TINVerifiedAt.Equal(verifiedAt) {
+				t.Errorf("verified at = %v, want the last successful check kept", customer.TINVerifiedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package delivery
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/mail"
+	businessRepository "einvoice-access-point/internal/repository/business"
+	repository "einvoice-access-point/internal/repository/delivery"
+	"einvoice-access-point/internal/services/invoice"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxDeliveryAttempts is the number of sends tried before a delivery is marked failed
+	MaxDeliveryAttempts = 5
+	// DeliveryInterval is how often the worker looks for due deliveries
+	DeliveryInterval = time.Minute
+
+	deliveryBatchSize = 20
+	deliveryLease     = 10 * time.Minute
+)
+
+// deliveryBackoff is the wait before each retry, indexed by the number of failed attempts
+var deliveryBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+var ErrDeliveryNotFound = errors.New("invoice has no delivery")
+
+func GetDelivery(db *gorm.DB, businessID, invoiceID string) (*models.InvoiceDelivery, error) {
+	pdb := inst.InitDB(db, true)
+
+	delivery, err := repository.FindDeliveryByInvoice(pdb, businessID, invoiceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// ResendDelivery queues the invoice email again, optionally to a different recipient
+func ResendDelivery(db *gorm.DB, businessID, invoiceID, recipient string) (*models.InvoiceDelivery, error) {
+	pdb := inst.InitDB(db, true)
+
+	delivery, err := GetDelivery(db, businessID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if recipient != "" {
+		delivery.Recipient = recipient
+	}
+	if delivery.Recipient == "" {
+		return nil, errors.New("buyer has no email address, provide a recipient")
+	}
+
+	now := time.Now()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = &now
+	delivery.BouncedAt = nil
+	if err := repository.UpdateDelivery(pdb, delivery); err != nil {
+		return nil, fmt.Errorf("failed to queue delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// ProcessDueDeliveries sends every delivery whose next attempt is due and returns how many were sent
+func ProcessDueDeliveries(db *gorm.DB, keys *utility.CryptoKeys, sender mail.Sender) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	deliveries, err := repository.FindDueDeliveries(pdb, time.Now(), deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		claimed, err := repository.ClaimDelivery(pdb, delivery, time.Now().Add(deliveryLease))
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		messageID, sendErr := send(db, keys, sender, delivery)
+		recordAttempt(delivery, messageID, sendErr)
+		if err := repository.UpdateDelivery(pdb, delivery); err != nil {
+			return sent, fmt.Errorf("failed to save delivery %s: %w", delivery.ID, err)
+		}
+		if delivery.Status == models.DeliverySent {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// StartDeliveryWorker runs ProcessDueDeliveries every DeliveryInterval until the process exits.
+// Nothing is started when no mail server is configured.
+func StartDeliveryWorker(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger) {
+	sender, err := mail.DefaultSender()
+	if err != nil {
+		logger.Warning("invoice delivery worker not started", err)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(DeliveryInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ProcessDueDeliveries(db, keys, sender)
+			if err != nil {
+				logger.Error("invoice delivery failed", err)
+				continue
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("delivered %d invoices to buyers", count))
+			}
+		}
+	}()
+}
+
+// recordAttempt stores the outcome of a send. Permanent SMTP rejections are recorded as bounces and
+// not retried; other failures are retried with backoff until MaxDeliveryAttempts is reached.
+func recordAttempt(delivery *models.InvoiceDelivery, messageID string, err error) {
+	now := time.Now()
+	delivery.Attempts++
+
+	if err == nil {
+		delivery.Status = models.DeliverySent
+		delivery.MessageID = messageID
+		delivery.LastError = ""
+		delivery.SentAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	delivery.LastError = err.Error()
+
+	var smtpErr *mail.SMTPError
+	if errors.As(err, &smtpErr) && smtpErr.Permanent() {
+		delivery.Status = models.DeliveryBounced
+		delivery.BouncedAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	if delivery.Attempts >= MaxDeliveryAttempts {
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	backoff := deliveryBackoff[len(deliveryBackoff)-1]
+	if delivery.Attempts <= len(deliveryBackoff) {
+		backoff = deliveryBackoff[delivery.Attempts-1]
+	}
+	next := now.Add(backoff)
+	delivery.Status = models.DeliveryPending
+	delivery.NextAttemptAt = &next
+}
+
+// send renders the signed invoice PDF, the QR image and the email body and hands them to the sender
+func send(db *gorm.DB, keys *utility.CryptoKeys, sender mail.Sender, delivery *models.InvoiceDelivery) (string, error) {
+	var doc firs_models.InvoiceRequest
+	if err := json.Unmarshal(delivery.Document, &doc); err != nil {
+		return "", fmt.Errorf("failed to decode invoice document: %w", err)
+	}
+	if doc.IRN == nil || *doc.IRN == "" {
+		return "", errors.New("invoice document has no IRN")
+	}
+
+	qrPNG, _, err := invoice.GetInvoiceQRCode(db, keys, delivery.BusinessID, *doc.IRN, invoice.DefaultQROptions())
+	if err != nil {
+		return "", fmt.Errorf("failed to render qr code: %w", err)
+	}
+
+	data := newEmailData(doc, supplierName(db, delivery.BusinessID, doc))
+	subject, text, html, err := renderEmail(data)
+	if err != nil {
+		return "", err
+	}
+	pdf, err := renderPDF(doc, data, qrPNG)
+	if err != nil {
+		return "", err
+	}
+
+	return sender.Send(mail.Message{
+		To:       delivery.Recipient,
+		Subject:  subject,
+		TextBody: text,
+		HTMLBody: html,
+		Attachments: []mail.Attachment{
+			{Filename: fmt.Sprintf("invoice-%s.pdf", doc.InvoiceNumber), ContentType: "application/pdf", Data: pdf},
+			{Filename: fmt.Sprintf("invoice-%s-qr.png", doc.InvoiceNumber), ContentType: "image/png", Data: qrPNG},
+		},
+	})
+}
+
+func supplierName(db *gorm.DB, businessID string, doc firs_models.InvoiceRequest) string {
+	if name := doc.AccountingSupplierParty.PartyName; name != nil && *name != "" {
+		return *name
+	}
+
+	business, err := businessRepository.FindBusinessByID(inst.InitDB(db, true), businessID)
+	if err != nil {
+		return "Your supplier"
+	}
+	if business.CompanyName != "" {
+		return business.CompanyName
+	}
+	return business.Name
+}
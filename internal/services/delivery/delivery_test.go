@@ -0,0 +1,74 @@
+package delivery
+
+import (
+	"einvoice-access-point/external/mail"
+	"einvoice-access-point/external/mail/mailtest"
+	"einvoice-access-point/pkg/models"
+	"testing"
+	"time"
+)
+
+func TestRecordAttempt(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	defer server.Close()
+
+	server.Reject("gone@example.com", "550 5.1.1 user unknown")
+	server.Reject("busy@example.com", "452 4.2.2 mailbox full")
+
+	sender := &mail.SMTPSender{Host: server.Host, Port: server.Port, From: "invoices@example.com", Timeout: 5 * time.Second}
+	send := func(to string) (string, error) {
+		return sender.Send(mail.Message{To: to, Subject: "Invoice", TextBody: "Invoice"})
+	}
+
+	t.Run("sent", func(t *testing.T) {
+		delivery := &models.InvoiceDelivery{Status: models.DeliveryPending, LastError: "smtp 452: mailbox full"}
+		messageID, err := send("buyer@example.com")
+		recordAttempt(delivery, messageID, err)
+
+		if delivery.Status != models.DeliverySent || delivery.MessageID == "" || delivery.SentAt == nil {
+			t.Errorf("delivery = %+v, want sent with a message id", delivery)
+		}
+		if delivery.LastError != "" || delivery.NextAttemptAt != nil {
+			t.Errorf("delivery = %+v, want no error and no retry", delivery)
+		}
+	})
+
+	t.Run("bounced", func(t *testing.T) {
+		delivery := &models.InvoiceDelivery{Status: models.DeliveryPending}
+		messageID, err := send("gone@example.com")
+		recordAttempt(delivery, messageID, err)
+
+		if delivery.Status != models.DeliveryBounced || delivery.BouncedAt == nil || delivery.NextAttemptAt != nil {
+			t.Errorf("delivery = %+v, want bounced without a retry", delivery)
+		}
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		delivery := &models.InvoiceDelivery{Status: models.DeliveryPending}
+		for attempt := 1; attempt < MaxDeliveryAttempts; attempt++ {
+			before := time.Now()
+			messageID, err := send("busy@example.com")
+			recordAttempt(delivery, messageID, err)
+
+			if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt == nil {
+				t.Fatalf("attempt %d: delivery = %+v, want pending with a retry", attempt, delivery)
+			}
+			backoff := deliveryBackoff[len(deliveryBackoff)-1]
+			if attempt <= len(deliveryBackoff) {
+				backoff = deliveryBackoff[attempt-1]
+			}
+			if delivery.NextAttemptAt.Before(before.Add(backoff)) {
+				t.Errorf("attempt %d: next attempt at %v, want at least %v later", attempt, delivery.NextAttemptAt, backoff)
+			}
+		}
+
+		messageID, err := send("busy@example.com")
+		recordAttempt(delivery, messageID, err)
+		if delivery.Status != models.DeliveryFailed || delivery.NextAttemptAt != nil {
+			t.Errorf("delivery = %+v, want failed after %d attempts", delivery, MaxDeliveryAttempts)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package delivery
+
+import (
+	"bytes"
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/internal/services/invoice"
+	"einvoice-access-point/pkg/utility"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/go-pdf/fpdf"
+	"gorm.io/gorm"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/invoice.html"))
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/invoice.txt"))
+)
+
+// emailData is the view of a signed invoice used by the email templates
+type emailData struct {
+	SupplierName  string
+	BuyerName     string
+	InvoiceNumber string
+	IRN           string
+	IssueDate     string
+	DueDate       string
+	Currency      string
+	PayableAmount string
+}
+
+func newEmailData(doc firs_models.InvoiceRequest, supplierName string) emailData {
+	data := emailData{
+		SupplierName:  supplierName,
+		BuyerName:     "Customer",
+		InvoiceNumber: doc.InvoiceNumber,
+		IssueDate:     doc.IssueDate,
+		Currency:      doc.DocumentCurrencyCode,
+		PayableAmount: formatAmount(doc.LegalMonetaryTotal.PayableAmount),
+	}
+	if doc.IRN != nil {
+		data.IRN = *doc.IRN
+	}
+	if doc.DueDate != nil {
+		data.DueDate = *doc.DueDate
+	}
+	if doc.AccountingCustomerParty.PartyName != nil && *doc.AccountingCustomerParty.PartyName != "" {
+		data.BuyerName = *doc.AccountingCustomerParty.PartyName
+	}
+	return data
+}
+
+func renderEmail(data emailData) (subject, text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := textTemplate.ExecuteTemplate(&textBuf, "invoice.txt", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render text email: %w", err)
+	}
+	if err := htmlTemplate.ExecuteTemplate(&htmlBuf, "invoice.html", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render html email: %w", err)
+	}
+
+	subject = fmt.Sprintf("Invoice %s from %s", data.InvoiceNumber, data.SupplierName)
+	return subject, textBuf.String(), htmlBuf.String(), nil
+}
+
+// RenderInvoicePDF renders the printable PDF of an invoice document, with its QR code once the IRN has been signed
+func RenderInvoicePDF(db *gorm.DB, keys *utility.CryptoKeys, businessID string, doc firs_models.InvoiceRequest) ([]byte, error) {
+	var qrPNG []byte
+	if doc.IRN != nil && *doc.IRN != "" {
+		image, _, err := invoice.GetInvoiceQRCode(db, keys, businessID, *doc.IRN, invoice.DefaultQROptions())
+		if err != nil && !errors.Is(err, invoice.ErrInvoiceNotSigned) {
+			return nil, fmt.Errorf("failed to render qr code: %w", err)
+		}
+		qrPNG = image
+	}
+
+	return renderPDF(doc, newEmailData(doc, supplierName(db, businessID, doc)), qrPNG)
+}
+
+// renderPDF lays the signed invoice out on a single A4 page with the FIRS QR code in the header
+func renderPDF(doc firs_models.InvoiceRequest, data emailData, qrPNG []byte) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	if len(qrPNG) > 0 {
+		pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qrPNG))
+		pdf.ImageOptions("qr", 160, 12, 35, 35, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(140, 10, "INVOICE", "", 1, "L", false, 0, "")
+
+	supplier := doc.AccountingSupplierParty
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(140, 6, tr(data.SupplierName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range partyLines(supplier) {
+		pdf.CellFormat(140, 5, tr(line), "", 1, "L", false, 0, "")
+	}
+
+	pdf.SetY(52)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(90, 6, "Bill to", "", 0, "L", false, 0, "")
+	pdf.CellFormat(90, 6, "Invoice details", "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 9)
+	buyerLines := append([]string{data.BuyerName}, partyLines(doc.AccountingCustomerParty)...)
+	detailLines := []string{
+		"Invoice number: " + data.InvoiceNumber,
+		"IRN: " + data.IRN,
+		"Issue date: " + data.IssueDate,
+	}
+	if data.DueDate != "" {
+		detailLines = append(detailLines, "Due date: "+data.DueDate)
+	}
+	for i := 0; i < len(buyerLines) || i < len(detailLines); i++ {
+		var left, right string
+		if i < len(buyerLines) {
+			left = buyerLines[i]
+		}
+		if i < len(detailLines) {
+			right = detailLines[i]
+		}
+		pdf.CellFormat(90, 5, tr(left), "", 0, "L", false, 0, "")
+		pdf.CellFormat(90, 5, tr(right), "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(6)
+	widths := []float64{80, 20, 40, 40}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for i, heading := range []string{"Item", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, heading, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range doc.InvoiceLine {
+		pdf.CellFormat(widths[0], 6, tr(line.Item.Name), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, strconv.Itoa(line.InvoicedQuantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, formatAmount(line.Price.PriceAmount), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, formatAmount(line.LineExtensionAmount), "", 1, "R", false, 0, "")
+	}
+
+	var taxAmount float64
+	for _, total := range doc.TaxTotal {
+		taxAmount += total.TaxAmount
+	}
+
+	pdf.Ln(2)
+	totals := [][2]string{
+		{"Subtotal", formatAmount(doc.LegalMonetaryTotal.TaxExclusiveAmount)},
+		{"Tax", formatAmount(taxAmount)},
+		{"Total payable (" + data.Currency + ")", data.PayableAmount},
+	}
+	for i, total := range totals {
+		style := ""
+		if i == len(totals)-1 {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 9)
+		pdf.CellFormat(widths[0]+widths[1]+widths[2], 6, total[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, total[1], "", 1, "R", false, 0, "")
+	}
+
+	if doc.PaymentTermsNote != nil && *doc.PaymentTermsNote != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.MultiCell(0, 4, tr(*doc.PaymentTermsNote), "", "L", false)
+	}
+
+	if len(qrPNG) > 0 {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.MultiCell(0, 4, "This invoice has been signed and confirmed by the Federal Inland Revenue Service. Scan the QR code to verify it.", "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render invoice pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func partyLines(party firs_models.Party) []string {
+	var lines []string
+	if party.PostalAddress != nil {
+		address := party.PostalAddress
+		if address.StreetName != "" {
+			lines = append(lines, address.StreetName)
+		}
+		var locality []string
+		for _, part := range []string{address.CityName, address.PostalZone, address.Country} {
+			if part != "" {
+				locality = append(locality, part)
+			}
+		}
+		if len(locality) > 0 {
+			lines = append(lines, strings.Join(locality, ", "))
+		}
+	}
+	if party.TIN != "" {
+		lines = append(lines, "TIN: "+party.TIN)
+	}
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	return lines
+}
+
+// formatAmount renders an amount with two decimals and thousands separators
+func formatAmount(amount float64) string {
+	raw := strconv.FormatFloat(amount, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(raw, "-") {
+		sign, raw = "-", raw[1:]
+	}
+
+	whole, fraction := raw[:len(raw)-3], raw[len(raw)-3:]
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + fraction
+}
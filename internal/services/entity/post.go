@@ -3,9 +3,14 @@ package entity
 import (
 	"einvoice-access-point/external/firs"
 	"einvoice-access-point/external/firs_models"
+	"errors"
 	"fmt"
+	"net/http"
 )
 
+// ErrTINRejected is returned when FIRS answers that the TIN is not valid, as opposed to a check FIRS could not complete
+var ErrTINRejected = errors.New("tin was rejected by FIRS")
+
 func VerifyTin(tin string) (*firs_models.FirsResponse, *string, error) {
 
 	resp, err := firs.VerifyTin(tin)
@@ -15,6 +20,10 @@ func VerifyTin(tin string) (*firs_models.FirsResponse, *string, error) {
 
 	theResp, errDetails, err := firs.ParseFIRSAPIResponse(resp)
 	if err != nil {
+		switch resp.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+			return nil, errDetails, fmt.Errorf("%w: %v", ErrTINRejected, err)
+		}
 		return nil, errDetails, fmt.Errorf("failed to parse FIRS API response: %w", err)
 	}
 
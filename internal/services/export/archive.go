@@ -0,0 +1,227 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"einvoice-access-point/external/firs_models"
+	repository "einvoice-access-point/internal/repository/export"
+	"einvoice-access-point/internal/services/delivery"
+	"einvoice-access-point/internal/services/invoice"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const manifestName = "manifest.csv"
+
+var manifestHeader = []string{"file", "invoice_number", "irn", "issue_date", "current_status", "size_bytes", "sha256", "note"}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// archive writes the entries of an export and records every file in the manifest
+type archive struct {
+	zip      *zip.Writer
+	manifest [][]string
+	folders  map[string]bool
+	created  time.Time
+}
+
+// build writes the archive of an export next to its final path and moves it into place once complete.
+// Each invoice gets its stored InvoiceData, the decrypted FIRS download, a PDF rendering and its StatusHistory.
+func build(db *gorm.DB, keys *utility.CryptoKeys, export *models.InvoiceExport) error {
+	pdb := inst.InitDB(db, true)
+
+	path := archivePath(export)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), export.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	checksum := sha256.New()
+	size := &countingWriter{}
+	a := &archive{
+		zip:      zip.NewWriter(io.MultiWriter(tmp, checksum, size)),
+		manifest: [][]string{manifestHeader},
+		folders:  map[string]bool{},
+		created:  time.Now(),
+	}
+
+	count := 0
+	for offset := 0; ; offset += exportInvoiceBatchSize {
+		invoices, err := repository.FindInvoicesForExport(pdb, export.BusinessID, export.FromDate, export.ToDate, export.Statuses, offset, exportInvoiceBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to load invoices: %w", err)
+		}
+		for i := range invoices {
+			if err := a.addInvoice(db, keys, &invoices[i]); err != nil {
+				return err
+			}
+		}
+		count += len(invoices)
+		if len(invoices) < exportInvoiceBatchSize {
+			break
+		}
+	}
+
+	if err := a.writeManifest(); err != nil {
+		return err
+	}
+	if err := a.zip.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write export archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store export archive: %w", err)
+	}
+
+	export.InvoiceCount = count
+	export.FilePath = path
+	export.FileSize = size.n
+	export.Checksum = hex.EncodeToString(checksum.Sum(nil))
+	return nil
+}
+
+// addInvoice writes the files of one invoice. Documents that cannot be produced, such as the FIRS download of an
+// invoice FIRS never signed, are listed in the manifest with the reason instead of failing the whole export.
+func (a *archive) addInvoice(db *gorm.DB, keys *utility.CryptoKeys, inv *models.Invoice) error {
+	folder := a.folder(inv)
+
+	// platform invoices keep the platform document as their invoice data, the FIRS document is the converted one
+	var doc firs_models.InvoiceRequest
+	var docErr error
+	if document := inv.FIRSData(); len(document) == 0 {
+		docErr = errors.New("invoice has not been converted to a FIRS document")
+	} else if err := json.Unmarshal(document, &doc); err != nil {
+		docErr = fmt.Errorf("invoice document could not be decoded: %w", err)
+	}
+	if doc.IRN == nil && inv.IRN != "" {
+		doc.IRN = &inv.IRN
+	}
+
+	row := func(file string, data []byte, note string) []string {
+		size, sum := "", ""
+		if data != nil {
+			size = strconv.Itoa(len(data))
+			digest := sha256.Sum256(data)
+			sum = hex.EncodeToString(digest[:])
+		}
+		return []string{file, inv.InvoiceNumber, inv.IRN, inv.IssueDate, inv.CurrentStatus, size, sum, note}
+	}
+	add := func(name string, data []byte) error {
+		file := folder + "/" + name
+		if err := a.write(file, data); err != nil {
+			return err
+		}
+		a.manifest = append(a.manifest, row(file, data, ""))
+		return nil
+	}
+	skip := func(name, reason string) {
+		a.manifest = append(a.manifest, row(folder+"/"+name, nil, reason))
+	}
+
+	if err := add("invoice_data.json", inv.InvoiceData); err != nil {
+		return err
+	}
+	if err := add("status_history.json", inv.StatusHistory); err != nil {
+		return err
+	}
+
+	switch {
+	case inv.IRN == "" || !signedByFIRS(inv):
+		skip("firs_invoice.json", "invoice has not been signed by FIRS")
+	default:
+		if downloaded, _, err := invoice.DownloadInvoice(inv.IRN); err != nil {
+			skip("firs_invoice.json", err.Error())
+		} else if err := add("firs_invoice.json", []byte(*downloaded)); err != nil {
+			return err
+		}
+	}
+
+	if docErr != nil {
+		skip("invoice.pdf", docErr.Error())
+		return nil
+	}
+	pdf, err := delivery.RenderInvoicePDF(db, keys, inv.BusinessID, doc)
+	if err != nil {
+		skip("invoice.pdf", err.Error())
+		return nil
+	}
+	return add("invoice.pdf", pdf)
+}
+
+func (a *archive) write(name string, data []byte) error {
+	w, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.created})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *archive) writeManifest() error {
+	w, err := a.zip.CreateHeader(&zip.FileHeader{Name: manifestName, Method: zip.Deflate, Modified: a.created})
+	if err != nil {
+		return fmt.Errorf("failed to add manifest: %w", err)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(a.manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// folder names the directory of an invoice after its number, falling back to its ID when two numbers clean up to the same name
+func (a *archive) folder(inv *models.Invoice) string {
+	name := unsafeNameChars.ReplaceAllString(inv.InvoiceNumber, "_")
+	if name == "" || a.folders[name] {
+		name = name + "_" + inv.ID
+	}
+	a.folders[name] = true
+	return "invoices/" + name
+}
+
+// signedByFIRS reports whether FIRS accepted the invoice signature, after which FIRS can return the invoice
+func signedByFIRS(inv *models.Invoice) bool {
+	var history []models.StatusHistoryEntry
+	if err := json.Unmarshal(inv.StatusHistory, &history); err != nil {
+		return false
+	}
+	for _, entry := range history {
+		if entry.Step == models.StatusSignedInvoice {
+			return entry.Status == "success"
+		}
+	}
+	return false
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
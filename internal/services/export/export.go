@@ -0,0 +1,231 @@
+package export
+
+import (
+	repository "einvoice-access-point/internal/repository/export"
+	"einvoice-access-point/pkg/config"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ExportInterval is how often the worker builds queued exports and removes expired ones
+	ExportInterval = 30 * time.Second
+	// MaxExportDays is the longest period a single export may cover
+	MaxExportDays = 366
+
+	defaultExportDir       = "exports"
+	defaultRetentionHours  = 72
+	exportBatchSize        = 5
+	exportStaleAfter       = time.Hour
+	exportInvoiceBatchSize = 100
+)
+
+var (
+	ErrExportNotFound  = errors.New("export not found")
+	ErrExportNotReady  = errors.New("export is not ready yet")
+	ErrExportExpired   = errors.New("export has expired, request a new one")
+	ErrExportForbidden = errors.New("exports can only be requested for your own business")
+)
+
+// CreateExport queues a ZIP archive of the invoices a business issued between the from and to dates inclusive
+func CreateExport(db *gorm.DB, businessID string, req models.CreateExportRequest) (*models.InvoiceExport, error) {
+	pdb := inst.InitDB(db, true)
+
+	if req.BusinessID != "" && req.BusinessID != businessID {
+		return nil, ErrExportForbidden
+	}
+
+	from, err := time.Parse(time.DateOnly, req.FromDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from_date: %w", err)
+	}
+	to, err := time.Parse(time.DateOnly, req.ToDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to_date: %w", err)
+	}
+	if to.Before(from) {
+		return nil, errors.New("to_date must not be before from_date")
+	}
+	if to.Sub(from) > MaxExportDays*24*time.Hour {
+		return nil, fmt.Errorf("an export can cover at most %d days", MaxExportDays)
+	}
+
+	export := &models.InvoiceExport{
+		BusinessID: businessID,
+		FromDate:   req.FromDate,
+		ToDate:     req.ToDate,
+		Statuses:   req.Statuses,
+		Status:     models.ExportPending,
+	}
+	if export.Statuses == nil {
+		export.Statuses = []string{}
+	}
+	if err := repository.CreateExport(pdb, export); err != nil {
+		return nil, fmt.Errorf("failed to queue export: %w", err)
+	}
+	return export, nil
+}
+
+func GetExport(db *gorm.DB, businessID, exportID string) (*models.InvoiceExport, error) {
+	pdb := inst.InitDB(db, true)
+
+	export, err := repository.FindExport(pdb, businessID, exportID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportNotFound
+	}
+	return export, err
+}
+
+func GetExports(db *gorm.DB, businessID string) ([]models.InvoiceExport, error) {
+	pdb := inst.InitDB(db, true)
+	return repository.FindExportsByBusiness(pdb, businessID)
+}
+
+// OpenExport opens the archive of a ready export for download
+func OpenExport(db *gorm.DB, businessID, exportID string) (*models.InvoiceExport, *os.File, error) {
+	export, err := GetExport(db, businessID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case export.Status == models.ExportExpired,
+		export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now()):
+		return nil, nil, ErrExportExpired
+	case export.Status == models.ExportFailed:
+		return nil, nil, fmt.Errorf("export failed: %s", export.Error)
+	case export.Status != models.ExportReady:
+		return nil, nil, ErrExportNotReady
+	}
+
+	file, err := os.Open(export.FilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrExportExpired
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	return export, file, nil
+}
+
+// ArchiveName is the file name an export is downloaded as
+func ArchiveName(export *models.InvoiceExport) string {
+	return fmt.Sprintf("invoices-%s-to-%s.zip", export.FromDate, export.ToDate)
+}
+
+// ProcessQueuedExports builds every queued export and returns how many became ready
+func ProcessQueuedExports(db *gorm.DB, keys *utility.CryptoKeys) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	exports, err := repository.FindQueuedExports(pdb, time.Now().Add(-exportStaleAfter), exportBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	ready := 0
+	for i := range exports {
+		export := &exports[i]
+
+		claimed, err := repository.ClaimExport(pdb, export)
+		if err != nil {
+			return ready, err
+		}
+		if !claimed {
+			continue
+		}
+
+		buildErr := build(db, keys, export)
+		now := time.Now()
+		expires := now.Add(retention())
+		export.CompletedAt = &now
+		export.ExpiresAt = &expires
+		if buildErr != nil {
+			export.Status = models.ExportFailed
+			export.Error = buildErr.Error()
+		} else {
+			export.Status = models.ExportReady
+			export.Error = ""
+			ready++
+		}
+		if err := repository.UpdateExport(pdb, export); err != nil {
+			return ready, fmt.Errorf("failed to save export %s: %w", export.ID, err)
+		}
+	}
+
+	return ready, nil
+}
+
+// PurgeExpiredExports deletes the archives that outlived the retention window and returns how many were removed
+func PurgeExpiredExports(db *gorm.DB) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	exports, err := repository.FindExpiredExports(pdb, time.Now(), exportInvoiceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range exports {
+		export := &exports[i]
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return i, fmt.Errorf("failed to remove export %s: %w", export.ID, err)
+			}
+		}
+		export.Status = models.ExportExpired
+		export.FilePath = ""
+		if err := repository.UpdateExport(pdb, export); err != nil {
+			return i, fmt.Errorf("failed to save export %s: %w", export.ID, err)
+		}
+	}
+
+	return len(exports), nil
+}
+
+// StartExportWorker builds queued exports and purges expired ones every ExportInterval until the process exits
+func StartExportWorker(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger) {
+	go func() {
+		ticker := time.NewTicker(ExportInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ProcessQueuedExports(db, keys)
+			if err != nil {
+				logger.Error("invoice export failed", err)
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("built %d invoice exports", count))
+			}
+
+			if _, err := PurgeExpiredExports(db); err != nil {
+				logger.Error("invoice export purge failed", err)
+			}
+		}
+	}()
+}
+
+func exportDir() string {
+	if configs := config.GetConfig(); configs != nil && configs.Export.Dir != "" {
+		return configs.Export.Dir
+	}
+	return defaultExportDir
+}
+
+func retention() time.Duration {
+	hours := defaultRetentionHours
+	if configs := config.GetConfig(); configs != nil && configs.Export.RetentionHours > 0 {
+		hours = configs.Export.RetentionHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func archivePath(export *models.InvoiceExport) string {
+	return filepath.Join(exportDir(), export.BusinessID, export.ID+".zip")
+}
@@ -0,0 +1,41 @@
+package invoice
+
+import (
+	"einvoice-access-point/external/firs_models"
+	repository "einvoice-access-point/internal/repository/delivery"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueueDelivery schedules the email that sends a confirmed invoice to the buyer. Invoices whose buyer has
+// no email address are recorded as failed so the missing address shows up in the delivery status.
+func QueueDelivery(db *gorm.DB, invoiceModel *models.Invoice, payload firs_models.InvoiceRequest) error {
+	pdb := inst.InitDB(db, true)
+
+	document, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invoice document: %w", err)
+	}
+
+	now := time.Now()
+	delivery := &models.InvoiceDelivery{
+		BusinessID:    invoiceModel.BusinessID,
+		InvoiceID:     invoiceModel.ID,
+		Recipient:     payload.AccountingCustomerParty.Email,
+		Document:      document,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if delivery.Recipient == "" {
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = "buyer has no email address"
+		delivery.NextAttemptAt = nil
+	}
+
+	return repository.UpsertDelivery(pdb, delivery)
+}
@@ -3,9 +3,12 @@ package invoice
 import (
 	"einvoice-access-point/external/firs_models"
 	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/pkg/database"
 	inst "einvoice-access-point/pkg/dbinit"
 	"einvoice-access-point/pkg/models"
+	"encoding/json"
 	"fmt"
+	"log"
 
 	"gorm.io/gorm"
 )
@@ -14,9 +17,13 @@ func FirsAllInOneProcess(payload firs_models.InvoiceRequest, invoiceModel *model
 
 	pdb := inst.InitDB(db, true)
 
+	if err := storeDocument(pdb, invoiceModel, payload); err != nil {
+		return fmt.Errorf("failed to store invoice document: %v", err), false
+	}
+
 	_, theErr, err := ValidateInvoice(payload)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusValidatedInvoice, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusValidatedInvoice, "failed", err.Error())
 		return fmt.Errorf("failed to validate invoice: %v - %v", *theErr, err), false
 	}
 
@@ -27,7 +34,7 @@ func FirsAllInOneProcess(payload firs_models.InvoiceRequest, invoiceModel *model
 
 	_, theErr, err = SignInvoice(payload)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusSignedInvoice, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusSignedInvoice, "failed", err.Error())
 		return fmt.Errorf("failed to sign invoice: %v - %v", *theErr, err), false
 	}
 	err = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusSignedInvoice, "success")
@@ -37,7 +44,7 @@ func FirsAllInOneProcess(payload firs_models.InvoiceRequest, invoiceModel *model
 
 	_, theErr, err = TransmitInvoice(*payload.IRN)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusTransmitted, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusTransmitted, "failed", err.Error())
 		return fmt.Errorf("failed to transmit invoice: %v - %v", *theErr, err), true
 	}
 	err = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusTransmitted, "success")
@@ -47,7 +54,7 @@ func FirsAllInOneProcess(payload firs_models.InvoiceRequest, invoiceModel *model
 
 	_, theErr, err = TransmitConfirmInvoice(*payload.IRN)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusConfirmed, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusConfirmed, "failed", err.Error())
 		return fmt.Errorf("failed to confirm transmit invoice: %v - %v", *theErr, err), true
 	}
 	err = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusConfirmed, "success")
@@ -55,6 +62,10 @@ func FirsAllInOneProcess(payload firs_models.InvoiceRequest, invoiceModel *model
 		return fmt.Errorf("failed to update invoice status: %v", err), true
 	}
 
+	if err := QueueDelivery(db, invoiceModel, payload); err != nil {
+		log.Printf("failed to queue delivery of invoice %s: %v", invoiceModel.InvoiceNumber, err)
+	}
+
 	confirmInvoiceResp, theErr, err := ConfirmInvoice(*payload.IRN)
 	if err != nil {
 		return fmt.Errorf("failed to confirm invoice: %v - %v", *theErr, err), true
@@ -66,3 +77,16 @@ func FirsAllInOneProcess(payload firs_models.InvoiceRequest, invoiceModel *model
 
 	return nil, false
 }
+
+// storeDocument records the issue date of the invoice and, for platform invoices whose invoice data is the platform
+// document, the FIRS document they were converted to
+func storeDocument(pdb database.DatabaseManager, invoiceModel *models.Invoice, payload firs_models.InvoiceRequest) error {
+	var document []byte
+	if invoiceModel.Platform != "internal" {
+		var err error
+		if document, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	return repository.UpdateInvoiceDocument(pdb, invoiceModel, document, payload.IssueDate)
+}
@@ -2,10 +2,13 @@ package invoice
 
 import (
 	"einvoice-access-point/external/firs_models"
+	businessRepository "einvoice-access-point/internal/repository/business"
 	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/internal/services/connector"
 	inst "einvoice-access-point/pkg/dbinit"
 	"einvoice-access-point/pkg/models"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -25,6 +28,47 @@ func GetInvoiceDetails(db *gorm.DB, businessID, invoiceID string) (*models.Invoi
 	return repository.FindInvoiceByBusinessAndID(pdb, businessID, invoiceID)
 }
 
+var ErrInvoiceNotFIRSFormat = errors.New("invoice was not stored in FIRS format")
+
+// GetInvoiceRequest returns the FIRS document of an invoice. Platform invoices that were not converted yet are
+// converted from their platform data by the connector of the platform.
+func GetInvoiceRequest(db *gorm.DB, businessID, invoiceID string) (*firs_models.InvoiceRequest, error) {
+	invoice, err := GetInvoiceDetails(db, businessID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if document := invoice.FIRSData(); len(document) > 0 {
+		var req firs_models.InvoiceRequest
+		if err := json.Unmarshal(document, &req); err != nil {
+			return nil, fmt.Errorf("failed to decode invoice data: %w", err)
+		}
+		if req.IRN == nil && invoice.IRN != "" {
+			req.IRN = &invoice.IRN
+		}
+		return &req, nil
+	}
+	if invoice.Platform == "internal" {
+		return nil, ErrInvoiceNotFIRSFormat
+	}
+
+	conn, err := connector.Get(invoice.Platform)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvoiceNotFIRSFormat, err)
+	}
+	pdb := inst.InitDB(db, true)
+	business, err := businessRepository.FindUserByID(pdb, businessID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, _, err := conn.Convert(db, business, invoice.InvoiceData, invoice.IRN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s invoice: %w", invoice.Platform, err)
+	}
+	return &req, nil
+}
+
 func CreateInvoice(db *gorm.DB, payload firs_models.InvoiceRequest, invoiceNumber, businessID string) (*models.Invoice, *string, error, bool) {
 
 	pdb := inst.InitDB(db, true)
@@ -51,6 +95,7 @@ func CreateInvoice(db *gorm.DB, payload firs_models.InvoiceRequest, invoiceNumbe
 		Platform:         "internal",
 		PlatformMetadata: platformMetadata,
 		InvoiceData:      invoiceData,
+		IssueDate:        payload.IssueDate,
 		CurrentStatus:    currentStatus,
 		StatusHistory:    statusHistory,
 		Timestamp:        time.Now(),
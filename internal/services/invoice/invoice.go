@@ -1,21 +1,23 @@
 package invoice
 
 import (
-	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"einvoice-access-point/external/firs"
 	"einvoice-access-point/external/firs_models"
+	businessRepository "einvoice-access-point/internal/repository/business"
+	irnRepository "einvoice-access-point/internal/repository/irn"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
 	"einvoice-access-point/pkg/utility"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"image/png"
 	"regexp"
 	"strings"
 	"time"
 
-	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
 )
 
 func PrepareIRN(irn string) string {
@@ -23,7 +25,11 @@ func PrepareIRN(irn string) string {
 	return fmt.Sprintf("%s.%d", irn, timestamp)
 }
 
-func GenerateIRNumber(invoiceNumber, serviceID string, timestamp time.Time) (string, error) {
+var irnPlaceholderRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+
+// GenerateIRNumber renders the business IRN template, falling back to the FIRS default
+// {invoice_number}-{service_id}-{yyyymmdd} shape when no template is configured.
+func GenerateIRNumber(template, invoiceNumber, serviceID string, timestamp time.Time) (string, error) {
 
 	if !regexp.MustCompile(`^[A-Za-z0-9]+$`).MatchString(invoiceNumber) {
 		return "", fmt.Errorf("invalid invoice number: only alphanumeric characters allowed")
@@ -33,13 +39,61 @@ func GenerateIRNumber(invoiceNumber, serviceID string, timestamp time.Time) (str
 		return "", fmt.Errorf("invalid service ID: must be 8 alphanumeric characters")
 	}
 
+	if template == "" {
+		template = models.DefaultIRNTemplate
+	}
+
 	dateString := timestamp.Format("20060102")
 
-	irn := fmt.Sprintf("%s-%s-%s", invoiceNumber, serviceID, dateString)
+	var renderErr error
+	irn := irnPlaceholderRegex.ReplaceAllStringFunc(template, func(match string) string {
+		name := strings.ToLower(irnPlaceholderRegex.FindStringSubmatch(match)[1])
+		switch name {
+		case "invoice_number", "invoice_id":
+			return invoiceNumber
+		case "service_id":
+			return serviceID
+		case "yyyymmdd", "date":
+			return dateString
+		default:
+			renderErr = fmt.Errorf("unknown irn template placeholder: %s", match)
+			return match
+		}
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+
+	if !regexp.MustCompile(`^[A-Za-z0-9-]+$`).MatchString(irn) {
+		return "", fmt.Errorf("invalid irn template: rendered irn %q contains invalid characters", irn)
+	}
+
+	if len(irn) > 50 {
+		return "", fmt.Errorf("invalid irn template: rendered irn is longer than 50 characters")
+	}
 
 	return irn, nil
 }
 
+// ValidateIRNTemplate checks that a template renders to a usable IRN and carries both the
+// invoice number and the date, which FIRS requires in every IRN.
+func ValidateIRNTemplate(template string) error {
+	placeholders := make(map[string]bool)
+	for _, match := range irnPlaceholderRegex.FindAllStringSubmatch(template, -1) {
+		placeholders[strings.ToLower(match[1])] = true
+	}
+
+	if !placeholders["invoice_number"] && !placeholders["invoice_id"] {
+		return fmt.Errorf("irn template must contain {{invoice_number}} or {{invoice_id}}")
+	}
+	if !placeholders["yyyymmdd"] && !placeholders["date"] {
+		return fmt.Errorf("irn template must contain {{YYYYMMDD}}")
+	}
+
+	_, err := GenerateIRNumber(template, "INV00001", "ABCD1234", time.Now())
+	return err
+}
+
 func ValidateIRN(invoiceReq firs_models.IRNValidationRequest) (*firs_models.FirsResponse, *string, error) {
 
 	resp, err := firs.ValidateIRN(invoiceReq)
@@ -73,6 +127,29 @@ func ValidateInvoice(invoiceReq firs_models.InvoiceRequest) (*firs_models.FirsRe
 }
 
 func SignIRN(irn string, keys *utility.CryptoKeys) (*firs_models.IRNSigningResponse, error) {
+	base64Encrypted, err := EncryptIRN(irn, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	qrImage, _, err := RenderQRCode(base64Encrypted, DefaultQROptions())
+	if err != nil {
+		return nil, err
+	}
+
+	base64QRImage := base64.StdEncoding.EncodeToString(qrImage)
+
+	theResp := &firs_models.IRNSigningResponse{
+		EncryptedMessage: base64Encrypted,
+		QrCodeImage:      base64QRImage,
+	}
+
+	//fmt.Printf("signed irn: %v", theResp)
+	return theResp, nil
+}
+
+// EncryptIRN encrypts the timestamped IRN and certificate with the FIRS public key; the base64 result is the QR payload
+func EncryptIRN(irn string, keys *utility.CryptoKeys) (string, error) {
 	formattedIRN := PrepareIRN(irn)
 
 	payload := firs_models.IRNSigningData{
@@ -82,36 +159,16 @@ func SignIRN(irn string, keys *utility.CryptoKeys) (*firs_models.IRNSigningRespo
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal JSON: %v", err)
+		return "", fmt.Errorf("failed to marshal JSON: %v", err)
 	}
 
 	//encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, keys.PublicKey, jsonData, nil)
 	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, keys.PublicKey, jsonData)
 	if err != nil {
-		return nil, fmt.Errorf("encryption failed: %v", err)
-	}
-
-	base64Encrypted := base64.StdEncoding.EncodeToString(encrypted)
-
-	qr, err := qrcode.New(base64Encrypted, qrcode.Medium)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR code: %v", err)
-	}
-
-	buf := new(bytes.Buffer)
-	if err := png.Encode(buf, qr.Image(256)); err != nil {
-		return nil, fmt.Errorf("failed to encode QR code: %v", err)
-	}
-
-	base64QRImage := base64.StdEncoding.EncodeToString(buf.Bytes())
-
-	theResp := &firs_models.IRNSigningResponse{
-		EncryptedMessage: base64Encrypted,
-		QrCodeImage:      base64QRImage,
+		return "", fmt.Errorf("encryption failed: %v", err)
 	}
 
-	//fmt.Printf("signed irn: %v", theResp)
-	return theResp, nil
+	return base64.StdEncoding.EncodeToString(encrypted), nil
 }
 
 func SignInvoice(invoiceReq firs_models.InvoiceRequest) (*firs_models.FirsResponse, *string, error) {
@@ -130,11 +187,41 @@ func SignInvoice(invoiceReq firs_models.InvoiceRequest) (*firs_models.FirsRespon
 	return theResp, nil, nil
 }
 
-func GenerateIRN(invoiceNumber, serviceId string) (*string, error) {
+func GenerateIRN(invoiceNumber, serviceId, template string) (*string, error) {
 	cleanInvoiceNumber := strings.ReplaceAll(invoiceNumber, "-", "")
-	irn, err := GenerateIRNumber(cleanInvoiceNumber, serviceId, time.Now())
+	irn, err := GenerateIRNumber(template, cleanInvoiceNumber, serviceId, time.Now())
 	if err != nil {
 		return nil, err
 	}
 	return &irn, nil
 }
+
+// IssueIRN generates an IRN from the business template and records it in the IRN registry
+func IssueIRN(db *gorm.DB, businessID, invoiceNumber string) (*string, error) {
+	pdb := inst.InitDB(db, true)
+
+	business, err := businessRepository.FindUserByID(pdb, businessID)
+	if err != nil {
+		return nil, fmt.Errorf("business not found: %w", err)
+	}
+
+	irn, err := GenerateIRN(invoiceNumber, business.ServiceID, business.IRNTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := irnRepository.ReserveIRN(pdb, business.ID, invoiceNumber, *irn); err != nil {
+		return nil, err
+	}
+
+	return irn, nil
+}
+
+// ErrIRNAlreadyIssued is returned when a client supplied IRN belongs to another invoice
+var ErrIRNAlreadyIssued = irnRepository.ErrIRNAlreadyIssued
+
+// RegisterIRN records an IRN supplied by the client in the IRN registry
+func RegisterIRN(db *gorm.DB, businessID, invoiceNumber, irn string) error {
+	pdb := inst.InitDB(db, true)
+	return irnRepository.ReserveIRN(pdb, businessID, invoiceNumber, irn)
+}
@@ -0,0 +1,25 @@
+package invoice
+
+import "testing"
+
+func TestValidateIRNTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{"{{invoice_number}}-{{service_id}}-{{YYYYMMDD}}", false},
+		{"{{ invoice_number }}-{{ service_id }}-{{ YYYYMMDD }}", false},
+		{"{{invoice_id}}-94ND90NR-{{date}}", false},
+		{"{{service_id}}-{{YYYYMMDD}}", true},
+		{"{{ invoice_number }}-{{service_id}}", true},
+		{"{{invoice_number}}-{{branch}}-{{YYYYMMDD}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if err := ValidateIRNTemplate(tt.template); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIRNTemplate(%q) error = %v, want error %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package invoice
+
+import (
+	"bytes"
+	"einvoice-access-point/external/firs_models"
+	repository "einvoice-access-point/internal/repository/invoice"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+
+	MinQRSize     = 64
+	MaxQRSize     = 2048
+	DefaultQRSize = 256
+)
+
+var ErrInvoiceNotSigned = errors.New("invoice IRN has not been signed")
+
+var qrRecoveryLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// QROptions selects the image format, size in pixels and error correction level of a QR code
+type QROptions struct {
+	Format string
+	Size   int
+	ECC    string
+}
+
+// DefaultQROptions matches the QR code FIRS expects on printed invoices
+func DefaultQROptions() QROptions {
+	return QROptions{Format: QRFormatPNG, Size: DefaultQRSize, ECC: "M"}
+}
+
+// ParseQROptions reads the format, size and ecc query values, using the defaults for blank values
+func ParseQROptions(format, size, ecc string) (QROptions, error) {
+	opts := DefaultQROptions()
+
+	if format != "" {
+		opts.Format = strings.ToLower(format)
+		if opts.Format != QRFormatPNG && opts.Format != QRFormatSVG {
+			return opts, fmt.Errorf("unsupported format %q, use png or svg", format)
+		}
+	}
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < MinQRSize || n > MaxQRSize {
+			return opts, fmt.Errorf("size must be a number of pixels between %d and %d", MinQRSize, MaxQRSize)
+		}
+		opts.Size = n
+	}
+	if ecc != "" {
+		opts.ECC = strings.ToUpper(ecc)
+		if _, ok := qrRecoveryLevels[opts.ECC]; !ok {
+			return opts, fmt.Errorf("unsupported ecc %q, use L, M, Q or H", ecc)
+		}
+	}
+
+	return opts, nil
+}
+
+// RenderQRCode encodes the payload as a QR image and returns it with its content type
+func RenderQRCode(payload string, opts QROptions) ([]byte, string, error) {
+	level, ok := qrRecoveryLevels[opts.ECC]
+	if !ok {
+		level = qrcode.Medium
+	}
+
+	qr, err := qrcode.New(payload, level)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate QR code: %v", err)
+	}
+
+	if opts.Format == QRFormatSVG {
+		return renderQRCodeSVG(qr, opts.Size), "image/svg+xml", nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, qr.Image(opts.Size)); err != nil {
+		return nil, "", fmt.Errorf("failed to encode QR code: %v", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// renderQRCodeSVG draws every dark module as a unit square of a single path scaled to the requested size
+func renderQRCodeSVG(qr *qrcode.QRCode, size int) []byte {
+	bitmap := qr.Bitmap()
+	modules := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`, modules, modules)
+	fmt.Fprintf(&buf, `<path d="%s" fill="#000000"/>`, path.String())
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// SaveSignature keeps the encrypted IRN payload and QR image produced when the invoice IRN was signed
+func SaveSignature(db *gorm.DB, invoiceModel *models.Invoice, signed *firs_models.IRNSigningResponse) error {
+	pdb := inst.InitDB(db, true)
+	return repository.UpdateInvoiceSignature(pdb, invoiceModel, signed.EncryptedMessage, signed.QrCodeImage)
+}
+
+// GetInvoiceQRCode regenerates the QR code of an invoice from its stored encrypted IRN payload.
+// Invoices stored before the payload was persisted are signed once with the given keys and backfilled.
+func GetInvoiceQRCode(db *gorm.DB, keys *utility.CryptoKeys, businessID, irn string, opts QROptions) ([]byte, string, error) {
+	pdb := inst.InitDB(db, true)
+
+	invoiceModel, err := repository.FindInvoiceByIRN(pdb, businessID, irn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if invoiceModel.EncryptedIRN == "" {
+		if keys == nil {
+			return nil, "", ErrInvoiceNotSigned
+		}
+		signed, err := SignIRN(invoiceModel.IRN, keys)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := SaveSignature(db, invoiceModel, signed); err != nil {
+			return nil, "", fmt.Errorf("failed to save signed IRN: %w", err)
+		}
+	}
+
+	return RenderQRCode(invoiceModel.EncryptedIRN, opts)
+}
@@ -0,0 +1,70 @@
+package peppol
+
+import (
+	"einvoice-access-point/external/peppol"
+	businessRepository "einvoice-access-point/internal/repository/business"
+	customerRepository "einvoice-access-point/internal/repository/customer"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/invoice"
+	inst "einvoice-access-point/pkg/dbinit"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var ErrPeppolNotEnabled = errors.New("peppol is not enabled for this business")
+
+// ExportInvoice renders a stored invoice as a validated PEPPOL BIS 3.0 document. The buyer endpoint
+// is looked up in the customer directory by the buyer TIN.
+func ExportInvoice(db *gorm.DB, businessID, invoiceID string) ([]byte, *peppol.SendRequest, error) {
+	pdb := inst.InitDB(db, true)
+
+	business, err := businessRepository.FindBusinessByID(pdb, businessID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !business.SupportPeppol {
+		return nil, nil, ErrPeppolNotEnabled
+	}
+
+	req, err := invoice.GetInvoiceRequest(db, businessID, invoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buyerEndpoint string
+	buyer, err := customerRepository.FindCustomerByTIN(pdb, businessID, req.AccountingCustomerParty.TIN)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	if buyer != nil {
+		buyerEndpoint = buyer.PeppolEndpoint
+	}
+
+	document, err := converter.ConvertFIRSToPeppol(*req, business.PeppolEndpoint, buyerEndpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return document, &peppol.SendRequest{
+		SenderID:   business.PeppolEndpoint,
+		ReceiverID: buyerEndpoint,
+		DocumentID: req.InvoiceNumber,
+		Document:   document,
+	}, nil
+}
+
+// SendInvoice exports an invoice as PEPPOL BIS 3.0 and hands it to the access point
+func SendInvoice(db *gorm.DB, accessPoint peppol.AccessPoint, businessID, invoiceID string) (*peppol.SendResult, error) {
+	_, sendReq, err := ExportInvoice(db, businessID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := accessPoint.Send(*sendReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send peppol document: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package platformmapping
+
+import (
+	repository "einvoice-access-point/internal/repository/platformmapping"
+	"einvoice-access-point/internal/services/converter"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// MappingError lists the problems of a platform mapping
+type MappingError struct {
+	Problems []string
+}
+
+func (e *MappingError) Error() string {
+	return "invalid platform mapping: " + strings.Join(e.Problems, "; ")
+}
+
+// GetMapping returns the saved mapping of the business for the platform, or an empty mapping using the platform defaults
+func GetMapping(db *gorm.DB, businessID, platform string) (*models.PlatformMapping, error) {
+	pdb := inst.InitDB(db, true)
+
+	mapping, err := repository.FindMapping(pdb, businessID, platform)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.PlatformMapping{
+			BusinessID:      businessID,
+			Platform:        platform,
+			TaxCategories:   models.TaxMappings{},
+			PaymentMeans:    models.PaymentMeansMappings{},
+			InvoiceTypeCode: models.DefaultInvoiceTypeCode,
+		}, nil
+	}
+	return mapping, err
+}
+
+// SaveMapping replaces the mapping of the business for the platform after checking every code it maps onto
+func SaveMapping(db *gorm.DB, businessID, platform string, req models.PlatformMappingRequest) (*models.PlatformMapping, error) {
+	pdb := inst.InitDB(db, true)
+
+	if err := checkMapping(req); err != nil {
+		return nil, err
+	}
+
+	mapping := &models.PlatformMapping{
+		BusinessID:      businessID,
+		Platform:        platform,
+		IRNField:        req.IRNField,
+		QRField:         req.QRField,
+		StatusField:     req.StatusField,
+		ErrorField:      req.ErrorField,
+		TaxCategories:   req.TaxCategories,
+		PaymentMeans:    req.PaymentMeans,
+		InvoiceTypeCode: req.InvoiceTypeCode,
+	}
+	if mapping.TaxCategories == nil {
+		mapping.TaxCategories = models.TaxMappings{}
+	}
+	if mapping.PaymentMeans == nil {
+		mapping.PaymentMeans = models.PaymentMeansMappings{}
+	}
+	if mapping.InvoiceTypeCode == "" {
+		mapping.InvoiceTypeCode = models.DefaultInvoiceTypeCode
+	}
+	if err := repository.UpsertMapping(pdb, mapping); err != nil {
+		return nil, fmt.Errorf("failed to save platform mapping: %w", err)
+	}
+
+	return repository.FindMapping(pdb, businessID, platform)
+}
+
+func checkMapping(req models.PlatformMappingRequest) error {
+	var problems []string
+
+	fields := []struct{ name, field string }{
+		{"irn_field", req.IRNField},
+		{"qr_field", req.QRField},
+		{"status_field", req.StatusField},
+		{"error_field", req.ErrorField},
+	}
+	usedBy := map[string]string{}
+	for _, f := range fields {
+		name, field := f.name, f.field
+		if field == "" {
+			continue
+		}
+		if !fieldNamePattern.MatchString(field) {
+			problems = append(problems, fmt.Sprintf("%s %q must only contain letters, digits and underscores", name, field))
+		}
+		if other, ok := usedBy[field]; ok {
+			problems = append(problems, fmt.Sprintf("%s and %s must be different custom fields", other, name))
+		}
+		usedBy[field] = name
+	}
+
+	categories := codeSet(converter.TaxCategoryCodes())
+	for taxID, tax := range req.TaxCategories {
+		if !categories[tax.Category] {
+			problems = append(problems, fmt.Sprintf("tax %s maps onto unknown tax category %s, use one of %s", taxID, tax.Category, strings.Join(converter.TaxCategoryCodes(), ", ")))
+		}
+		if tax.Percent != nil && (*tax.Percent < 0 || *tax.Percent > 100) {
+			problems = append(problems, fmt.Sprintf("tax %s percent must be between 0 and 100", taxID))
+		}
+	}
+
+	paymentMeans := codeSet(converter.PaymentMeansCodes())
+	for terms, code := range req.PaymentMeans {
+		if !paymentMeans[code] {
+			problems = append(problems, fmt.Sprintf("payment terms %s map onto unknown payment means code %s", terms, code))
+		}
+	}
+
+	if req.InvoiceTypeCode != "" && !codeSet(converter.InvoiceTypeCodes())[req.InvoiceTypeCode] {
+		problems = append(problems, fmt.Sprintf("invoice_type_code %s is not an invoice type code", req.InvoiceTypeCode))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &MappingError{Problems: problems}
+	}
+	return nil
+}
+
+func codeSet(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
@@ -0,0 +1,363 @@
+package platformsync
+
+import (
+	businessRepository "einvoice-access-point/internal/repository/business"
+	repository "einvoice-access-point/internal/repository/sync"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/token"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// SyncInterval is how often the worker pulls changed invoices from the platforms
+	SyncInterval = 5 * time.Minute
+	// MaxBackfillDays is the longest invoice date range a single backfill may cover
+	MaxBackfillDays = 366
+
+	syncBatchSize = 10
+	syncLease     = 15 * time.Minute
+	syncPerPage   = 200
+	syncMaxPages  = 5
+	// syncMaxAttempts is how many runs in a row an invoice may fail before the cursor is moved past it, two hours of
+	// runs so an outage of FIRS does not skip invoices
+	syncMaxAttempts = 24
+	syncMaxSkipped  = 50
+)
+
+var ErrSyncNotFound = errors.New("sync has not been set up for the platform")
+
+func GetSync(db *gorm.DB, businessID, platform string) (*models.PlatformSync, error) {
+	pdb := inst.InitDB(db, true)
+
+	sync, err := repository.FindSync(pdb, businessID, platform)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSyncNotFound
+	}
+	return sync, err
+}
+
+// UpdateSync turns polling on or off for the organisation of the business on the platform. A newly enabled sync only
+// pulls invoices changed from then on, older invoices are brought in with a backfill.
+func UpdateSync(db *gorm.DB, businessID, platform string, req models.UpdateSyncRequest) (*models.PlatformSync, error) {
+	sync, err := findOrCreateSync(db, businessID, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	sync.Enabled = *req.Enabled
+	if sync.Enabled && sync.Cursor == nil {
+		now := time.Now()
+		sync.Cursor = &now
+	}
+	if err := repository.UpdateSync(inst.InitDB(db, true), sync); err != nil {
+		return nil, fmt.Errorf("failed to save sync: %w", err)
+	}
+	return sync, nil
+}
+
+// RequestBackfill queues the pull of every invoice dated between the from and to dates inclusive
+func RequestBackfill(db *gorm.DB, businessID, platform string, req models.BackfillRequest) (*models.PlatformSync, error) {
+	from, err := time.Parse(time.DateOnly, req.FromDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from_date: %w", err)
+	}
+	to, err := time.Parse(time.DateOnly, req.ToDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to_date: %w", err)
+	}
+	if to.Before(from) {
+		return nil, errors.New("to_date must not be before from_date")
+	}
+	if to.Sub(from) > MaxBackfillDays*24*time.Hour {
+		return nil, fmt.Errorf("a backfill can cover at most %d days", MaxBackfillDays)
+	}
+
+	sync, err := findOrCreateSync(db, businessID, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	sync.BackfillFrom = req.FromDate
+	sync.BackfillTo = req.ToDate
+	sync.BackfillStatus = models.BackfillPending
+	sync.BackfillPage = 1
+	if err := repository.UpdateSync(inst.InitDB(db, true), sync); err != nil {
+		return nil, fmt.Errorf("failed to queue backfill: %w", err)
+	}
+	return sync, nil
+}
+
+// findOrCreateSync returns the sync of the business, following the organisation set in its platform configs
+func findOrCreateSync(db *gorm.DB, businessID, platform string) (*models.PlatformSync, error) {
+	pdb := inst.InitDB(db, true)
+
+	business, err := businessRepository.FindBusinessByID(pdb, businessID)
+	if err != nil {
+		return nil, err
+	}
+	accConfig, ok := business.PlatformConfigs[platform]
+	if !ok || accConfig.OrgID == "" {
+		return nil, token.ErrPlatformNotConfigured
+	}
+
+	sync, err := repository.FindSync(pdb, businessID, platform)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		sync = &models.PlatformSync{BusinessID: businessID, Platform: platform, OrgID: accConfig.OrgID}
+		if err := repository.CreateSync(pdb, sync); err != nil {
+			return nil, fmt.Errorf("failed to create sync: %w", err)
+		}
+		return sync, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sync.OrgID != accConfig.OrgID {
+		now := time.Now()
+		sync.OrgID = accConfig.OrgID
+		sync.Cursor = &now
+		sync.BackfillStatus = ""
+		sync.FailedInvoice = ""
+		sync.FailedAttempts = 0
+	}
+	return sync, nil
+}
+
+// ProcessSyncs pulls the changed invoices of every due organisation of each registered platform and returns how many
+// invoices were processed. Only platforms that are polled have syncs.
+func ProcessSyncs(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger) (int, error) {
+	processed := 0
+	for _, platform := range connector.Names() {
+		count, err := processSyncs(db, keys, logger, platform)
+		processed += count
+		if err != nil {
+			return processed, err
+		}
+	}
+	return processed, nil
+}
+
+func processSyncs(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger, platform string) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	syncs, err := repository.FindDueSyncs(pdb, platform, time.Now(), syncBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i := range syncs {
+		sync := &syncs[i]
+
+		now := time.Now()
+		claimed, err := repository.ClaimSync(pdb, sync, now, now.Add(syncLease))
+		if err != nil {
+			return processed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		count, syncErr := syncOrganisation(db, keys, logger, sync)
+		processed += count
+
+		finished := time.Now()
+		sync.Processed += count
+		sync.LastSyncAt = &finished
+		sync.LockedUntil = nil
+		sync.LastError = ""
+		if syncErr != nil {
+			sync.LastError = syncErr.Error()
+		}
+		if err := repository.UpdateSync(pdb, sync); err != nil {
+			return processed, fmt.Errorf("failed to save sync %s: %w", sync.ID, err)
+		}
+	}
+
+	return processed, nil
+}
+
+// StartSyncWorker runs ProcessSyncs every SyncInterval until the process exits
+func StartSyncWorker(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger) {
+	go func() {
+		ticker := time.NewTicker(SyncInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ProcessSyncs(db, keys, logger)
+			if err != nil {
+				logger.Error("platform sync failed", err)
+				continue
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("synced %d platform invoices", count))
+			}
+		}
+	}()
+}
+
+// organisationSync pulls the invoices of one organisation through the poll of its connector
+type organisationSync struct {
+	db        *gorm.DB
+	keys      *utility.CryptoKeys
+	logger    *utility.Logger
+	conn      connector.Connector
+	sync      *models.PlatformSync
+	business  *models.Business
+	account   connector.Account
+	failures  []string
+	processed int
+}
+
+// syncOrganisation runs the pending backfill page by page, then pulls the invoices modified since the cursor.
+// Invoices that fail to process are reported and skipped so one bad invoice does not hold up the rest.
+func syncOrganisation(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger, sync *models.PlatformSync) (int, error) {
+	conn, err := connector.Get(sync.Platform)
+	if err != nil {
+		return 0, err
+	}
+	business, err := businessRepository.FindBusinessByID(inst.InitDB(db, true), sync.BusinessID)
+	if err != nil {
+		return 0, fmt.Errorf("business not found: %w", err)
+	}
+	account, err := connector.LoadAccount(db, sync.BusinessID, sync.Platform)
+	if err != nil {
+		return 0, err
+	}
+
+	s := &organisationSync{db: db, keys: keys, logger: logger, conn: conn, sync: sync, business: business, account: account}
+	return s.run()
+}
+
+func (s *organisationSync) run() (int, error) {
+	if s.sync.BackfillStatus == models.BackfillPending {
+		if err := s.backfill(); err != nil {
+			s.sync.BackfillStatus = models.BackfillFailed
+			return s.processed, fmt.Errorf("backfill failed: %w", err)
+		}
+	}
+	if s.sync.Enabled {
+		if err := s.pullChanges(); err != nil {
+			return s.processed, err
+		}
+	}
+
+	if len(s.failures) > 0 {
+		return s.processed, fmt.Errorf("%d invoices failed: %s", len(s.failures), strings.Join(s.failures, "; "))
+	}
+	return s.processed, nil
+}
+
+func (s *organisationSync) backfill() error {
+	for pages := 0; pages < syncMaxPages; pages++ {
+		page, err := s.poll(connector.PollQuery{
+			DateFrom: s.sync.BackfillFrom,
+			DateTo:   s.sync.BackfillTo,
+			Page:     s.sync.BackfillPage,
+			PerPage:  syncPerPage,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !page.More {
+			s.sync.BackfillStatus = models.BackfillDone
+			return nil
+		}
+		s.sync.BackfillPage++
+	}
+	return nil
+}
+
+// pullChanges processes the invoices modified since the cursor, oldest first, and moves the cursor past them. The
+// cursor stops before the first invoice that fails, so it is pulled again on the next run; the invoices after it are
+// processed all the same and left alone when they are pulled again unchanged. An invoice that keeps failing for
+// syncMaxAttempts runs is skipped so it does not hold the cursor for good; it is pulled again once it is edited.
+func (s *organisationSync) pullChanges() error {
+	since := time.Now()
+	if s.sync.Cursor != nil {
+		since = *s.sync.Cursor
+	}
+
+	failed := false
+	for number := 1; number <= syncMaxPages; number++ {
+		page, err := s.poll(connector.PollQuery{ModifiedSince: since, Page: number, PerPage: syncPerPage})
+		if err != nil {
+			return err
+		}
+
+		for _, polled := range page.Invoices {
+			if failed {
+				continue
+			}
+			if polled.Err != nil && !s.skipFailed(polled) {
+				failed = true
+				continue
+			}
+			if polled.Modified.IsZero() {
+				continue
+			}
+			if s.sync.Cursor == nil || polled.Modified.After(*s.sync.Cursor) {
+				modified := polled.Modified
+				s.sync.Cursor = &modified
+			}
+		}
+
+		if !page.More {
+			break
+		}
+	}
+
+	if !failed {
+		s.sync.FailedInvoice = ""
+		s.sync.FailedAttempts = 0
+	}
+	return nil
+}
+
+// skipFailed counts another failed run of the invoice holding the cursor. It reports true once the invoice failed
+// syncMaxAttempts runs in a row, recording it among the skipped invoices.
+func (s *organisationSync) skipFailed(polled connector.PolledInvoice) bool {
+	if s.sync.FailedInvoice != polled.Number {
+		s.sync.FailedInvoice = polled.Number
+		s.sync.FailedAttempts = 0
+	}
+	s.sync.FailedAttempts++
+	if s.sync.FailedAttempts < syncMaxAttempts {
+		return false
+	}
+
+	s.sync.Skipped = append(s.sync.Skipped, polled.Number)
+	if len(s.sync.Skipped) > syncMaxSkipped {
+		s.sync.Skipped = s.sync.Skipped[len(s.sync.Skipped)-syncMaxSkipped:]
+	}
+	s.sync.FailedInvoice = ""
+	s.sync.FailedAttempts = 0
+	return true
+}
+
+// poll pulls a page of invoices through the connector and records how they went
+func (s *organisationSync) poll(query connector.PollQuery) (*connector.PollPage, error) {
+	page, err := s.conn.PollInvoices(s.db, s.logger, s.keys, s.business, s.account, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, polled := range page.Invoices {
+		if polled.Changed {
+			s.processed++
+		}
+		if polled.Err != nil {
+			s.failures = append(s.failures, fmt.Sprintf("%s: %v", polled.Number, polled.Err))
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,205 @@
+package platformsync
+
+import (
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pollingConnector serves polls from a fixed list of invoices, failing the ones named in failing
+type pollingConnector struct {
+	connector.Connector
+
+	invoices []connector.PolledInvoice
+	failing  map[string]bool
+	perPage  int
+	queries  []connector.PollQuery
+}
+
+func (p *pollingConnector) PollInvoices(db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, business *models.Business,
+	account connector.Account, query connector.PollQuery) (*connector.PollPage, error) {
+
+	p.queries = append(p.queries, query)
+
+	var selected []connector.PolledInvoice
+	for _, polled := range p.invoices {
+		if !polled.Modified.Before(query.ModifiedSince) {
+			selected = append(selected, polled)
+		}
+	}
+
+	page := &connector.PollPage{}
+	start := (query.Page - 1) * p.perPage
+	for i := start; i < len(selected) && i < start+p.perPage; i++ {
+		polled := selected[i]
+		if p.failing[polled.Number] {
+			polled.Err = errors.New("firs unavailable")
+		} else {
+			polled.Changed = true
+		}
+		page.Invoices = append(page.Invoices, polled)
+	}
+	page.More = start+p.perPage < len(selected)
+	return page, nil
+}
+
+func TestPullChanges(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	invoices := []connector.PolledInvoice{
+		{Number: "INV-001", Modified: at(1)},
+		{Number: "INV-002", Modified: at(2)},
+		{Number: "INV-003", Modified: at(3)},
+		{Number: "INV-004", Modified: at(4)},
+	}
+
+	tests := []struct {
+		name          string
+		failing       map[string]bool
+		wantCursor    time.Time
+		wantProcessed int
+		wantErr       string
+	}{
+		{"all processed", nil, at(4), 4, ""},
+		{"failure holds the cursor", map[string]bool{"INV-003": true}, at(2), 3, "1 invoices failed: INV-003: firs unavailable"},
+		{"first invoice fails", map[string]bool{"INV-001": true}, at(0), 3, "1 invoices failed: INV-001: firs unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &pollingConnector{invoices: invoices, failing: tt.failing, perPage: 3}
+			cursor := start
+			s := &organisationSync{conn: conn, sync: &models.PlatformSync{Enabled: true, Cursor: &cursor}}
+
+			processed, err := s.run()
+
+			if processed != tt.wantProcessed {
+				t.Errorf("processed = %d, want %d", processed, tt.wantProcessed)
+			}
+			if (err == nil) != (tt.wantErr == "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Errorf("run() error = %v, want %q", err, tt.wantErr)
+			}
+			if !s.sync.Cursor.Equal(tt.wantCursor) {
+				t.Errorf("cursor = %v, want %v", s.sync.Cursor, tt.wantCursor)
+			}
+			if len(conn.queries) != 2 || conn.queries[1].Page != 2 {
+				t.Errorf("queries = %+v, want both pages pulled", conn.queries)
+			}
+		})
+	}
+}
+
+func TestPullChangesRetriesFailedInvoice(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	conn := &pollingConnector{
+		invoices: []connector.PolledInvoice{
+			{Number: "INV-001", Modified: start.Add(time.Minute)},
+			{Number: "INV-002", Modified: start.Add(2 * time.Minute)},
+		},
+		failing: map[string]bool{"INV-001": true},
+		perPage: 10,
+	}
+	cursor := start
+	sync := &models.PlatformSync{Enabled: true, Cursor: &cursor}
+
+	if _, err := (&organisationSync{conn: conn, sync: sync}).run(); err == nil {
+		t.Fatalf("run() error = nil, want the failed invoice reported")
+	}
+	if !sync.Cursor.Equal(start) {
+		t.Fatalf("cursor = %v, want it held before the failed invoice", sync.Cursor)
+	}
+
+	conn.failing = nil
+	if _, err := (&organisationSync{conn: conn, sync: sync}).run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !conn.queries[1].ModifiedSince.Equal(start) {
+		t.Errorf("second poll since %v, want %v", conn.queries[1].ModifiedSince, start)
+	}
+	if !sync.Cursor.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("cursor = %v, want past the retried invoice", sync.Cursor)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	var invoices []connector.PolledInvoice
+	for i := 0; i < 5; i++ {
+		invoices = append(invoices, connector.PolledInvoice{Number: "INV"})
+	}
+	conn := &pollingConnector{invoices: invoices, perPage: 2}
+	sync := &models.PlatformSync{BackfillStatus: models.BackfillPending, BackfillPage: 1, BackfillFrom: "2025-01-01", BackfillTo: "2025-01-31"}
+
+	processed, err := (&organisationSync{conn: conn, sync: sync}).run()
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if processed != 5 || sync.BackfillStatus != models.BackfillDone || sync.BackfillPage != 3 {
+		t.Errorf("processed %d, backfill %s at page %d, want 5 done at page 3", processed, sync.BackfillStatus, sync.BackfillPage)
+	}
+	var pages []string
+	for _, query := range conn.queries {
+		if query.DateFrom != "2025-01-01" || query.DateTo != "2025-01-31" {
+			t.Errorf("query = %+v, want the backfill range", query)
+		}
+		pages = append(pages, strconv.Itoa(query.Page))
+	}
+	if strings.Join(pages, ",") != "1,2,3" {
+		t.Errorf("pages = %v, want 1,2,3", pages)
+	}
+}
+
+func TestPullChangesSkipsPoisonedInvoice(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	conn := &pollingConnector{
+		invoices: []connector.PolledInvoice{
+			{Number: "INV-001", Modified: start.Add(time.Minute)},
+			{Number: "INV-002", Modified: start.Add(2 * time.Minute)},
+			{Number: "INV-003", Modified: start.Add(3 * time.Minute)},
+		},
+		failing: map[string]bool{"INV-001": true, "INV-003": true},
+		perPage: 10,
+	}
+	cursor := start
+	sync := &models.PlatformSync{Enabled: true, Cursor: &cursor}
+
+	for attempt := 1; attempt < syncMaxAttempts; attempt++ {
+		if _, err := (&organisationSync{conn: conn, sync: sync}).run(); err == nil {
+			t.Fatalf("run() error = nil, want the failed invoices reported")
+		}
+	}
+	if !sync.Cursor.Equal(start) || sync.FailedInvoice != "INV-001" || sync.FailedAttempts != syncMaxAttempts-1 {
+		t.Fatalf("cursor = %v held by %s after %d attempts, want it held by INV-001 after %d",
+			sync.Cursor, sync.FailedInvoice, sync.FailedAttempts, syncMaxAttempts-1)
+	}
+
+	// The last attempt skips INV-001, and INV-003 holds the cursor in its place
+	if _, err := (&organisationSync{conn: conn, sync: sync}).run(); err == nil {
+		t.Fatalf("run() error = nil, want the failed invoices reported")
+	}
+	if !sync.Cursor.Equal(start.Add(2*time.Minute)) || sync.FailedInvoice != "INV-003" || sync.FailedAttempts != 1 {
+		t.Errorf("cursor = %v held by %s after %d attempts, want it past INV-002 held by INV-003 after 1",
+			sync.Cursor, sync.FailedInvoice, sync.FailedAttempts)
+	}
+	if len(sync.Skipped) != 1 || sync.Skipped[0] != "INV-001" {
+		t.Errorf("skipped = %v, want INV-001", sync.Skipped)
+	}
+
+	conn.failing = nil
+	if _, err := (&organisationSync{conn: conn, sync: sync}).run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !sync.Cursor.Equal(start.Add(3*time.Minute)) || sync.FailedInvoice != "" || sync.FailedAttempts != 0 {
+		t.Errorf("cursor = %v held by %q after %d attempts, want it past INV-003 and released",
+			sync.Cursor, sync.FailedInvoice, sync.FailedAttempts)
+	}
+}
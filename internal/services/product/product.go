@@ -0,0 +1,157 @@
+package product
+
+import (
+	repository "einvoice-access-point/internal/repository/product"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+
+func GetProducts(db *gorm.DB, businessID, platform string) ([]models.Product, error) {
+	pdb := inst.InitDB(db, true)
+	return repository.FindProductsByBusiness(pdb, businessID, platform)
+}
+
+// ImportProducts upserts the products into the business catalogue, keyed by platform and item ID. An item
+// listed more than once in the request is imported as its last entry.
+func ImportProducts(db *gorm.DB, businessID string, req []models.ProductRequest) ([]models.Product, error) {
+	pdb := inst.InitDB(db, true)
+
+	products := buildProducts(businessID, req)
+	if err := repository.UpsertProducts(pdb, products); err != nil {
+		return nil, fmt.Errorf("failed to import products: %w", err)
+	}
+
+	return products, nil
+}
+
+// buildProducts returns one product per platform item. Postgres rejects an upsert that touches the same row twice.
+func buildProducts(businessID string, req []models.ProductRequest) []models.Product {
+	products := make([]models.Product, 0, len(req))
+	index := make(map[string]int, len(req))
+	for _, p := range req {
+		product := models.Product{
+			BusinessID:      businessID,
+			Platform:        strings.ToLower(p.Platform),
+			PlatformItemID:  p.PlatformItemID,
+			Name:            p.Name,
+			HSCode:          p.HSCode,
+			ProductCategory: p.ProductCategory,
+			UnitOfMeasure:   p.UnitOfMeasure,
+			TaxCategory:     p.TaxCategory,
+			TaxPercent:      p.TaxPercent,
+		}
+
+		key := product.Platform + "\x00" + product.PlatformItemID
+		if i, ok := index[key]; ok {
+			products[i] = product
+			continue
+		}
+		index[key] = len(products)
+		products = append(products, product)
+	}
+	return products
+}
+
+func DeleteProduct(db *gorm.DB, businessID, productID string) error {
+	pdb := inst.InitDB(db, true)
+
+	affected, err := repository.DeleteProduct(pdb, businessID, productID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// GetCatalogue returns the catalogue entries of the given platform items keyed by platform item ID
+func GetCatalogue(db *gorm.DB, businessID, platform string, itemIDs []string) (map[string]models.Product, error) {
+	pdb := inst.InitDB(db, true)
+
+	catalogue := make(map[string]models.Product)
+	if len(itemIDs) == 0 {
+		return catalogue, nil
+	}
+
+	products, err := repository.FindProductsByItemIDs(pdb, businessID, platform, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range products {
+		catalogue[p.PlatformItemID] = p
+	}
+	return catalogue, nil
+}
+
+// ParseProductsCSV reads a catalogue CSV whose header row uses the product request field names
+func ParseProductsCSV(r io.Reader) ([]models.ProductRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"platform", "platform_item_id", "hs_code", "product_category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %s", required)
+		}
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var products []models.ProductRequest
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		var taxPercent float64
+		if raw := value(record, "tax_percent"); raw != "" {
+			taxPercent, err = strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid tax_percent %q", row, raw)
+			}
+		}
+
+		products = append(products, models.ProductRequest{
+			Platform:        value(record, "platform"),
+			PlatformItemID:  value(record, "platform_item_id"),
+			Name:            value(record, "name"),
+			HSCode:          value(record, "hs_code"),
+			ProductCategory: value(record, "product_category"),
+			UnitOfMeasure:   value(record, "unit_of_measure"),
+			TaxCategory:     value(record, "tax_category"),
+			TaxPercent:      taxPercent,
+		})
+	}
+
+	return products, nil
+}
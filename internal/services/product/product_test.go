@@ -0,0 +1,25 @@
+package product
+
+import (
+	"einvoice-access-point/pkg/models"
+	"testing"
+)
+
+func TestBuildProductsRepeatedItem(t *testing.T) {
+	req := []models.ProductRequest{
+		{Platform: "Zoho", PlatformItemID: "1001", Name: "Paper", HSCode: "4802.56"},
+		{Platform: "quickbooks", PlatformItemID: "1001", Name: "Toner", HSCode: "3707.10"},
+		{Platform: "zoho", PlatformItemID: "1001", Name: "A4 Paper", HSCode: "4802.57"},
+	}
+
+	products := buildProducts("8a8e2f7c-3c1d-4a5e-9d2b-6f1e0c4b7a21", req)
+	if len(products) != 2 {
+		t.Fatalf("buildProducts() = %d products, want one per platform item", len(products))
+	}
+	if products[0].Platform != "zoho" || products[0].Name != "A4 Paper" || products[0].HSCode != "4802.57" {
+		t.Errorf("zoho item = %+v, want the last entry of the request", products[0])
+	}
+	if products[1].Platform != "quickbooks" || products[1].Name != "Toner" {
+		t.Errorf("quickbooks item = %+v, want it kept apart from the zoho item", products[1])
+	}
+}
@@ -0,0 +1,225 @@
+package schema
+
+import (
+	"bytes"
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/pkg/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	validator "github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// InvoiceSchemaPath is where the invoice schema is published
+const InvoiceSchemaPath = "/schemas/invoice.json"
+
+// schemaNames are the properties the schema publishes under another name than the one FIRS uses on the wire.
+// Older payloads still send the FIRS name, which the schema reports as renamed.
+var schemaNames = map[string]string{
+	"_document_reference": "additional_document_reference",
+}
+
+var (
+	invoiceOnce     sync.Once
+	invoiceDocument []byte
+	invoiceSchema   *validator.Schema
+	invoiceErr      error
+)
+
+// FieldError is a schema violation located by a JSON pointer into the submitted document
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every schema violation of a document
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invoice does not match the schema: %d errors", len(e.Errors))
+}
+
+// InvoiceSchema returns the draft 2020-12 JSON Schema of firs_models.InvoiceRequest
+func InvoiceSchema() ([]byte, error) {
+	loadInvoiceSchema()
+	return invoiceDocument, invoiceErr
+}
+
+// ValidateInvoice checks a JSON invoice document against the invoice schema.
+// Violations are returned as a *ValidationError; other errors mean the document is not JSON.
+func ValidateInvoice(data []byte) error {
+	loadInvoiceSchema()
+	if invoiceErr != nil {
+		return invoiceErr
+	}
+
+	instance, err := validator.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	err = invoiceSchema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *validator.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return err
+	}
+
+	printer := message.NewPrinter(language.English)
+	result := &ValidationError{}
+	collectErrors(schemaErr, printer, result)
+	return result
+}
+
+// ValidateInvoiceRequest checks an invoice built by the access point, such as one converted from UBL or read from
+// a spreadsheet, against the invoice schema
+func ValidateInvoiceRequest(req firs_models.InvoiceRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	for wire, published := range schemaNames {
+		if value, ok := document[wire]; ok {
+			delete(document, wire)
+			document[published] = value
+		}
+	}
+
+	if data, err = json.Marshal(document); err != nil {
+		return err
+	}
+	return ValidateInvoice(data)
+}
+
+func loadInvoiceSchema() {
+	invoiceOnce.Do(func() {
+		invoiceDocument, invoiceErr = generateInvoiceSchema()
+		if invoiceErr != nil {
+			return
+		}
+
+		doc, err := validator.UnmarshalJSON(bytes.NewReader(invoiceDocument))
+		if err != nil {
+			invoiceErr = err
+			return
+		}
+
+		compiler := validator.NewCompiler()
+		compiler.AssertFormat()
+		if err := compiler.AddResource(schemaID(), doc); err != nil {
+			invoiceErr = err
+			return
+		}
+		invoiceSchema, invoiceErr = compiler.Compile(schemaID())
+	})
+}
+
+// generateInvoiceSchema reflects the invoice models, which carry their formats and bounds in jsonschema tags,
+// and adds the document type codes shared with the converters
+func generateInvoiceSchema() ([]byte, error) {
+	reflector := &jsonschema.Reflector{
+		RequiredFromJSONSchemaTags: true,
+		ExpandedStruct:             true,
+		Anonymous:                  true,
+	}
+
+	s := reflector.Reflect(&firs_models.InvoiceRequest{})
+	s.ID = jsonschema.ID(schemaID())
+	s.Title = "InvoiceRequest"
+	s.Description = "Invoice accepted by /invoice/upload and /invoice/create"
+
+	if prop, ok := s.Properties.Get("invoice_type_code"); ok {
+		prop.Enum = toAny(converter.DocumentTypeCodes())
+	}
+
+	properties := jsonschema.NewProperties()
+	for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		name := pair.Key
+		if published, ok := schemaNames[name]; ok {
+			name = published
+		}
+		properties.Set(name, pair.Value)
+	}
+	s.Properties = properties
+
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func schemaID() string {
+	base := "http://localhost"
+	if configs := config.GetConfig(); configs != nil && configs.App.Url != "" {
+		base = strings.TrimSuffix(configs.App.Url, "/")
+	}
+	return base + InvoiceSchemaPath
+}
+
+// collectErrors flattens the error tree into its leaves. Missing and unknown properties are reported
+// once per property so every pointer names the offending field.
+func collectErrors(err *validator.ValidationError, printer *message.Printer, result *ValidationError) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectErrors(cause, printer, result)
+		}
+		return
+	}
+
+	pointer := toPointer(err.InstanceLocation)
+	keyword := strings.Join(err.ErrorKind.KeywordPath(), "/")
+
+	switch k := err.ErrorKind.(type) {
+	case *kind.Required:
+		for _, field := range k.Missing {
+			result.Errors = append(result.Errors, FieldError{Pointer: pointer + "/" + escapeToken(field), Keyword: keyword, Message: "is required"})
+		}
+	case *kind.AdditionalProperties:
+		for _, field := range k.Properties {
+			msg := "unknown field"
+			if renamed, ok := schemaNames[field]; ok {
+				msg = fmt.Sprintf("unknown field, it has been renamed to %s", renamed)
+			}
+			result.Errors = append(result.Errors, FieldError{Pointer: pointer + "/" + escapeToken(field), Keyword: keyword, Message: msg})
+		}
+	default:
+		result.Errors = append(result.Errors, FieldError{Pointer: pointer, Keyword: keyword, Message: err.ErrorKind.LocalizedString(printer)})
+	}
+}
+
+func toPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/" + escapeToken(token))
+	}
+	return b.String()
+}
+
+// escapeToken escapes a JSON pointer reference token as described in RFC 6901
+func escapeToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func toAny(values []string) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package series
+
+import (
+	repository "einvoice-access-point/internal/repository/series"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+func GetAllSeries(db *gorm.DB, businessID string) ([]models.InvoiceSeries, error) {
+	pdb := inst.InitDB(db, true)
+	return repository.FindSeriesByBusiness(pdb, businessID)
+}
+
+// SaveSeries creates a series or updates the numbering format of an existing one. The counter
+// is only moved when start_value is given and ahead of the current value, so numbers are never reused.
+func SaveSeries(db *gorm.DB, businessID string, req models.InvoiceSeriesRequest) (*models.InvoiceSeries, error) {
+	pdb := inst.InitDB(db, true)
+
+	if req.Padding == 0 {
+		req.Padding = 5
+	}
+	return repository.SaveSeries(pdb, businessID, req)
+}
+
+func ReserveInvoiceNumbers(db *gorm.DB, businessID, name string, count int) ([]string, error) {
+	pdb := inst.InitDB(db, true)
+	return repository.ReserveNumbers(pdb, businessID, name, count)
+}
+
+// ReleaseInvoiceNumbers returns reserved numbers that no invoice was stored with to the series
+func ReleaseInvoiceNumbers(db *gorm.DB, businessID, name string, numbers []string) error {
+	pdb := inst.InitDB(db, true)
+	return repository.ReleaseNumbers(pdb, businessID, name, numbers)
+}
@@ -1,55 +1,222 @@
 package token
 
 import (
+	"crypto/rand"
 	"einvoice-access-point/external/zoho"
+	businessRepository "einvoice-access-point/internal/repository/business"
 	repository "einvoice-access-point/internal/repository/token"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/pkg/common"
+	"einvoice-access-point/pkg/database"
+	"einvoice-access-point/pkg/database/redis"
 	inst "einvoice-access-point/pkg/dbinit"
 	"einvoice-access-point/pkg/models"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 )
 
-func GetValidAccessToken(db *gorm.DB, accConfig models.AccountingPlatformConfig, provider, orgID string, code ...string) (string, error) {
+const oauthStateTTL = 10 * time.Minute
+
+var refreshGroup singleflight.Group
+
+var (
+	ErrReconnectRequired     = errors.New("the platform rejected the refresh token, connect the organisation again")
+	ErrInvalidOAuthState     = errors.New("authorization state is invalid or has expired, start the connection again")
+	ErrPlatformNotConfigured = errors.New("set the organisation id in the business platform configs before connecting")
+	ErrNotConnected          = errors.New("the organisation has not been connected, authorize it first")
+)
+
+// oauthState is what a state value handed to a platform stands for until the callback comes back
+type oauthState struct {
+	BusinessID string `json:"business_id"`
+	OrgID      string `json:"org_id"`
+	Platform   string `json:"platform"`
+}
+
+// StartAuthorization returns the consent URL of the platform for the business, carrying a random single-use
+// state that binds the callback to the business and the organisation configured for that platform
+func StartAuthorization(db *gorm.DB, businessID string, conn connector.Connector) (string, error) {
 	pdb := inst.InitDB(db, true)
 
-	token, err := repository.FindToken(pdb, provider, orgID)
+	business, err := businessRepository.FindBusinessByID(pdb, businessID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			if len(code) == 0 {
-				return "", errors.New("authorization code required for new token")
-			}
-			newToken, err := zoho.ExchangeCodeForTokens(code[0], string(accConfig.APIKey), string(accConfig.APISecret))
-			if err != nil {
-				return "", err
-			}
+		return "", err
+	}
+	accConfig, ok := business.PlatformConfigs[conn.Name()]
+	if !ok || accConfig.OrgID == "" {
+		return "", ErrPlatformNotConfigured
+	}
 
-			if err := repository.SaveNewZohoToken(pdb, provider, orgID, newToken.AccessToken, newToken.RefreshToken, newToken.ExpiresIn); err != nil {
-				return "", err
-			}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	state := hex.EncodeToString(raw)
 
-			return newToken.AccessToken, nil
-		}
+	authURL, err := conn.AuthURL(state)
+	if err != nil {
 		return "", err
 	}
 
-	if time.Now().After(token.ExpiresAt.Add(-5 * time.Minute)) {
-		newToken, err := zoho.RefreshAccessToken(token.RefreshToken, string(accConfig.APIKey), string(accConfig.APISecret))
+	value, err := json.Marshal(oauthState{BusinessID: business.ID, OrgID: accConfig.OrgID, Platform: conn.Name()})
+	if err != nil {
+		return "", err
+	}
+
+	redisClient := redis.NewClient()
+	defer redisClient.Close()
+	if err := redisClient.Set(redisClient.Context(), oauthStateKey(state), value, oauthStateTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return authURL, nil
+}
+
+// CompleteAuthorization consumes the state returned on the callback and stores the tokens the code is exchanged for
+// by the platform the state was issued for. The callback parameters tell the platform where the grant was issued.
+func CompleteAuthorization(db *gorm.DB, state, code string, callback map[string]string) (*models.TokenManager, error) {
+	pdb := inst.InitDB(db, true)
+
+	redisClient := redis.NewClient()
+	defer redisClient.Close()
+	value, err := redisClient.GetDel(redisClient.Context(), oauthStateKey(state)).Bytes()
+	if err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+
+	bound := oauthState{Platform: zoho.InvoiceAPI.Platform}
+	if err := json.Unmarshal(value, &bound); err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+
+	conn, err := connector.Get(bound.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := conn.ExchangeCode(code, bound.OrgID, callback)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.RefreshToken == "" {
+		return nil, fmt.Errorf("%s did not return a refresh token, remove the app from the %s account and connect again", conn.Name(), conn.Name())
+	}
+
+	token := newToken(bound.Platform, bound.OrgID, tokens)
+	token.BusinessID = bound.BusinessID
+	if err := repository.UpsertToken(pdb, token); err != nil {
+		return nil, fmt.Errorf("failed to save tokens: %w", err)
+	}
+	return token, nil
+}
+
+// GetValidAccessToken returns the stored tokens of the organisation, refreshed when they are about to expire.
+// Organisations are connected through StartAuthorization and CompleteAuthorization.
+func GetValidAccessToken(db *gorm.DB, provider, orgID string) (*models.TokenManager, error) {
+	pdb := inst.InitDB(db, true)
+
+	token, err := repository.FindToken(pdb, provider, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotConnected
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token.NeedsReconnect {
+		return nil, fmt.Errorf("%w: %s", ErrReconnectRequired, token.ReconnectError)
+	}
+	if !refreshDue(token) {
+		return token, nil
+	}
+
+	// callers in this process share one refresh, the row lock covers the other instances
+	refreshed, err, _ := refreshGroup.Do(provider+":"+orgID, func() (interface{}, error) {
+		return refreshToken(pdb, provider, orgID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refreshed.(*models.TokenManager), nil
+}
+
+// GetConnection returns the tokens of the business for the platform, telling whether the organisation has to be connected again
+func GetConnection(db *gorm.DB, businessID, platform string) (*models.TokenManager, error) {
+	pdb := inst.InitDB(db, true)
+
+	business, err := businessRepository.FindBusinessByID(pdb, businessID)
+	if err != nil {
+		return nil, err
+	}
+	accConfig, ok := business.PlatformConfigs[platform]
+	if !ok || accConfig.OrgID == "" {
+		return nil, ErrPlatformNotConfigured
+	}
+
+	return repository.FindToken(pdb, platform, accConfig.OrgID)
+}
+
+func refreshToken(pdb database.DatabaseManager, provider, orgID string) (*models.TokenManager, error) {
+	conn, err := connector.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := repository.RefreshToken(pdb, provider, orgID, refreshDue, func(token *models.TokenManager) error {
+		tokens, err := conn.RefreshTokens(token)
+		if errors.Is(err, connector.ErrTokenRevoked) {
+			token.NeedsReconnect = true
+			token.ReconnectError = err.Error()
+			return nil
+		}
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		refreshToken := token.RefreshToken
-		if newToken.RefreshToken != "" {
-			refreshToken = newToken.RefreshToken
+		token.AccessToken = common.EncryptedString(tokens.AccessToken)
+		if tokens.RefreshToken != "" {
+			token.RefreshToken = common.EncryptedString(tokens.RefreshToken)
 		}
-
-		if err := repository.UpdateZohoToken(pdb, provider, orgID, newToken.AccessToken, refreshToken, newToken.ExpiresIn); err != nil {
-			return "", err
+		if tokens.APIDomain != "" {
+			token.APIDomain = tokens.APIDomain
 		}
-		return newToken.AccessToken, nil
+		token.ExpiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if token.NeedsReconnect {
+		return nil, fmt.Errorf("%w: %s", ErrReconnectRequired, token.ReconnectError)
 	}
+	return token, nil
+}
+
+// newToken builds the stored tokens of an organisation from the tokens the platform issued
+func newToken(provider, orgID string, tokens *connector.Tokens) *models.TokenManager {
+	return &models.TokenManager{
+		Provider:       provider,
+		OrganizationID: orgID,
+		AccessToken:    common.EncryptedString(tokens.AccessToken),
+		RefreshToken:   common.EncryptedString(tokens.RefreshToken),
+		ExpiresAt:      time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+		AccountsServer: tokens.AccountsServer,
+		APIDomain:      tokens.APIDomain,
+	}
+}
+
+// refreshDue reports whether the access token expires within the next five minutes
+func refreshDue(token *models.TokenManager) bool {
+	return !token.NeedsReconnect && time.Now().After(token.ExpiresAt.Add(-5*time.Minute))
+}
 
-	return token.AccessToken, nil
+func oauthStateKey(state string) string {
+	return "zoho_oauth_state_" + state
 }
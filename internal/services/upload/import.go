@@ -0,0 +1,849 @@
+package upload
+
+import (
+	"bytes"
+	"einvoice-access-point/external/firs_models"
+	businessRepository "einvoice-access-point/internal/repository/business"
+	repository "einvoice-access-point/internal/repository/importmapping"
+	importRepository "einvoice-access-point/internal/repository/invoiceimport"
+	"einvoice-access-point/internal/services/schema"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// MaxImportRows caps the number of line item rows read from a single file
+const MaxImportRows = 5000
+
+// Import outcomes of a grouped invoice
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusSubmitted = "submitted"
+	ImportStatusInvalid   = "invalid"
+	ImportStatusFailed    = "failed"
+)
+
+const (
+	// ImportInterval is how often the worker submits the invoices of queued imports
+	ImportInterval = 15 * time.Second
+
+	importBatchSize  = 2
+	importStaleAfter = 30 * time.Minute
+)
+
+var (
+	ErrImportNotFound        = errors.New("import not found")
+	ErrUnsupportedImportFile = errors.New("unsupported file type, upload a .csv or .xlsx file")
+	ErrEmptyImportFile       = errors.New("file has no line item rows")
+	ErrTooManyImportRows     = fmt.Errorf("file has more than %d line item rows", MaxImportRows)
+)
+
+// RowError is validation feedback for a single cell or row of the imported file
+type RowError struct {
+	Row           int    `json:"row"`
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+	Field         string `json:"field,omitempty"`
+	Column        string `json:"column,omitempty"`
+	Message       string `json:"message"`
+}
+
+// ImportedInvoice is the outcome of one invoice grouped from the file
+type ImportedInvoice struct {
+	InvoiceNumber string `json:"invoice_number"`
+	Rows          []int  `json:"rows"`
+	Status        string `json:"status"`
+	InvoiceID     string `json:"invoice_id,omitempty"`
+	IRN           string `json:"irn,omitempty"`
+	Signed        bool   `json:"signed"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ImportResult summarises a spreadsheet import
+type ImportResult struct {
+	Rows      int               `json:"rows"`
+	Submitted int               `json:"submitted"`
+	Invalid   int               `json:"invalid"`
+	Failed    int               `json:"failed"`
+	Invoices  []ImportedInvoice `json:"invoices"`
+	Errors    []RowError        `json:"errors"`
+}
+
+// pendingInvoice is a prepared invoice of an import waiting to be submitted
+type pendingInvoice struct {
+	Index    int                        `json:"index"`    // position of the invoice in the import result
+	Assigned string                     `json:"assigned"` // invoice number taken from the default series
+	Invoice  firs_models.InvoiceRequest `json:"invoice"`
+}
+
+// MappingError lists the problems of a column mapping
+type MappingError struct {
+	Problems []string
+}
+
+func (e *MappingError) Error() string {
+	return "invalid column mapping: " + strings.Join(e.Problems, "; ")
+}
+
+// GetImportMapping returns the saved column mapping of the business, or the default mapping when none is saved
+func GetImportMapping(db *gorm.DB, businessID string) (*models.ImportMapping, error) {
+	pdb := inst.InitDB(db, true)
+
+	mapping, err := repository.FindMappingByBusiness(pdb, businessID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultImportMapping(businessID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mapping.DateFormat == "" {
+		mapping.DateFormat = models.DefaultImportDateFormat
+	}
+	return mapping, nil
+}
+
+// SaveImportMapping replaces the column mapping of the business after checking it covers the required fields
+func SaveImportMapping(db *gorm.DB, businessID string, req models.ImportMappingRequest) (*models.ImportMapping, error) {
+	pdb := inst.InitDB(db, true)
+
+	if err := checkImportMapping(req.Columns); err != nil {
+		return nil, err
+	}
+
+	mapping := &models.ImportMapping{
+		BusinessID: businessID,
+		Columns:    req.Columns,
+		DateFormat: req.DateFormat,
+	}
+	if mapping.DateFormat == "" {
+		mapping.DateFormat = models.DefaultImportDateFormat
+	}
+	if err := repository.UpsertMapping(pdb, mapping); err != nil {
+		return nil, fmt.Errorf("failed to save column mapping: %w", err)
+	}
+
+	return repository.FindMappingByBusiness(pdb, businessID)
+}
+
+func checkImportMapping(columns models.ImportColumns) error {
+	known := make(map[string]bool, len(models.ImportFields))
+	for _, field := range models.ImportFields {
+		known[field] = true
+	}
+
+	var problems []string
+	for field := range columns {
+		if !known[field] {
+			problems = append(problems, fmt.Sprintf("unknown field %s", field))
+		}
+	}
+	for _, field := range models.RequiredImportFields {
+		if strings.TrimSpace(columns[field]) == "" {
+			problems = append(problems, fmt.Sprintf("field %s must be mapped to a column", field))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &MappingError{Problems: problems}
+	}
+	return nil
+}
+
+// ImportInvoices reads a CSV or XLSX file of line item rows, groups the rows into invoices by invoice number and
+// queues every invoice without row or validation errors for the import worker to send through the upload pipeline
+func ImportInvoices(db *gorm.DB, validate *validator.Validate, businessID, filename string, r io.Reader) (*models.InvoiceImport, error) {
+	pdb := inst.InitDB(db, true)
+
+	mapping, err := GetImportMapping(db, businessID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load column mapping: %w", err)
+	}
+	if err := checkImportMapping(mapping.Columns); err != nil {
+		return nil, err
+	}
+
+	business, err := businessRepository.FindUserByID(pdb, businessID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load business: %w", err)
+	}
+
+	records, err := readSpreadsheet(filename, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, ErrEmptyImportFile
+	}
+	if len(records)-1 > MaxImportRows {
+		return nil, ErrTooManyImportRows
+	}
+
+	sheet, err := newImportSheet(records[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Invoices: []ImportedInvoice{}, Errors: []RowError{}}
+	groups := make(map[string]*importGroup)
+	var order []string
+
+	for i, record := range records[1:] {
+		row := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+		result.Rows++
+
+		number := sheet.value(record, models.ImportFieldInvoiceNumber)
+		if number == "" {
+			result.Errors = append(result.Errors, sheet.rowError(row, "", models.ImportFieldInvoiceNumber, "is required"))
+			continue
+		}
+
+		group, ok := groups[number]
+		if !ok {
+			group = &importGroup{number: number, first: record}
+			groups[number] = group
+			order = append(order, number)
+		}
+		group.rows = append(group.rows, row)
+		group.records = append(group.records, record)
+	}
+
+	pending := []pendingInvoice{}
+	for _, number := range order {
+		group := groups[number]
+		req, rowErrors := sheet.buildInvoice(group)
+
+		imported := ImportedInvoice{InvoiceNumber: number, Rows: group.rows}
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			imported.Status = ImportStatusInvalid
+			result.Invalid++
+			result.Invoices = append(result.Invoices, imported)
+			continue
+		}
+
+		req.BusinessID = business.BusinessID
+		assigned, err := Prepare(db, businessID, &req)
+		if err != nil {
+			imported.Status = ImportStatusFailed
+			imported.Error = err.Error()
+			result.Failed++
+			result.Invoices = append(result.Invoices, imported)
+			continue
+		}
+
+		if err := validateInvoice(validate, req); err != nil {
+			Release(db, businessID, assigned)
+			result.Errors = append(result.Errors, sheet.validationErrors(err, validate, group)...)
+			imported.Status = ImportStatusInvalid
+			result.Invalid++
+			result.Invoices = append(result.Invoices, imported)
+			continue
+		}
+
+		imported.Status = ImportStatusQueued
+		pending = append(pending, pendingInvoice{Index: len(result.Invoices), Assigned: assigned, Invoice: req})
+		result.Invoices = append(result.Invoices, imported)
+	}
+
+	invoiceImport := &models.InvoiceImport{
+		BusinessID: businessID,
+		Filename:   filename,
+		Status:     models.ImportPending,
+	}
+	if len(pending) == 0 {
+		now := time.Now()
+		invoiceImport.Status = models.ImportCompleted
+		invoiceImport.CompletedAt = &now
+	}
+	if err := setImportProgress(invoiceImport, result, pending); err != nil {
+		releasePending(db, businessID, pending)
+		return nil, err
+	}
+	if err := importRepository.CreateImport(pdb, invoiceImport); err != nil {
+		releasePending(db, businessID, pending)
+		return nil, fmt.Errorf("failed to queue import: %w", err)
+	}
+	return invoiceImport, nil
+}
+
+func GetImport(db *gorm.DB, businessID, importID string) (*models.InvoiceImport, error) {
+	pdb := inst.InitDB(db, true)
+
+	invoiceImport, err := importRepository.FindImport(pdb, businessID, importID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportNotFound
+	}
+	return invoiceImport, err
+}
+
+// ProcessQueuedImports submits the invoices of queued imports and returns how many imports were completed
+func ProcessQueuedImports(db *gorm.DB, keys *utility.CryptoKeys) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	imports, err := importRepository.FindQueuedImports(pdb, time.Now().Add(-importStaleAfter), importBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for i := range imports {
+		invoiceImport := &imports[i]
+
+		claimed, err := importRepository.ClaimImport(pdb, invoiceImport)
+		if err != nil {
+			return completed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := submitImport(db, keys, invoiceImport); err != nil {
+			return completed, fmt.Errorf("failed to submit import %s: %w", invoiceImport.ID, err)
+		}
+		completed++
+	}
+	return completed, nil
+}
+
+// submitImport sends the pending invoices of an import through the upload pipeline. Progress is saved after
+// every invoice, so an import picked up again after a crash does not submit an invoice twice.
+func submitImport(db *gorm.DB, keys *utility.CryptoKeys, invoiceImport *models.InvoiceImport) error {
+	pdb := inst.InitDB(db, true)
+
+	var result ImportResult
+	if err := json.Unmarshal(invoiceImport.Result, &result); err != nil {
+		return fmt.Errorf("failed to decode import result: %w", err)
+	}
+	var pending []pendingInvoice
+	if err := json.Unmarshal(invoiceImport.Pending, &pending); err != nil {
+		return fmt.Errorf("failed to decode pending invoices: %w", err)
+	}
+
+	for len(pending) > 0 {
+		next := pending[0]
+		imported := &result.Invoices[next.Index]
+
+		submitted, err := Submit(db, keys, invoiceImport.BusinessID, next.Invoice)
+		if submitted != nil {
+			imported.IRN = submitted.IRN
+			imported.Signed = submitted.Signed
+			if submitted.Invoice != nil {
+				imported.InvoiceID = submitted.Invoice.ID
+			}
+		}
+		if err != nil && (submitted == nil || submitted.Invoice == nil) {
+			Release(db, invoiceImport.BusinessID, next.Assigned)
+			imported.Status = ImportStatusFailed
+			imported.Error = err.Error()
+			result.Failed++
+		} else {
+			imported.Status = ImportStatusSubmitted
+			if err != nil {
+				imported.Error = err.Error()
+			}
+			result.Submitted++
+		}
+
+		pending = pending[1:]
+		if len(pending) == 0 {
+			now := time.Now()
+			invoiceImport.Status = models.ImportCompleted
+			invoiceImport.CompletedAt = &now
+		}
+		if err := setImportProgress(invoiceImport, &result, pending); err != nil {
+			return err
+		}
+		if err := importRepository.UpdateImport(pdb, invoiceImport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartImportWorker submits the invoices of queued imports every ImportInterval until the process exits
+func StartImportWorker(db *gorm.DB, keys *utility.CryptoKeys, logger *utility.Logger) {
+	go func() {
+		ticker := time.NewTicker(ImportInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ProcessQueuedImports(db, keys)
+			if err != nil {
+				logger.Error("invoice import failed", err)
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("completed %d invoice imports", count))
+			}
+		}
+	}()
+}
+
+func setImportProgress(invoiceImport *models.InvoiceImport, result *ImportResult, pending []pendingInvoice) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode import result: %w", err)
+	}
+	queued, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending invoices: %w", err)
+	}
+	invoiceImport.Result = data
+	invoiceImport.Pending = queued
+	return nil
+}
+
+func releasePending(db *gorm.DB, businessID string, pending []pendingInvoice) {
+	for _, invoice := range pending {
+		Release(db, businessID, invoice.Assigned)
+	}
+}
+
+// readSpreadsheet returns every row of the CSV file or of the first XLSX sheet, header row included
+func readSpreadsheet(filename string, r io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		reader := csv.NewReader(r)
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		return records, nil
+	case ".xlsx":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spreadsheet: %w", err)
+		}
+		workbook, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open spreadsheet: %w", err)
+		}
+		defer workbook.Close()
+
+		sheets := workbook.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, ErrEmptyImportFile
+		}
+		records, err := workbook.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %s: %w", sheets[0], err)
+		}
+		return records, nil
+	default:
+		return nil, ErrUnsupportedImportFile
+	}
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+type importGroup struct {
+	number  string
+	first   []string
+	rows    []int
+	records [][]string
+}
+
+// importSheet resolves mapped fields to column positions of the header row
+type importSheet struct {
+	columns    models.ImportColumns
+	dateFormat string
+	index      map[string]int
+}
+
+func newImportSheet(header []string, mapping *models.ImportMapping) (*importSheet, error) {
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		positions[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	sheet := &importSheet{columns: mapping.Columns, dateFormat: mapping.DateFormat, index: make(map[string]int)}
+	var problems []string
+	for field, column := range mapping.Columns {
+		i, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if ok {
+			sheet.index[field] = i
+			continue
+		}
+		for _, required := range models.RequiredImportFields {
+			if required == field {
+				problems = append(problems, fmt.Sprintf("column %q mapped to %s is missing from the header row", column, field))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &MappingError{Problems: problems}
+	}
+	return sheet, nil
+}
+
+func (s *importSheet) value(record []string, field string) string {
+	i, ok := s.index[field]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (s *importSheet) rowError(row int, invoiceNumber, field, message string) RowError {
+	return RowError{
+		Row:           row,
+		InvoiceNumber: invoiceNumber,
+		Field:         field,
+		Column:        s.columns[field],
+		Message:       message,
+	}
+}
+
+// buildInvoice maps the rows of one invoice onto an invoice request. Invoice level fields are read from the
+// first row and must not disagree on the following rows.
+func (s *importSheet) buildInvoice(group *importGroup) (firs_models.InvoiceRequest, []RowError) {
+	var rowErrors []RowError
+	fail := func(row int, field, message string) {
+		rowErrors = append(rowErrors, s.rowError(row, group.number, field, message))
+	}
+
+	firstRow := group.rows[0]
+	header := func(field string) string {
+		return s.value(group.first, field)
+	}
+
+	invoiceFields := []string{
+		models.ImportFieldIssueDate, models.ImportFieldDueDate, models.ImportFieldIssueTime,
+		models.ImportFieldInvoiceTypeCode, models.ImportFieldCurrency, models.ImportFieldPaymentStatus,
+		models.ImportFieldCustomerName, models.ImportFieldCustomerTIN,
+	}
+	for i, record := range group.records[1:] {
+		for _, field := range invoiceFields {
+			if v := s.value(record, field); v != "" && v != header(field) {
+				fail(group.rows[i+1], field, fmt.Sprintf("%q differs from %q on row %d of the same invoice", v, header(field), firstRow))
+			}
+		}
+	}
+
+	req := firs_models.InvoiceRequest{
+		InvoiceNumber:        group.number,
+		InvoiceTypeCode:      "380",
+		DocumentCurrencyCode: "NGN",
+	}
+
+	issueDate, err := s.parseDate(header(models.ImportFieldIssueDate))
+	if err != nil {
+		fail(firstRow, models.ImportFieldIssueDate, err.Error())
+	}
+	req.IssueDate = issueDate
+
+	if raw := header(models.ImportFieldDueDate); raw != "" {
+		dueDate, err := s.parseDate(raw)
+		if err != nil {
+			fail(firstRow, models.ImportFieldDueDate, err.Error())
+		}
+		req.DueDate = &dueDate
+	}
+	if raw := header(models.ImportFieldIssueTime); raw != "" {
+		if _, err := time.Parse("15:04:05", raw); err != nil {
+			fail(firstRow, models.ImportFieldIssueTime, "must be in HH:MM:SS format")
+		}
+		req.IssueTime = &raw
+	}
+	if code := header(models.ImportFieldInvoiceTypeCode); code != "" {
+		req.InvoiceTypeCode = code
+	}
+	if currency := header(models.ImportFieldCurrency); currency != "" {
+		if len(currency) != 3 {
+			fail(firstRow, models.ImportFieldCurrency, "must be a three letter ISO 4217 code")
+		}
+		req.DocumentCurrencyCode = strings.ToUpper(currency)
+	}
+	if status := header(models.ImportFieldPaymentStatus); status != "" {
+		status = strings.ToUpper(status)
+		req.PaymentStatus = &status
+	}
+	if note := header(models.ImportFieldNote); note != "" {
+		req.Note = &note
+	}
+
+	req.AccountingCustomerParty, rowErrors = s.customerParty(group, rowErrors)
+
+	type taxKey struct {
+		category string
+		percent  float64
+	}
+	subtotals := make(map[taxKey]*firs_models.TaxSubtotal)
+	var taxOrder []taxKey
+	var lineTotal, taxTotal float64
+
+	for i, record := range group.records {
+		row := group.rows[i]
+		line := firs_models.InvoiceLine{
+			HSNCode:         s.value(record, models.ImportFieldHSCode),
+			ProductCategory: s.value(record, models.ImportFieldProductCategory),
+			Item: firs_models.Item{
+				Name:        s.value(record, models.ImportFieldItemName),
+				Description: s.value(record, models.ImportFieldItemDescription),
+			},
+		}
+		if line.Item.Name == "" {
+			fail(row, models.ImportFieldItemName, "is required")
+		}
+		if line.Item.Description == "" {
+			line.Item.Description = line.Item.Name
+		}
+		if line.HSNCode == "" {
+			fail(row, models.ImportFieldHSCode, "is required")
+		}
+		if line.ProductCategory == "" {
+			fail(row, models.ImportFieldProductCategory, "is required")
+		}
+		if itemID := s.value(record, models.ImportFieldItemID); itemID != "" {
+			line.Item.SellersItemIdentification = &itemID
+		}
+
+		quantity, err := parseImportAmount(s.value(record, models.ImportFieldQuantity), true)
+		if err != nil {
+			fail(row, models.ImportFieldQuantity, err.Error())
+		} else if quantity <= 0 || quantity != math.Trunc(quantity) {
+			fail(row, models.ImportFieldQuantity, "must be a whole number greater than zero")
+		}
+		price, err := parseImportAmount(s.value(record, models.ImportFieldUnitPrice), true)
+		if err != nil {
+			fail(row, models.ImportFieldUnitPrice, err.Error())
+		} else if price < 0 {
+			fail(row, models.ImportFieldUnitPrice, "must not be negative")
+		}
+		discount, err := parseImportAmount(s.value(record, models.ImportFieldDiscountAmount), false)
+		if err != nil {
+			fail(row, models.ImportFieldDiscountAmount, err.Error())
+		} else if discount < 0 || discount > quantity*price {
+			fail(row, models.ImportFieldDiscountAmount, "must be between zero and the line amount")
+		}
+		taxPercent, err := parseImportAmount(s.value(record, models.ImportFieldTaxPercent), false)
+		if err != nil {
+			fail(row, models.ImportFieldTaxPercent, err.Error())
+		} else if taxPercent < 0 || taxPercent > 100 {
+			fail(row, models.ImportFieldTaxPercent, "must be between 0 and 100")
+		}
+		taxCategory := s.value(record, models.ImportFieldTaxCategory)
+		if taxPercent > 0 && taxCategory == "" {
+			fail(row, models.ImportFieldTaxCategory, "is required when tax_percent is set")
+		}
+
+		line.InvoicedQuantity = int(quantity)
+		line.DiscountAmount = discount
+		line.LineExtensionAmount = quantity*price - discount
+		line.Price = firs_models.Price{
+			PriceAmount:  price,
+			BaseQuantity: 1,
+			PriceUnit:    req.DocumentCurrencyCode + " per 1",
+		}
+		req.InvoiceLine = append(req.InvoiceLine, line)
+		lineTotal += line.LineExtensionAmount
+
+		if taxCategory != "" {
+			key := taxKey{category: taxCategory, percent: taxPercent}
+			subtotal, ok := subtotals[key]
+			if !ok {
+				subtotal = &firs_models.TaxSubtotal{TaxCategory: firs_models.TaxCategory{ID: taxCategory, Percent: taxPercent}}
+				subtotals[key] = subtotal
+				taxOrder = append(taxOrder, key)
+			}
+			taxAmount := line.LineExtensionAmount * (taxPercent / 100)
+			subtotal.TaxableAmount += line.LineExtensionAmount
+			subtotal.TaxAmount += taxAmount
+			taxTotal += taxAmount
+		}
+	}
+
+	if len(taxOrder) > 0 {
+		total := firs_models.TaxTotal{TaxAmount: taxTotal}
+		for _, key := range taxOrder {
+			total.TaxSubtotal = append(total.TaxSubtotal, *subtotals[key])
+		}
+		req.TaxTotal = []firs_models.TaxTotal{total}
+	}
+
+	req.LegalMonetaryTotal = firs_models.LegalMonetaryTotal{
+		LineExtensionAmount: lineTotal,
+		TaxExclusiveAmount:  lineTotal,
+		TaxInclusiveAmount:  lineTotal + taxTotal,
+		PayableAmount:       lineTotal + taxTotal,
+	}
+
+	return req, rowErrors
+}
+
+func (s *importSheet) customerParty(group *importGroup, rowErrors []RowError) (firs_models.Party, []RowError) {
+	row := group.rows[0]
+	value := func(field string) string {
+		return s.value(group.first, field)
+	}
+
+	name := value(models.ImportFieldCustomerName)
+	if name == "" {
+		rowErrors = append(rowErrors, s.rowError(row, group.number, models.ImportFieldCustomerName, "is required"))
+	}
+	party := firs_models.Party{
+		PartyName: &name,
+		TIN:       value(models.ImportFieldCustomerTIN),
+		Email:     value(models.ImportFieldCustomerEmail),
+	}
+	if party.TIN == "" {
+		rowErrors = append(rowErrors, s.rowError(row, group.number, models.ImportFieldCustomerTIN, "is required"))
+	}
+	if telephone := value(models.ImportFieldCustomerTelephone); telephone != "" {
+		telephone = utility.FormatPhone(telephone)
+		party.Telephone = &telephone
+	}
+
+	address := &firs_models.PostalAddress{
+		StreetName: value(models.ImportFieldCustomerStreet),
+		CityName:   value(models.ImportFieldCustomerCity),
+		PostalZone: value(models.ImportFieldCustomerPostalZone),
+		Country:    strings.ToUpper(value(models.ImportFieldCustomerCountry)),
+	}
+	if *address != (firs_models.PostalAddress{}) {
+		party.PostalAddress = address
+	}
+
+	return party, rowErrors
+}
+
+// parseDate reads a date in the date format of the mapping
+func (s *importSheet) parseDate(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("is required")
+	}
+	for _, layout := range models.ImportDateLayouts[s.dateFormat] {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a %s date, the date format of the column mapping", raw, s.dateFormat)
+}
+
+// validateInvoice runs the checks an uploaded invoice goes through: the struct validation and the invoice JSON Schema
+func validateInvoice(validate *validator.Validate, req firs_models.InvoiceRequest) error {
+	if err := validate.Struct(&req); err != nil {
+		return err
+	}
+	return schema.ValidateInvoiceRequest(req)
+}
+
+// schemaImportFields are the import fields the invoice fields are read from, by JSON pointer
+var schemaImportFields = map[string]string{
+	"/issue_date":                           models.ImportFieldIssueDate,
+	"/due_date":                             models.ImportFieldDueDate,
+	"/issue_time":                           models.ImportFieldIssueTime,
+	"/invoice_type_code":                    models.ImportFieldInvoiceTypeCode,
+	"/document_currency_code":               models.ImportFieldCurrency,
+	"/payment_status":                       models.ImportFieldPaymentStatus,
+	"/note":                                 models.ImportFieldNote,
+	"/accounting_customer_party/party_name": models.ImportFieldCustomerName,
+	"/accounting_customer_party/tin":        models.ImportFieldCustomerTIN,
+	"/accounting_customer_party/email":      models.ImportFieldCustomerEmail,
+	"/accounting_customer_party/telephone":  models.ImportFieldCustomerTelephone,
+	"/accounting_customer_party/postal_address/street_name": models.ImportFieldCustomerStreet,
+	"/accounting_customer_party/postal_address/city_name":   models.ImportFieldCustomerCity,
+	"/accounting_customer_party/postal_address/postal_zone": models.ImportFieldCustomerPostalZone,
+	"/accounting_customer_party/postal_address/country":     models.ImportFieldCustomerCountry,
+}
+
+// schemaLineFields are the import fields the fields of an invoice line are read from, by JSON pointer within the line
+var schemaLineFields = map[string]string{
+	"/hsn_code":                         models.ImportFieldHSCode,
+	"/product_category":                 models.ImportFieldProductCategory,
+	"/invoiced_quantity":                models.ImportFieldQuantity,
+	"/discount_amount":                  models.ImportFieldDiscountAmount,
+	"/item/name":                        models.ImportFieldItemName,
+	"/item/description":                 models.ImportFieldItemDescription,
+	"/item/sellers_item_identification": models.ImportFieldItemID,
+	"/price/price_amount":               models.ImportFieldUnitPrice,
+}
+
+// schemaRowError reports a schema violation on the row and column the invalid value was read from. Violations of
+// values the import does not read, such as the supplier party, are reported on the first row of the invoice.
+func (s *importSheet) schemaRowError(group *importGroup, fieldErr schema.FieldError) RowError {
+	if field, ok := schemaImportFields[fieldErr.Pointer]; ok {
+		return s.rowError(group.rows[0], group.number, field, fieldErr.Message)
+	}
+
+	if rest, ok := strings.CutPrefix(fieldErr.Pointer, "/invoice_line/"); ok {
+		index, pointer, _ := strings.Cut(rest, "/")
+		line, err := strconv.Atoi(index)
+		if field, ok := schemaLineFields["/"+pointer]; ok && err == nil && line < len(group.rows) {
+			return s.rowError(group.rows[line], group.number, field, fieldErr.Message)
+		}
+	}
+
+	return RowError{Row: group.rows[0], InvoiceNumber: group.number, Field: fieldErr.Pointer, Message: fieldErr.Message}
+}
+
+// validationErrors reports the fields of an invoice that failed validation, on the rows they were read from
+func (s *importSheet) validationErrors(err error, validate *validator.Validate, group *importGroup) []RowError {
+	var schemaErr *schema.ValidationError
+	if errors.As(err, &schemaErr) {
+		rowErrors := make([]RowError, 0, len(schemaErr.Errors))
+		for _, fieldErr := range schemaErr.Errors {
+			rowErrors = append(rowErrors, s.schemaRowError(group, fieldErr))
+		}
+		return rowErrors
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return []RowError{{Row: group.rows[0], InvoiceNumber: group.number, Message: err.Error()}}
+	}
+
+	translations := utility.ValidationResponse(invalid, validate)
+	fields := make([]string, 0, len(translations))
+	for field := range translations {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	rowErrors := make([]RowError, 0, len(fields))
+	for _, field := range fields {
+		rowErrors = append(rowErrors, RowError{Row: group.rows[0], InvoiceNumber: group.number, Field: field, Message: translations[field]})
+	}
+	return rowErrors
+}
+
+// parseImportAmount reads a number that may carry thousands separators; blank optional values are zero
+func parseImportAmount(raw string, required bool) (float64, error) {
+	if raw == "" {
+		if required {
+			return 0, errors.New("is required")
+		}
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", raw)
+	}
+	return amount, nil
+}
@@ -0,0 +1,85 @@
+package upload
+
+import (
+	"einvoice-access-point/pkg/models"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		format  string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{models.ImportDateDayFirst, "03/04/2025", "2025-04-03", false},
+		{models.ImportDateDayFirst, "3/4/2025", "2025-04-03", false},
+		{models.ImportDateDayFirst, "2025-04-03", "2025-04-03", false},
+		{models.ImportDateDayFirst, "03-Apr-2025", "2025-04-03", false},
+		{models.ImportDateDayFirst, "04/13/2025", "", true},
+		{models.ImportDateMonthFirst, "03/04/2025", "2025-03-04", false},
+		{models.ImportDateMonthFirst, "04/13/2025", "2025-04-13", false},
+		{models.ImportDateMonthFirst, "Apr 3, 2025", "2025-04-03", false},
+		{models.ImportDateISO, "2025/04/03", "2025-04-03", false},
+		{models.ImportDateISO, "03/04/2025", "", true},
+		{models.ImportDateISO, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.raw, func(t *testing.T) {
+			sheet := &importSheet{dateFormat: tt.format}
+			got, err := sheet.parseDate(tt.raw)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("parseDate(%q) = %q, %v, want %q, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildInvoiceFailsValidation(t *testing.T) {
+	mapping := models.DefaultImportMapping("")
+	header := []string{"invoice_number", "issue_date", "customer_name", "customer_tin", "item_name", "hsn_code", "product_category", "invoiced_quantity", "price_amount", "document_currency_code"}
+	sheet, err := newImportSheet(header, mapping)
+	if err != nil {
+		t.Fatalf("newImportSheet() error = %v", err)
+	}
+
+	// The row is complete, but the currency is not one FIRS accepts
+	record := []string{"INV-1", "01/03/2025", "Globex Nigeria Ltd", "98765432-0001", "Paper", "4802.56", "Paper", "2", "4000", "NG1"}
+	group := &importGroup{number: "INV-1", first: record, rows: []int{2}, records: [][]string{record}}
+	req, rowErrors := sheet.buildInvoice(group)
+	if len(rowErrors) > 0 {
+		t.Fatalf("buildInvoice() row errors = %v", rowErrors)
+	}
+	if req.IssueDate != "2025-03-01" {
+		t.Errorf("issue date = %q, want the day first date of the default mapping", req.IssueDate)
+	}
+
+	req.BusinessID = "8a8e2f7c-3c1d-4a5e-9d2b-6f1e0c4b7a21"
+
+	validate := validator.New()
+	err = validateInvoice(validate, req)
+	if err == nil {
+		t.Fatal("validateInvoice() error = nil, want the invoice rejected")
+	}
+	reported := sheet.validationErrors(err, validate, group)
+	fields := map[string]string{}
+	for _, rowError := range reported {
+		if rowError.Row != 2 || rowError.InvoiceNumber != "INV-1" {
+			t.Errorf("row error = %+v, want it on row 2 of INV-1", rowError)
+		}
+		fields[rowError.Field] = rowError.Column
+	}
+	// The currency and the missing email map back onto their columns, the supplier party has none
+	if column, ok := fields[models.ImportFieldCurrency]; !ok || column != models.ImportFieldCurrency {
+		t.Errorf("row errors = %+v, want one on the currency column", reported)
+	}
+	if _, ok := fields[models.ImportFieldCustomerEmail]; !ok {
+		t.Errorf("row errors = %+v, want one for the customer email", reported)
+	}
+	if _, ok := fields["/accounting_supplier_party/tin"]; !ok {
+		t.Errorf("row errors = %+v, want one for the supplier TIN", reported)
+	}
+}
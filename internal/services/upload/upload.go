@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"einvoice-access-point/external/firs_models"
+	businessRepository "einvoice-access-point/internal/repository/business"
+	"einvoice-access-point/internal/services/business"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/invoice"
+	"einvoice-access-point/internal/services/schema"
+	"einvoice-access-point/internal/services/series"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"fmt"
+	"io"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Result is the outcome of an invoice sent through the upload pipeline
+type Result struct {
+	Invoice       *models.Invoice
+	InvoiceNumber string
+	IRN           string
+	QRCode        string
+	Signed        bool
+}
+
+// ReadUBLInvoice converts a UBL 2.1 Invoice or CreditNote document uploaded by the business to a FIRS invoice
+// and checks it against the invoice schema
+func ReadUBLInvoice(db *gorm.DB, businessID string, r io.Reader) (firs_models.InvoiceRequest, error) {
+	pdb := inst.InitDB(db, true)
+
+	business, err := businessRepository.FindUserByID(pdb, businessID)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, fmt.Errorf("failed to load business: %w", err)
+	}
+	return readUBLInvoice(business, r)
+}
+
+func readUBLInvoice(business *models.Business, r io.Reader) (firs_models.InvoiceRequest, error) {
+	req, err := converter.ConvertUBLToFIRS(r)
+	if err != nil {
+		return req, err
+	}
+
+	// UBL documents do not carry the FIRS business ID, the invoice is issued by the business uploading it
+	req.BusinessID = business.BusinessID
+	return req, schema.ValidateInvoiceRequest(req)
+}
+
+// Prepare assigns an invoice number from the default series when none is given and applies the supplier profile.
+// It returns the number it assigned, which must be given back with Release when the invoice is not stored.
+func Prepare(db *gorm.DB, businessID string, req *firs_models.InvoiceRequest) (string, error) {
+	var assigned string
+	if req.InvoiceNumber == "" {
+		numbers, err := series.ReserveInvoiceNumbers(db, businessID, models.DefaultInvoiceSeries, 1)
+		if err != nil {
+			return "", fmt.Errorf("unable to assign invoice number: %w", err)
+		}
+		assigned = numbers[0]
+		req.InvoiceNumber = assigned
+	}
+
+	if err := business.InjectSupplierParty(db, businessID, req); err != nil {
+		Release(db, businessID, assigned)
+		return "", fmt.Errorf("unable to apply supplier profile: %w", err)
+	}
+	return assigned, nil
+}
+
+// Release returns a number Prepare assigned to the default series, so the series keeps no gap for an invoice
+// that was never stored
+func Release(db *gorm.DB, businessID, assigned string) {
+	if assigned == "" {
+		return
+	}
+	if err := series.ReleaseInvoiceNumbers(db, businessID, models.DefaultInvoiceSeries, []string{assigned}); err != nil {
+		log.Printf("failed to release invoice number %s: %v", assigned, err)
+	}
+}
+
+// Submit issues or registers the IRN of a prepared invoice, stores it and runs it through the FIRS steps.
+// The result carries the stored invoice even when a later FIRS step fails.
+func Submit(db *gorm.DB, keys *utility.CryptoKeys, businessID string, req firs_models.InvoiceRequest) (*Result, error) {
+	result := &Result{InvoiceNumber: req.InvoiceNumber}
+	var signedIRNResponse *firs_models.IRNSigningResponse
+
+	if req.IRN == nil {
+		generatedIRN, err := invoice.IssueIRN(db, businessID, req.InvoiceNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		_, _, err = invoice.ValidateIRN(firs_models.IRNValidationRequest{
+			InvoiceReference: req.InvoiceNumber,
+			BusinessID:       req.BusinessID,
+			IRN:              *generatedIRN,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		signedIRNResponse, err = invoice.SignIRN(*generatedIRN, keys)
+		if err != nil {
+			return nil, err
+		}
+		result.IRN = *generatedIRN
+		result.QRCode = signedIRNResponse.QrCodeImage
+	} else {
+		if err := invoice.RegisterIRN(db, businessID, req.InvoiceNumber, *req.IRN); err != nil {
+			return nil, err
+		}
+		result.IRN = *req.IRN
+	}
+
+	irn := result.IRN
+	req.IRN = &irn
+
+	createdInvoice, _, err, isInvoiceSigned := invoice.CreateInvoice(db, req, req.InvoiceNumber, businessID)
+	result.Invoice = createdInvoice
+	result.Signed = isInvoiceSigned
+
+	if createdInvoice != nil && signedIRNResponse != nil {
+		if saveErr := invoice.SaveSignature(db, createdInvoice, signedIRNResponse); saveErr != nil {
+			log.Printf("failed to save signed IRN of invoice %s: %v", result.InvoiceNumber, saveErr)
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,50 @@
+package upload
+
+import (
+	"bytes"
+	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/schema"
+	"einvoice-access-point/pkg/models"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestReadUBLInvoice(t *testing.T) {
+	document, err := os.ReadFile("testdata/invoice.xml")
+	if err != nil {
+		t.Fatalf("read testdata: %v", err)
+	}
+	business := &models.Business{ID: "2f0b9a61-52f4-4d0c-9a3e-3a4d8f0c1e7b", BusinessID: "ac0d4848-c898-49ce-8fc7-46f529a9354a"}
+
+	req, err := readUBLInvoice(business, bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("readUBLInvoice() error = %v", err)
+	}
+	if req.BusinessID != business.BusinessID {
+		t.Errorf("business_id = %q, want the FIRS business ID %q", req.BusinessID, business.BusinessID)
+	}
+	if req.InvoiceNumber != "INV00142" || req.InvoiceTypeCode != "380" || len(req.InvoiceLine) == 0 {
+		t.Errorf("invoice = %s %s with %d lines, want INV00142 380 with its lines", req.InvoiceNumber, req.InvoiceTypeCode, len(req.InvoiceLine))
+	}
+}
+
+func TestReadUBLInvoiceRejected(t *testing.T) {
+	document, err := os.ReadFile("testdata/invoice.xml")
+	if err != nil {
+		t.Fatalf("read testdata: %v", err)
+	}
+
+	// A business that has not registered its FIRS business ID cannot issue the invoice
+	_, err = readUBLInvoice(&models.Business{}, bytes.NewReader(document))
+	var schemaErr *schema.ValidationError
+	if !errors.As(err, &schemaErr) || len(schemaErr.Errors) != 1 || schemaErr.Errors[0].Pointer != "/business_id" {
+		t.Errorf("readUBLInvoice() error = %v, want a schema error on /business_id", err)
+	}
+
+	_, err = readUBLInvoice(&models.Business{}, bytes.NewReader([]byte(`<Order><ID>1</ID></Order>`)))
+	var mappingErr *converter.UBLMappingError
+	if !errors.As(err, &mappingErr) {
+		t.Errorf("readUBLInvoice() error = %v, want a UBL mapping error", err)
+	}
+}
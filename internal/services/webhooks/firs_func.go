@@ -4,8 +4,13 @@ import (
 	"einvoice-access-point/external/firs_models"
 	"einvoice-access-point/external/zoho"
 	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/internal/services/attachment"
 	"einvoice-access-point/internal/services/converter"
+	"einvoice-access-point/internal/services/customer"
 	"einvoice-access-point/internal/services/invoice"
+	"einvoice-access-point/internal/services/platformmapping"
+	"einvoice-access-point/internal/services/product"
+	"einvoice-access-point/pkg/database"
 	inst "einvoice-access-point/pkg/dbinit"
 	"einvoice-access-point/pkg/models"
 	"einvoice-access-point/pkg/utility"
@@ -14,87 +19,131 @@ import (
 	"gorm.io/gorm"
 )
 
-func FirsZohoAllInOneProcess(payload zoho.WebhookPayload, firsKeys *utility.CryptoKeys, business *models.Business,
+func FirsZohoAllInOneProcess(api zoho.API, payload zoho.WebhookPayload, firsKeys *utility.CryptoKeys, business *models.Business,
 	invoiceModel *models.Invoice, db *gorm.DB) (*string, *string, error) {
 
+	doc, warnings, err := convertBeforeIRN(db, invoiceModel, func() (firs_models.InvoiceRequest, []string, error) {
+		return ConvertZohoInvoice(db, api, business, payload.Invoice, "")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	theIRN, signedQR, err := signPlatformIRN(db, firsKeys, business, invoiceModel, payload.Invoice.InvoiceNumber, payload.Invoice.InvoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	irn := *theIRN
+	doc.IRN = &irn
+
+	go func(p zoho.WebhookPayload, b *models.Business, inv *models.Invoice, d *gorm.DB, irn string) {
+		if err := otherFirsProcesses(api, p, b, inv, d, doc, warnings, irn); err != nil {
+			fmt.Println("Error in otherFirsProcesses: ", err)
+		}
+	}(payload, business, invoiceModel, db, irn)
+
+	return theIRN, signedQR, nil
+
+}
+
+// convertBeforeIRN converts a platform invoice before its IRN is issued, so an invoice that can never be submitted,
+// such as one with lines missing from the product catalogue, does not use up an IRN. The IRN is set on the
+// document once it is issued.
+func convertBeforeIRN(db *gorm.DB, invoiceModel *models.Invoice, convert func() (firs_models.InvoiceRequest, []string, error)) (firs_models.InvoiceRequest, []string, error) {
+	doc, warnings, err := convert()
+	if err != nil {
+		pdb := inst.InitDB(db, true)
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusValidatedInvoice, "failed", err.Error())
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+	return doc, warnings, nil
+}
+
+// signPlatformIRN issues the IRN of a platform invoice, has FIRS validate it and signs it, recording each step on the
+// invoice. It returns the IRN and the signed QR payload written back to the platform.
+func signPlatformIRN(db *gorm.DB, firsKeys *utility.CryptoKeys, business *models.Business, invoiceModel *models.Invoice,
+	invoiceNumber, reference string) (*string, *string, error) {
+
 	pdb := inst.InitDB(db, true)
 
-	theIRN, err := invoice.GenerateIRN(payload.Invoice.InvoiceNumber, business.ServiceID)
+	theIRN, err := invoice.IssueIRN(db, business.ID, invoiceNumber)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusGeneratedIRN, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusGeneratedIRN, "failed", err.Error())
 		return nil, nil, err
 	}
 
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusGeneratedIRN, "success")
 
 	validateIrn := firs_models.IRNValidationRequest{
-		InvoiceReference: payload.Invoice.InvoiceID,
+		InvoiceReference: reference,
 		BusinessID:       business.BusinessID,
 		IRN:              *theIRN,
 	}
 
 	_, theErr, err := invoice.ValidateIRN(validateIrn)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusValidatedIRN, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusValidatedIRN, "failed", err.Error())
 		return nil, nil, fmt.Errorf("failed to validate irn: %v - %v", *theErr, err)
 	}
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusValidatedIRN, "success")
 
 	signIRNResp, err := invoice.SignIRN(*theIRN, firsKeys)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusSignedIRN, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusSignedIRN, "failed", err.Error())
 		return nil, nil, err
 	}
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusSignedIRN, "success")
 	_ = repository.UpdateInvoiceIRN(pdb, invoiceModel, *theIRN)
-
-	go func(p zoho.WebhookPayload, b *models.Business, inv *models.Invoice, d *gorm.DB, irn string) {
-		if err := otherFirsProcesses(p, b, inv, d, irn); err != nil {
-			fmt.Println("Error in otherFirsProcesses: ", err)
-		}
-	}(payload, business, invoiceModel, db, *theIRN)
+	_ = repository.UpdateInvoiceSignature(pdb, invoiceModel, signIRNResp.EncryptedMessage, signIRNResp.QrCodeImage)
 
 	return theIRN, &signIRNResp.EncryptedMessage, nil
-
 }
 
-func otherFirsProcesses(payload zoho.WebhookPayload, business *models.Business, invoiceModel *models.Invoice, db *gorm.DB, theIRN string) error {
+func otherFirsProcesses(api zoho.API, payload zoho.WebhookPayload, business *models.Business, invoiceModel *models.Invoice, db *gorm.DB,
+	newInvoiceResp firs_models.InvoiceRequest, warnings []string, theIRN string) error {
 
 	pdb := inst.InitDB(db, true)
 
-	newInvoiceResp, err := converter.ConvertZohoToFIRS(payload.Invoice, business.BusinessID, business.Name, theIRN)
-	if err != nil {
-		return err
-	}
+	recordConversionWarnings(pdb, invoiceModel, warnings)
 
 	_, theErr, err := invoice.ValidateInvoice(newInvoiceResp)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusValidatedInvoice, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusValidatedInvoice, "failed", err.Error())
 		return fmt.Errorf("failed to validate invoice: %v - %v", *theErr, err)
 	}
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusValidatedInvoice, "success")
 
 	_, theErr, err = invoice.SignInvoice(newInvoiceResp)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusSignedInvoice, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusSignedInvoice, "failed", err.Error())
 		return fmt.Errorf("failed to sign invoice: %v - %v", *theErr, err)
 	}
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusSignedInvoice, "success")
 
+	if accConfig := business.PlatformConfigs[api.Platform]; accConfig.AttachPDF {
+		if err := attachment.QueueInvoicePDF(db, invoiceModel, api.Platform, accConfig.OrgID, payload.Invoice.InvoiceID, newInvoiceResp); err != nil {
+			fmt.Println("Error queueing invoice pdf attachment: ", err)
+		}
+	}
+
 	_, theErr, err = invoice.TransmitInvoice(*newInvoiceResp.IRN)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusTransmitted, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusTransmitted, "failed", err.Error())
 		return fmt.Errorf("failed to transmit invoice: %v - %v", *theErr, err)
 	}
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusTransmitted, "success")
 
 	_, theErr, err = invoice.TransmitConfirmInvoice(*newInvoiceResp.IRN)
 	if err != nil {
-		_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusConfirmed, "failed")
+		_ = repository.UpdateInvoiceStatusDetail(pdb, invoiceModel, models.StatusConfirmed, "failed", err.Error())
 		return fmt.Errorf("failed to confirm transmit invoice: %v - %v", *theErr, err)
 	}
 	_ = repository.UpdateInvoiceStatus(pdb, invoiceModel, models.StatusConfirmed, "success")
 
+	if err := invoice.QueueDelivery(db, invoiceModel, newInvoiceResp); err != nil {
+		fmt.Println("Error queueing invoice delivery: ", err)
+	}
+
 	confirmInvoiceResp, theErr, err := invoice.ConfirmInvoice(theIRN)
 	if err != nil {
 		return fmt.Errorf("failed to confirm invoice: %v - %v", *theErr, err)
@@ -114,3 +163,40 @@ func otherFirsProcesses(payload zoho.WebhookPayload, business *models.Business,
 	return nil
 
 }
+
+// ConvertZohoInvoice converts an invoice of the Zoho product with the product catalogue, customer directory and mapping the business keeps for it
+func ConvertZohoInvoice(db *gorm.DB, api zoho.API, business *models.Business, zohoInvoice zoho.Invoice, theIRN string) (firs_models.InvoiceRequest, []string, error) {
+	itemIDs := make([]string, 0, len(zohoInvoice.LineItems))
+	for _, item := range zohoInvoice.LineItems {
+		itemIDs = append(itemIDs, item.ItemID)
+	}
+
+	catalogue, err := product.GetCatalogue(db, business.ID, api.Platform, itemIDs)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	buyer, err := customer.FindCustomer(db, business.ID, api.Platform, zohoInvoice.CustomerID)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	mapping, err := platformmapping.GetMapping(db, business.ID, api.Platform)
+	if err != nil {
+		return firs_models.InvoiceRequest{}, nil, err
+	}
+
+	if api.Platform == zoho.BooksAPI.Platform {
+		return converter.ConvertZohoBooksToFIRS(zohoInvoice, business, theIRN, catalogue, buyer, mapping)
+	}
+	return converter.ConvertZohoToFIRS(zohoInvoice, business, theIRN, catalogue, buyer, mapping)
+}
+
+// recordConversionWarnings adds the adjustments made while converting the invoice to its timeline
+func recordConversionWarnings(pdb database.DatabaseManager, invoiceModel *models.Invoice, warnings []string) {
+	for _, warning := range warnings {
+		if err := repository.AppendInvoiceEvent(pdb, invoiceModel, models.EventConversionWarning, "warning", warning); err != nil {
+			fmt.Println("Error recording conversion warning: ", err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package webhooks
+
+import (
+	"einvoice-access-point/external/firs_models"
+	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/internal/services/attachment"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/invoice"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Events a platform invoice is handled as
+const (
+	EventInvoiceCreated  = "invoice_created"
+	EventInvoiceUpdated  = "invoice_updated"
+	EventInvoiceVoided   = "invoice_voided"
+	EventPaymentRecorded = "payment_recorded"
+)
+
+// PlatformInvoiceResponse tells what was done with an invoice received from a platform
+type PlatformInvoiceResponse struct {
+	Platform       string  `json:"platform"`
+	InvoiceID      string  `json:"invoice_id"`
+	InvoiceNumber  string  `json:"invoice_number"`
+	Total          float64 `json:"total"`
+	OrganizationID string  `json:"organization_id"`
+	Event          string  `json:"event"`
+	Updated        bool    `json:"updated"`
+}
+
+// ProcessPlatformInvoice handles an invoice a connector read from its platform. A new invoice is stored and sent through
+// the FIRS steps with the IRN and QR code written back; a known one is voided, has its payment recorded or its data
+// replaced, depending on what changed on the platform. Unchanged invoices are left alone.
+func ProcessPlatformInvoice(conn connector.Connector, platformInvoice connector.Invoice, db *gorm.DB, logger *utility.Logger,
+	firsKeys *utility.CryptoKeys, business *models.Business, accConfig models.AccountingPlatformConfig) (*PlatformInvoiceResponse, *string, error) {
+
+	pdb := inst.InitDB(db, true)
+	platform := conn.Name()
+
+	existing, err := repository.FindInvoiceByPlatformID(pdb, business.ID, platform, platformInvoice.ExternalID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		errDetails := "failed to look up invoice"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+	if err != nil {
+		existing = nil
+	}
+
+	event := platformEvent(platform, platformInvoice, existing)
+	logger.Info("Processing platform invoice",
+		zap.String("platform", platform),
+		zap.String("event", event),
+		zap.String("invoice_id", platformInvoice.ExternalID),
+		zap.String("invoice_number", platformInvoice.Number))
+
+	resp := &PlatformInvoiceResponse{
+		Platform:       platform,
+		InvoiceID:      platformInvoice.ExternalID,
+		InvoiceNumber:  platformInvoice.Number,
+		Total:          platformInvoice.Total,
+		OrganizationID: accConfig.OrgID,
+		Event:          event,
+	}
+
+	if existing == nil {
+		if event != EventInvoiceCreated {
+			errDetails := fmt.Sprintf("%s received for an unknown invoice", event)
+			return nil, &errDetails, ErrInvoiceNotReceived
+		}
+		if errDetails, err := createPlatformInvoice(conn, platformInvoice, db, logger, firsKeys, business, accConfig); err != nil {
+			return nil, errDetails, err
+		}
+		resp.Updated = true
+		return resp, nil, nil
+	}
+
+	latest := models.InvoicePlatformData{
+		InvoiceID:    platformInvoice.ExternalID,
+		Status:       platformInvoice.Status,
+		Total:        platformInvoice.Total,
+		CurrencyCode: platformInvoice.Currency,
+	}
+	metadata, err := withPlatformData(platform, existing, latest)
+	if err != nil {
+		errDetails := "failed to marshal platform metadata"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	var errDetails *string
+	switch event {
+	case EventInvoiceVoided:
+		// the voided copy on the platform may have its amounts zeroed, the credit note reverses the invoice as it was received
+		stored := existing.InvoiceData
+		voided := models.InvoicePlatformData{
+			Status:        platformInvoice.Status,
+			Total:         platformData(platform, existing).Total,
+			CurrencyCode:  platformData(platform, existing).CurrencyCode,
+			ExternalRefID: platformInvoice.ExternalID,
+		}
+		resp.Updated, errDetails, err = voidInvoice(db, platform, business, existing, metadata, voided, func(irn string) (firs_models.InvoiceRequest, []string, error) {
+			return conn.Convert(db, business, stored, irn)
+		})
+	case EventPaymentRecorded:
+		resp.Updated, errDetails, err = recordPayment(pdb, existing, platformPaymentStatus(platformInvoice), platformInvoice.Number, metadata)
+	default:
+		if sameJSON(existing.InvoiceData, platformInvoice.Data) {
+			return resp, nil, nil
+		}
+		resp.Updated, errDetails, err = replaceInvoiceData(pdb, existing, platformInvoice.Data, metadata)
+	}
+	if err != nil {
+		logger.Error("Failed to handle platform invoice", zap.String("platform", platform), zap.String("event", event), zap.Error(err))
+		return nil, errDetails, err
+	}
+	return resp, nil, nil
+}
+
+// platformEvent resolves what happened to the invoice on the platform. Voids win; a status change of an invoice
+// with payments against it is a payment, anything else an update.
+func platformEvent(platform string, platformInvoice connector.Invoice, existing *models.Invoice) string {
+	switch {
+	case existing == nil && platformInvoice.Voided:
+		return EventInvoiceVoided
+	case existing == nil:
+		return EventInvoiceCreated
+	case platformInvoice.Voided:
+		return EventInvoiceVoided
+	case platformData(platform, existing).Status != platformInvoice.Status && platformInvoice.Balance < platformInvoice.Total:
+		return EventPaymentRecorded
+	}
+	return EventInvoiceUpdated
+}
+
+// sameJSON reports whether two JSON documents hold the same values, whatever the key order and spacing jsonb stored them with
+func sameJSON(a, b []byte) bool {
+	var left, right interface{}
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// platformPaymentStatus maps the balance left on a platform invoice to the FIRS payment status
+func platformPaymentStatus(platformInvoice connector.Invoice) string {
+	if platformInvoice.Balance <= 0 && platformInvoice.Total > 0 {
+		return "PAID"
+	}
+	return "PENDING"
+}
+
+// createPlatformInvoice stores a new platform invoice, has its IRN issued and signed and writes both back to the
+// platform. The invoice is converted and sent through the remaining FIRS steps in the background.
+func createPlatformInvoice(conn connector.Connector, platformInvoice connector.Invoice, db *gorm.DB, logger *utility.Logger,
+	firsKeys *utility.CryptoKeys, business *models.Business, accConfig models.AccountingPlatformConfig) (*string, error) {
+
+	pdb := inst.InitDB(db, true)
+	platform := conn.Name()
+
+	metadataBytes, err := json.Marshal(models.PlatformMetadata{
+		platform: models.InvoicePlatformData{
+			InvoiceID:    platformInvoice.ExternalID,
+			Status:       platformInvoice.Status,
+			Total:        platformInvoice.Total,
+			CurrencyCode: platformInvoice.Currency,
+		},
+	})
+	if err != nil {
+		errDetails := "failed to marshal platform metadata"
+		return &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	currentStatus, statusHistory, err := models.InitPlatformInvoiceStatus()
+	if err != nil {
+		errDetails := "failed to initialize invoice status"
+		return &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	invoiceModel := &models.Invoice{
+		InvoiceNumber:    platformInvoice.Number,
+		BusinessID:       business.ID,
+		Platform:         platform,
+		PlatformMetadata: string(metadataBytes),
+		InvoiceData:      platformInvoice.Data,
+		CurrentStatus:    currentStatus,
+		StatusHistory:    statusHistory,
+		Timestamp:        time.Now(),
+	}
+	if err := repository.CreateInvoice(pdb, invoiceModel); err != nil {
+		errDetails := "failed to save invoice"
+		return &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	doc, warnings, err := convertBeforeIRN(db, invoiceModel, func() (firs_models.InvoiceRequest, []string, error) {
+		return conn.Convert(db, business, invoiceModel.InvoiceData, "")
+	})
+	if err != nil {
+		errDetails := "failed to convert invoice"
+		logger.Error("Failed to convert platform invoice", zap.Error(err), zap.String("platform", platform), zap.String("invoice_id", platformInvoice.ExternalID))
+		return &errDetails, err
+	}
+
+	theIRN, signedQR, err := signPlatformIRN(db, firsKeys, business, invoiceModel, platformInvoice.Number, platformInvoice.ExternalID)
+	if err != nil {
+		errDetails := "failed to running one or more firs process"
+		logger.Error("Failed to running firs processes", zap.Error(err))
+		return &errDetails, err
+	}
+	irn := *theIRN
+	doc.IRN = &irn
+
+	go func(inv *models.Invoice) {
+		if err := otherPlatformFirsProcesses(conn, business, accConfig, inv, db, doc, warnings, platformInvoice.ExternalID); err != nil {
+			fmt.Println("Error in otherPlatformFirsProcesses: ", err)
+		}
+	}(invoiceModel)
+
+	if err := attachment.QueueQRCode(db, invoiceModel, platform, accConfig.OrgID, platformInvoice.ExternalID); err != nil {
+		logger.Error("Failed to queue qr code attachment", zap.Error(err), zap.String("invoice_id", platformInvoice.ExternalID))
+	}
+
+	account := connector.Account{BusinessID: business.ID, Config: accConfig}
+	if err := conn.WriteBackIRN(db, account, platformInvoice.ExternalID, *theIRN, *signedQR); err != nil {
+		errDetails := err.Error()
+		logger.Error("Failed to update invoice", zap.Error(err), zap.String("platform", platform), zap.String("invoice_id", platformInvoice.ExternalID))
+		return &errDetails, fmt.Errorf("failed to update invoice in %s: %w", platform, err)
+	}
+	return nil, nil
+}
+
+// otherPlatformFirsProcesses takes the converted platform invoice through validation, signing and transmission
+func otherPlatformFirsProcesses(conn connector.Connector, business *models.Business, accConfig models.AccountingPlatformConfig,
+	invoiceModel *models.Invoice, db *gorm.DB, doc firs_models.InvoiceRequest, warnings []string, externalID string) error {
+
+	pdb := inst.InitDB(db, true)
+
+	recordConversionWarnings(pdb, invoiceModel, warnings)
+
+	err, signed := invoice.FirsAllInOneProcess(doc, invoiceModel, db)
+	if (err == nil || signed) && accConfig.AttachPDF {
+		if err := attachment.QueueInvoicePDF(db, invoiceModel, conn.Name(), accConfig.OrgID, externalID, doc); err != nil {
+			fmt.Println("Error queueing invoice pdf attachment: ", err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package webhooks
+
+import (
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// HandlePlatformWebhook handles the parsed webhooks of any registered platform. The business of every organisation
+// is looked up and the connector verifies the signature with the organisation's secret before any webhook is handled.
+// A webhook carrying a single organisation answers with its own response, otherwise with the list of responses.
+func HandlePlatformWebhook(conn connector.Connector, req connector.WebhookRequest, webhooks []*connector.Webhook,
+	db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys) (interface{}, *string, error) {
+
+	businesses := make([]*models.Business, len(webhooks))
+	configs := make([]models.AccountingPlatformConfig, len(webhooks))
+	for i, webhook := range webhooks {
+		business, config, err := GetBuinessConfigs(db, conn.Name(), webhook.OrgID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := conn.VerifyWebhook(req, *config); err != nil {
+			logger.Error("Invalid webhook signature", zap.String("platform", conn.Name()), zap.String("organization_id", webhook.OrgID))
+			return nil, nil, err
+		}
+		businesses[i], configs[i] = business, *config
+	}
+
+	responses := make([]interface{}, 0, len(webhooks))
+	for i, webhook := range webhooks {
+		resp, errDetails, err := conn.HandleWebhook(webhook, db, logger, firsKeys, businesses[i], configs[i])
+		if err != nil {
+			return nil, errDetails, err
+		}
+		responses = append(responses, resp)
+	}
+
+	if len(responses) == 1 {
+		return responses[0], nil, nil
+	}
+	return responses, nil, nil
+}
@@ -0,0 +1,410 @@
+package webhooks
+
+import (
+	"einvoice-access-point/external/firs_models"
+	"einvoice-access-point/external/zoho"
+	repository "einvoice-access-point/internal/repository/invoice"
+	"einvoice-access-point/internal/services/invoice"
+	"einvoice-access-point/pkg/database"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const creditNoteTypeCode = models.CreditNoteTypeCode
+
+var ErrInvoiceNotReceived = errors.New("invoice was never received from the platform")
+
+// zohoEventAliases maps the event names a Zoho webhook can be configured to send onto the handled events
+var zohoEventAliases = map[string]string{
+	"invoice_created":  zoho.EventInvoiceCreated,
+	"created":          zoho.EventInvoiceCreated,
+	"invoice_updated":  zoho.EventInvoiceUpdated,
+	"updated":          zoho.EventInvoiceUpdated,
+	"edited":           zoho.EventInvoiceUpdated,
+	"invoice_voided":   zoho.EventInvoiceVoided,
+	"voided":           zoho.EventInvoiceVoided,
+	"void":             zoho.EventInvoiceVoided,
+	"payment_recorded": zoho.EventPaymentRecorded,
+	"payment_received": zoho.EventPaymentRecorded,
+	"payment":          zoho.EventPaymentRecorded,
+}
+
+// routeZohoEvent dispatches a webhook to the handler of its event. Invoices that were already received are never
+// created again, so a repeated or edited invoice no longer collides with the stored invoice number.
+func routeZohoEvent(api zoho.API, payload zoho.WebhookPayload, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (*zoho.WebhookResponse, *string, error) {
+
+	pdb := inst.InitDB(db, true)
+
+	existing, err := repository.FindInvoiceByPlatformID(pdb, business.ID, api.Platform, payload.Invoice.InvoiceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		existing, err = repository.FindInvoiceByNumber(pdb, business.ID, payload.Invoice.InvoiceNumber)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		errDetails := "failed to look up invoice"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+	if err != nil {
+		existing = nil
+	}
+
+	event := zohoEvent(api, payload, existing)
+	logger.Info("Routing zoho webhook",
+		zap.String("event", event),
+		zap.String("invoice_id", payload.Invoice.InvoiceID),
+		zap.String("invoice_number", payload.Invoice.InvoiceNumber))
+
+	if existing == nil && event != zoho.EventInvoiceCreated {
+		errDetails := fmt.Sprintf("%s received for an unknown invoice", event)
+		return nil, &errDetails, ErrInvoiceNotReceived
+	}
+
+	var (
+		updated    bool
+		errDetails *string
+	)
+	switch event {
+	case zoho.EventInvoiceCreated:
+		return processZohoWebhook(api, payload, db, logger, firsKeys, business, accConfig)
+	case zoho.EventInvoiceUpdated:
+		updated, errDetails, err = updateZohoInvoice(pdb, api, payload, existing)
+	case zoho.EventPaymentRecorded:
+		updated, errDetails, err = recordZohoPayment(pdb, api, payload, existing)
+	case zoho.EventInvoiceVoided:
+		updated, errDetails, err = voidZohoInvoice(db, api, payload, business, existing)
+	}
+	if err != nil {
+		logger.Error("Failed to handle zoho event", zap.String("event", event), zap.Error(err))
+		return nil, errDetails, err
+	}
+
+	return &zoho.WebhookResponse{
+		InvoiceID:      payload.Invoice.InvoiceID,
+		InvoiceNumber:  payload.Invoice.InvoiceNumber,
+		CustomerName:   payload.Invoice.CustomerName,
+		Total:          payload.Invoice.Total,
+		OrganizationID: accConfig.OrgID,
+		Event:          event,
+		Updated:        updated,
+	}, nil, nil
+}
+
+// zohoEvent resolves the event of a webhook. A configured event type wins, except that a repeated create of a
+// known invoice is an update; updates and untyped webhooks are told apart by the Zoho status.
+func zohoEvent(api zoho.API, payload zoho.WebhookPayload, existing *models.Invoice) string {
+	event := zohoEventAliases[strings.ToLower(strings.TrimSpace(payload.EventType))]
+
+	switch {
+	case existing == nil && (event == "" || event == zoho.EventInvoiceUpdated):
+		return zoho.EventInvoiceCreated
+	case event == zoho.EventInvoiceVoided, event == zoho.EventPaymentRecorded:
+		return event
+	case existing == nil:
+		return event
+	}
+
+	switch payload.Invoice.Status {
+	case "void":
+		return zoho.EventInvoiceVoided
+	case "paid", "partially_paid":
+		if zohoPlatformData(api, existing).Status != payload.Invoice.Status {
+			return zoho.EventPaymentRecorded
+		}
+	}
+	return zoho.EventInvoiceUpdated
+}
+
+// updateZohoInvoice replaces the stored Zoho invoice with the edited one
+func updateZohoInvoice(pdb database.DatabaseManager, api zoho.API, payload zoho.WebhookPayload, existing *models.Invoice) (bool, *string, error) {
+	invoiceData, err := json.Marshal(payload.Invoice)
+	if err != nil {
+		errDetails := "failed to marshal invoice data"
+		return false, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+	metadata, err := withZohoPlatformData(api, existing, payload.Invoice)
+	if err != nil {
+		errDetails := "failed to marshal platform metadata"
+		return false, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	return replaceInvoiceData(pdb, existing, invoiceData, metadata)
+}
+
+// replaceInvoiceData stores the edited platform invoice until the invoice is transmitted to FIRS.
+// Later edits are only recorded, a transmitted invoice has to be corrected with a credit note.
+func replaceInvoiceData(pdb database.DatabaseManager, existing *models.Invoice, invoiceData []byte, metadata string) (bool, *string, error) {
+	if stepSucceeded(existing, models.StatusTransmitted) {
+		err := repository.AppendInvoiceEvent(pdb, existing, models.EventPlatformUpdated, "failed",
+			"invoice was already transmitted to FIRS, the edit was not applied")
+		return false, nil, err
+	}
+
+	if err := repository.UpdateInvoiceData(pdb, existing, invoiceData, metadata); err != nil {
+		errDetails := "failed to save invoice"
+		return false, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+	if err := repository.AppendInvoiceEvent(pdb, existing, models.EventPlatformUpdated, "success", "invoice data replaced"); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// recordZohoPayment sends the payment status of a paid invoice to FIRS
+func recordZohoPayment(pdb database.DatabaseManager, api zoho.API, payload zoho.WebhookPayload, existing *models.Invoice) (bool, *string, error) {
+	metadata, err := withZohoPlatformData(api, existing, payload.Invoice)
+	if err != nil {
+		errDetails := "failed to marshal platform metadata"
+		return false, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	return recordPayment(pdb, existing, zohoPaymentStatus(payload.Invoice), payload.Invoice.InvoiceNumber, metadata)
+}
+
+// recordPayment sends the payment status of an invoice FIRS signed and keeps the latest platform metadata
+func recordPayment(pdb database.DatabaseManager, existing *models.Invoice, paymentStatus, reference, metadata string) (bool, *string, error) {
+	if existing.IRN == "" || !stepSucceeded(existing, models.StatusSignedInvoice) {
+		errDetails := "invoice has not been signed by FIRS yet"
+		_ = repository.AppendInvoiceEvent(pdb, existing, models.EventPaymentRecorded, "failed", errDetails)
+		return false, &errDetails, errors.New(errDetails)
+	}
+
+	_, errDetails, err := invoice.UpdateInvoice(firs_models.UpdateInvoice{PaymentStatus: paymentStatus, Reference: &reference}, existing.IRN)
+	if err != nil {
+		detail := err.Error()
+		if errDetails != nil {
+			detail = fmt.Sprintf("%s: %s", detail, *errDetails)
+		}
+		_ = repository.AppendInvoiceEvent(pdb, existing, models.EventPaymentRecorded, "failed", detail)
+		return false, errDetails, err
+	}
+
+	existing.PlatformMetadata = metadata
+	if err := repository.AppendInvoiceEvent(pdb, existing, models.EventPaymentRecorded, "success", "payment_status "+paymentStatus); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// voidZohoInvoice cancels a voided Zoho invoice
+func voidZohoInvoice(db *gorm.DB, api zoho.API, payload zoho.WebhookPayload, business *models.Business, existing *models.Invoice) (bool, *string, error) {
+	metadata, err := withZohoPlatformData(api, existing, payload.Invoice)
+	if err != nil {
+		errDetails := "failed to marshal platform metadata"
+		return false, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	platformData := models.InvoicePlatformData{
+		Status:        "void",
+		Total:         payload.Invoice.Total,
+		CurrencyCode:  payload.Invoice.CurrencyCode,
+		ExternalRefID: payload.Invoice.InvoiceID,
+	}
+	return voidInvoice(db, api.Platform, business, existing, metadata, platformData, func(irn string) (firs_models.InvoiceRequest, []string, error) {
+		return ConvertZohoInvoice(db, api, business, payload.Invoice, irn)
+	})
+}
+
+// voidInvoice cancels a voided platform invoice. Invoices FIRS already signed are reversed with a credit note that
+// references the original IRN, earlier ones only have the void recorded.
+func voidInvoice(db *gorm.DB, platform string, business *models.Business, existing *models.Invoice, metadata string,
+	platformData models.InvoicePlatformData, convert creditNoteConverter) (bool, *string, error) {
+
+	pdb := inst.InitDB(db, true)
+	existing.PlatformMetadata = metadata
+
+	if existing.IRN == "" || !stepSucceeded(existing, models.StatusSignedInvoice) {
+		err := repository.AppendInvoiceEvent(pdb, existing, models.EventVoided, "success", "voided before FIRS signed the invoice, no credit note needed")
+		return err == nil, nil, err
+	}
+
+	creditNoteNumber := "CN-" + existing.InvoiceNumber
+	if _, err := repository.FindInvoiceByNumber(pdb, business.ID, creditNoteNumber); err == nil {
+		return false, nil, nil
+	}
+
+	creditNote, errDetails, err := issueCreditNote(db, platform, business, existing, creditNoteNumber, platformData, convert)
+	if err != nil {
+		_ = repository.AppendInvoiceEvent(pdb, existing, models.EventVoided, "failed", err.Error())
+		return false, errDetails, err
+	}
+
+	if err := repository.AppendInvoiceEvent(pdb, existing, models.EventVoided, "success", "credit note "+creditNote.InvoiceNumber+" issued"); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// creditNoteConverter converts the voided invoice under the IRN issued for its credit note
+type creditNoteConverter func(irn string) (firs_models.InvoiceRequest, []string, error)
+
+// issueCreditNote stores a credit note for the voided invoice and sends it through the FIRS steps in the background
+func issueCreditNote(db *gorm.DB, platform string, business *models.Business, original *models.Invoice, creditNoteNumber string,
+	platformData models.InvoicePlatformData, convert creditNoteConverter) (*models.Invoice, *string, error) {
+
+	pdb := inst.InitDB(db, true)
+
+	theIRN, err := invoice.IssueIRN(db, business.ID, creditNoteNumber)
+	if err != nil {
+		errDetails := "failed to issue credit note IRN"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	doc, warnings, err := convert(*theIRN)
+	if err != nil {
+		errDetails := "failed to convert credit note"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	now := time.Now()
+	issueDate := now.Format(time.DateOnly)
+	issueTime := now.Format(time.TimeOnly)
+	originalIssueDate := doc.IssueDate
+	doc.InvoiceTypeCode = creditNoteTypeCode
+	doc.IssueDate = issueDate
+	doc.IssueTime = &issueTime
+	doc.TaxPointDate = &issueDate
+	doc.DueDate = nil
+	doc.PaymentStatus = nil
+	doc.BillingReference = []firs_models.DocumentReference{{IRN: original.IRN, IssueDate: originalIssueDate}}
+
+	invoiceData, err := json.Marshal(doc)
+	if err != nil {
+		errDetails := "failed to marshal credit note"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+	metadata, err := json.Marshal(models.PlatformMetadata{platform: platformData})
+	if err != nil {
+		errDetails := "failed to marshal platform metadata"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	currentStatus, statusHistory, err := models.InitNewInvoiceStatus()
+	if err != nil {
+		errDetails := "failed to initialize invoice status"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+
+	creditNote := &models.Invoice{
+		InvoiceNumber:    creditNoteNumber,
+		IRN:              *theIRN,
+		BusinessID:       business.ID,
+		Platform:         platform,
+		PlatformMetadata: string(metadata),
+		InvoiceData:      invoiceData,
+		CurrentStatus:    currentStatus,
+		StatusHistory:    statusHistory,
+		Timestamp:        now,
+	}
+	if err := repository.CreateInvoice(pdb, creditNote); err != nil {
+		errDetails := "failed to save credit note"
+		return nil, &errDetails, fmt.Errorf("%s: %w", errDetails, err)
+	}
+	recordConversionWarnings(pdb, creditNote, warnings)
+
+	go func(d firs_models.InvoiceRequest, inv *models.Invoice, gdb *gorm.DB) {
+		if err, _ := invoice.FirsAllInOneProcess(d, inv, gdb); err != nil {
+			fmt.Println("Error processing credit note: ", err)
+		}
+	}(doc, creditNote, db)
+
+	return creditNote, nil, nil
+}
+
+// zohoPaymentStatus maps the balance of a Zoho invoice to the FIRS payment status
+func zohoPaymentStatus(zohoInvoice zoho.Invoice) string {
+	if zohoInvoice.Status == "paid" || (zohoInvoice.Balance == 0 && zohoInvoice.Total > 0) {
+		return "PAID"
+	}
+	return "PENDING"
+}
+
+func zohoPlatformData(api zoho.API, inv *models.Invoice) models.InvoicePlatformData {
+	return platformData(api.Platform, inv)
+}
+
+// withZohoPlatformData returns the platform metadata of the invoice updated with the latest Zoho status and total
+func withZohoPlatformData(api zoho.API, inv *models.Invoice, zohoInvoice zoho.Invoice) (string, error) {
+	return withPlatformData(api.Platform, inv, models.InvoicePlatformData{
+		InvoiceID:    zohoInvoice.InvoiceID,
+		Status:       zohoInvoice.Status,
+		Total:        zohoInvoice.Total,
+		CurrencyCode: zohoInvoice.CurrencyCode,
+	})
+}
+
+// platformData returns what the invoice metadata records about the invoice on the platform
+func platformData(platform string, inv *models.Invoice) models.InvoicePlatformData {
+	var metadata models.PlatformMetadata
+	_ = json.Unmarshal([]byte(inv.PlatformMetadata), &metadata)
+	return metadata[platform]
+}
+
+// withPlatformData returns the platform metadata of the invoice updated with the latest platform ID, status and
+// total. The currency recorded when the invoice was received is kept.
+func withPlatformData(platform string, inv *models.Invoice, latest models.InvoicePlatformData) (string, error) {
+	metadata := models.PlatformMetadata{}
+	_ = json.Unmarshal([]byte(inv.PlatformMetadata), &metadata)
+
+	data := metadata[platform]
+	data.InvoiceID = latest.InvoiceID
+	data.Status = latest.Status
+	data.Total = latest.Total
+	if data.CurrencyCode == "" {
+		data.CurrencyCode = latest.CurrencyCode
+	}
+	metadata[platform] = data
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// stepSucceeded reports whether a processing step of the invoice completed
+func stepSucceeded(inv *models.Invoice, step string) bool {
+	var history []models.StatusHistoryEntry
+	if err := json.Unmarshal(inv.StatusHistory, &history); err != nil {
+		return false
+	}
+	for _, entry := range history {
+		if entry.Step == step {
+			return entry.Status == "success"
+		}
+	}
+	return false
+}
+
+// ProcessZohoInvoice feeds an invoice read from the Zoho API into the webhook processing path. It reports false
+// without doing anything when the stored copy already carries the same last modified time and status.
+func ProcessZohoInvoice(api zoho.API, zohoInvoice zoho.Invoice, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (bool, error) {
+
+	pdb := inst.InitDB(db, true)
+
+	existing, err := repository.FindInvoiceByPlatformID(pdb, business.ID, api.Platform, zohoInvoice.InvoiceID)
+	if err == nil {
+		var stored zoho.Invoice
+		if json.Unmarshal(existing.InvoiceData, &stored) == nil &&
+			stored.LastModifiedTime == zohoInvoice.LastModifiedTime && zohoPlatformData(api, existing).Status == zohoInvoice.Status {
+			return false, nil
+		}
+	}
+
+	if _, errDetails, err := routeZohoEvent(api, zoho.WebhookPayload{Invoice: zohoInvoice}, db, logger, firsKeys, business, accConfig); err != nil {
+		if errDetails != nil && *errDetails != err.Error() {
+			return false, fmt.Errorf("%s: %w", *errDetails, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -4,7 +4,8 @@ import (
 	"einvoice-access-point/external/zoho"
 	businessRepository "einvoice-access-point/internal/repository/business"
 	repository "einvoice-access-point/internal/repository/invoice"
-	"einvoice-access-point/internal/services/token"
+	"einvoice-access-point/internal/services/attachment"
+	"einvoice-access-point/internal/services/connector"
 	inst "einvoice-access-point/pkg/dbinit"
 	"einvoice-access-point/pkg/models"
 	"einvoice-access-point/pkg/utility"
@@ -20,27 +21,22 @@ import (
 var (
 	ErrOrganizationNotFound = errors.New("organization not found")
 	ErrZohoAPIUpdateFailed  = errors.New("failed to update invoice in Zoho")
-	ErrInvalidSignature     = fmt.Errorf("invalid webhook signature")
+	ErrInvalidSignature     = connector.ErrInvalidSignature
 )
 
-// HandleZohoWebhookService handles all the webhook logic
-func HandleZohoWebhookService(payload zoho.WebhookPayload, rawBody string, signature string,
-	db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys, orgID string) (*zoho.WebhookResponse, *string, error) {
-	platform := "zoho"
-
-	business, config, err := GetBuinessConfigs(db, platform, orgID)
-	if err != nil {
-		return nil, nil, err
+// VerifyZohoSignature checks the signature a Zoho product sent with the webhook against the secret of the organisation
+func VerifyZohoSignature(rawBody []byte, signature string, accConfig models.AccountingPlatformConfig) error {
+	if !utility.VerifyWebhookSignature(rawBody, string(accConfig.HMACSecret), signature) {
+		return ErrInvalidSignature
 	}
+	return nil
+}
 
-	print(string(config.HMACSecret), string(config.AuthToken), string(config.APIKey), string(config.APISecret))
-
-	if utility.VerifyWebhookSignature([]byte(rawBody), string(config.HMACSecret), signature) {
-		logger.Error("Invalid webhook signature", zap.String("organization_id", payload.Invoice.InvoiceID))
-		return nil, nil, ErrInvalidSignature
-	}
+// HandleZohoEvent handles a verified webhook of the Zoho product
+func HandleZohoEvent(api zoho.API, payload zoho.WebhookPayload, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+	business *models.Business, accConfig models.AccountingPlatformConfig) (*zoho.WebhookResponse, *string, error) {
 
-	respData, errDetails, err := processZohoWebhook(payload, db, logger, firsKeys, business, *config)
+	respData, errDetails, err := routeZohoEvent(api, payload, db, logger, firsKeys, business, accConfig)
 	if err != nil {
 		return nil, errDetails, err
 	}
@@ -48,8 +44,8 @@ func HandleZohoWebhookService(payload zoho.WebhookPayload, rawBody string, signa
 	return respData, nil, nil
 }
 
-// ProcessWebhook processes the Zoho webhook payload
-func processZohoWebhook(payload zoho.WebhookPayload, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
+// processZohoWebhook stores a newly created Zoho invoice and sends it through the FIRS steps
+func processZohoWebhook(api zoho.API, payload zoho.WebhookPayload, db *gorm.DB, logger *utility.Logger, firsKeys *utility.CryptoKeys,
 	business *models.Business, accConfig models.AccountingPlatformConfig) (*zoho.WebhookResponse, *string, error) {
 
 	logger.Info("Processing invoice",
@@ -61,9 +57,9 @@ func processZohoWebhook(payload zoho.WebhookPayload, db *gorm.DB, logger *utilit
 	pdb := inst.InitDB(db, true)
 
 	platformMetadata := models.PlatformMetadata{
-		"zoho": models.InvoicePlatformData{
+		api.Platform: models.InvoicePlatformData{
 			InvoiceID:    payload.Invoice.InvoiceID,
-			Status:       "sent",
+			Status:       payload.Invoice.Status,
 			Total:        payload.Invoice.Total,
 			CurrencyCode: "NGN",
 		},
@@ -92,7 +88,7 @@ func processZohoWebhook(payload zoho.WebhookPayload, db *gorm.DB, logger *utilit
 	invoice := &models.Invoice{
 		InvoiceNumber:    payload.Invoice.InvoiceNumber,
 		BusinessID:       business.ID,
-		Platform:         "zoho",
+		Platform:         api.Platform,
 		PlatformMetadata: string(metadataBytes),
 		InvoiceData:      invoiceData,
 		CurrentStatus:    currentStatus,
@@ -106,20 +102,25 @@ func processZohoWebhook(payload zoho.WebhookPayload, db *gorm.DB, logger *utilit
 		return nil, &errDetails, fmt.Errorf("failed to save invoice: %w", err)
 	}
 
-	theIRN, theQrCode, err := FirsZohoAllInOneProcess(payload, firsKeys, business, invoice, db)
+	theIRN, theQrCode, err := FirsZohoAllInOneProcess(api, payload, firsKeys, business, invoice, db)
 	if err != nil {
 		errDetails := "failed to running one or more firs process"
 		logger.Error("Failed to running firs processes", zap.Error(err))
 		return nil, &errDetails, err
 	}
 
-	accessToken, err := token.GetValidAccessToken(db, accConfig, "zoho", accConfig.OrgID)
+	if err := attachment.QueueQRCode(db, invoice, api.Platform, accConfig.OrgID, payload.Invoice.InvoiceID); err != nil {
+		logger.Error("Failed to queue qr code attachment", zap.Error(err), zap.String("invoice_id", payload.Invoice.InvoiceID))
+	}
+
+	conn, err := connector.Get(api.Platform)
 	if err != nil {
-		errDetails := "failed to marshal platform metadata"
+		errDetails := err.Error()
 		return nil, &errDetails, err
 	}
 
-	err = zoho.UpdateZohoInvoice(accessToken, payload.Invoice.InvoiceID, *theIRN, *theQrCode, accConfig)
+	account := connector.Account{BusinessID: business.ID, Config: accConfig}
+	err = conn.WriteBackIRN(db, account, payload.Invoice.InvoiceID, *theIRN, *theQrCode)
 	if err != nil {
 		errDetails := err.Error()
 		logger.Error("Failed to update invoice", zap.Error(err), zap.String("invoice_id", payload.Invoice.InvoiceID))
@@ -132,6 +133,7 @@ func processZohoWebhook(payload zoho.WebhookPayload, db *gorm.DB, logger *utilit
 		CustomerName:   payload.Invoice.CustomerName,
 		Total:          payload.Invoice.Total,
 		OrganizationID: accConfig.OrgID,
+		Event:          zoho.EventInvoiceCreated,
 		Updated:        true,
 	}
 
@@ -0,0 +1,151 @@
+package writeback
+
+import (
+	repository "einvoice-access-point/internal/repository/writeback"
+	"einvoice-access-point/internal/services/connector"
+	inst "einvoice-access-point/pkg/dbinit"
+	"einvoice-access-point/pkg/models"
+	"einvoice-access-point/pkg/utility"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxWriteBackAttempts is the number of sends tried before a write-back is marked failed
+	MaxWriteBackAttempts = 5
+	// WriteBackInterval is how often the worker looks for due write-backs
+	WriteBackInterval = 30 * time.Second
+
+	writeBackBatchSize = 50
+	writeBackLease     = 5 * time.Minute
+)
+
+// writeBackBackoff is the wait before each retry, indexed by the number of failed attempts
+var writeBackBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+var ErrWriteBackNotFound = errors.New("invoice has no status write-back")
+
+func GetWriteBack(db *gorm.DB, businessID, invoiceID string) (*models.StatusWriteBack, error) {
+	pdb := inst.InitDB(db, true)
+
+	writeBack, err := repository.FindWriteBackByInvoice(pdb, businessID, invoiceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWriteBackNotFound
+	}
+	return writeBack, err
+}
+
+// RetryWriteBack queues the current status of the invoice again with a fresh attempt budget
+func RetryWriteBack(db *gorm.DB, businessID, invoiceID string) (*models.StatusWriteBack, error) {
+	pdb := inst.InitDB(db, true)
+
+	writeBack, err := GetWriteBack(db, businessID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repository.RequeueWriteBack(pdb, writeBack, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to queue write-back: %w", err)
+	}
+	return GetWriteBack(db, businessID, invoiceID)
+}
+
+// ProcessDueWriteBacks sends every write-back whose next attempt is due and returns how many were sent
+func ProcessDueWriteBacks(db *gorm.DB) (int, error) {
+	pdb := inst.InitDB(db, true)
+
+	writeBacks, err := repository.FindDueWriteBacks(pdb, time.Now(), writeBackBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range writeBacks {
+		writeBack := &writeBacks[i]
+
+		claimed, err := repository.ClaimWriteBack(pdb, writeBack, time.Now().Add(writeBackLease))
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		recordAttempt(writeBack, send(db, writeBack))
+		current, err := repository.RecordWriteBackAttempt(pdb, writeBack)
+		if err != nil {
+			return sent, fmt.Errorf("failed to save write-back %s: %w", writeBack.ID, err)
+		}
+		if current && writeBack.Status == models.WriteBackSent {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// StartWriteBackWorker runs ProcessDueWriteBacks every WriteBackInterval until the process exits
+func StartWriteBackWorker(db *gorm.DB, logger *utility.Logger) {
+	go func() {
+		ticker := time.NewTicker(WriteBackInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := ProcessDueWriteBacks(db)
+			if err != nil {
+				logger.Error("invoice status write-back failed", err)
+				continue
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("wrote back the status of %d invoices", count))
+			}
+		}
+	}()
+}
+
+// recordAttempt stores the outcome of a send, failures are retried with backoff until MaxWriteBackAttempts is reached
+func recordAttempt(writeBack *models.StatusWriteBack, err error) {
+	now := time.Now()
+	writeBack.Attempts++
+
+	if err == nil {
+		writeBack.Status = models.WriteBackSent
+		writeBack.LastError = ""
+		writeBack.SentAt = &now
+		writeBack.NextAttemptAt = nil
+		return
+	}
+
+	writeBack.LastError = err.Error()
+
+	if writeBack.Attempts >= MaxWriteBackAttempts {
+		writeBack.Status = models.WriteBackFailed
+		writeBack.NextAttemptAt = nil
+		return
+	}
+
+	backoff := writeBackBackoff[len(writeBackBackoff)-1]
+	if writeBack.Attempts <= len(writeBackBackoff) {
+		backoff = writeBackBackoff[writeBack.Attempts-1]
+	}
+	next := now.Add(backoff)
+	writeBack.Status = models.WriteBackPending
+	writeBack.NextAttemptAt = &next
+}
+
+// send writes the status to the invoice on the platform through its connector
+func send(db *gorm.DB, writeBack *models.StatusWriteBack) error {
+	conn, err := connector.Get(writeBack.Platform)
+	if err != nil {
+		return fmt.Errorf("status cannot be written back to %s", writeBack.Platform)
+	}
+
+	account, err := connector.LoadAccount(db, writeBack.BusinessID, writeBack.Platform)
+	if err != nil {
+		return err
+	}
+	return conn.WriteBackStatus(db, account, writeBack.ExternalInvoiceID, writeBack.FirsStatus, writeBack.FirsError)
+}
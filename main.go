@@ -18,6 +18,19 @@ import (
 	"github.com/go-playground/validator/v10"
 
 	v1 "einvoice-access-point/api/v1"
+	"einvoice-access-point/external/zoho"
+	"einvoice-access-point/internal/services/attachment"
+	"einvoice-access-point/internal/services/connector"
+	"einvoice-access-point/internal/services/connector/odooconnector"
+	"einvoice-access-point/internal/services/connector/quickbooksconnector"
+	"einvoice-access-point/internal/services/connector/xeroconnector"
+	"einvoice-access-point/internal/services/connector/zohoconnector"
+	"einvoice-access-point/internal/services/customer"
+	"einvoice-access-point/internal/services/delivery"
+	"einvoice-access-point/internal/services/export"
+	"einvoice-access-point/internal/services/platformsync"
+	"einvoice-access-point/internal/services/upload"
+	"einvoice-access-point/internal/services/writeback"
 	"einvoice-access-point/pkg/config"
 	"einvoice-access-point/pkg/database"
 	"einvoice-access-point/pkg/database/postgresql"
@@ -50,6 +63,34 @@ func main() {
 		// seed.SeedDatabase(db)
 	}
 
+	// Periodically re-check the TINs in the customer directory
+	connector.Register(zohoconnector.New(zoho.InvoiceAPI))
+	connector.Register(zohoconnector.New(zoho.BooksAPI))
+	connector.Register(quickbooksconnector.New())
+	connector.Register(xeroconnector.New())
+	connector.Register(odooconnector.New())
+
+	customer.StartTINReverification(db.Postgresql.DB(), logger)
+
+	// Email confirmed invoices to their buyers
+	delivery.StartDeliveryWorker(db.Postgresql.DB(), keys, logger)
+
+	// Build queued invoice archive exports and remove expired ones
+	export.StartExportWorker(db.Postgresql.DB(), keys, logger)
+
+	// Submit the invoices of queued spreadsheet imports
+	upload.StartImportWorker(db.Postgresql.DB(), keys, logger)
+
+	// Upload QR codes and invoice PDFs to the invoices on the accounting platforms
+	attachment.StartAttachmentWorker(db.Postgresql.DB(), keys, logger)
+
+	// Write the FIRS processing status back to the invoices on the accounting platforms
+	writeback.StartWriteBackWorker(db.Postgresql.DB(), logger)
+
+	// Pull changed invoices of organisations that poll instead of sending webhooks, such as Zoho
+	// organisations with the sync enabled and connected Odoo databases
+	platformsync.StartSyncWorker(db.Postgresql.DB(), keys, logger)
+
 	app := v1.Setup(logger, validatorRef, db, keys)
 
 	host := os.Getenv("HOST")
@@ -12,7 +12,11 @@ type Configuration struct {
 	App          App
 	Firs         FIRS
 	Zoho         ZOHO
+	QuickBooks   QUICKBOOKS
+	Xero         XERO
+	Peppol       PEPPOL
 	Mail         MAIL
+	Export       EXPORT
 	Redis        Redis
 }
 
@@ -58,16 +62,45 @@ type BaseConfig struct {
 	FIRS_PUBLIC_KEY string `mapstructure:"FIRS_PUBLIC_KEY"`
 	FIRS_CERT_KEY   string `mapstructure:"FIRS_CERT_KEY"`
 
-	MAIL_SERVER   string `mapstructure:"MAIL_SERVER"`
-	MAIL_PASSWORD string `mapstructure:"MAIL_PASSWORD"`
-	MAIL_USERNAME string `mapstructure:"MAIL_USERNAME"`
-	MAIL_PORT     string `mapstructure:"MAIL_PORT"`
+	MAIL_SERVER    string `mapstructure:"MAIL_SERVER"`
+	MAIL_PASSWORD  string `mapstructure:"MAIL_PASSWORD"`
+	MAIL_USERNAME  string `mapstructure:"MAIL_USERNAME"`
+	MAIL_PORT      string `mapstructure:"MAIL_PORT"`
+	MAIL_FROM      string `mapstructure:"MAIL_FROM"`
+	MAIL_FROM_NAME string `mapstructure:"MAIL_FROM_NAME"`
+
+	EXPORT_DIR             string `mapstructure:"EXPORT_DIR"`
+	EXPORT_RETENTION_HOURS int    `mapstructure:"EXPORT_RETENTION_HOURS"`
 
 	REDIS_PORT string `mapstructure:"REDIS_PORT"`
 	REDIS_HOST string `mapstructure:"REDIS_HOST"`
 	REDIS_DB   string `mapstructure:"REDIS_DB"`
 
-	ZOHO_API_URL string `mapstructure:"ZOHO_API_URL"`
+	ZOHO_API_URL       string `mapstructure:"ZOHO_API_URL"`
+	ZOHO_BOOKS_API_URL string `mapstructure:"ZOHO_BOOKS_API_URL"`
+	ZOHO_CLIENT_ID     string `mapstructure:"ZOHO_CLIENT_ID"`
+	ZOHO_CLIENT_SECRET string `mapstructure:"ZOHO_CLIENT_SECRET"`
+	ZOHO_REDIRECT_URL  string `mapstructure:"ZOHO_REDIRECT_URL"`
+	ZOHO_ACCOUNTS_URL  string `mapstructure:"ZOHO_ACCOUNTS_URL"`
+
+	QUICKBOOKS_API_URL          string `mapstructure:"QUICKBOOKS_API_URL"`
+	QUICKBOOKS_AUTH_URL         string `mapstructure:"QUICKBOOKS_AUTH_URL"`
+	QUICKBOOKS_TOKEN_URL        string `mapstructure:"QUICKBOOKS_TOKEN_URL"`
+	QUICKBOOKS_CLIENT_ID        string `mapstructure:"QUICKBOOKS_CLIENT_ID"`
+	QUICKBOOKS_CLIENT_SECRET    string `mapstructure:"QUICKBOOKS_CLIENT_SECRET"`
+	QUICKBOOKS_REDIRECT_URL     string `mapstructure:"QUICKBOOKS_REDIRECT_URL"`
+	QUICKBOOKS_WEBHOOK_VERIFIER string `mapstructure:"QUICKBOOKS_WEBHOOK_VERIFIER"`
+
+	XERO_API_URL       string `mapstructure:"XERO_API_URL"`
+	XERO_AUTH_URL      string `mapstructure:"XERO_AUTH_URL"`
+	XERO_TOKEN_URL     string `mapstructure:"XERO_TOKEN_URL"`
+	XERO_CLIENT_ID     string `mapstructure:"XERO_CLIENT_ID"`
+	XERO_CLIENT_SECRET string `mapstructure:"XERO_CLIENT_SECRET"`
+	XERO_REDIRECT_URL  string `mapstructure:"XERO_REDIRECT_URL"`
+	XERO_WEBHOOK_KEY   string `mapstructure:"XERO_WEBHOOK_KEY"`
+
+	PEPPOL_AP_URL string `mapstructure:"PEPPOL_AP_URL"`
+	PEPPOL_AP_KEY string `mapstructure:"PEPPOL_AP_KEY"`
 }
 
 func (config *BaseConfig) SetupConfigurationn() *Configuration {
@@ -128,7 +161,34 @@ func (config *BaseConfig) SetupConfigurationn() *Configuration {
 			FirsCertKey:   config.FIRS_CERT_KEY,
 		},
 		Zoho: ZOHO{
-			ZohoApiUrl: config.ZOHO_API_URL,
+			ZohoApiUrl:   config.ZOHO_API_URL,
+			BooksApiUrl:  config.ZOHO_BOOKS_API_URL,
+			ClientID:     config.ZOHO_CLIENT_ID,
+			ClientSecret: config.ZOHO_CLIENT_SECRET,
+			RedirectUrl:  config.ZOHO_REDIRECT_URL,
+			AccountsUrl:  config.ZOHO_ACCOUNTS_URL,
+		},
+		QuickBooks: QUICKBOOKS{
+			ApiUrl:          config.QUICKBOOKS_API_URL,
+			AuthUrl:         config.QUICKBOOKS_AUTH_URL,
+			TokenUrl:        config.QUICKBOOKS_TOKEN_URL,
+			ClientID:        config.QUICKBOOKS_CLIENT_ID,
+			ClientSecret:    config.QUICKBOOKS_CLIENT_SECRET,
+			RedirectUrl:     config.QUICKBOOKS_REDIRECT_URL,
+			WebhookVerifier: config.QUICKBOOKS_WEBHOOK_VERIFIER,
+		},
+		Xero: XERO{
+			ApiUrl:       config.XERO_API_URL,
+			AuthUrl:      config.XERO_AUTH_URL,
+			TokenUrl:     config.XERO_TOKEN_URL,
+			ClientID:     config.XERO_CLIENT_ID,
+			ClientSecret: config.XERO_CLIENT_SECRET,
+			RedirectUrl:  config.XERO_REDIRECT_URL,
+			WebhookKey:   config.XERO_WEBHOOK_KEY,
+		},
+		Peppol: PEPPOL{
+			AccessPointUrl: config.PEPPOL_AP_URL,
+			AccessPointKey: config.PEPPOL_AP_KEY,
 		},
 
 		Mail: MAIL{
@@ -136,6 +196,12 @@ func (config *BaseConfig) SetupConfigurationn() *Configuration {
 			Password: config.MAIL_PASSWORD,
 			Port:     config.MAIL_PORT,
 			Username: config.MAIL_USERNAME,
+			From:     config.MAIL_FROM,
+			FromName: config.MAIL_FROM_NAME,
+		},
+		Export: EXPORT{
+			Dir:            config.EXPORT_DIR,
+			RetentionHours: config.EXPORT_RETENTION_HOURS,
 		},
 
 		Redis: Redis{
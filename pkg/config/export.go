@@ -0,0 +1,6 @@
+package config
+
+type EXPORT struct {
+	Dir            string
+	RetentionHours int
+}
@@ -9,5 +9,35 @@ type FIRS struct {
 }
 
 type ZOHO struct {
-	ZohoApiUrl string
+	ZohoApiUrl   string
+	BooksApiUrl  string
+	ClientID     string
+	ClientSecret string
+	RedirectUrl  string
+	AccountsUrl  string
+}
+
+type QUICKBOOKS struct {
+	ApiUrl          string
+	AuthUrl         string
+	TokenUrl        string
+	ClientID        string
+	ClientSecret    string
+	RedirectUrl     string
+	WebhookVerifier string
+}
+
+type XERO struct {
+	ApiUrl       string
+	AuthUrl      string
+	TokenUrl     string
+	ClientID     string
+	ClientSecret string
+	RedirectUrl  string
+	WebhookKey   string
+}
+
+type PEPPOL struct {
+	AccessPointUrl string
+	AccessPointKey string
 }
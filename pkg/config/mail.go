@@ -5,4 +5,6 @@ type MAIL struct {
 	Port     string
 	Username string
 	Password string
+	From     string
+	FromName string
 }
@@ -10,6 +10,8 @@ import (
 func RunAllMigrations(db *database.Database) {
 
 	MigrateModels(db.Postgresql.DB(), AuthMigrationModels(), AlterColumnModels())
+	DropConstraints(db.Postgresql.DB(), DropConstraintModels())
+	BackfillColumns(db.Postgresql.DB(), BackfillColumnModels())
 
 }
 
@@ -25,3 +27,35 @@ func MigrateModels(db *gorm.DB, models []interface{}, AlterColums []AlterColumn)
 	}
 
 }
+
+func DropConstraints(db *gorm.DB, constraints []DropConstraint) {
+	for _, d := range constraints {
+		if err := d.Drop(db); err != nil {
+			fmt.Println("error dropping constraint ", d.Constraint, "on", d.TableName, ": ", err)
+		}
+	}
+}
+
+func BackfillColumns(db *gorm.DB, columns []BackfillColumn) {
+	for _, b := range columns {
+		if err := b.Backfill(db); err != nil {
+			fmt.Println("error backfilling ", b.TableName, "column", b.Column, ": ", err)
+		}
+	}
+}
+
+func DropIndexes(db *gorm.DB, indexes []DropIndex) {
+	for _, d := range indexes {
+		if err := d.Drop(db); err != nil {
+			fmt.Println("error dropping index ", d.Index, ": ", err)
+		}
+	}
+}
+
+func EncryptColumns(db *gorm.DB, columns []EncryptColumn) {
+	for _, e := range columns {
+		if err := e.Encrypt(db); err != nil {
+			fmt.Println("error encrypting ", e.TableName, "column", e.Column, ": ", err)
+		}
+	}
+}
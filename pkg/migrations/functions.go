@@ -1,6 +1,7 @@
 package migrations
 
 import (
+	"einvoice-access-point/pkg/common"
 	"fmt"
 
 	"gorm.io/gorm"
@@ -13,6 +14,38 @@ type AlterColumn struct {
 	Type      string
 }
 
+type DropConstraint struct {
+	Model      interface{}
+	TableName  string
+	Constraint string
+}
+
+type DropIndex struct {
+	Model interface{}
+	Index string
+}
+
+// BackfillColumn fills a column added to a table from the values its rows already hold elsewhere
+type BackfillColumn struct {
+	TableName string
+	Column    string
+	Value     string // SQL expression the column is set to
+	Where     string // rows to fill, besides those where the column is still null
+}
+
+// EncryptColumn encrypts the plaintext values left in a column from before its field became a common.EncryptedString
+type EncryptColumn struct {
+	TableName string
+	Column    string
+}
+
+func (d *DropConstraint) Drop(db *gorm.DB) error {
+	if !db.Migrator().HasConstraint(d.Model, d.Constraint) {
+		return nil
+	}
+	return db.Migrator().DropConstraint(d.Model, d.Constraint)
+}
+
 func (a *AlterColumn) UpdateColumnType(db *gorm.DB) error {
 	if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", a.TableName, a.Column, a.Type, a.Column, a.Type)).Error; err != nil {
 		return err
@@ -24,3 +57,49 @@ func (a *AlterColumn) UpdateColumnType(db *gorm.DB) error {
 
 	return nil
 }
+
+// Backfill only sets the column where it is null, so it is safe to run on every start
+func (b *BackfillColumn) Backfill(db *gorm.DB) error {
+	query := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", b.TableName, b.Column, b.Value, b.Column)
+	if b.Where != "" {
+		query += " AND " + b.Where
+	}
+	return db.Exec(query).Error
+}
+
+func (d *DropIndex) Drop(db *gorm.DB) error {
+	if !db.Migrator().HasIndex(d.Model, d.Index) {
+		return nil
+	}
+	return db.Migrator().DropIndex(d.Model, d.Index)
+}
+
+// Encrypt rewrites every value that does not decrypt yet, so it is safe to run on every start
+func (e *EncryptColumn) Encrypt(db *gorm.DB) error {
+	var rows []map[string]interface{}
+	if err := db.Table(e.TableName).
+		Select("id, " + e.Column + " AS value").
+		Where(e.Column + " IS NOT NULL AND " + e.Column + " <> ''").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		value, ok := row["value"].(string)
+		if !ok {
+			continue
+		}
+		if _, err := common.DecryptAES(value); err == nil {
+			continue
+		}
+
+		encrypted, err := common.EncryptAES(value)
+		if err != nil {
+			return err
+		}
+		if err := db.Table(e.TableName).Where("id = ?", row["id"]).Update(e.Column, encrypted).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,6 +10,19 @@ func AuthMigrationModels() []interface{} {
 		&models.Invoice{},
 		&models.AccessToken{},
 		&models.TokenManager{},
+		&models.IRNRegistry{},
+		&models.InvoiceSeries{},
+		&models.ReleasedInvoiceNumber{},
+		&models.Product{},
+		&models.Customer{},
+		&models.ImportMapping{},
+		&models.InvoiceDelivery{},
+		&models.InvoiceExport{},
+		&models.InvoiceImport{},
+		&models.InvoiceAttachment{},
+		&models.PlatformSync{},
+		&models.PlatformMapping{},
+		&models.StatusWriteBack{},
 	}
 
 }
@@ -17,3 +30,32 @@ func AuthMigrationModels() []interface{} {
 func AlterColumnModels() []AlterColumn {
 	return []AlterColumn{}
 }
+
+func DropConstraintModels() []DropConstraint {
+	return []DropConstraint{
+		// invoice numbers used to be unique across all businesses
+		{Model: &models.Invoice{}, TableName: "invoices", Constraint: "invoices_invoice_number_key"},
+		{Model: &models.Invoice{}, TableName: "invoices", Constraint: "uni_invoices_invoice_number"},
+	}
+}
+
+func DropIndexModels() []DropIndex {
+	return []DropIndex{
+		// refresh tokens are encrypted with a random nonce, so an index on them is never used
+		{Model: &models.TokenManager{}, Index: "idx_token_managers_refresh_token"},
+	}
+}
+
+func BackfillColumnModels() []BackfillColumn {
+	return []BackfillColumn{
+		// invoices uploaded through the API were filtered on the issue date in their invoice data before the column existed
+		{TableName: "invoices", Column: "issue_date", Value: "invoice_data->>'issue_date'", Where: "platform = 'internal'"},
+	}
+}
+
+func EncryptColumnModels() []EncryptColumn {
+	return []EncryptColumn{
+		{TableName: "token_managers", Column: "access_token"},
+		{TableName: "token_managers", Column: "refresh_token"},
+	}
+}
@@ -7,6 +7,7 @@ type CreateUserRequestModel struct {
 	CompanyName     string              `json:"company_name" validate:"required"`
 	TIN             string              `json:"tin" validate:"required"`
 	PhoneNumber     string              `json:"phone_number" validate:"required"`
+	BusinessID      string              `json:"business_id" validate:"required,uuid"`
 	PlatformConfigs PlatformConfigsAuth `json:"platform_configs" validate:"dive"`
 }
 
@@ -41,8 +42,11 @@ type LoginResponse struct {
 type PlatformConfigsAuth map[string]AccountingPlatformConfigAuth
 type AccountingPlatformConfigAuth struct {
 	OrgID      string `json:"org_id"`
+	BaseURL    string `json:"base_url" validate:"omitempty,url"`
+	Username   string `json:"username"`
 	AuthToken  string `json:"auth_token"`
 	HMACSecret string `json:"hmac_secret"`
 	APIKey     string `json:"api_key"`
 	APISecret  string `json:"api_secret"`
+	AttachPDF  bool   `json:"attach_pdf"`
 }
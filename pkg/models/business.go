@@ -3,3 +3,29 @@ package models
 type UpdateBusinessIDRequest struct {
 	BusinessID string `json:"business_id" validate:"required,uuid"`
 }
+
+type UpdateIRNTemplateRequest struct {
+	IRNTemplate string `json:"irn_template" validate:"required,max=100"`
+}
+
+type UpdateSupplierProfileRequest struct {
+	CompanyName         string `json:"company_name" validate:"required,max=250"`
+	TIN                 string `json:"tin" validate:"required,max=20"`
+	PhoneNumber         string `json:"phone_number" validate:"omitempty,max=13"`
+	Email               string `json:"email" validate:"omitempty,email,max=100"`
+	BusinessDescription string `json:"business_description" validate:"max=1000"`
+	StreetName          string `json:"street_name" validate:"required,max=250"`
+	CityName            string `json:"city_name" validate:"required,max=100"`
+	PostalZone          string `json:"postal_zone" validate:"max=20"`
+	Country             string `json:"country" validate:"required,len=2"`
+	BankName            string `json:"bank_name" validate:"max=100"`
+	BankAccountName     string `json:"bank_account_name" validate:"max=250"`
+	BankAccountNumber   string `json:"bank_account_number" validate:"omitempty,numeric,max=20"`
+	PaymentMeansCode    string `json:"payment_means_code" validate:"omitempty,max=4"`
+	PaymentTermsNote    string `json:"payment_terms_note" validate:"max=1000"`
+}
+
+type UpdatePeppolSettingsRequest struct {
+	SupportPeppol  bool   `json:"support_peppol"`
+	PeppolEndpoint string `json:"peppol_endpoint" validate:"required_if=SupportPeppol true,max=100"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Customer is a buyer of a business keyed by its ID on the accounting platform
+type Customer struct {
+	ID                 string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID         string         `gorm:"column:business_id;type:uuid;not null;uniqueIndex:idx_business_platform_customer" json:"business_id"`
+	Platform           string         `gorm:"column:platform;type:varchar(20);not null;uniqueIndex:idx_business_platform_customer" json:"platform"`
+	PlatformCustomerID string         `gorm:"column:platform_customer_id;type:varchar(100);not null;uniqueIndex:idx_business_platform_customer" json:"platform_customer_id"`
+	Name               string         `gorm:"column:name;type:varchar(250);not null" json:"name"`
+	TIN                string         `gorm:"column:tin;type:varchar(50);not null" json:"tin"`
+	Email              string         `gorm:"column:email;type:varchar(100)" json:"email"`
+	Telephone          string         `gorm:"column:telephone;type:varchar(20)" json:"telephone"`
+	StreetName         string         `gorm:"column:street_name;type:varchar(250)" json:"street_name"`
+	CityName           string         `gorm:"column:city_name;type:varchar(100)" json:"city_name"`
+	PostalZone         string         `gorm:"column:postal_zone;type:varchar(20)" json:"postal_zone"`
+	Country            string         `gorm:"column:country;type:varchar(2)" json:"country"`
+	PeppolEndpoint     string         `gorm:"column:peppol_endpoint;type:varchar(100)" json:"peppol_endpoint"`
+	TINVerified        bool           `gorm:"column:tin_verified;default:false" json:"tin_verified"`
+	TINVerifiedAt      *time.Time     `gorm:"column:tin_verified_at" json:"tin_verified_at"`
+	LastAttemptAt      *time.Time     `gorm:"column:last_attempt_at;index" json:"last_attempt_at"` // the last TIN check, whatever its outcome
+	VerificationError  string         `gorm:"column:verification_error;type:text" json:"verification_error,omitempty"`
+	CreatedAt          time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type CustomerRequest struct {
+	Platform           string `json:"platform" validate:"required,max=20"`
+	PlatformCustomerID string `json:"platform_customer_id" validate:"required,max=100"`
+	Name               string `json:"name" validate:"required,max=250"`
+	TIN                string `json:"tin" validate:"required,max=50"`
+	Email              string `json:"email" validate:"omitempty,email"`
+	Telephone          string `json:"telephone" validate:"max=20"`
+	StreetName         string `json:"street_name" validate:"max=250"`
+	CityName           string `json:"city_name" validate:"max=100"`
+	PostalZone         string `json:"postal_zone" validate:"max=20"`
+	Country            string `json:"country" validate:"omitempty,len=2"`
+	PeppolEndpoint     string `json:"peppol_endpoint" validate:"omitempty,max=100,contains=:"`
+}
+
+type ImportCustomersRequest struct {
+	Customers []CustomerRequest `json:"customers" validate:"required,min=1,max=500,dive"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (c *Customer) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -23,9 +23,13 @@ type Business struct {
 	APIKeyHash      string                 `gorm:"type:text;index" json:"-"`
 	BusinessID      string                 `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
 	ServiceID       string                 `gorm:"column:service_id;type:varchar(20);not null;index" json:"service_id"`
+	IRNTemplate     string                 `gorm:"column:irn_template;type:varchar(100)" json:"irn_template"`
 	TIN             string                 `gorm:"column:tin;type:varchar(20)" json:"tin"`
 	PhoneNumber     string                 `gorm:"column:phone_number;type:varchar(13)" json:"phone_number"`
 	CompanyName     string                 `gorm:"column:company_name;type:varchar(250)" json:"company_name"`
+	SupplierProfile SupplierProfile        `gorm:"embedded;embeddedPrefix:supplier_" json:"supplier_profile"`
+	SupportPeppol   bool                   `gorm:"column:support_peppol;default:false" json:"support_peppol"`
+	PeppolEndpoint  string                 `gorm:"column:peppol_endpoint;type:varchar(100)" json:"peppol_endpoint"`
 	PlatformConfigs PlatformConfigs        `gorm:"type:jsonb;not null;default:'{}'" json:"platform_configs"`
 	Invoices        []Invoice              `gorm:"foreignKey:BusinessID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"invoices"`
 	CreatedAt       time.Time              `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
@@ -33,16 +37,34 @@ type Business struct {
 	DeletedAt       gorm.DeletedAt         `gorm:"index" json:"-"`
 }
 
+// SupplierProfile holds the details sent as the accounting supplier party on every invoice of a business
+type SupplierProfile struct {
+	Email               string `gorm:"column:email;type:varchar(100)" json:"email"`
+	BusinessDescription string `gorm:"column:business_description;type:text" json:"business_description"`
+	StreetName          string `gorm:"column:street_name;type:varchar(250)" json:"street_name"`
+	CityName            string `gorm:"column:city_name;type:varchar(100)" json:"city_name"`
+	PostalZone          string `gorm:"column:postal_zone;type:varchar(20)" json:"postal_zone"`
+	Country             string `gorm:"column:country;type:varchar(2)" json:"country"`
+	BankName            string `gorm:"column:bank_name;type:varchar(100)" json:"bank_name"`
+	BankAccountName     string `gorm:"column:bank_account_name;type:varchar(250)" json:"bank_account_name"`
+	BankAccountNumber   string `gorm:"column:bank_account_number;type:varchar(20)" json:"bank_account_number"`
+	PaymentMeansCode    string `gorm:"column:payment_means_code;type:varchar(4)" json:"payment_means_code"`
+	PaymentTermsNote    string `gorm:"column:payment_terms_note;type:text" json:"payment_terms_note"`
+}
+
 // PlatformConfigs represents the structure for platform-specific configurations
 type PlatformConfigs map[string]AccountingPlatformConfig
 
 // AccountingPlatformConfig holds configuration for a single accounting platform
 type AccountingPlatformConfig struct {
 	OrgID      string                 `json:"org_id"`
+	BaseURL    string                 `json:"base_url,omitempty"` // instance of self-hosted platforms such as Odoo
+	Username   string                 `json:"username,omitempty"` // login the API key belongs to, for platforms without OAuth
 	AuthToken  common.EncryptedString `json:"auth_token"`
 	HMACSecret common.EncryptedString `json:"hmac_secret"`
 	APIKey     common.EncryptedString `json:"api_key"`
 	APISecret  common.EncryptedString `json:"api_secret"`
+	AttachPDF  bool                   `json:"attach_pdf"` // also upload the FIRS invoice PDF next to the QR code
 }
 
 // BeforeCreate sets the ID if not provided
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invoice level import fields, repeated on every line item row of the same invoice
+const (
+	ImportFieldInvoiceNumber      = "invoice_number"
+	ImportFieldIssueDate          = "issue_date"
+	ImportFieldDueDate            = "due_date"
+	ImportFieldIssueTime          = "issue_time"
+	ImportFieldInvoiceTypeCode    = "invoice_type_code"
+	ImportFieldCurrency           = "document_currency_code"
+	ImportFieldPaymentStatus      = "payment_status"
+	ImportFieldNote               = "note"
+	ImportFieldCustomerName       = "customer_name"
+	ImportFieldCustomerTIN        = "customer_tin"
+	ImportFieldCustomerEmail      = "customer_email"
+	ImportFieldCustomerTelephone  = "customer_telephone"
+	ImportFieldCustomerStreet     = "customer_street_name"
+	ImportFieldCustomerCity       = "customer_city_name"
+	ImportFieldCustomerPostalZone = "customer_postal_zone"
+	ImportFieldCustomerCountry    = "customer_country"
+)
+
+// Line item import fields
+const (
+	ImportFieldItemName        = "item_name"
+	ImportFieldItemDescription = "item_description"
+	ImportFieldItemID          = "sellers_item_identification"
+	ImportFieldHSCode          = "hsn_code"
+	ImportFieldProductCategory = "product_category"
+	ImportFieldQuantity        = "invoiced_quantity"
+	ImportFieldUnitPrice       = "price_amount"
+	ImportFieldDiscountAmount  = "discount_amount"
+	ImportFieldTaxCategory     = "tax_category"
+	ImportFieldTaxPercent      = "tax_percent"
+)
+
+// ImportFields lists every field a spreadsheet column can be mapped onto
+var ImportFields = []string{
+	ImportFieldInvoiceNumber, ImportFieldIssueDate, ImportFieldDueDate, ImportFieldIssueTime,
+	ImportFieldInvoiceTypeCode, ImportFieldCurrency, ImportFieldPaymentStatus, ImportFieldNote,
+	ImportFieldCustomerName, ImportFieldCustomerTIN, ImportFieldCustomerEmail, ImportFieldCustomerTelephone,
+	ImportFieldCustomerStreet, ImportFieldCustomerCity, ImportFieldCustomerPostalZone, ImportFieldCustomerCountry,
+	ImportFieldItemName, ImportFieldItemDescription, ImportFieldItemID, ImportFieldHSCode,
+	ImportFieldProductCategory, ImportFieldQuantity, ImportFieldUnitPrice, ImportFieldDiscountAmount,
+	ImportFieldTaxCategory, ImportFieldTaxPercent,
+}
+
+// RequiredImportFields must be mapped onto a column before a file can be imported
+var RequiredImportFields = []string{
+	ImportFieldInvoiceNumber, ImportFieldIssueDate, ImportFieldCustomerName, ImportFieldCustomerTIN,
+	ImportFieldItemName, ImportFieldHSCode, ImportFieldProductCategory, ImportFieldQuantity, ImportFieldUnitPrice,
+}
+
+// Date formats a mapping can read date columns in. ISO dates are read whatever the format.
+const (
+	ImportDateISO        = "YYYY-MM-DD"
+	ImportDateDayFirst   = "DD/MM/YYYY"
+	ImportDateMonthFirst = "MM/DD/YYYY"
+)
+
+// DefaultImportDateFormat is the format of mappings that do not set one, the day first convention used in Nigeria
+const DefaultImportDateFormat = ImportDateDayFirst
+
+// ImportDateFormats lists the date formats a mapping can use
+var ImportDateFormats = []string{ImportDateISO, ImportDateDayFirst, ImportDateMonthFirst}
+
+// ImportDateLayouts are the layouts each date format is parsed with. Dates with the month spelled out are
+// unambiguous and read in every format.
+var ImportDateLayouts = map[string][]string{
+	ImportDateISO:        {"2006-01-02", "2006/01/02", "02-Jan-2006", "2 Jan 2006"},
+	ImportDateDayFirst:   {"2006-01-02", "02/01/2006", "2/1/2006", "02-01-2006", "02-Jan-2006", "2 Jan 2006"},
+	ImportDateMonthFirst: {"2006-01-02", "01/02/2006", "1/2/2006", "01-02-2006", "Jan 2, 2006", "02-Jan-2006"},
+}
+
+// ImportColumns maps an import field onto the spreadsheet column header it is read from
+type ImportColumns map[string]string
+
+// ImportMapping is the saved column mapping a business uses for spreadsheet imports
+type ImportMapping struct {
+	ID         string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID string         `gorm:"column:business_id;type:uuid;not null;uniqueIndex" json:"business_id"`
+	Columns    ImportColumns  `gorm:"column:columns;type:jsonb;not null" json:"columns"`
+	DateFormat string         `gorm:"column:date_format;type:varchar(20);not null;default:'DD/MM/YYYY'" json:"date_format"`
+	CreatedAt  time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type ImportMappingRequest struct {
+	Columns    ImportColumns `json:"columns" validate:"required,min=1,dive,keys,required,endkeys,required,max=100"`
+	DateFormat string        `json:"date_format" validate:"omitempty,oneof=YYYY-MM-DD DD/MM/YYYY MM/DD/YYYY"`
+}
+
+// DefaultImportMapping reads every field from a column with the same name and dates in the default format
+func DefaultImportMapping(businessID string) *ImportMapping {
+	return &ImportMapping{BusinessID: businessID, Columns: DefaultImportColumns(), DateFormat: DefaultImportDateFormat}
+}
+
+// DefaultImportColumns reads every field from a column with the same name
+func DefaultImportColumns() ImportColumns {
+	columns := make(ImportColumns, len(ImportFields))
+	for _, field := range ImportFields {
+		columns[field] = field
+	}
+	return columns
+}
+
+// BeforeCreate sets the ID if not provided
+func (m *ImportMapping) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface, so ImportColumns can be saved into DB
+func (ic ImportColumns) Value() (driver.Value, error) {
+	if len(ic) == 0 {
+		return "{}", nil
+	}
+	return json.Marshal(ic)
+}
+
+// Scan implements the sql.Scanner interface, so ImportColumns can be read from DB
+func (ic *ImportColumns) Scan(value interface{}) error {
+	if value == nil {
+		*ic = make(ImportColumns)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal ImportColumns: %v", value)
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+
+	*ic = result
+	return nil
+}
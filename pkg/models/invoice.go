@@ -20,22 +20,36 @@ const (
 	StatusConfirmed        = "confirmed_invoice"
 )
 
+// Platform events recorded on the invoice timeline after the processing steps
+const (
+	EventPlatformUpdated = "platform_updated"
+	EventPaymentRecorded = "payment_recorded"
+	EventVoided          = "voided"
+	// EventConversionWarning records a value the converter had to adjust, one entry per warning
+	EventConversionWarning = "conversion_warning"
+)
+
 // StatusHistoryEntry represents one step in the invoice process
 type StatusHistoryEntry struct {
 	Step      string    `json:"step"`
 	Status    string    `json:"status"` // success | pending | failed
 	Timestamp time.Time `json:"timestamp"`
+	Detail    string    `json:"detail,omitempty"`
 }
 
 // Invoice represents an invoice with support for multiple accounting platforms
 type Invoice struct {
 	ID               string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
-	InvoiceNumber    string         `gorm:"column:invoice_number;type:varchar(50);not null;unique;index" json:"invoice_number"`
+	InvoiceNumber    string         `gorm:"column:invoice_number;type:varchar(50);not null;uniqueIndex:idx_business_invoice_number" json:"invoice_number"`
 	IRN              string         `gorm:"column:irn;type:varchar(50);null" json:"irn"`
-	BusinessID       string         `gorm:"column:business_id;type:uuid;not null" json:"business_id"`
+	EncryptedIRN     string         `gorm:"column:encrypted_irn;type:text" json:"encrypted_irn,omitempty"` // base64 payload encoded in the QR code
+	QRCode           string         `gorm:"column:qr_code;type:text" json:"qr_code,omitempty"`             // base64 PNG produced at signing time
+	BusinessID       string         `gorm:"column:business_id;type:uuid;not null;uniqueIndex:idx_business_invoice_number" json:"business_id"`
 	Platform         string         `gorm:"column:platform;type:varchar(20);not null" json:"platform"` // e.g., zoho, quickbooks
 	PlatformMetadata string         `gorm:"type:jsonb;not null;default:'{}'" json:"platform_metadata"`
 	InvoiceData      datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'" json:"invoice_data"`
+	FIRSDocument     datatypes.JSON `gorm:"column:firs_document;type:jsonb" json:"firs_document,omitempty"`       // FIRS document a platform invoice was converted to
+	IssueDate        string         `gorm:"column:issue_date;type:varchar(10);index" json:"issue_date,omitempty"` // YYYY-MM-DD, set once the FIRS document is known
 	CurrentStatus    string         `gorm:"column:current_status;type:varchar(50);not null;default:'created'" json:"current_status"`
 	StatusHistory    datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"status_history"`
 	Timestamp        time.Time      `gorm:"column:timestamp;not null" json:"timestamp"`
@@ -66,6 +80,15 @@ type MinimalInvoiceDTO struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// FIRSData is the FIRS document of the invoice: the invoice data of invoices uploaded through the API, or the
+// document a platform invoice was converted to. It is empty for platform invoices that were not converted yet.
+func (i *Invoice) FIRSData() []byte {
+	if i.Platform == "internal" {
+		return i.InvoiceData
+	}
+	return i.FIRSDocument
+}
+
 // BeforeCreate sets the ID if not provided
 func (i *Invoice) BeforeCreate(tx *gorm.DB) error {
 	if i.ID == "" {
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	AttachmentQRCode     = "qr_code"
+	AttachmentInvoicePDF = "invoice_pdf"
+
+	AttachmentPending = "pending"
+	AttachmentSent    = "sent"
+	AttachmentFailed  = "failed"
+)
+
+// InvoiceAttachment tracks a file uploaded to the invoice on the accounting platform it came from
+type InvoiceAttachment struct {
+	ID                string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID        string         `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	InvoiceID         string         `gorm:"column:invoice_id;type:uuid;not null;uniqueIndex:idx_invoice_attachments_invoice_kind" json:"invoice_id"`
+	Kind              string         `gorm:"column:kind;type:varchar(20);not null;uniqueIndex:idx_invoice_attachments_invoice_kind" json:"kind"`
+	Platform          string         `gorm:"column:platform;type:varchar(50);not null" json:"platform"`
+	OrgID             string         `gorm:"column:org_id;type:varchar(100);not null" json:"org_id"`
+	ExternalInvoiceID string         `gorm:"column:external_invoice_id;type:varchar(100);not null" json:"external_invoice_id"`
+	IRN               string         `gorm:"column:irn;type:varchar(100);not null" json:"irn"`
+	Filename          string         `gorm:"column:filename;type:varchar(250);not null" json:"filename"`
+	Document          datatypes.JSON `gorm:"column:document;type:jsonb;not null;default:'{}'" json:"-"` // FIRS invoice the PDF is rendered from
+	Status            string         `gorm:"column:status;type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts          int            `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError         string         `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	NextAttemptAt     *time.Time     `gorm:"column:next_attempt_at;index" json:"next_attempt_at"`
+	SentAt            *time.Time     `gorm:"column:sent_at" json:"sent_at"`
+	CreatedAt         time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (a *InvoiceAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
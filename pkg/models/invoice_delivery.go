@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliveryBounced = "bounced"
+)
+
+// InvoiceDelivery tracks the email that sends a confirmed invoice to the buyer
+type InvoiceDelivery struct {
+	ID            string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID    string         `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	InvoiceID     string         `gorm:"column:invoice_id;type:uuid;not null;uniqueIndex" json:"invoice_id"`
+	Recipient     string         `gorm:"column:recipient;type:varchar(100)" json:"recipient"`
+	Document      datatypes.JSON `gorm:"column:document;type:jsonb;not null;default:'{}'" json:"-"` // FIRS invoice the email is rendered from
+	Status        string         `gorm:"column:status;type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts      int            `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError     string         `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	MessageID     string         `gorm:"column:message_id;type:varchar(250)" json:"message_id,omitempty"`
+	NextAttemptAt *time.Time     `gorm:"column:next_attempt_at;index" json:"next_attempt_at"`
+	SentAt        *time.Time     `gorm:"column:sent_at" json:"sent_at"`
+	BouncedAt     *time.Time     `gorm:"column:bounced_at" json:"bounced_at"`
+	CreatedAt     time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type ResendDeliveryRequest struct {
+	Recipient string `json:"recipient" validate:"omitempty,email"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (d *InvoiceDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+	ExportExpired    = "expired"
+)
+
+// InvoiceExport is a ZIP archive of every invoice a business issued in a period, built in the background
+type InvoiceExport struct {
+	ID           string                      `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID   string                      `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	FromDate     string                      `gorm:"column:from_date;type:varchar(10);not null" json:"from_date"`
+	ToDate       string                      `gorm:"column:to_date;type:varchar(10);not null" json:"to_date"`
+	Statuses     datatypes.JSONSlice[string] `gorm:"column:statuses;type:jsonb;not null;default:'[]'" json:"statuses"` // empty exports every status
+	Status       string                      `gorm:"column:status;type:varchar(20);not null;default:'pending';index" json:"status"`
+	InvoiceCount int                         `gorm:"column:invoice_count;not null;default:0" json:"invoice_count"`
+	FilePath     string                      `gorm:"column:file_path;type:text" json:"-"`
+	FileSize     int64                       `gorm:"column:file_size;not null;default:0" json:"file_size"`
+	Checksum     string                      `gorm:"column:checksum;type:varchar(64)" json:"checksum,omitempty"` // sha256 of the archive
+	Error        string                      `gorm:"column:error;type:text" json:"error,omitempty"`
+	CompletedAt  *time.Time                  `gorm:"column:completed_at" json:"completed_at"`
+	ExpiresAt    *time.Time                  `gorm:"column:expires_at;index" json:"expires_at"`
+	CreatedAt    time.Time                   `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time                   `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt              `gorm:"index" json:"-"`
+}
+
+type CreateExportRequest struct {
+	BusinessID string   `json:"business_id" validate:"omitempty,uuid"`
+	FromDate   string   `json:"from_date" validate:"required,datetime=2006-01-02"`
+	ToDate     string   `json:"to_date" validate:"required,datetime=2006-01-02"`
+	Statuses   []string `json:"statuses" validate:"omitempty,dive,oneof=created generated_irn validated_irn signed_irn validated_invoice signed_invoice transmitted_invoice confirmed_invoice"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (e *InvoiceExport) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportPending    = "pending"
+	ImportProcessing = "processing"
+	ImportCompleted  = "completed"
+)
+
+// InvoiceImport is a spreadsheet import. The file is read and checked when it is uploaded, the invoices that pass
+// are submitted to FIRS in the background.
+type InvoiceImport struct {
+	ID          string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID  string         `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	Filename    string         `gorm:"column:filename;type:varchar(255)" json:"filename"`
+	Status      string         `gorm:"column:status;type:varchar(20);not null;default:'pending';index" json:"status"`
+	Result      datatypes.JSON `gorm:"column:result;type:jsonb;not null;default:'{}'" json:"result"` // outcome of every row and invoice of the file
+	Pending     datatypes.JSON `gorm:"column:pending;type:jsonb;not null;default:'[]'" json:"-"`     // prepared invoices still to be submitted
+	Error       string         `gorm:"column:error;type:text" json:"error,omitempty"`
+	CompletedAt *time.Time     `gorm:"column:completed_at" json:"completed_at"`
+	CreatedAt   time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (i *InvoiceImport) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
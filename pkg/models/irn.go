@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultIRNTemplate is used for businesses that have not configured their own FIRS IRN template
+const DefaultIRNTemplate = "{{invoice_number}}-{{service_id}}-{{YYYYMMDD}}"
+
+// IRNRegistry keeps every IRN issued by the access point so the same IRN is never handed out twice
+type IRNRegistry struct {
+	ID            string    `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	IRN           string    `gorm:"column:irn;type:varchar(50);not null;uniqueIndex" json:"irn"`
+	BusinessID    string    `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	InvoiceNumber string    `gorm:"column:invoice_number;type:varchar(50);not null" json:"invoice_number"`
+	CreatedAt     time.Time `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (r *IRNRegistry) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
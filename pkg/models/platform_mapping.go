@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultInvoiceTypeCode is the type code used when a mapping sets none, the code platform invoices were
+// always sent to FIRS with
+const DefaultInvoiceTypeCode = "381"
+
+// CreditNoteTypeCode is the UNCL1001 code FIRS expects on credit notes
+const CreditNoteTypeCode = "381"
+
+// TaxCategoryMapping is the FIRS tax category a platform tax is reported under
+type TaxCategoryMapping struct {
+	Category string   `json:"category"`
+	Percent  *float64 `json:"percent,omitempty"` // overrides the rate applied on the platform
+}
+
+// TaxMappings maps a platform tax ID onto its FIRS tax category
+type TaxMappings map[string]TaxCategoryMapping
+
+// PaymentMeansMappings maps a platform payment terms label onto a UNCL4461 payment means code
+type PaymentMeansMappings map[string]string
+
+// PlatformMapping is how a business maps the fields, taxes and payment terms of an accounting platform onto FIRS.
+// Blank custom field names write back to the platform's default fields.
+type PlatformMapping struct {
+	ID              string               `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID      string               `gorm:"column:business_id;type:uuid;not null;uniqueIndex:idx_platform_mappings_business_platform" json:"business_id"`
+	Platform        string               `gorm:"column:platform;type:varchar(50);not null;uniqueIndex:idx_platform_mappings_business_platform" json:"platform"`
+	IRNField        string               `gorm:"column:irn_field;type:varchar(100)" json:"irn_field"`
+	QRField         string               `gorm:"column:qr_field;type:varchar(100)" json:"qr_field"`
+	StatusField     string               `gorm:"column:status_field;type:varchar(100)" json:"status_field"`
+	ErrorField      string               `gorm:"column:error_field;type:varchar(100)" json:"error_field"`
+	TaxCategories   TaxMappings          `gorm:"column:tax_categories;type:jsonb;not null" json:"tax_categories"`
+	PaymentMeans    PaymentMeansMappings `gorm:"column:payment_means;type:jsonb;not null" json:"payment_means"`
+	InvoiceTypeCode string               `gorm:"column:invoice_type_code;type:varchar(10)" json:"invoice_type_code"`
+	CreatedAt       time.Time            `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time            `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt       `gorm:"index" json:"-"`
+}
+
+type PlatformMappingRequest struct {
+	IRNField        string               `json:"irn_field" validate:"omitempty,max=100"`
+	QRField         string               `json:"qr_field" validate:"omitempty,max=100"`
+	StatusField     string               `json:"status_field" validate:"omitempty,max=100"`
+	ErrorField      string               `json:"error_field" validate:"omitempty,max=100"`
+	TaxCategories   TaxMappings          `json:"tax_categories" validate:"omitempty,dive,keys,required,max=100,endkeys"`
+	PaymentMeans    PaymentMeansMappings `json:"payment_means" validate:"omitempty,dive,keys,required,max=100,endkeys,required"`
+	InvoiceTypeCode string               `json:"invoice_type_code" validate:"omitempty,max=10"`
+}
+
+// TaxCategory returns the mapping of a platform tax, if any
+func (m *PlatformMapping) TaxCategory(taxID string) (TaxCategoryMapping, bool) {
+	if m == nil || taxID == "" {
+		return TaxCategoryMapping{}, false
+	}
+	tax, ok := m.TaxCategories[taxID]
+	return tax, ok
+}
+
+// PaymentMeansCode returns the payment means code mapped onto a platform payment terms label, if any
+func (m *PlatformMapping) PaymentMeansCode(terms string) (string, bool) {
+	if m == nil || terms == "" {
+		return "", false
+	}
+	code, ok := m.PaymentMeans[terms]
+	return code, ok
+}
+
+// DocumentTypeCode returns the invoice type code of the mapping, or DefaultInvoiceTypeCode
+func (m *PlatformMapping) DocumentTypeCode() string {
+	if m == nil || m.InvoiceTypeCode == "" {
+		return DefaultInvoiceTypeCode
+	}
+	return m.InvoiceTypeCode
+}
+
+// BeforeCreate sets the ID if not provided
+func (m *PlatformMapping) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface, so TaxMappings can be saved into DB
+func (tm TaxMappings) Value() (driver.Value, error) {
+	if len(tm) == 0 {
+		return "{}", nil
+	}
+	return json.Marshal(tm)
+}
+
+// Scan implements the sql.Scanner interface, so TaxMappings can be read from DB
+func (tm *TaxMappings) Scan(value interface{}) error {
+	if value == nil {
+		*tm = make(TaxMappings)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal TaxMappings: %v", value)
+	}
+
+	var result map[string]TaxCategoryMapping
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+
+	*tm = result
+	return nil
+}
+
+// Value implements the driver.Valuer interface, so PaymentMeansMappings can be saved into DB
+func (pm PaymentMeansMappings) Value() (driver.Value, error) {
+	if len(pm) == 0 {
+		return "{}", nil
+	}
+	return json.Marshal(pm)
+}
+
+// Scan implements the sql.Scanner interface, so PaymentMeansMappings can be read from DB
+func (pm *PaymentMeansMappings) Scan(value interface{}) error {
+	if value == nil {
+		*pm = make(PaymentMeansMappings)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal PaymentMeansMappings: %v", value)
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+
+	*pm = result
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	BackfillPending = "pending"
+	BackfillDone    = "done"
+	BackfillFailed  = "failed"
+)
+
+// PlatformSync is the polling state of an organisation whose invoices are pulled from its accounting platform
+// instead of being pushed by webhooks
+type PlatformSync struct {
+	ID             string                      `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID     string                      `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	Platform       string                      `gorm:"column:platform;type:varchar(50);not null;uniqueIndex:idx_platform_syncs_platform_org" json:"platform"`
+	OrgID          string                      `gorm:"column:org_id;type:varchar(100);not null;uniqueIndex:idx_platform_syncs_platform_org" json:"org_id"`
+	Enabled        bool                        `gorm:"column:enabled;not null;default:false;index" json:"enabled"`
+	Cursor         *time.Time                  `gorm:"column:cursor_at" json:"cursor"` // last modified time of the newest invoice pulled
+	LastSyncAt     *time.Time                  `gorm:"column:last_sync_at" json:"last_sync_at"`
+	LastError      string                      `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	Processed      int                         `gorm:"column:processed;not null;default:0" json:"processed"`
+	FailedInvoice  string                      `gorm:"column:failed_invoice;type:varchar(100)" json:"failed_invoice,omitempty"` // number of the failed invoice holding the cursor
+	FailedAttempts int                         `gorm:"column:failed_attempts;not null;default:0" json:"failed_attempts,omitempty"`
+	Skipped        datatypes.JSONSlice[string] `gorm:"column:skipped;type:jsonb;not null;default:'[]'" json:"skipped"` // numbers of the invoices the cursor was moved past after failing too often
+	BackfillFrom   string                      `gorm:"column:backfill_from;type:varchar(10)" json:"backfill_from,omitempty"`
+	BackfillTo     string                      `gorm:"column:backfill_to;type:varchar(10)" json:"backfill_to,omitempty"`
+	BackfillStatus string                      `gorm:"column:backfill_status;type:varchar(20)" json:"backfill_status,omitempty"`
+	BackfillPage   int                         `gorm:"column:backfill_page;not null;default:0" json:"backfill_page,omitempty"`
+	LockedUntil    *time.Time                  `gorm:"column:locked_until" json:"-"`
+	CreatedAt      time.Time                   `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time                   `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt      gorm.DeletedAt              `gorm:"index" json:"-"`
+}
+
+type UpdateSyncRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+type BackfillRequest struct {
+	FromDate string `json:"from_date" validate:"required,datetime=2006-01-02"`
+	ToDate   string `json:"to_date" validate:"required,datetime=2006-01-02"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (s *PlatformSync) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Product maps an item of an accounting platform onto the FIRS product classification of a business
+type Product struct {
+	ID              string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID      string         `gorm:"column:business_id;type:uuid;not null;uniqueIndex:idx_business_platform_item" json:"business_id"`
+	Platform        string         `gorm:"column:platform;type:varchar(20);not null;uniqueIndex:idx_business_platform_item" json:"platform"`
+	PlatformItemID  string         `gorm:"column:platform_item_id;type:varchar(100);not null;uniqueIndex:idx_business_platform_item" json:"platform_item_id"`
+	Name            string         `gorm:"column:name;type:varchar(250)" json:"name"`
+	HSCode          string         `gorm:"column:hs_code;type:varchar(20);not null" json:"hs_code"`
+	ProductCategory string         `gorm:"column:product_category;type:varchar(100);not null" json:"product_category"`
+	UnitOfMeasure   string         `gorm:"column:unit_of_measure;type:varchar(20)" json:"unit_of_measure"`
+	TaxCategory     string         `gorm:"column:tax_category;type:varchar(50)" json:"tax_category"`
+	TaxPercent      float64        `gorm:"column:tax_percent;type:numeric(5,2);default:0" json:"tax_percent"`
+	CreatedAt       time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type ProductRequest struct {
+	Platform        string  `json:"platform" validate:"required,max=20"`
+	PlatformItemID  string  `json:"platform_item_id" validate:"required,max=100"`
+	Name            string  `json:"name" validate:"max=250"`
+	HSCode          string  `json:"hs_code" validate:"required,max=20"`
+	ProductCategory string  `json:"product_category" validate:"required,max=100"`
+	UnitOfMeasure   string  `json:"unit_of_measure" validate:"max=20"`
+	TaxCategory     string  `json:"tax_category" validate:"max=50"`
+	TaxPercent      float64 `json:"tax_percent" validate:"gte=0,lte=100"`
+}
+
+type ImportProductsRequest struct {
+	Products []ProductRequest `json:"products" validate:"required,min=1,dive"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (p *Product) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const DefaultInvoiceSeries = "default"
+
+// InvoiceSeries holds the numbering configuration and counter for one invoice number series of a business
+type InvoiceSeries struct {
+	ID          string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID  string         `gorm:"column:business_id;type:uuid;not null;uniqueIndex:idx_business_series" json:"business_id"`
+	Name        string         `gorm:"column:name;type:varchar(50);not null;uniqueIndex:idx_business_series" json:"name"`
+	Prefix      string         `gorm:"column:prefix;type:varchar(10);not null;default:'INV'" json:"prefix"`
+	BranchCode  string         `gorm:"column:branch_code;type:varchar(10)" json:"branch_code"`
+	Padding     int            `gorm:"column:padding;type:int;not null;default:5" json:"padding"`
+	ResetYearly bool           `gorm:"column:reset_yearly;type:bool;not null;default:false" json:"reset_yearly"`
+	CurrentYear int            `gorm:"column:current_year;type:int;not null;default:0" json:"current_year"`
+	NextValue   int64          `gorm:"column:next_value;type:bigint;not null;default:1" json:"next_value"`
+	CreatedAt   time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ReleasedInvoiceNumber is a number of a series that was handed out but never used by an invoice. Released numbers
+// are handed out again before the counter moves on, so the series stays free of gaps.
+type ReleasedInvoiceNumber struct {
+	ID        string    `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	SeriesID  string    `gorm:"column:series_id;type:uuid;not null;uniqueIndex:idx_series_released_value" json:"series_id"`
+	Value     int64     `gorm:"column:value;type:bigint;not null;uniqueIndex:idx_series_released_value" json:"value"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+}
+
+type InvoiceSeriesRequest struct {
+	Name        string `json:"name" validate:"required,alphanum,max=50"`
+	Prefix      string `json:"prefix" validate:"required,alphanum,max=10"`
+	BranchCode  string `json:"branch_code" validate:"omitempty,alphanum,max=10"`
+	Padding     int    `json:"padding" validate:"omitempty,gte=1,lte=12"`
+	ResetYearly bool   `json:"reset_yearly"`
+	StartValue  int64  `json:"start_value" validate:"gte=0"`
+}
+
+type ReserveInvoiceNumbersRequest struct {
+	Count int `json:"count" validate:"required,gte=1,lte=500"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (s *InvoiceSeries) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// BeforeCreate sets the ID if not provided
+func (r *ReleasedInvoiceNumber) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
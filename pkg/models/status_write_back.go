@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	WriteBackPending = "pending"
+	WriteBackSent    = "sent"
+	WriteBackFailed  = "failed"
+)
+
+// StatusWriteBack is the outbox entry carrying the latest FIRS processing status of an invoice back to the platform
+// it came from. Every status change replaces the entry and bumps its revision, so only the newest state is sent.
+type StatusWriteBack struct {
+	ID                string         `gorm:"type:uuid;primaryKey;unique;not null" json:"id"`
+	BusinessID        string         `gorm:"column:business_id;type:uuid;not null;index" json:"business_id"`
+	InvoiceID         string         `gorm:"column:invoice_id;type:uuid;not null;uniqueIndex" json:"invoice_id"`
+	Platform          string         `gorm:"column:platform;type:varchar(50);not null" json:"platform"`
+	ExternalInvoiceID string         `gorm:"column:external_invoice_id;type:varchar(100);not null" json:"external_invoice_id"`
+	FirsStatus        string         `gorm:"column:firs_status;type:varchar(100);not null" json:"firs_status"` // step:status, e.g. transmitted_invoice:failed
+	FirsError         string         `gorm:"column:firs_error;type:text" json:"firs_error,omitempty"`
+	Revision          int            `gorm:"column:revision;not null;default:1" json:"revision"`
+	Status            string         `gorm:"column:status;type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts          int            `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError         string         `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	NextAttemptAt     *time.Time     `gorm:"column:next_attempt_at;index" json:"next_attempt_at"`
+	SentAt            *time.Time     `gorm:"column:sent_at" json:"sent_at"`
+	CreatedAt         time.Time      `gorm:"column:created_at;not null;autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"column:updated_at;null;autoUpdateTime" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate sets the ID if not provided
+func (w *StatusWriteBack) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = uuid.New().String()
+	}
+	return nil
+}
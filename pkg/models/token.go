@@ -1,18 +1,25 @@
 package models
 
 import (
+	"einvoice-access-point/pkg/common"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// TokenManager holds the OAuth tokens of an accounting platform organisation
 type TokenManager struct {
-	ID             uint      `gorm:"primaryKey"`
-	Provider       string    `gorm:"not null;index:idx_provider_org,unique"`
-	OrganizationID string    `gorm:"not null;index:idx_provider_org,unique"`
-	RefreshToken   string    `gorm:"not null;index"`
-	AccessToken    string    `gorm:"not null"`
-	ExpiresAt      time.Time `gorm:"not null"`
+	ID             uint                   `gorm:"primaryKey"`
+	Provider       string                 `gorm:"not null;index:idx_provider_org,unique"`
+	OrganizationID string                 `gorm:"not null;index:idx_provider_org,unique"`
+	BusinessID     string                 `gorm:"type:varchar(36);index"`
+	RefreshToken   common.EncryptedString `gorm:"type:text;not null" json:"-"`
+	AccessToken    common.EncryptedString `gorm:"type:text;not null" json:"-"`
+	ExpiresAt      time.Time              `gorm:"not null"`
+	AccountsServer string                 `gorm:"type:varchar(100)"`      // OAuth server of the organisation's datacenter
+	APIDomain      string                 `gorm:"type:varchar(100)"`      // API host of the organisation's datacenter
+	NeedsReconnect bool                   `gorm:"not null;default:false"` // set when the platform rejects the refresh token
+	ReconnectError string                 `gorm:"type:varchar(250)"`
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	DeletedAt      gorm.DeletedAt `gorm:"index"`
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"strconv"
 )
 
@@ -22,6 +24,14 @@ type RequestConfig struct {
 	Body    interface{}
 }
 
+// MultipartFile is a file sent as one part of a multipart/form-data body
+type MultipartFile struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
 type Response struct {
 	StatusCode int
 	Body       []byte
@@ -86,6 +96,52 @@ func PostRequest(client HTTPClient, config RequestConfig, response interface{})
 	return doRequest(client, req, config.Headers, response)
 }
 
+// PostMultipartRequest posts the fields and the file as multipart/form-data, config.Body is ignored
+func PostMultipartRequest(client HTTPClient, config RequestConfig, fields map[string]string, file MultipartFile, response interface{}) (*Response, error) {
+	return PostMultipartFilesRequest(client, config, fields, []MultipartFile{file}, response)
+}
+
+// PostMultipartFilesRequest posts the fields and every file as parts of one multipart/form-data body
+func PostMultipartFilesRequest(client HTTPClient, config RequestConfig, fields map[string]string, files []MultipartFile, response interface{}) (*Response, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range files {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, file.Field, file.Filename))
+		header.Set("Content-Type", file.ContentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(file.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, config.URL, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	headers["Content-Type"] = writer.FormDataContentType()
+
+	return doRequest(client, req, headers, response)
+}
+
 func PutRequest(client HTTPClient, config RequestConfig, response interface{}) (*Response, error) {
 	body, err := json.Marshal(config.Body)
 	if err != nil {
@@ -100,6 +156,22 @@ func PutRequest(client HTTPClient, config RequestConfig, response interface{}) (
 	return doRequest(client, req, config.Headers, response)
 }
 
+// PutBytesRequest puts the data as the raw request body with its content type
+func PutBytesRequest(client HTTPClient, config RequestConfig, contentType string, data []byte, response interface{}) (*Response, error) {
+	req, err := http.NewRequest(http.MethodPut, config.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	headers["Content-Type"] = contentType
+
+	return doRequest(client, req, headers, response)
+}
+
 func PatchRequest(client HTTPClient, config RequestConfig, response interface{}) (*Response, error) {
 	body, err := json.Marshal(config.Body)
 	if err != nil {
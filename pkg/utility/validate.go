@@ -64,7 +64,7 @@ func VerifyWebhookSignature(body []byte, secret, signature string) bool {
 	hash := hmac.New(sha256.New, []byte(secret))
 	hash.Write(body)
 	expected := hex.EncodeToString(hash.Sum(nil))
-	return signature == expected
+	return hmac.Equal([]byte(signature), []byte(expected))
 }
 
 func DecodeJSONWithDefaults(r io.Reader, v interface{}) error {